@@ -0,0 +1,530 @@
+// Package aof keeps an append-only log of the writes made to a store, so that
+// the writes since the last snapshot survive a restart.
+//
+// The log starts with the 4 byte magic "CAOF" and a big endian uint32 format
+// version, followed by records:
+//
+//	length   big endian uint32, length of the payload
+//	checksum big endian uint32, CRC-32C of the payload
+//	payload  operation (1 byte: 1 put, 2 put of an expiring entry, 3 delete,
+//	         4 and 5 puts like 1 and 2 that carry the entry's metadata)
+//	         key length (uvarint) and key
+//	         value length (uvarint) and value, for puts
+//	         expiration seconds (varint) and nanoseconds (uvarint) since
+//	         the Unix epoch, for puts of expiring entries
+//	         content type and content encoding, each as a length (uvarint)
+//	         followed by the string, for puts carrying metadata
+//	         creation time, as seconds (varint) and nanoseconds (uvarint)
+//	         since the Unix epoch, for puts carrying metadata
+//	         number of user-defined attributes (uvarint), each as a name and
+//	         a value length-prefixed like the content type, for puts
+//	         carrying metadata
+//
+// Puts record the entry stored rather than the operation that stored it, so
+// replaying a record always has the same outcome. Entry versions and
+// modification times are not recorded.
+package aof
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// ErrFormat is returned when replaying a file that is not a log.
+var ErrFormat = errors.New("aof: not an append-only log")
+
+// CorruptError is returned when replaying a log holding a corrupt record
+// before its end. Records at the end of the log that were not completely
+// written are not corrupt, but torn; see Replay.
+type CorruptError struct {
+	Offset int64 // position of the corrupt record in the log
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("aof: corrupt record at offset %d", e.Offset)
+}
+
+// Version is the format version written by this package. Version 1 logs
+// predate the puts carrying metadata, operations 4 and 5, and are otherwise
+// the same; they are upgraded when opened for appending.
+const Version = 2
+
+var magic = []byte("CAOF")
+
+const headerSize = 8
+
+// SyncPolicy controls how often the log is flushed to disk. Records are
+// handed to the operating system as soon as they are appended, so they
+// survive the process crashing whatever the policy; the policy decides how
+// many survive the machine crashing.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes every record before Commit returns.
+	SyncAlways SyncPolicy = iota
+
+	// SyncEverySecond flushes the log once a second, losing at most about a
+	// second of writes.
+	SyncEverySecond
+
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// SyncPolicies lists the names accepted by ParseSyncPolicy.
+var SyncPolicies = []string{"always", "everysec", "never"}
+
+// ParseSyncPolicy returns the policy with the given name.
+func ParseSyncPolicy(name string) (SyncPolicy, error) {
+	for i, n := range SyncPolicies {
+		if n == name {
+			return SyncPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("aof: unknown sync policy %q", name)
+}
+
+// Log appends the changes made to a store to a file. It is safe for
+// concurrent use.
+type Log struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	policy SyncPolicy
+	size   int64 // bytes in f
+	base   int64 // bytes in f right after it was last compacted
+	dirty  bool  // whether f has been written since it was last synced
+	err    error // first failure writing or syncing f, after which the log is unusable
+
+	compacting bool
+	pending    []byte // records appended while compacting
+
+	buf  []byte // scratch space for encoding records
+	done chan struct{}
+}
+
+// Open opens the log at path for appending, creating it if needed. The log
+// should be replayed first.
+func Open(path string, policy SyncPolicy) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l := &Log{
+		path:   path,
+		f:      f,
+		policy: policy,
+		size:   fi.Size(),
+		base:   fi.Size(),
+		done:   make(chan struct{}),
+	}
+	if l.size == 0 {
+		if err := l.write(header()); err != nil {
+			f.Close()
+			return nil, err
+		}
+		l.base = l.size
+	} else if err := upgrade(path, f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if policy == SyncEverySecond {
+		go l.syncEverySecond()
+	}
+	return l, nil
+}
+
+// upgrade rewrites the format version in the header of the log in f, opened
+// at path for appending, if it is older than Version, since the records of
+// older versions are valid records of the current one.
+func upgrade(path string, f *os.File) error {
+	h := make([]byte, headerSize)
+	if _, err := f.ReadAt(h, 0); err != nil || string(h[:len(magic)]) != string(magic) {
+		// logs without a valid header are reported by Replay
+		return nil
+	}
+	if binary.BigEndian.Uint32(h[len(magic):]) >= Version {
+		return nil
+	}
+
+	// files opened for appending cannot be written at an offset
+	w, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := w.WriteAt(header()[len(magic):], int64(len(magic))); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Sync(); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func header() []byte {
+	b := make([]byte, headerSize)
+	copy(b, magic)
+	binary.BigEndian.PutUint32(b[len(magic):], Version)
+	return b
+}
+
+// Append records ev in the log. Expirations are not recorded, since replaying
+// the put of an expired entry does not restore it; evictions are recorded as
+// deletes. Append can be called from a store.Notifier: it blocks while the
+// record is handed to the operating system, but leaves flushing it to disk to
+// Commit, which can be called once the store has released its locks.
+//
+// Once the log fails to be written, every call returns the error.
+func (l *Log) Append(ev store.Event) error {
+	rec := record{key: ev.Key}
+	switch ev.Type {
+	case store.EventPut, store.EventUpdate:
+		rec.op = opPut
+		rec.entry = ev.Entry
+	case store.EventDelete, store.EventEvict:
+		rec.op = opDelete
+	default:
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return l.err
+	}
+	l.buf = appendRecord(l.buf[:0], rec)
+	if err := l.write(l.buf); err != nil {
+		return err
+	}
+	if l.compacting {
+		l.pending = append(l.pending, l.buf...)
+	}
+	return nil
+}
+
+// Commit returns once the records appended so far are as durable as the
+// policy requires: with SyncAlways, it flushes them to disk unless that has
+// been done since. Writers of the store call it before acknowledging their
+// writes, which lets the records of concurrent writes be flushed together.
+//
+// It returns the first failure to write or flush the log, if any.
+func (l *Log) Commit() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return l.err
+	}
+	if l.policy == SyncAlways {
+		return l.sync()
+	}
+	return nil
+}
+
+// write appends b to the log file. The caller must hold l.mu.
+func (l *Log) write(b []byte) error {
+	n, err := l.f.Write(b)
+	l.size += int64(n)
+	l.dirty = true
+	if err != nil {
+		l.err = err
+	}
+	return err
+}
+
+// sync flushes the log file to disk. The caller must hold l.mu.
+func (l *Log) sync() error {
+	if !l.dirty {
+		return nil
+	}
+	if err := l.f.Sync(); err != nil {
+		l.err = err
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// syncEverySecond flushes the log once a second until it is closed.
+func (l *Log) syncEverySecond() {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			l.mu.Lock()
+			if l.err == nil {
+				l.sync()
+			}
+			l.mu.Unlock()
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Size returns the size of the log in bytes.
+func (l *Log) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.size
+}
+
+// NeedsCompaction reports whether the log has grown to more than twice its
+// size after it was last compacted, and to at least minSize bytes.
+func (l *Log) NeedsCompaction(minSize int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.size >= minSize && l.size > 2*l.base
+}
+
+// Compact rewrites the log as a put of every live entry in s, which must be
+// the store the log records. Writes keep being appended while the entries are
+// written, and are carried over to the rewritten log before it replaces the
+// current one.
+func (l *Log) Compact(s store.Store) (err error) {
+	l.mu.Lock()
+	if l.err != nil || l.compacting {
+		l.mu.Unlock()
+		return l.err
+	}
+	l.compacting = true
+	l.pending = nil
+	l.mu.Unlock()
+
+	dir, base := filepath.Split(l.path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, base+".tmp")
+	if err != nil {
+		l.stopCompacting()
+		return err
+	}
+	defer func() {
+		if err != nil {
+			l.stopCompacting()
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	// collect the entries first so that writers are not held up by the disk
+	var entries []store.KeyEntry
+	s.Range(func(key string, e store.Entry) bool {
+		entries = append(entries, store.KeyEntry{Key: key, Entry: e})
+		return true
+	})
+
+	w := bufio.NewWriter(f)
+	size := int64(headerSize)
+	w.Write(header())
+	var buf []byte
+	for _, ke := range entries {
+		buf = appendRecord(buf[:0], record{op: opPut, key: ke.Key, entry: ke.Entry})
+		w.Write(buf)
+		size += int64(len(buf))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return l.err
+	}
+	if _, err := f.Write(l.pending); err != nil {
+		return err
+	}
+	size += int64(len(l.pending))
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), l.path); err != nil {
+		return err
+	}
+	syncDir(dir)
+
+	l.f.Close()
+	l.f = f
+	l.size = size
+	l.base = size
+	l.dirty = false
+	l.compacting = false
+	l.pending = nil
+	return nil
+}
+
+// stopCompacting abandons a compaction.
+func (l *Log) stopCompacting() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.compacting = false
+	l.pending = nil
+}
+
+// syncDir flushes a directory to disk, so that renames within it survive a
+// crash. Failures are ignored, since some platforms cannot sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
+
+// Close flushes the log to disk and closes it.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	close(l.done)
+	err := l.sync()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Replay restores the entries recorded in the log at path into s, replacing
+// the entries already at their keys, and returns the number of entries
+// restored. Entries that have expired by now are skipped. Records longer than
+// maxRecordSize bytes are taken to be corrupt.
+//
+// A record that was not completely written, as happens when the process
+// crashes in the middle of an append, ends the log: it is truncated there,
+// and the number of bytes discarded is returned. The torn record may be cut
+// short, fail its checksum if it is the last record, or have been replaced
+// with zeros by the file system. A corrupt record followed by more of the log
+// fails the replay with a *CorruptError instead, and leaves the log and s
+// untouched. If there is no log at path, the error satisfies os.IsNotExist.
+func Replay(path string, s store.Store, maxRecordSize int64) (n int, truncated int64, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r := bufio.NewReader(f)
+	var valid int64
+	h := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, h); err != nil {
+		// the log was created but its header never made it to disk
+		return 0, fi.Size(), truncate(f, 0)
+	}
+	if string(h[:len(magic)]) != string(magic) {
+		return 0, 0, ErrFormat
+	}
+	if v := binary.BigEndian.Uint32(h[len(magic):]); v < 1 || v > Version {
+		return 0, 0, fmt.Errorf("aof: unsupported format version %d", v)
+	}
+	valid = headerSize
+
+	entries := make(map[string]store.Entry)
+	for {
+		rec, size, err := readRecord(r, maxRecordSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			torn := err == errIncomplete || valid+size == fi.Size()
+			if !torn {
+				if torn, err = zeros(f, valid); err != nil {
+					return 0, 0, err
+				}
+			}
+			if !torn {
+				return 0, 0, &CorruptError{Offset: valid}
+			}
+			truncated = fi.Size() - valid
+			if err := truncate(f, valid); err != nil {
+				return 0, 0, err
+			}
+			break
+		}
+		valid += size
+
+		if rec.op == opDelete {
+			delete(entries, rec.key)
+		} else {
+			entries[rec.key] = rec.entry
+		}
+	}
+
+	now := time.Now()
+	writes := make([]store.Write, 0, len(entries))
+	for key, e := range entries {
+		if !e.Expired(now) {
+			writes = append(writes, store.Write{Key: key, Entry: e, Mode: store.ModeUpsert})
+		}
+	}
+	for i, res := range store.PutBatch(s, writes) {
+		if res.Err != nil {
+			if err == nil {
+				err = fmt.Errorf("aof: restoring %q: %v", writes[i].Key, res.Err)
+			}
+			continue
+		}
+		n++
+	}
+	return n, truncated, err
+}
+
+// zeros reports whether f only holds zeros from offset on.
+func zeros(f *os.File, offset int64) (bool, error) {
+	r := bufio.NewReader(io.NewSectionReader(f, offset, 1<<63-1-offset))
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if b != 0 {
+			return false, nil
+		}
+	}
+}
+
+// truncate cuts f down to size and flushes it to disk.
+func truncate(f *os.File, size int64) error {
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	return f.Sync()
+}
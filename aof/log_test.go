@@ -0,0 +1,454 @@
+package aof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+const testMaxRecordSize = 1 << 20
+
+func tempLog(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "aof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "cachely.aof"), func() { os.RemoveAll(dir) }
+}
+
+// logged returns a store whose changes are appended to a log opened at path.
+func logged(t *testing.T, path string, policy SyncPolicy) (*store.Memory, *Log) {
+	t.Helper()
+	l, err := Open(path, policy)
+	if err != nil {
+		t.Fatalf("Open: unexpected error: %v", err)
+	}
+	s := store.NewMemory(0, nil)
+	s.Notify(func(ev store.Event) {
+		if err := l.Append(ev); err != nil {
+			t.Errorf("Append(%+v): unexpected error: %v", ev, err)
+		}
+	})
+	return s, l
+}
+
+func mustPut(t *testing.T, s store.Store, key string, value string) {
+	t.Helper()
+	if _, err := s.Put(key, store.Entry{Value: []byte(value)}); err != nil {
+		t.Fatalf("Put(%q): unexpected error: %v", key, err)
+	}
+}
+
+func mustClose(t *testing.T, l *Log) {
+	t.Helper()
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+}
+
+// checkValues checks that s holds exactly the values in want.
+func checkValues(t *testing.T, s store.Store, want map[string]string) {
+	t.Helper()
+	got := make(map[string]string)
+	s.Range(func(key string, e store.Entry) bool {
+		got[key] = string(e.Value)
+		return true
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries: want %v, got %v", want, got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	now := time.Now()
+	s, l := logged(t, path, SyncAlways)
+	mustPut(t, s, "plain", "value")
+	mustPut(t, s, "", "empty key")
+	mustPut(t, s, "deleted", "gone")
+	mustPut(t, s, "updated", "old")
+	if _, err := s.Put("metadata", store.Entry{
+		Value:           []byte("{}"),
+		ExpiresAt:       now.Add(time.Hour),
+		ContentType:     "application/json",
+		ContentEncoding: "gzip",
+		CreatedAt:       time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC),
+		Metadata:        map[string]string{"owner": "me"},
+	}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if _, err := s.Put("expired", store.Entry{Value: []byte("soon"), ExpiresAt: now.Add(-time.Second)}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if _, err := s.Update("updated", func(e store.Entry, ok bool) (store.Entry, error) {
+		e.Value = []byte("new")
+		return e, nil
+	}); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if err := s.Delete("deleted"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if err := l.Commit(); err != nil {
+		t.Fatalf("Commit: unexpected error: %v", err)
+	}
+	mustClose(t, l)
+
+	// entries already in the store are replaced
+	dst := store.NewMemory(0, nil)
+	mustPut(t, dst, "plain", "replaced")
+	n, truncated, err := Replay(path, dst, testMaxRecordSize)
+	if err != nil || n != 4 || truncated != 0 {
+		t.Fatalf("Replay: want 4 entries and nothing truncated, got %d, %d and %v", n, truncated, err)
+	}
+	checkValues(t, dst, map[string]string{
+		"plain":    "value",
+		"":         "empty key",
+		"updated":  "new",
+		"metadata": "{}",
+	})
+	e, err := dst.Get("metadata")
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if !e.ExpiresAt.Equal(now.Add(time.Hour)) || e.ContentType != "application/json" ||
+		e.ContentEncoding != "gzip" || !e.CreatedAt.Equal(time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC)) ||
+		!reflect.DeepEqual(e.Metadata, map[string]string{"owner": "me"}) {
+		t.Errorf("Get: want the entry's metadata restored, got %+v", e)
+	}
+}
+
+func TestReplayEvictions(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	l, err := Open(path, SyncNever)
+	if err != nil {
+		t.Fatalf("Open: unexpected error: %v", err)
+	}
+	s := store.NewMemory(10, nil)
+	s.Notify(func(ev store.Event) { l.Append(ev) })
+	mustPut(t, s, "a", "12345")
+	mustPut(t, s, "b", "12345")
+	mustPut(t, s, "c", "12345")
+	mustClose(t, l)
+
+	dst := store.NewMemory(0, nil)
+	if _, _, err := Replay(path, dst, testMaxRecordSize); err != nil {
+		t.Fatalf("Replay: unexpected error: %v", err)
+	}
+	want := make(map[string]string)
+	s.Range(func(key string, e store.Entry) bool {
+		want[key] = string(e.Value)
+		return true
+	})
+	checkValues(t, dst, want)
+}
+
+// writeLog writes a log holding a put of each key, with the key as its value,
+// and returns the offsets at which the records start.
+func writeLog(t *testing.T, path string, keys ...string) []int64 {
+	t.Helper()
+	s, l := logged(t, path, SyncNever)
+	var offsets []int64
+	for _, key := range keys {
+		offsets = append(offsets, l.Size())
+		mustPut(t, s, key, key)
+	}
+	mustClose(t, l)
+	return offsets
+}
+
+func TestReplayTornTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(b []byte, last int64) []byte
+		intact bool // whether the last record survives the damage
+	}{
+		{"header cut short", func(b []byte, last int64) []byte {
+			return b[:last+3]
+		}, false},
+		{"payload cut short", func(b []byte, last int64) []byte {
+			return b[:len(b)-1]
+		}, false},
+		{"checksum mismatch", func(b []byte, last int64) []byte {
+			b[len(b)-1] ^= 0xff
+			return b
+		}, false},
+		{"zero filled", func(b []byte, last int64) []byte {
+			for i := last; i < int64(len(b)); i++ {
+				b[i] = 0
+			}
+			return append(b, make([]byte, 100)...)
+		}, false},
+		{"zeros after the last record", func(b []byte, last int64) []byte {
+			return append(b, make([]byte, 100)...)
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := tempLog(t)
+			defer cleanup()
+
+			offsets := writeLog(t, path, "a", "b", "c")
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			valid := offsets[len(offsets)-1]
+			want := map[string]string{"a": "a", "b": "b"}
+			if tt.intact {
+				valid = int64(len(b))
+				want["c"] = "c"
+			}
+			damaged := tt.damage(b, offsets[len(offsets)-1])
+			if err := ioutil.WriteFile(path, damaged, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := store.NewMemory(0, nil)
+			n, truncated, err := Replay(path, dst, testMaxRecordSize)
+			if err != nil || n != len(want) || truncated != int64(len(damaged))-valid {
+				t.Fatalf("Replay: want %d entries and %d bytes truncated, got %d, %d and %v",
+					len(want), int64(len(damaged))-valid, n, truncated, err)
+			}
+			checkValues(t, dst, want)
+			if fi, err := os.Stat(path); err != nil || fi.Size() != valid {
+				t.Fatalf("Stat: want the log truncated to %d bytes, got %v", valid, err)
+			}
+
+			// the truncated log can be appended to
+			s, l := logged(t, path, SyncNever)
+			mustPut(t, s, "d", "d")
+			mustClose(t, l)
+			dst = store.NewMemory(0, nil)
+			if _, truncated, err := Replay(path, dst, testMaxRecordSize); err != nil || truncated != 0 {
+				t.Fatalf("Replay: want nothing truncated, got %d and %v", truncated, err)
+			}
+			want["d"] = "d"
+			checkValues(t, dst, want)
+		})
+	}
+}
+
+func TestReplayCorrupt(t *testing.T) {
+	long := "0123456789abcdef"
+	tests := []struct {
+		name          string
+		keys          []string
+		maxRecordSize int64 // zero for the size of the first record
+		damage        func(b []byte, offsets []int64)
+		corrupt       int // index of the record reported corrupt
+	}{
+		{"checksum mismatch", []string{"a", "b", "c"}, testMaxRecordSize, func(b []byte, offsets []int64) {
+			b[offsets[1]+recordHeaderSize] ^= 0xff
+		}, 1},
+		{"length too long", []string{"a", "b", "c"}, testMaxRecordSize, func(b []byte, offsets []int64) {
+			binary.BigEndian.PutUint32(b[offsets[0]:], 1<<30)
+		}, 0},
+		{"zeroed record", []string{"a", "b", "c"}, testMaxRecordSize, func(b []byte, offsets []int64) {
+			for i := offsets[1]; i < offsets[2]; i++ {
+				b[i] = 0
+			}
+		}, 1},
+		{"record over the limit", []string{"a", long, "c"}, 0, func(b []byte, offsets []int64) {}, 1},
+		{"last record over the limit", []string{"a", long}, 0, func(b []byte, offsets []int64) {}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := tempLog(t)
+			defer cleanup()
+
+			offsets := writeLog(t, path, tt.keys...)
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.damage(b, offsets)
+			if err := ioutil.WriteFile(path, b, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			maxRecordSize := tt.maxRecordSize
+			if maxRecordSize == 0 {
+				maxRecordSize = offsets[1] - offsets[0]
+			}
+			dst := store.NewMemory(0, nil)
+			_, _, err = Replay(path, dst, maxRecordSize)
+			cerr, ok := err.(*CorruptError)
+			if !ok || cerr.Offset != offsets[tt.corrupt] {
+				t.Fatalf("Replay: want a corrupt record at offset %d, got %v", offsets[tt.corrupt], err)
+			}
+			checkValues(t, dst, map[string]string{})
+			if got, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(got, b) {
+				t.Errorf("ReadFile: want the log left untouched, got %d bytes and %v", len(got), err)
+			}
+		})
+	}
+}
+
+func TestReplayHeader(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	if _, _, err := Replay(path, store.NewMemory(0, nil), testMaxRecordSize); !os.IsNotExist(err) {
+		t.Errorf("Replay: want a missing log reported, got %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("CAO"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if n, truncated, err := Replay(path, store.NewMemory(0, nil), testMaxRecordSize); err != nil || n != 0 || truncated != 3 {
+		t.Errorf("Replay: want a torn header truncated, got %d, %d and %v", n, truncated, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not a log at all"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Replay(path, store.NewMemory(0, nil), testMaxRecordSize); err != ErrFormat {
+		t.Errorf("Replay: want %v, got %v", ErrFormat, err)
+	}
+
+	for _, v := range []uint32{0, Version + 1} {
+		b := header()
+		binary.BigEndian.PutUint32(b[len(magic):], v)
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := Replay(path, store.NewMemory(0, nil), testMaxRecordSize); err == nil {
+			t.Errorf("Replay of version %d: want an unsupported version reported, got nil", v)
+		}
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	writeLog(t, path, "a", "b")
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := make([]byte, 4)
+	binary.BigEndian.PutUint32(v1, 1)
+	if _, err := f.WriteAt(v1, int64(len(magic))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// version 1 logs are replayed, and upgraded once appended to
+	dst := store.NewMemory(0, nil)
+	if n, _, err := Replay(path, dst, testMaxRecordSize); err != nil || n != 2 {
+		t.Fatalf("Replay of a version 1 log: want 2 records, got %d and %v", n, err)
+	}
+	s, l := logged(t, path, SyncNever)
+	if _, err := s.Put("c", store.Entry{Value: []byte("c"), Metadata: map[string]string{"owner": "team-a"}}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	mustClose(t, l)
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := binary.BigEndian.Uint32(b[len(magic):]); v != Version {
+		t.Errorf("header: want version %d, got %d", Version, v)
+	}
+	dst = store.NewMemory(0, nil)
+	if n, _, err := Replay(path, dst, testMaxRecordSize); err != nil || n != 3 {
+		t.Fatalf("Replay: want 3 records, got %d and %v", n, err)
+	}
+	checkValues(t, dst, map[string]string{"a": "a", "b": "b", "c": "c"})
+}
+
+func TestCompact(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	s, l := logged(t, path, SyncNever)
+	for i := 0; i < 10; i++ {
+		mustPut(t, s, "a", "value")
+		if err := s.Delete("a"); err != nil {
+			t.Fatalf("Delete: unexpected error: %v", err)
+		}
+	}
+	mustPut(t, s, "b", "value")
+	if !l.NeedsCompaction(0) {
+		t.Error("NeedsCompaction: want true, got false")
+	}
+	before := l.Size()
+	if err := l.Compact(s); err != nil {
+		t.Fatalf("Compact: unexpected error: %v", err)
+	}
+	if l.Size() >= before || l.NeedsCompaction(0) {
+		t.Errorf("Compact: want the log shrunk below %d bytes, got %d", before, l.Size())
+	}
+
+	// writes after the compaction are appended to the rewritten log
+	mustPut(t, s, "c", "value")
+	mustClose(t, l)
+	dst := store.NewMemory(0, nil)
+	if _, _, err := Replay(path, dst, testMaxRecordSize); err != nil {
+		t.Fatalf("Replay: unexpected error: %v", err)
+	}
+	checkValues(t, dst, map[string]string{"b": "value", "c": "value"})
+}
+
+func TestCommit(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	for _, policy := range []SyncPolicy{SyncAlways, SyncEverySecond, SyncNever} {
+		s, l := logged(t, path, policy)
+		mustPut(t, s, "a", "value")
+		if err := l.Commit(); err != nil {
+			t.Errorf("Commit(%s): unexpected error: %v", SyncPolicies[policy], err)
+		}
+		if policy == SyncAlways && l.dirty {
+			t.Errorf("Commit(%s): want the log flushed", SyncPolicies[policy])
+		}
+		mustClose(t, l)
+	}
+
+	// once the log fails, every append and commit fails
+	l, err := Open(path, SyncAlways)
+	if err != nil {
+		t.Fatalf("Open: unexpected error: %v", err)
+	}
+	defer l.Close()
+	l.f.Close()
+	ev := store.Event{Type: store.EventPut, Key: "a", Entry: store.Entry{Value: []byte("value")}}
+	if err := l.Append(ev); err == nil {
+		t.Fatal("Append: want an error writing a closed file, got nil")
+	}
+	if err := l.Append(store.Event{Type: store.EventDelete, Key: "a"}); err == nil {
+		t.Error("Append: want the failure repeated, got nil")
+	}
+	if err := l.Commit(); err == nil {
+		t.Error("Commit: want the failure repeated, got nil")
+	}
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	for i, name := range SyncPolicies {
+		if p, err := ParseSyncPolicy(name); err != nil || p != SyncPolicy(i) {
+			t.Errorf("ParseSyncPolicy(%q): want %d, got %d and %v", name, i, p, err)
+		}
+	}
+	if _, err := ParseSyncPolicy("sometimes"); err == nil {
+		t.Error(`ParseSyncPolicy("sometimes"): want an error, got nil`)
+	}
+}
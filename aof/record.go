@@ -0,0 +1,247 @@
+package aof
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+var table = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// errIncomplete is returned by readRecord for a record cut short by the
+	// end of the log.
+	errIncomplete = errors.New("aof: incomplete record")
+
+	// errCorrupt is returned by readRecord for a record that is too long,
+	// fails its checksum or cannot be decoded.
+	errCorrupt = errors.New("aof: corrupt record")
+)
+
+const (
+	opPut                 = 1
+	opPutExpiring         = 2
+	opDelete              = 3
+	opPutMetadata         = 4
+	opPutExpiringMetadata = 5
+)
+
+// recordHeaderSize is the size of the length and checksum preceding every
+// record payload.
+const recordHeaderSize = 8
+
+// record is a single operation in the log.
+type record struct {
+	op    byte
+	key   string
+	entry store.Entry // only set for puts
+}
+
+// appendRecord appends the encoding of rec to b.
+func appendRecord(b []byte, rec record) []byte {
+	start := len(b)
+	b = append(b, make([]byte, recordHeaderSize)...)
+
+	e := rec.entry
+	expiring := !e.ExpiresAt.IsZero()
+	metadata := e.ContentType != "" || e.ContentEncoding != "" || !e.CreatedAt.IsZero() || len(e.Metadata) > 0
+	op := rec.op
+	if op == opPut {
+		switch {
+		case expiring && metadata:
+			op = opPutExpiringMetadata
+		case metadata:
+			op = opPutMetadata
+		case expiring:
+			op = opPutExpiring
+		}
+	}
+	b = append(b, op)
+	b = appendString(b, rec.key)
+	if op == opDelete {
+		return finishRecord(b, start)
+	}
+
+	b = appendUvarint(b, uint64(len(e.Value)))
+	b = append(b, e.Value...)
+	if expiring {
+		b = appendTime(b, e.ExpiresAt)
+	}
+	if metadata {
+		b = appendString(b, e.ContentType)
+		b = appendString(b, e.ContentEncoding)
+		b = appendTime(b, e.CreatedAt)
+		b = appendUvarint(b, uint64(len(e.Metadata)))
+		for name, value := range e.Metadata {
+			b = appendString(b, name)
+			b = appendString(b, value)
+		}
+	}
+	return finishRecord(b, start)
+}
+
+// finishRecord fills in the length and checksum of the record starting at
+// start in b.
+func finishRecord(b []byte, start int) []byte {
+	payload := b[start+recordHeaderSize:]
+	binary.BigEndian.PutUint32(b[start:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(b[start+4:], crc32.Checksum(payload, table))
+	return b
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendTime(b []byte, t time.Time) []byte {
+	b = appendVarint(b, t.Unix())
+	return appendUvarint(b, uint64(t.Nanosecond()))
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// readRecord reads the next record from r and returns it along with its
+// encoded size. It returns io.EOF at the end of the log, errIncomplete if the
+// log ends in the middle of the record, and errCorrupt if the record is longer
+// than maxSize bytes, fails its checksum or cannot be decoded. The size of
+// corrupt records is still returned when their length could be read.
+func readRecord(r io.Reader, maxSize int64) (record, int64, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+		return record{}, 0, io.EOF
+	} else if err != nil {
+		return record{}, 0, errIncomplete
+	}
+
+	n := binary.BigEndian.Uint32(header[:4])
+	size := int64(recordHeaderSize) + int64(n)
+	if size > maxSize {
+		return record{}, 0, errCorrupt
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return record{}, size, errIncomplete
+	}
+	if crc32.Checksum(payload, table) != binary.BigEndian.Uint32(header[4:]) {
+		return record{}, size, errCorrupt
+	}
+
+	rec, ok := decodePayload(payload)
+	if !ok {
+		return record{}, size, errCorrupt
+	}
+	return rec, size, nil
+}
+
+// decodePayload decodes a record payload whose checksum has been verified.
+func decodePayload(p []byte) (record, bool) {
+	if len(p) == 0 {
+		return record{}, false
+	}
+	rec := record{op: p[0]}
+	p = p[1:]
+
+	key, p, ok := field(p)
+	if !ok {
+		return record{}, false
+	}
+	rec.key = string(key)
+
+	switch rec.op {
+	case opDelete:
+		return rec, len(p) == 0
+	case opPut, opPutExpiring, opPutMetadata, opPutExpiringMetadata:
+		rec.entry.Value, p, ok = field(p)
+		if !ok {
+			return record{}, false
+		}
+		if rec.op == opPutExpiring || rec.op == opPutExpiringMetadata {
+			if rec.entry.ExpiresAt, p, ok = timeField(p); !ok {
+				return record{}, false
+			}
+		}
+		if rec.op == opPutMetadata || rec.op == opPutExpiringMetadata {
+			if p, ok = metadataFields(p, &rec.entry); !ok {
+				return record{}, false
+			}
+		}
+		rec.op = opPut
+		return rec, len(p) == 0
+	}
+	return record{}, false
+}
+
+// timeField decodes a time encoded as seconds and nanoseconds since the Unix
+// epoch from p and returns the rest of p.
+func timeField(p []byte) (time.Time, []byte, bool) {
+	sec, n := binary.Varint(p)
+	if n <= 0 {
+		return time.Time{}, nil, false
+	}
+	nsec, m := binary.Uvarint(p[n:])
+	if m <= 0 || nsec >= uint64(time.Second) {
+		return time.Time{}, nil, false
+	}
+	return time.Unix(sec, int64(nsec)), p[n+m:], true
+}
+
+// metadataFields decodes the metadata of a put from p into e and returns the
+// rest of p.
+func metadataFields(p []byte, e *store.Entry) ([]byte, bool) {
+	contentType, p, ok := field(p)
+	if !ok {
+		return nil, false
+	}
+	contentEncoding, p, ok := field(p)
+	if !ok {
+		return nil, false
+	}
+	e.ContentType, e.ContentEncoding = string(contentType), string(contentEncoding)
+	if e.CreatedAt, p, ok = timeField(p); !ok {
+		return nil, false
+	}
+
+	n, m := binary.Uvarint(p)
+	if m <= 0 || n > uint64(len(p)) {
+		return nil, false
+	}
+	p = p[m:]
+	for i := uint64(0); i < n; i++ {
+		var name, value []byte
+		if name, p, ok = field(p); !ok {
+			return nil, false
+		}
+		if value, p, ok = field(p); !ok {
+			return nil, false
+		}
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata[string(name)] = string(value)
+	}
+	return p, true
+}
+
+// field decodes a length-prefixed key or value from p and returns the rest
+// of p.
+func field(p []byte) ([]byte, []byte, bool) {
+	n, m := binary.Uvarint(p)
+	if m <= 0 || n > uint64(len(p)-m) {
+		return nil, nil, false
+	}
+	p = p[m:]
+	return p[:n:n], p[n:], true
+}
@@ -0,0 +1,220 @@
+// Package backend forwards the writes made to a cache to the durable store it
+// fronts, so that the cache can be written to as if it were the store.
+//
+// Writes are forwarded either synchronously, before they are made to the
+// cache, or from a queue drained in the background, which batches the writes
+// and retries them until the backend accepts them.
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// Backend is a durable store the writes made to a cache are forwarded to.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Put stores e at key, replacing any entry already there.
+	Put(key string, e store.Entry) error
+
+	// Delete removes the entry at key. Deleting a missing key is not an
+	// error.
+	Delete(key string) error
+}
+
+// Op is a write forwarded to a Backend.
+type Op struct {
+	Key    string
+	Entry  store.Entry // the entry to put, unless Delete is set
+	Delete bool
+}
+
+// Batcher is implemented by backends that can apply several writes at once
+// more efficiently than one at a time.
+type Batcher interface {
+	// Write applies ops in order. Either every write is applied or none is.
+	Write(ops []Op) error
+}
+
+// Write applies ops to b in order, in a single batch if b is a Batcher. When
+// it is not, the writes up to the one that failed are left applied.
+func Write(b Backend, ops []Op) error {
+	if bb, ok := b.(Batcher); ok {
+		return bb.Write(ops)
+	}
+
+	for _, op := range ops {
+		if err := apply(b, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply forwards a single write to b.
+func apply(b Backend, op Op) error {
+	if op.Delete {
+		return b.Delete(op.Key)
+	}
+	return b.Put(op.Key, op.Entry)
+}
+
+// WriteError is returned when a write could not be forwarded to the backend.
+type WriteError struct {
+	Key string
+	Err error
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("backend: writing %s: %v", e.Key, e.Err)
+}
+
+var (
+	// errChecked aborts the update CompareAndDelete makes to check the
+	// version of an entry.
+	errChecked = errors.New("backend: version checked")
+
+	// errSuperseded aborts the rollback of a write the store no longer
+	// holds.
+	errSuperseded = errors.New("backend: write superseded")
+)
+
+// Store is a store.Store whose writes are forwarded to a backend. Writes to a
+// key are forwarded in the order they are made to the store.
+//
+// Puts and updates are forwarded once the store has accepted them, with the
+// entry it holds, so that the backend is neither handed an entry the store
+// rejects nor held up while the store is locked. A write the backend fails is
+// rolled back, restoring the entry it replaced, and may be seen by readers in
+// the meantime. Deletes are forwarded before they are made to the store, and
+// whether or not the key is in the store, since the store may have evicted an
+// entry the backend still holds.
+type Store struct {
+	store.Store
+	forward func(Op) error
+
+	// locks serialize the writes to a key, so that they reach the backend in
+	// the order they are made to the store.
+	locks [64]sync.Mutex
+}
+
+// WriteThrough returns a Store forwarding the writes made to s to b
+// synchronously. A write that b fails is undone in s, and fails with a
+// *WriteError.
+func WriteThrough(s store.Store, b Backend) *Store {
+	return &Store{
+		Store: s,
+		forward: func(op Op) error {
+			if err := apply(b, op); err != nil {
+				return &WriteError{Key: op.Key, Err: err}
+			}
+			return nil
+		},
+	}
+}
+
+// WriteBehind returns a Store forwarding the writes made to s through q. A
+// write q has no room for is undone in s, and fails with ErrQueueFull.
+func WriteBehind(s store.Store, q *Queue) *Store {
+	return &Store{Store: s, forward: q.Enqueue}
+}
+
+// lock returns the mutex serializing the writes to key.
+func (s *Store) lock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &s.locks[h.Sum32()%uint32(len(s.locks))]
+}
+
+// Put stores e at key if it is absent, then forwards the write.
+func (s *Store) Put(key string, e store.Entry) (store.Entry, error) {
+	return s.Update(key, func(_ store.Entry, exists bool) (store.Entry, error) {
+		if exists {
+			return store.Entry{}, store.ErrExists
+		}
+		return e, nil
+	})
+}
+
+// Update replaces the entry at key with the one returned by fn, then forwards
+// the write.
+func (s *Store) Update(key string, fn store.UpdateFunc) (store.Entry, error) {
+	mu := s.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	var prev store.Entry
+	var existed bool
+	e, err := s.Store.Update(key, func(cur store.Entry, exists bool) (store.Entry, error) {
+		prev, existed = cur, exists
+		return fn(cur, exists)
+	})
+	if err != nil {
+		return store.Entry{}, err
+	}
+	if err := s.forward(Op{Key: key, Entry: e}); err != nil {
+		s.rollback(key, e.Version, prev, existed)
+		return store.Entry{}, err
+	}
+	return e, nil
+}
+
+// rollback restores prev at key in place of the entry at version, or removes
+// that entry if the key did not exist before it. The entry is left alone if
+// it has been evicted or has expired since.
+func (s *Store) rollback(key string, version uint64, prev store.Entry, existed bool) {
+	if !existed {
+		store.CompareAndDelete(s.Store, key, version)
+		return
+	}
+	s.Store.Update(key, func(cur store.Entry, exists bool) (store.Entry, error) {
+		if !exists || cur.Version != version {
+			return store.Entry{}, errSuperseded
+		}
+		return prev, nil
+	})
+}
+
+// Delete forwards the deletion of key, then removes its entry.
+func (s *Store) Delete(key string) error {
+	mu := s.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := s.forward(Op{Key: key, Delete: true}); err != nil {
+		return err
+	}
+	return s.Store.Delete(key)
+}
+
+// CompareAndDelete removes the entry at key if it is at version, forwarding
+// the deletion first. The deletion is only forwarded for entries that are at
+// version in the store.
+func (s *Store) CompareAndDelete(key string, version uint64) error {
+	mu := s.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	_, err := s.Store.Update(key, func(cur store.Entry, exists bool) (store.Entry, error) {
+		switch {
+		case !exists:
+			return store.Entry{}, store.ErrNotFound
+		case cur.Version != version:
+			return store.Entry{}, store.ErrVersionMismatch
+		}
+		return store.Entry{}, errChecked
+	})
+	if err != errChecked {
+		return err
+	}
+	if err := s.forward(Op{Key: key, Delete: true}); err != nil {
+		return err
+	}
+	// the entry may have expired or been evicted since it was checked, which
+	// leaves nothing to delete but the backend's copy
+	return store.CompareAndDelete(s.Store, key, version)
+}
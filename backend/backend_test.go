@@ -0,0 +1,207 @@
+package backend_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/backend"
+	"github.com/timraymond/cachely/store"
+)
+
+// logBackend is a backend.Backend logging the writes it applies, which fails
+// the writes to the keys in fail. It calls hook, if set, with every write.
+type logBackend struct {
+	hook func(backend.Op)
+
+	mu   sync.Mutex
+	ops  []backend.Op
+	fail map[string]bool
+}
+
+func (b *logBackend) Put(key string, e store.Entry) error {
+	return b.log(backend.Op{Key: key, Entry: e})
+}
+
+func (b *logBackend) Delete(key string) error {
+	return b.log(backend.Op{Key: key, Delete: true})
+}
+
+func (b *logBackend) log(op backend.Op) error {
+	if b.hook != nil {
+		b.hook(op)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail[op.Key] {
+		return errors.New("rejected")
+	}
+	b.ops = append(b.ops, op)
+	return nil
+}
+
+// keys returns the keys written so far, deletions prefixed with a dash.
+func (b *logBackend) keys() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var keys []string
+	for _, op := range b.ops {
+		if op.Delete {
+			keys = append(keys, "-"+op.Key)
+		} else {
+			keys = append(keys, op.Key)
+		}
+	}
+	return keys
+}
+
+func TestWrite(t *testing.T) {
+	b := &logBackend{fail: map[string]bool{"bad": true}}
+	ops := []backend.Op{put("a", "1"), {Key: "b", Delete: true}, put("bad", "1"), put("c", "1")}
+	if err := backend.Write(b, ops); err == nil {
+		t.Errorf("Write: want the failure of bad, got nil")
+	}
+	// backends that are not a Batcher keep the writes before the failure
+	if want := []string{"a", "-b"}; !reflect.DeepEqual(b.keys(), want) {
+		t.Errorf("Write: want %q applied, got %q", want, b.keys())
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	b := &logBackend{fail: map[string]bool{"bad": true}}
+	s := backend.WriteThrough(store.NewMemory(0, nil), b)
+
+	before := time.Now()
+	stored, err := s.Put("a", store.Entry{Value: []byte("1")})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if _, err := s.Update("a", func(cur store.Entry, exists bool) (store.Entry, error) {
+		cur.Value = []byte("2")
+		return cur, nil
+	}); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if _, err := s.Put("a", store.Entry{Value: []byte("3")}); err != store.ErrExists {
+		t.Errorf("Put of an existing key: want ErrExists, got %v", err)
+	}
+	// the backend is given the entries along with their timestamps
+	if len(b.ops) != 2 || string(b.ops[1].Entry.Value) != "2" {
+		t.Fatalf("writes: want the Put and the Update forwarded, got %+v", b.ops)
+	}
+	for _, op := range b.ops {
+		if op.Entry.CreatedAt.Before(before) || op.Entry.ModifiedAt.Before(op.Entry.CreatedAt) {
+			t.Errorf("write of %q: want its timestamps set, got %+v", op.Entry.Value, op.Entry)
+		}
+	}
+	if !reflect.DeepEqual(b.ops[0].Entry, stored) || !b.ops[1].Entry.CreatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("writes: want the entries stored, got %+v and %+v for %+v", b.ops[0].Entry, b.ops[1].Entry, stored)
+	}
+
+	// a write the backend fails is not made to the store
+	_, err = s.Put("bad", store.Entry{Value: []byte("1")})
+	if werr, ok := err.(*backend.WriteError); !ok || werr.Key != "bad" {
+		t.Errorf("Put rejected by the backend: want a *WriteError, got %v", err)
+	}
+	if _, err := s.Get("bad"); err != store.ErrNotFound {
+		t.Errorf("Get of a write the backend failed: want ErrNotFound, got %v", err)
+	}
+
+	// deletions are forwarded even for keys the store does not hold, but not
+	// when the version of the entry does not match
+	if err := s.Delete("evicted"); err != store.ErrNotFound {
+		t.Errorf("Delete of a missing key: want ErrNotFound, got %v", err)
+	}
+	cur, _ := s.Get("a")
+	if err := s.CompareAndDelete("a", cur.Version+1); err != store.ErrVersionMismatch {
+		t.Errorf("CompareAndDelete of another version: want ErrVersionMismatch, got %v", err)
+	}
+	if err := s.CompareAndDelete("a", cur.Version); err != nil {
+		t.Errorf("CompareAndDelete: unexpected error: %v", err)
+	}
+	if want := []string{"a", "a", "-evicted", "-a"}; !reflect.DeepEqual(b.keys(), want) {
+		t.Errorf("writes: want %q, got %q", want, b.keys())
+	}
+	if _, err := s.Get("a"); err != store.ErrNotFound {
+		t.Errorf("Get after CompareAndDelete: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestWriteThroughRollback(t *testing.T) {
+	b := &logBackend{fail: map[string]bool{}}
+	s := backend.WriteThrough(store.NewMemory(0, nil), b)
+
+	old, err := s.Put("a", store.Entry{Value: []byte("1"), Metadata: map[string]string{"owner": "team-a"}})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	b.fail["a"], b.fail["b"] = true, true
+
+	// the entry replaced is restored
+	_, err = s.Update("a", func(cur store.Entry, exists bool) (store.Entry, error) {
+		return store.Entry{Value: []byte("2")}, nil
+	})
+	if _, ok := err.(*backend.WriteError); !ok {
+		t.Errorf("Update rejected by the backend: want a *WriteError, got %v", err)
+	}
+	e, err := s.Get("a")
+	if err != nil || string(e.Value) != "1" || !reflect.DeepEqual(e.Metadata, old.Metadata) || !e.CreatedAt.Equal(old.CreatedAt) {
+		t.Errorf("Get after a failed Update: want %+v, got %+v and %v", old, e, err)
+	}
+
+	// and a new entry is removed
+	if _, err := s.Put("b", store.Entry{Value: []byte("1")}); err == nil {
+		t.Errorf("Put rejected by the backend: want an error, got nil")
+	}
+	if _, err := s.Get("b"); err != store.ErrNotFound {
+		t.Errorf("Get after a failed Put: want ErrNotFound, got %v", err)
+	}
+	if stats := s.Stats(); stats.Keys != 1 {
+		t.Errorf("Stats: want the key written before only, got %+v", stats)
+	}
+}
+
+func TestWriteThroughRejected(t *testing.T) {
+	b := &logBackend{}
+	s := backend.WriteThrough(store.NewMemory(64, nil), b)
+
+	// writes the store rejects never reach the backend
+	if _, err := s.Put("large", store.Entry{Value: make([]byte, 100)}); err != store.ErrTooLarge {
+		t.Errorf("Put of an entry too large for the store: want ErrTooLarge, got %v", err)
+	}
+	failed := errors.New("failed")
+	if _, err := s.Update("k", func(store.Entry, bool) (store.Entry, error) { return store.Entry{}, failed }); err != failed {
+		t.Errorf("Update failed by its function: want its error, got %v", err)
+	}
+	if keys := b.keys(); len(keys) != 0 {
+		t.Errorf("writes: want none, got %q", keys)
+	}
+}
+
+func TestWriteThroughUnlocked(t *testing.T) {
+	b := &logBackend{}
+	s := backend.WriteThrough(store.NewMemory(0, nil), b)
+
+	// the backend is called without the store being locked, which would
+	// deadlock the read made from it
+	b.hook = func(op backend.Op) {
+		s.Get(op.Key)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Put("a", store.Entry{Value: []byte("1")})
+		e, _ := s.Get("a")
+		s.CompareAndDelete("a", e.Version)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Put: want the store unlocked while the write is forwarded")
+	}
+	if want := []string{"a", "-a"}; !reflect.DeepEqual(b.keys(), want) {
+		t.Errorf("writes: want %q, got %q", want, b.keys())
+	}
+}
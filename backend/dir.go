@@ -0,0 +1,162 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// Dir is a Backend keeping each entry in a file of its own under a
+// directory. Keys may hold any byte, so files are named after the SHA-256
+// hash of their key, and spread over 256 subdirectories named after the first
+// byte of the hash. Each file holds its entry along with its key as JSON.
+//
+// Entries are replaced by writing a new file and renaming it over the old
+// one, so readers never see a partly written entry. The file and its
+// directory are synced to disk before the write returns, so that the entry
+// survives a crash once it has been written, as does its removal.
+type Dir struct {
+	path string
+}
+
+// fileEntry is the JSON form of the entries kept by a Dir.
+type fileEntry struct {
+	Key             string            `json:"key"`
+	Value           []byte            `json:"value"`
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty"`
+	ContentType     string            `json:"content_type,omitempty"`
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	ModifiedAt      time.Time         `json:"modified_at"`
+}
+
+// OpenDir returns a Dir keeping its entries under path, which is created if
+// it does not exist.
+func OpenDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	return &Dir{path: path}, nil
+}
+
+// file returns the path of the file holding the entry at key.
+func (d *Dir) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(d.path, name[:2], name)
+}
+
+// Put writes e to the file of key.
+func (d *Dir) Put(key string, e store.Entry) error {
+	fe := fileEntry{
+		Key:             key,
+		Value:           e.Value,
+		ContentType:     e.ContentType,
+		ContentEncoding: e.ContentEncoding,
+		Metadata:        e.Metadata,
+		CreatedAt:       e.CreatedAt,
+		ModifiedAt:      e.ModifiedAt,
+	}
+	if !e.ExpiresAt.IsZero() {
+		fe.ExpiresAt = &e.ExpiresAt
+	}
+	data, err := json.Marshal(fe)
+	if err != nil {
+		return err
+	}
+
+	path := d.file(key)
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+			return err
+		}
+		syncDir(d.path)
+	}
+	f, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// Delete removes the file of key.
+func (d *Dir) Delete(key string) error {
+	path := d.file(key)
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes a directory to disk, so that the files created, renamed and
+// removed within it survive a crash. Failures are ignored, since some
+// platforms cannot sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
+
+// Get reads the entry at key back from its file, or returns
+// store.ErrNotFound. Expired entries are returned as they are.
+func (d *Dir) Get(key string) (store.Entry, error) {
+	data, err := ioutil.ReadFile(d.file(key))
+	if os.IsNotExist(err) {
+		return store.Entry{}, store.ErrNotFound
+	}
+	if err != nil {
+		return store.Entry{}, err
+	}
+
+	var fe fileEntry
+	if err := json.Unmarshal(data, &fe); err != nil {
+		return store.Entry{}, err
+	}
+	e := store.Entry{
+		Value:           fe.Value,
+		ContentType:     fe.ContentType,
+		ContentEncoding: fe.ContentEncoding,
+		Metadata:        fe.Metadata,
+		CreatedAt:       fe.CreatedAt,
+		ModifiedAt:      fe.ModifiedAt,
+	}
+	if fe.ExpiresAt != nil {
+		e.ExpiresAt = *fe.ExpiresAt
+	}
+	return e, nil
+}
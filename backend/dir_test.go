@@ -0,0 +1,112 @@
+package backend_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/backend"
+	"github.com/timraymond/cachely/store"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cachely-backend-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %v", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestDir(t *testing.T) {
+	path, cleanup := tempDir(t)
+	defer cleanup()
+	d, err := backend.OpenDir(filepath.Join(path, "entries"))
+	if err != nil {
+		t.Fatalf("OpenDir: unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	entries := map[string]store.Entry{
+		"users/42": {
+			Value:           []byte("profile"),
+			ExpiresAt:       now.Add(time.Hour),
+			ContentType:     "text/plain",
+			ContentEncoding: "gzip",
+			Metadata:        map[string]string{"owner": "team-a"},
+			CreatedAt:       now.Add(-time.Hour),
+			ModifiedAt:      now,
+		},
+		"\x00\xff binary": {Value: []byte{0, 1, 2}, CreatedAt: now, ModifiedAt: now},
+		"":                {Value: []byte{}, CreatedAt: now, ModifiedAt: now},
+	}
+	for key, e := range entries {
+		if err := d.Put(key, e); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+	for key, want := range entries {
+		got, err := d.Get(key)
+		if err != nil {
+			t.Errorf("Get(%q): unexpected error: %v", key, err)
+			continue
+		}
+		if string(got.Value) != string(want.Value) || !got.ExpiresAt.Equal(want.ExpiresAt) ||
+			got.ContentType != want.ContentType || got.ContentEncoding != want.ContentEncoding ||
+			!reflect.DeepEqual(got.Metadata, want.Metadata) ||
+			!got.CreatedAt.Equal(want.CreatedAt) || !got.ModifiedAt.Equal(want.ModifiedAt) {
+			t.Errorf("Get(%q): want %+v, got %+v", key, want, got)
+		}
+	}
+
+	// entries are replaced whole
+	if err := d.Put("users/42", store.Entry{Value: []byte("new")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	got, err := d.Get("users/42")
+	if err != nil || string(got.Value) != "new" || got.ContentType != "" || got.Metadata != nil || !got.ExpiresAt.IsZero() {
+		t.Errorf("Get after a replacement: want only the new entry, got %+v and %v", got, err)
+	}
+
+	if err := d.Delete("users/42"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if _, err := d.Get("users/42"); err != store.ErrNotFound {
+		t.Errorf("Get after Delete: want ErrNotFound, got %v", err)
+	}
+	if err := d.Delete("users/42"); err != nil {
+		t.Errorf("Delete of a missing key: unexpected error: %v", err)
+	}
+
+	// every entry is a file of its own in a subdirectory, with no temporary
+	// file left behind
+	var files []string
+	filepath.Walk(filepath.Join(path, "entries"), func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if len(files) != 2 {
+		t.Errorf("files: want one per entry, got %q", files)
+	}
+	for _, f := range files {
+		name := filepath.Base(f)
+		if strings.HasPrefix(name, ".tmp-") || len(name) != 64 || filepath.Base(filepath.Dir(f)) != name[:2] {
+			t.Errorf("files: want files named after the hash of their key, got %s", f)
+		}
+	}
+
+	// entries outlive the Dir
+	reopened, err := backend.OpenDir(filepath.Join(path, "entries"))
+	if err != nil {
+		t.Fatalf("OpenDir: unexpected error: %v", err)
+	}
+	if got, err := reopened.Get("\x00\xff binary"); err != nil || string(got.Value) != "\x00\x01\x02" {
+		t.Errorf("Get after reopening: want the entry written, got %+v and %v", got, err)
+	}
+}
@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxBackoff bounds the delay between the retries of a batch the backend
+// keeps failing.
+const maxBackoff = 30 * time.Second
+
+// ErrQueueFull is returned when enqueuing a write to a Queue that already
+// holds as many writes as it may.
+var ErrQueueFull = errors.New("backend: write-behind queue is full")
+
+// QueueStats is a point in time summary of the activity of a Queue.
+type QueueStats struct {
+	Depth     int    `json:"depth"`     // writes waiting to be applied, including those being applied
+	Written   uint64 `json:"written"`   // writes applied to the backend
+	Coalesced uint64 `json:"coalesced"` // writes superseded by a later write to their key before being applied
+	Batches   uint64 `json:"batches"`   // batches applied to the backend
+	Failures  uint64 `json:"failures"`  // attempts at applying a batch that failed
+}
+
+// Queue applies writes to a backend in the background. Writes are applied in
+// batches, and a batch the backend fails is retried with an increasing delay
+// until it succeeds. A write to a key that is still waiting to be applied is
+// replaced by the next write to that key, which takes its place in the queue,
+// so only the last of them is applied. Writes to a key are thus applied in
+// the order they were enqueued, but writes to different keys are not: the
+// write replacing another is applied ahead of the writes to other keys
+// enqueued between them, and the backend may hold it without them for a
+// while. It is safe for concurrent use.
+type Queue struct {
+	backend   Backend
+	batchSize int
+	interval  time.Duration
+	maxDepth  int
+
+	mu       sync.Mutex
+	pending  []Op
+	taken    int            // number of writes ever removed from the head of pending
+	index    map[string]int // position of the pending write to each key, counting the writes taken
+	inFlight int            // size of the batch being applied
+	stats    QueueStats
+
+	wake chan struct{} // signaled once a full batch is pending
+	stop chan struct{} // closed by Close
+	done chan struct{} // closed once the queue is drained after Close
+}
+
+// NewQueue returns a Queue applying writes to b in batches of up to batchSize
+// writes, every interval or as soon as a batch is full. Enqueuing fails once
+// maxDepth writes are waiting, unless maxDepth is zero.
+func NewQueue(b Backend, batchSize int, interval time.Duration, maxDepth int) *Queue {
+	if batchSize <= 0 {
+		panic("backend: non-positive batch size")
+	}
+	if interval <= 0 {
+		panic("backend: non-positive flush interval")
+	}
+
+	q := &Queue{
+		backend:   b,
+		batchSize: batchSize,
+		interval:  interval,
+		maxDepth:  maxDepth,
+		index:     make(map[string]int),
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Enqueue adds op to the queue, replacing the pending write to its key if any.
+// It returns ErrQueueFull if the queue has no room left.
+func (q *Queue) Enqueue(op Op) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i, ok := q.index[op.Key]; ok {
+		q.pending[i-q.taken] = op
+		q.stats.Coalesced++
+		return nil
+	}
+	if q.maxDepth > 0 && len(q.pending)+q.inFlight >= q.maxDepth {
+		return ErrQueueFull
+	}
+	q.index[op.Key] = q.taken + len(q.pending)
+	q.pending = append(q.pending, op)
+	if len(q.pending) == q.batchSize {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Stats summarizes the activity of the queue.
+func (q *Queue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := q.stats
+	stats.Depth = len(q.pending) + q.inFlight
+	return stats
+}
+
+// Close stops the queue once the writes waiting in it have been applied, or
+// once timeout has elapsed, whichever comes first. It returns the number of
+// writes left unapplied.
+func (q *Queue) Close(timeout time.Duration) int {
+	close(q.stop)
+	select {
+	case <-q.done:
+	case <-time.After(timeout):
+	}
+	return q.Stats().Depth
+}
+
+// run applies the pending writes every interval, or as soon as a batch is
+// full, until the queue is closed and drained.
+func (q *Queue) run() {
+	defer close(q.done)
+	t := time.NewTicker(q.interval)
+	defer t.Stop()
+
+	for {
+		stopping := false
+		select {
+		case <-t.C:
+		case <-q.wake:
+		case <-q.stop:
+			stopping = true
+		}
+		q.flush()
+		if stopping {
+			return
+		}
+	}
+}
+
+// flush applies the pending writes batch after batch until none is left. A
+// batch the backend fails is retried after a delay that doubles with every
+// failure.
+func (q *Queue) flush() {
+	backoff := q.interval
+	for batch := q.take(); len(batch) > 0; batch = q.take() {
+		for !q.apply(batch) {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// take removes up to a batch of writes from the head of the queue, and
+// records them as being applied.
+func (q *Queue) take() []Op {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.pending)
+	if n > q.batchSize {
+		n = q.batchSize
+	}
+	batch := make([]Op, n)
+	copy(batch, q.pending)
+	for i, op := range batch {
+		delete(q.index, op.Key)
+		q.pending[i] = Op{} // let the entry be collected once applied
+	}
+	q.pending = q.pending[n:]
+	q.taken += n
+	q.inFlight = n
+	return batch
+}
+
+// apply applies batch to the backend, and reports whether it succeeded.
+func (q *Queue) apply(batch []Op) bool {
+	if err := Write(q.backend, batch); err != nil {
+		log.Printf("failed to write %d keys to the backend, will retry: %v\n", len(batch), err)
+		q.mu.Lock()
+		q.stats.Failures++
+		q.mu.Unlock()
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inFlight = 0
+	q.stats.Written += uint64(len(batch))
+	q.stats.Batches++
+	return true
+}
@@ -0,0 +1,272 @@
+package backend_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/backend"
+	"github.com/timraymond/cachely/store"
+)
+
+// recorder is a backend.Batcher recording the batches it applies. Batches
+// wait for gate to be open, if it is set, and fail while failures remain.
+type recorder struct {
+	started int32 // batches received, accessed atomically
+	gate    chan struct{}
+
+	mu       sync.Mutex
+	batches  [][]string
+	attempts []time.Time
+	failures int
+}
+
+func (r *recorder) Put(key string, e store.Entry) error {
+	return r.Write([]backend.Op{{Key: key, Entry: e}})
+}
+
+func (r *recorder) Delete(key string) error {
+	return r.Write([]backend.Op{{Key: key, Delete: true}})
+}
+
+func (r *recorder) Write(ops []backend.Op) error {
+	atomic.AddInt32(&r.started, 1)
+	if r.gate != nil {
+		<-r.gate
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, time.Now())
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("backend down")
+	}
+	var batch []string
+	for _, op := range ops {
+		if op.Delete {
+			batch = append(batch, "-"+op.Key)
+		} else {
+			batch = append(batch, op.Key+"="+string(op.Entry.Value))
+		}
+	}
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+// inFlight reports whether a batch is waiting for the gate to open.
+func (r *recorder) inFlight() bool {
+	return atomic.LoadInt32(&r.started) == 1
+}
+
+// applied returns the batches applied so far.
+func (r *recorder) applied() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string(nil), r.batches...)
+}
+
+func put(key, value string) backend.Op {
+	return backend.Op{Key: key, Entry: store.Entry{Value: []byte(value)}}
+}
+
+// waitFor polls cond until it holds or a second has passed, and reports
+// whether it held.
+func waitFor(cond func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return cond()
+}
+
+func TestQueueBatches(t *testing.T) {
+	r := &recorder{}
+	q := backend.NewQueue(r, 2, time.Hour, 0)
+
+	// a full batch is applied right away
+	for _, op := range []backend.Op{put("a", "1"), put("b", "1")} {
+		if err := q.Enqueue(op); err != nil {
+			t.Fatalf("Enqueue: unexpected error: %v", err)
+		}
+	}
+	if !waitFor(func() bool { return len(r.applied()) == 1 }) {
+		t.Fatalf("Enqueue of a full batch: want it applied, got %v", r.applied())
+	}
+
+	// the rest waits for the interval, or for Close
+	if err := q.Enqueue(put("c", "1")); err != nil {
+		t.Fatalf("Enqueue: unexpected error: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if want := [][]string{{"a=1", "b=1"}}; !reflect.DeepEqual(r.applied(), want) {
+		t.Errorf("applied: want %v, got %v", want, r.applied())
+	}
+	stats := q.Stats()
+	if stats.Depth != 1 || stats.Written != 2 || stats.Batches != 1 {
+		t.Errorf("Stats: want a write left and 2 written in a batch, got %+v", stats)
+	}
+
+	if n := q.Close(time.Second); n != 0 {
+		t.Errorf("Close: want every write applied, got %d left", n)
+	}
+	if want := [][]string{{"a=1", "b=1"}, {"c=1"}}; !reflect.DeepEqual(r.applied(), want) {
+		t.Errorf("applied after Close: want %v, got %v", want, r.applied())
+	}
+}
+
+func TestQueueInterval(t *testing.T) {
+	r := &recorder{}
+	q := backend.NewQueue(r, 100, 10*time.Millisecond, 0)
+	defer q.Close(time.Second)
+
+	q.Enqueue(put("a", "1"))
+	q.Enqueue(backend.Op{Key: "b", Delete: true})
+	if !waitFor(func() bool { return len(r.applied()) == 1 }) {
+		t.Fatalf("Enqueue: want the writes applied after the interval, got %v", r.applied())
+	}
+	if want := [][]string{{"a=1", "-b"}}; !reflect.DeepEqual(r.applied(), want) {
+		t.Errorf("applied: want %v, got %v", want, r.applied())
+	}
+}
+
+func TestQueueCoalescing(t *testing.T) {
+	r := &recorder{gate: make(chan struct{})}
+	q := backend.NewQueue(r, 10, 5*time.Millisecond, 0)
+
+	// the first write is held in flight, where it cannot be replaced
+	q.Enqueue(put("a", "1"))
+	if !waitFor(r.inFlight) {
+		t.Fatal("Enqueue: want the first write in flight")
+	}
+	for _, op := range []backend.Op{put("a", "2"), put("b", "1"), put("a", "3"), put("c", "1"), put("b", "2")} {
+		if err := q.Enqueue(op); err != nil {
+			t.Fatalf("Enqueue: unexpected error: %v", err)
+		}
+	}
+	stats := q.Stats()
+	if stats.Depth != 4 || stats.Coalesced != 2 {
+		t.Errorf("Stats: want the write in flight and 3 pending, 2 of them coalesced, got %+v", stats)
+	}
+
+	close(r.gate)
+	if n := q.Close(time.Second); n != 0 {
+		t.Fatalf("Close: want every write applied, got %d left", n)
+	}
+	// the write replacing another takes its place, ahead of the writes to
+	// other keys enqueued before it
+	if want := [][]string{{"a=1"}, {"a=3", "b=2", "c=1"}}; !reflect.DeepEqual(r.applied(), want) {
+		t.Errorf("applied: want %v, got %v", want, r.applied())
+	}
+	if stats := q.Stats(); stats.Written != 4 || stats.Coalesced != 2 || stats.Depth != 0 {
+		t.Errorf("Stats after Close: want 4 written and 2 coalesced, got %+v", stats)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	r := &recorder{failures: 3}
+	interval := 10 * time.Millisecond
+	q := backend.NewQueue(r, 10, interval, 0)
+	defer q.Close(time.Second)
+
+	q.Enqueue(put("a", "1"))
+	if !waitFor(func() bool { return len(r.applied()) == 1 }) {
+		t.Fatalf("Enqueue to a failing backend: want the write applied once it recovers, got %v", r.applied())
+	}
+	stats := q.Stats()
+	if stats.Failures != 3 || stats.Written != 1 || stats.Batches != 1 || stats.Depth != 0 {
+		t.Errorf("Stats: want 3 failures before the write was applied, got %+v", stats)
+	}
+
+	// the delay between attempts doubles from the interval
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, want := range []time.Duration{interval, 2 * interval, 4 * interval} {
+		if delay := r.attempts[i+1].Sub(r.attempts[i]); delay < want {
+			t.Errorf("attempt %d: want it made %s after the previous one, got %s", i+2, want, delay)
+		}
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	r := &recorder{gate: make(chan struct{})}
+	q := backend.NewQueue(r, 1, time.Millisecond, 2)
+
+	q.Enqueue(put("a", "1"))
+	if !waitFor(r.inFlight) {
+		t.Fatal("Enqueue: want the first write in flight")
+	}
+	if err := q.Enqueue(put("b", "1")); err != nil {
+		t.Fatalf("Enqueue: unexpected error: %v", err)
+	}
+	if err := q.Enqueue(put("c", "1")); err != backend.ErrQueueFull {
+		t.Errorf("Enqueue past the depth: want ErrQueueFull, got %v", err)
+	}
+	// the writes in flight count towards the depth, while a write replacing
+	// a pending one takes no room
+	if err := q.Enqueue(put("b", "2")); err != nil {
+		t.Errorf("Enqueue replacing a pending write: unexpected error: %v", err)
+	}
+	if err := q.Enqueue(put("a", "2")); err != backend.ErrQueueFull {
+		t.Errorf("Enqueue of the key in flight: want ErrQueueFull, got %v", err)
+	}
+
+	close(r.gate)
+	if n := q.Close(time.Second); n != 0 {
+		t.Errorf("Close: want every write applied, got %d left", n)
+	}
+	if want := [][]string{{"a=1"}, {"b=2"}}; !reflect.DeepEqual(r.applied(), want) {
+		t.Errorf("applied: want %v, got %v", want, r.applied())
+	}
+}
+
+func TestQueueCloseTimeout(t *testing.T) {
+	r := &recorder{failures: 1 << 30}
+	q := backend.NewQueue(r, 1, time.Millisecond, 0)
+	for _, key := range []string{"a", "b", "c"} {
+		q.Enqueue(put(key, "1"))
+	}
+
+	start := time.Now()
+	if n := q.Close(50 * time.Millisecond); n != 3 {
+		t.Errorf("Close with a failing backend: want 3 writes left unapplied, got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Close: want it to give up after its timeout, got %s", elapsed)
+	}
+
+	// let the queue drain rather than retry for the rest of the tests
+	r.mu.Lock()
+	r.failures = 0
+	r.mu.Unlock()
+}
+
+func TestWriteBehind(t *testing.T) {
+	r := &recorder{gate: make(chan struct{})}
+	q := backend.NewQueue(r, 1, time.Millisecond, 1)
+	s := backend.WriteBehind(store.NewMemory(0, nil), q)
+
+	if _, err := s.Put("a", store.Entry{Value: []byte("1")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if !waitFor(r.inFlight) {
+		t.Fatal("Put: want the write in flight")
+	}
+	if _, err := s.Put("b", store.Entry{Value: []byte("1")}); err != backend.ErrQueueFull {
+		t.Errorf("Put to a full queue: want ErrQueueFull, got %v", err)
+	}
+	if _, err := s.Get("b"); err != store.ErrNotFound {
+		t.Errorf("Get of a write the queue had no room for: want ErrNotFound, got %v", err)
+	}
+	if e, err := s.Get("a"); err != nil || string(e.Value) != "1" {
+		t.Errorf("Get of a queued write: want it in the store, got %q and %v", e.Value, err)
+	}
+
+	close(r.gate)
+	if n := q.Close(time.Second); n != 0 {
+		t.Errorf("Close: want every write applied, got %d left", n)
+	}
+}
@@ -0,0 +1,159 @@
+// Package sqlite provides a backend keeping the entries of a cache in a
+// SQLite database.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	// registers the sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/timraymond/cachely/backend"
+	"github.com/timraymond/cachely/store"
+)
+
+// schema creates the table entries are kept in. Times are stored as
+// nanoseconds since the Unix epoch, or zero when they are unset, as for
+// entries that never expire. Metadata is stored as a JSON object.
+const schema = `CREATE TABLE IF NOT EXISTS entries (
+	key              TEXT PRIMARY KEY,
+	value            BLOB NOT NULL,
+	expires_at       INTEGER NOT NULL,
+	content_type     TEXT NOT NULL,
+	content_encoding TEXT NOT NULL,
+	metadata         TEXT NOT NULL,
+	created_at       INTEGER NOT NULL,
+	modified_at      INTEGER NOT NULL
+)`
+
+const (
+	putQuery = `INSERT OR REPLACE INTO entries
+		(key, value, expires_at, content_type, content_encoding, metadata, created_at, modified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	deleteQuery = `DELETE FROM entries WHERE key = ?`
+	getQuery    = `SELECT value, expires_at, content_type, content_encoding, metadata, created_at, modified_at
+		FROM entries WHERE key = ?`
+)
+
+// Backend keeps entries in the entries table of a SQLite database. It is a
+// backend.Batcher, applying each batch in a transaction.
+type Backend struct {
+	db *sql.DB
+}
+
+// Open returns a Backend keeping its entries in the SQLite database at path,
+// which is created if it does not exist.
+func Open(path string) (*Backend, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer at a time, so writes are serialized here
+	// rather than failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Backend{db: db}, nil
+}
+
+// Close closes the database.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
+
+// Put stores e at key, replacing any entry already there.
+func (b *Backend) Put(key string, e store.Entry) error {
+	args, err := putArgs(key, e)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec(putQuery, args...)
+	return err
+}
+
+// Delete removes the entry at key.
+func (b *Backend) Delete(key string) error {
+	_, err := b.db.Exec(deleteQuery, key)
+	return err
+}
+
+// Write applies ops in order in a single transaction.
+func (b *Backend) Write(ops []backend.Op) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if op.Delete {
+			_, err = tx.Exec(deleteQuery, op.Key)
+		} else {
+			var args []interface{}
+			if args, err = putArgs(op.Key, op.Entry); err == nil {
+				_, err = tx.Exec(putQuery, args...)
+			}
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Get reads the entry at key back, or returns store.ErrNotFound. Expired
+// entries are returned as they are.
+func (b *Backend) Get(key string) (store.Entry, error) {
+	var e store.Entry
+	var expiresAt, createdAt, modifiedAt int64
+	var metadata string
+	err := b.db.QueryRow(getQuery, key).Scan(&e.Value, &expiresAt, &e.ContentType, &e.ContentEncoding, &metadata, &createdAt, &modifiedAt)
+	if err == sql.ErrNoRows {
+		return store.Entry{}, store.ErrNotFound
+	}
+	if err != nil {
+		return store.Entry{}, err
+	}
+
+	if err := json.Unmarshal([]byte(metadata), &e.Metadata); err != nil {
+		return store.Entry{}, err
+	}
+	e.ExpiresAt = fromUnixNano(expiresAt)
+	e.CreatedAt = fromUnixNano(createdAt)
+	e.ModifiedAt = fromUnixNano(modifiedAt)
+	return e, nil
+}
+
+// putArgs returns the arguments of putQuery storing e at key.
+func putArgs(key string, e store.Entry) ([]interface{}, error) {
+	metadata, err := json.Marshal(e.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	value := e.Value
+	if value == nil {
+		// NULL would violate the NOT NULL constraint
+		value = []byte{}
+	}
+	return []interface{}{key, value, unixNano(e.ExpiresAt), e.ContentType, e.ContentEncoding, string(metadata), unixNano(e.CreatedAt), unixNano(e.ModifiedAt)}, nil
+}
+
+// unixNano returns t as nanoseconds since the Unix epoch, or zero for the
+// zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano is the inverse of unixNano.
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/backend"
+	"github.com/timraymond/cachely/store"
+)
+
+// open returns a Backend keeping its entries in a temporary database, and a
+// function closing and removing it.
+func open(t *testing.T) (*Backend, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cachely-sqlite-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %v", err)
+	}
+	b, err := Open(filepath.Join(dir, "cache.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Open: unexpected error: %v", err)
+	}
+	return b, func() {
+		b.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	b, cleanup := open(t)
+	defer cleanup()
+
+	now := time.Now()
+	entries := map[string]store.Entry{
+		"users/42": {
+			Value:           []byte("profile"),
+			ExpiresAt:       now.Add(time.Hour),
+			ContentType:     "text/plain",
+			ContentEncoding: "gzip",
+			Metadata:        map[string]string{"owner": "team-a"},
+			CreatedAt:       now.Add(-time.Hour),
+			ModifiedAt:      now,
+		},
+		// nil values and zero times are stored as empty values and zeros
+		"empty": {},
+	}
+	for key, e := range entries {
+		if err := b.Put(key, e); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+	for key, want := range entries {
+		got, err := b.Get(key)
+		if err != nil {
+			t.Errorf("Get(%q): unexpected error: %v", key, err)
+			continue
+		}
+		if string(got.Value) != string(want.Value) || !got.ExpiresAt.Equal(want.ExpiresAt) ||
+			got.ContentType != want.ContentType || got.ContentEncoding != want.ContentEncoding ||
+			!reflect.DeepEqual(got.Metadata, want.Metadata) ||
+			!got.CreatedAt.Equal(want.CreatedAt) || !got.ModifiedAt.Equal(want.ModifiedAt) {
+			t.Errorf("Get(%q): want %+v, got %+v", key, want, got)
+		}
+	}
+
+	if err := b.Put("users/42", store.Entry{Value: []byte("new")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if got, err := b.Get("users/42"); err != nil || string(got.Value) != "new" || got.Metadata != nil {
+		t.Errorf("Get after a replacement: want only the new entry, got %+v and %v", got, err)
+	}
+	if err := b.Delete("users/42"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if _, err := b.Get("users/42"); err != store.ErrNotFound {
+		t.Errorf("Get after Delete: want ErrNotFound, got %v", err)
+	}
+	if err := b.Delete("users/42"); err != nil {
+		t.Errorf("Delete of a missing key: unexpected error: %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	b, cleanup := open(t)
+	defer cleanup()
+
+	if err := b.Put("a", store.Entry{Value: []byte("old")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	ops := []backend.Op{
+		{Key: "a", Delete: true},
+		{Key: "b", Entry: store.Entry{Value: []byte("1")}},
+		{Key: "b", Entry: store.Entry{Value: []byte("2")}},
+	}
+	if err := backend.Write(b, ops); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	if _, err := b.Get("a"); err != store.ErrNotFound {
+		t.Errorf("Get of a deleted key: want ErrNotFound, got %v", err)
+	}
+	if got, err := b.Get("b"); err != nil || string(got.Value) != "2" {
+		t.Errorf("Get: want the last write to the key, got %q and %v", got.Value, err)
+	}
+}
+
+func TestWriteRollback(t *testing.T) {
+	b, cleanup := open(t)
+	defer cleanup()
+
+	if _, err := b.db.Exec(`CREATE TRIGGER reject BEFORE INSERT ON entries
+		WHEN NEW.key = 'bad' BEGIN SELECT RAISE(ABORT, 'rejected'); END`); err != nil {
+		t.Fatalf("creating trigger: %v", err)
+	}
+	if err := b.Put("a", store.Entry{Value: []byte("old")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	ops := []backend.Op{
+		{Key: "a", Entry: store.Entry{Value: []byte("new")}},
+		{Key: "b", Entry: store.Entry{Value: []byte("1")}},
+		{Key: "bad", Entry: store.Entry{Value: []byte("1")}},
+	}
+	if err := b.Write(ops); err == nil {
+		t.Fatal("Write: want the failure of bad, got nil")
+	}
+
+	// none of the batch is applied
+	if got, err := b.Get("a"); err != nil || string(got.Value) != "old" {
+		t.Errorf("Get after a failed Write: want old, got %q and %v", got.Value, err)
+	}
+	if _, err := b.Get("b"); err != store.ErrNotFound {
+		t.Errorf("Get after a failed Write: want ErrNotFound, got %v", err)
+	}
+
+	// and the connection is left usable
+	if err := b.Write(ops[:2]); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	if got, err := b.Get("b"); err != nil || string(got.Value) != "1" {
+		t.Errorf("Get: want 1, got %q and %v", got.Value, err)
+	}
+}
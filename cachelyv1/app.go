@@ -1,2 +1,2 @@
-//go:generate protoc -I/usr/local/include -I/usr/local/go-global/1.12/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis --proto_path=../_protos --gogo_out=plugins=grpc:. --grpc-gateway_out=logtostderr=true:. cachely/v1/cache_api.proto
+//go:generate protoc -I/usr/local/include -I/usr/local/go-global/1.12/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis --proto_path=../_protos --gogo_out=plugins=grpc,Mgoogle/protobuf/duration.proto=github.com/gogo/protobuf/types,Mgoogle/protobuf/timestamp.proto=github.com/gogo/protobuf/types,Mgoogle/protobuf/wrappers.proto=github.com/gogo/protobuf/types:. --grpc-gateway_out=logtostderr=true:. cachely/v1/cache_api.proto cachely/v1/lock_api.proto cachely/v1/origin_api.proto
 package cachelyv1
@@ -7,6 +7,7 @@ import (
 	context "context"
 	fmt "fmt"
 	proto "github.com/gogo/protobuf/proto"
+	types "github.com/gogo/protobuf/types"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
@@ -25,8 +26,92 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 
+// WriteMode controls how a Put treats an existing entry at its key.
+type WriteMode int32
+
+const (
+	// WRITE_MODE_INSERT only stores the value if the key is absent, and fails
+	// with ALREADY_EXISTS otherwise.
+	WriteMode_WRITE_MODE_INSERT WriteMode = 0
+	// WRITE_MODE_REPLACE only stores the value if the key is present, and fails
+	// with NOT_FOUND otherwise.
+	WriteMode_WRITE_MODE_REPLACE WriteMode = 1
+	// WRITE_MODE_UPSERT stores the value whether or not the key is present.
+	WriteMode_WRITE_MODE_UPSERT WriteMode = 2
+)
+
+var WriteMode_name = map[int32]string{
+	0: "WRITE_MODE_INSERT",
+	1: "WRITE_MODE_REPLACE",
+	2: "WRITE_MODE_UPSERT",
+}
+
+var WriteMode_value = map[string]int32{
+	"WRITE_MODE_INSERT":  0,
+	"WRITE_MODE_REPLACE": 1,
+	"WRITE_MODE_UPSERT":  2,
+}
+
+func (x WriteMode) String() string {
+	return proto.EnumName(WriteMode_name, int32(x))
+}
+
+func (WriteMode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{0}
+}
+
+// EventType describes how the entry at a key changed.
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED EventType = 0
+	// EVENT_TYPE_PUT is a write to a key that held no entry.
+	EventType_EVENT_TYPE_PUT EventType = 1
+	// EVENT_TYPE_UPDATE is a write replacing the entry at a key.
+	EventType_EVENT_TYPE_UPDATE EventType = 2
+	// EVENT_TYPE_DELETE is the removal of an entry by Delete.
+	EventType_EVENT_TYPE_DELETE EventType = 3
+	// EVENT_TYPE_EXPIRE is the removal of an expired entry.
+	EventType_EVENT_TYPE_EXPIRE EventType = 4
+	// EVENT_TYPE_EVICT is the removal of an entry to stay within the cache's
+	// memory budget.
+	EventType_EVENT_TYPE_EVICT EventType = 5
+)
+
+var EventType_name = map[int32]string{
+	0: "EVENT_TYPE_UNSPECIFIED",
+	1: "EVENT_TYPE_PUT",
+	2: "EVENT_TYPE_UPDATE",
+	3: "EVENT_TYPE_DELETE",
+	4: "EVENT_TYPE_EXPIRE",
+	5: "EVENT_TYPE_EVICT",
+}
+
+var EventType_value = map[string]int32{
+	"EVENT_TYPE_UNSPECIFIED": 0,
+	"EVENT_TYPE_PUT":         1,
+	"EVENT_TYPE_UPDATE":      2,
+	"EVENT_TYPE_DELETE":      3,
+	"EVENT_TYPE_EXPIRE":      4,
+	"EVENT_TYPE_EVICT":       5,
+}
+
+func (x EventType) String() string {
+	return proto.EnumName(EventType_name, int32(x))
+}
+
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{1}
+}
+
 type GetRequest struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// offset and length optionally restrict the value returned to length bytes
+	// starting at offset. A length of zero, or one that goes past the end of the
+	// value, reads up to its end. An offset past the end of the value fails
+	// with OUT_OF_RANGE.
+	Offset               int64    `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length               int64    `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -63,9 +148,43 @@ func (m *GetRequest) GetKey() string {
 	return ""
 }
 
+func (m *GetRequest) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *GetRequest) GetLength() int64 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
 type GetResponse struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// expires_at is the time after which the entry will no longer be served. It
+	// is unset for entries that never expire.
+	ExpiresAt *types.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// version identifies this revision of the entry. Versions increase every
+	// time an entry is written, and are never reused for the same key.
+	Version uint64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	// content_type is the media type the value was stored with, if any.
+	ContentType string `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// content_encoding is the encoding applied to the value, such as gzip, if
+	// any.
+	ContentEncoding string `protobuf:"bytes,6,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
+	// metadata holds the user-defined attributes of the entry.
+	Metadata map[string]string `protobuf:"bytes,7,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// created_at is when the key was first written since it was last absent.
+	CreatedAt *types.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// modified_at is when the entry was last written.
+	ModifiedAt *types.Timestamp `protobuf:"bytes,9,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	// value_size is the length of the whole value in bytes, which is more than
+	// that of value when only part of it was requested.
+	ValueSize            int64    `protobuf:"varint,10,opt,name=value_size,json=valueSize,proto3" json:"value_size,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -109,9 +228,90 @@ func (m *GetResponse) GetValue() []byte {
 	return nil
 }
 
+func (m *GetResponse) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *GetResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *GetResponse) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *GetResponse) GetContentEncoding() string {
+	if m != nil {
+		return m.ContentEncoding
+	}
+	return ""
+}
+
+func (m *GetResponse) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *GetResponse) GetCreatedAt() *types.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *GetResponse) GetModifiedAt() *types.Timestamp {
+	if m != nil {
+		return m.ModifiedAt
+	}
+	return nil
+}
+
+func (m *GetResponse) GetValueSize() int64 {
+	if m != nil {
+		return m.ValueSize
+	}
+	return 0
+}
+
 type PutRequest struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// ttl optionally limits the lifetime of the entry, measured from when it is
+	// stored. It may not be combined with expires_at. Entries stored without an
+	// expiration are kept until they are deleted.
+	Ttl *types.Duration `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// expires_at optionally sets the absolute time at which the entry expires.
+	// It may not be combined with ttl.
+	ExpiresAt *types.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// mode controls what happens when the key already holds a value.
+	Mode WriteMode `protobuf:"varint,5,opt,name=mode,proto3,enum=cachely.v1.WriteMode" json:"mode,omitempty"`
+	// content_type optionally records the media type of the value, such as
+	// image/png, to be returned along with it.
+	ContentType string `protobuf:"bytes,6,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// content_encoding optionally records the encoding applied to the value,
+	// such as gzip, so that readers know how to decode it.
+	ContentEncoding string `protobuf:"bytes,7,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
+	// metadata holds user-defined attributes returned along with the value.
+	// Names are made of lower case letters, digits and dashes, values of
+	// printable ASCII characters, and the names and values of an entry may not
+	// exceed 8 KiB in total.
+	Metadata map[string]string `protobuf:"bytes,8,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// expected_version, when set, only stores the value if the entry is at
+	// this version, as with CompareAndSwap, and mode is then ignored. The write
+	// fails with FAILED_PRECONDITION if the entry has been written since, and
+	// NOT_FOUND if it is absent.
+	ExpectedVersion      uint64   `protobuf:"varint,9,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -155,11 +355,69 @@ func (m *PutRequest) GetValue() []byte {
 	return nil
 }
 
+func (m *PutRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+func (m *PutRequest) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *PutRequest) GetMode() WriteMode {
+	if m != nil {
+		return m.Mode
+	}
+	return WriteMode_WRITE_MODE_INSERT
+}
+
+func (m *PutRequest) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *PutRequest) GetContentEncoding() string {
+	if m != nil {
+		return m.ContentEncoding
+	}
+	return ""
+}
+
+func (m *PutRequest) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *PutRequest) GetExpectedVersion() uint64 {
+	if m != nil {
+		return m.ExpectedVersion
+	}
+	return 0
+}
+
 type PutResponse struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// expires_at is the time at which the stored entry expires. It is unset for
+	// entries that never expire.
+	ExpiresAt *types.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// version identifies the revision of the entry that was stored.
+	Version uint64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// created_at and modified_at are the times of the entry stored, as in
+	// GetResponse.
+	CreatedAt            *types.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ModifiedAt           *types.Timestamp `protobuf:"bytes,5,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *PutResponse) Reset()         { *m = PutResponse{} }
@@ -193,196 +451,2459 @@ func (m *PutResponse) GetKey() string {
 	return ""
 }
 
-type DeleteRequest struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *PutResponse) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
 }
 
-func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
-func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
-func (*DeleteRequest) ProtoMessage()    {}
-func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1a7b39a1e3392aa2, []int{4}
-}
-func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
-}
-func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
-}
-func (m *DeleteRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_DeleteRequest.Merge(m, src)
-}
-func (m *DeleteRequest) XXX_Size() int {
-	return xxx_messageInfo_DeleteRequest.Size(m)
-}
-func (m *DeleteRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
+func (m *PutResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
 }
 
-var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
+func (m *PutResponse) GetCreatedAt() *types.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
 
-func (m *DeleteRequest) GetKey() string {
+func (m *PutResponse) GetModifiedAt() *types.Timestamp {
 	if m != nil {
-		return m.Key
+		return m.ModifiedAt
 	}
-	return ""
+	return nil
 }
 
-type DeleteResponse struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type CompareAndSwapRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// expected_version is the version the entry must be at for the swap to
+	// succeed. Zero expects the key to be absent, so the swap inserts it.
+	ExpectedVersion uint64 `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	Value           []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// ttl and expires_at set the expiration of the new entry, as in PutRequest.
+	Ttl       *types.Duration  `protobuf:"bytes,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	ExpiresAt *types.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// content_type, content_encoding and metadata describe the new value, as
+	// in PutRequest.
+	ContentType          string            `protobuf:"bytes,6,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ContentEncoding      string            `protobuf:"bytes,7,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
+	Metadata             map[string]string `protobuf:"bytes,8,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
-func (m *DeleteResponse) Reset()         { *m = DeleteResponse{} }
-func (m *DeleteResponse) String() string { return proto.CompactTextString(m) }
-func (*DeleteResponse) ProtoMessage()    {}
-func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1a7b39a1e3392aa2, []int{5}
+func (m *CompareAndSwapRequest) Reset()         { *m = CompareAndSwapRequest{} }
+func (m *CompareAndSwapRequest) String() string { return proto.CompactTextString(m) }
+func (*CompareAndSwapRequest) ProtoMessage()    {}
+func (*CompareAndSwapRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{4}
 }
-func (m *DeleteResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_DeleteResponse.Unmarshal(m, b)
+func (m *CompareAndSwapRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompareAndSwapRequest.Unmarshal(m, b)
 }
-func (m *DeleteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_DeleteResponse.Marshal(b, m, deterministic)
+func (m *CompareAndSwapRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompareAndSwapRequest.Marshal(b, m, deterministic)
 }
-func (m *DeleteResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_DeleteResponse.Merge(m, src)
+func (m *CompareAndSwapRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompareAndSwapRequest.Merge(m, src)
 }
-func (m *DeleteResponse) XXX_Size() int {
-	return xxx_messageInfo_DeleteResponse.Size(m)
+func (m *CompareAndSwapRequest) XXX_Size() int {
+	return xxx_messageInfo_CompareAndSwapRequest.Size(m)
 }
-func (m *DeleteResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_DeleteResponse.DiscardUnknown(m)
+func (m *CompareAndSwapRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompareAndSwapRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_DeleteResponse proto.InternalMessageInfo
+var xxx_messageInfo_CompareAndSwapRequest proto.InternalMessageInfo
 
-func (m *DeleteResponse) GetKey() string {
+func (m *CompareAndSwapRequest) GetKey() string {
 	if m != nil {
 		return m.Key
 	}
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*GetRequest)(nil), "cachely.v1.GetRequest")
-	proto.RegisterType((*GetResponse)(nil), "cachely.v1.GetResponse")
-	proto.RegisterType((*PutRequest)(nil), "cachely.v1.PutRequest")
-	proto.RegisterType((*PutResponse)(nil), "cachely.v1.PutResponse")
-	proto.RegisterType((*DeleteRequest)(nil), "cachely.v1.DeleteRequest")
-	proto.RegisterType((*DeleteResponse)(nil), "cachely.v1.DeleteResponse")
-}
-
-func init() { proto.RegisterFile("cachely/v1/cache_api.proto", fileDescriptor_1a7b39a1e3392aa2) }
-
-var fileDescriptor_1a7b39a1e3392aa2 = []byte{
-	// 345 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0x4a, 0x4e, 0x4c, 0xce,
-	0x48, 0xcd, 0xa9, 0xd4, 0x2f, 0x33, 0xd4, 0x07, 0x33, 0xe3, 0x13, 0x0b, 0x32, 0xf5, 0x0a, 0x8a,
-	0xf2, 0x4b, 0xf2, 0x85, 0xb8, 0xa0, 0x72, 0x7a, 0x65, 0x86, 0x52, 0x32, 0xe9, 0xf9, 0xf9, 0xe9,
-	0x39, 0xa9, 0xfa, 0x89, 0x05, 0x99, 0xfa, 0x89, 0x79, 0x79, 0xf9, 0x25, 0x89, 0x25, 0x99, 0xf9,
-	0x79, 0xc5, 0x10, 0x95, 0x4a, 0x72, 0x5c, 0x5c, 0xee, 0xa9, 0x25, 0x41, 0xa9, 0x85, 0xa5, 0xa9,
-	0xc5, 0x25, 0x42, 0x02, 0x5c, 0xcc, 0xd9, 0xa9, 0x95, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0x9c, 0x41,
-	0x20, 0xa6, 0x92, 0x29, 0x17, 0x37, 0x58, 0xbe, 0xb8, 0x20, 0x3f, 0xaf, 0x38, 0x15, 0x53, 0x81,
-	0x90, 0x08, 0x17, 0x6b, 0x59, 0x62, 0x4e, 0x69, 0xaa, 0x04, 0x93, 0x02, 0xa3, 0x06, 0x4f, 0x10,
-	0x84, 0xa3, 0x64, 0xc2, 0xc5, 0x15, 0x50, 0x8a, 0xdb, 0x58, 0x1c, 0xba, 0xe4, 0xb9, 0xb8, 0xc1,
-	0xba, 0x70, 0x59, 0xa6, 0xa4, 0xc8, 0xc5, 0xeb, 0x92, 0x9a, 0x93, 0x5a, 0x92, 0x8a, 0xdb, 0xc1,
-	0x4a, 0x5c, 0x7c, 0x30, 0x25, 0xb8, 0x8c, 0x31, 0x5a, 0xc8, 0xc4, 0xc5, 0xe1, 0x0c, 0x0a, 0x21,
-	0xc7, 0x00, 0x4f, 0xa1, 0x48, 0x2e, 0x66, 0xf7, 0xd4, 0x12, 0x21, 0x31, 0x3d, 0x44, 0x98, 0xe9,
-	0x21, 0x82, 0x44, 0x4a, 0x1c, 0x43, 0x1c, 0x62, 0xac, 0x92, 0x62, 0xd3, 0xe5, 0x27, 0x93, 0x99,
-	0xa4, 0x85, 0x24, 0xf5, 0x91, 0x22, 0x22, 0x3f, 0x29, 0x2b, 0x35, 0xb9, 0xa4, 0x58, 0xbf, 0x3a,
-	0x3b, 0xb5, 0xb2, 0x56, 0x28, 0x8c, 0x8b, 0x39, 0xa0, 0x14, 0xcd, 0x68, 0x44, 0xb0, 0xa0, 0x1a,
-	0x8d, 0xe4, 0x71, 0x25, 0x39, 0xb0, 0xd1, 0x12, 0x4a, 0xc2, 0x58, 0x8c, 0xb6, 0x62, 0xd4, 0x12,
-	0x4a, 0xe2, 0x62, 0x83, 0xf8, 0x51, 0x48, 0x12, 0xd9, 0x08, 0x94, 0xa0, 0x91, 0x92, 0xc2, 0x26,
-	0x85, 0xea, 0x76, 0x2d, 0xdc, 0x6e, 0x77, 0xf2, 0xe1, 0xe2, 0x4b, 0xce, 0xcf, 0x45, 0x32, 0xc3,
-	0x89, 0x17, 0x12, 0x64, 0x05, 0x99, 0x01, 0xa0, 0x94, 0x13, 0xc0, 0x18, 0xc5, 0x09, 0x95, 0x2c,
-	0x33, 0x5c, 0xc4, 0xc4, 0xec, 0x1c, 0x11, 0xb1, 0x8a, 0x89, 0xcb, 0x19, 0xaa, 0x3c, 0xcc, 0xf0,
-	0x14, 0x9c, 0x13, 0x13, 0x66, 0x98, 0xc4, 0x06, 0x4e, 0x6d, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff,
-	0xff, 0x33, 0x4e, 0xe7, 0x7b, 0xb5, 0x02, 0x00, 0x00,
+func (m *CompareAndSwapRequest) GetExpectedVersion() uint64 {
+	if m != nil {
+		return m.ExpectedVersion
+	}
+	return 0
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// CacheAPIClient is the client API for CacheAPI service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type CacheAPIClient interface {
-	// Get retrieves a value from the cache.
-	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
-	// Put adds a new value to the cache.
-	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
-	// Delete removes a cached value from the cache.
-	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+func (m *CompareAndSwapRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
 }
 
-type cacheAPIClient struct {
-	cc *grpc.ClientConn
+func (m *CompareAndSwapRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
 }
 
-func NewCacheAPIClient(cc *grpc.ClientConn) CacheAPIClient {
-	return &cacheAPIClient{cc}
+func (m *CompareAndSwapRequest) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
 }
 
-func (c *cacheAPIClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
-	out := new(GetResponse)
-	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Get", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *CompareAndSwapRequest) GetContentType() string {
+	if m != nil {
+		return m.ContentType
 	}
-	return out, nil
+	return ""
 }
 
-func (c *cacheAPIClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error) {
-	out := new(PutResponse)
-	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Put", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *CompareAndSwapRequest) GetContentEncoding() string {
+	if m != nil {
+		return m.ContentEncoding
 	}
-	return out, nil
+	return ""
 }
 
-func (c *cacheAPIClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
-	out := new(DeleteResponse)
-	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Delete", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *CompareAndSwapRequest) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
 	}
-	return out, nil
+	return nil
 }
 
-// CacheAPIServer is the server API for CacheAPI service.
-type CacheAPIServer interface {
-	// Get retrieves a value from the cache.
-	Get(context.Context, *GetRequest) (*GetResponse, error)
-	// Put adds a new value to the cache.
-	Put(context.Context, *PutRequest) (*PutResponse, error)
-	// Delete removes a cached value from the cache.
-	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+type CompareAndSwapResponse struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// expires_at is the time at which the stored entry expires. It is unset for
+	// entries that never expire.
+	ExpiresAt *types.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// version identifies the revision of the entry that was stored.
+	Version uint64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// created_at and modified_at are the times of the entry stored, as in
+	// GetResponse.
+	CreatedAt            *types.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ModifiedAt           *types.Timestamp `protobuf:"bytes,5,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
-// UnimplementedCacheAPIServer can be embedded to have forward compatible implementations.
-type UnimplementedCacheAPIServer struct {
+func (m *CompareAndSwapResponse) Reset()         { *m = CompareAndSwapResponse{} }
+func (m *CompareAndSwapResponse) String() string { return proto.CompactTextString(m) }
+func (*CompareAndSwapResponse) ProtoMessage()    {}
+func (*CompareAndSwapResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{5}
 }
-
-func (*UnimplementedCacheAPIServer) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+func (m *CompareAndSwapResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompareAndSwapResponse.Unmarshal(m, b)
 }
-func (*UnimplementedCacheAPIServer) Put(ctx context.Context, req *PutRequest) (*PutResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+func (m *CompareAndSwapResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompareAndSwapResponse.Marshal(b, m, deterministic)
 }
-func (*UnimplementedCacheAPIServer) Delete(ctx context.Context, req *DeleteRequest) (*DeleteResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+func (m *CompareAndSwapResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompareAndSwapResponse.Merge(m, src)
+}
+func (m *CompareAndSwapResponse) XXX_Size() int {
+	return xxx_messageInfo_CompareAndSwapResponse.Size(m)
+}
+func (m *CompareAndSwapResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompareAndSwapResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompareAndSwapResponse proto.InternalMessageInfo
+
+func (m *CompareAndSwapResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *CompareAndSwapResponse) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *CompareAndSwapResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *CompareAndSwapResponse) GetCreatedAt() *types.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *CompareAndSwapResponse) GetModifiedAt() *types.Timestamp {
+	if m != nil {
+		return m.ModifiedAt
+	}
+	return nil
+}
+
+type IncrementRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// delta is added to the counter. It defaults to 1 when unset, while a delta
+	// of 0 leaves the counter as it is, which reads it, creating it if it is
+	// missing.
+	Delta *types.Int64Value `protobuf:"bytes,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	// initial_value is the value a missing counter starts at, before delta is
+	// added.
+	InitialValue int64 `protobuf:"varint,3,opt,name=initial_value,json=initialValue,proto3" json:"initial_value,omitempty"`
+	// ttl optionally limits the lifetime of a counter created by the request.
+	// The expiration of existing counters is left as it is.
+	Ttl                  *types.Duration `protobuf:"bytes,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *IncrementRequest) Reset()         { *m = IncrementRequest{} }
+func (m *IncrementRequest) String() string { return proto.CompactTextString(m) }
+func (*IncrementRequest) ProtoMessage()    {}
+func (*IncrementRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{6}
+}
+func (m *IncrementRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrementRequest.Unmarshal(m, b)
+}
+func (m *IncrementRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrementRequest.Marshal(b, m, deterministic)
+}
+func (m *IncrementRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrementRequest.Merge(m, src)
+}
+func (m *IncrementRequest) XXX_Size() int {
+	return xxx_messageInfo_IncrementRequest.Size(m)
+}
+func (m *IncrementRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrementRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrementRequest proto.InternalMessageInfo
+
+func (m *IncrementRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *IncrementRequest) GetDelta() *types.Int64Value {
+	if m != nil {
+		return m.Delta
+	}
+	return nil
+}
+
+func (m *IncrementRequest) GetInitialValue() int64 {
+	if m != nil {
+		return m.InitialValue
+	}
+	return 0
+}
+
+func (m *IncrementRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+type IncrementResponse struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value is the value of the counter after the increment.
+	Value int64 `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	// version identifies the revision of the entry that was stored.
+	Version uint64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// expires_at is the time at which the counter expires. It is unset for
+	// counters that never expire.
+	ExpiresAt            *types.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *IncrementResponse) Reset()         { *m = IncrementResponse{} }
+func (m *IncrementResponse) String() string { return proto.CompactTextString(m) }
+func (*IncrementResponse) ProtoMessage()    {}
+func (*IncrementResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{7}
+}
+func (m *IncrementResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrementResponse.Unmarshal(m, b)
+}
+func (m *IncrementResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrementResponse.Marshal(b, m, deterministic)
+}
+func (m *IncrementResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrementResponse.Merge(m, src)
+}
+func (m *IncrementResponse) XXX_Size() int {
+	return xxx_messageInfo_IncrementResponse.Size(m)
+}
+func (m *IncrementResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrementResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrementResponse proto.InternalMessageInfo
+
+func (m *IncrementResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *IncrementResponse) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *IncrementResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *IncrementResponse) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+type DecrementRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// delta is subtracted from the counter. It defaults to 1 when unset, and
+	// may be 0, as in IncrementRequest.
+	Delta *types.Int64Value `protobuf:"bytes,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	// initial_value and ttl apply to missing counters, as in IncrementRequest.
+	InitialValue         int64           `protobuf:"varint,3,opt,name=initial_value,json=initialValue,proto3" json:"initial_value,omitempty"`
+	Ttl                  *types.Duration `protobuf:"bytes,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *DecrementRequest) Reset()         { *m = DecrementRequest{} }
+func (m *DecrementRequest) String() string { return proto.CompactTextString(m) }
+func (*DecrementRequest) ProtoMessage()    {}
+func (*DecrementRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{8}
+}
+func (m *DecrementRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DecrementRequest.Unmarshal(m, b)
+}
+func (m *DecrementRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DecrementRequest.Marshal(b, m, deterministic)
+}
+func (m *DecrementRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DecrementRequest.Merge(m, src)
+}
+func (m *DecrementRequest) XXX_Size() int {
+	return xxx_messageInfo_DecrementRequest.Size(m)
+}
+func (m *DecrementRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DecrementRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DecrementRequest proto.InternalMessageInfo
+
+func (m *DecrementRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *DecrementRequest) GetDelta() *types.Int64Value {
+	if m != nil {
+		return m.Delta
+	}
+	return nil
+}
+
+func (m *DecrementRequest) GetInitialValue() int64 {
+	if m != nil {
+		return m.InitialValue
+	}
+	return 0
+}
+
+func (m *DecrementRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+type DecrementResponse struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value is the value of the counter after the decrement.
+	Value int64 `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	// version and expires_at describe the entry stored, as in
+	// IncrementResponse.
+	Version              uint64           `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	ExpiresAt            *types.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *DecrementResponse) Reset()         { *m = DecrementResponse{} }
+func (m *DecrementResponse) String() string { return proto.CompactTextString(m) }
+func (*DecrementResponse) ProtoMessage()    {}
+func (*DecrementResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{9}
+}
+func (m *DecrementResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DecrementResponse.Unmarshal(m, b)
+}
+func (m *DecrementResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DecrementResponse.Marshal(b, m, deterministic)
+}
+func (m *DecrementResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DecrementResponse.Merge(m, src)
+}
+func (m *DecrementResponse) XXX_Size() int {
+	return xxx_messageInfo_DecrementResponse.Size(m)
+}
+func (m *DecrementResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DecrementResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DecrementResponse proto.InternalMessageInfo
+
+func (m *DecrementResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *DecrementResponse) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *DecrementResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *DecrementResponse) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+type AppendRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value is added to the end of the stored value.
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// max_value_size optionally bounds the length in bytes of the resulting
+	// value.
+	MaxValueSize int64 `protobuf:"varint,3,opt,name=max_value_size,json=maxValueSize,proto3" json:"max_value_size,omitempty"`
+	// create_if_missing stores value at the key when it is missing, instead of
+	// failing with NOT_FOUND.
+	CreateIfMissing bool `protobuf:"varint,4,opt,name=create_if_missing,json=createIfMissing,proto3" json:"create_if_missing,omitempty"`
+	// ttl optionally limits the lifetime of an entry created by the request.
+	// The expiration of existing entries is left as it is.
+	Ttl                  *types.Duration `protobuf:"bytes,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *AppendRequest) Reset()         { *m = AppendRequest{} }
+func (m *AppendRequest) String() string { return proto.CompactTextString(m) }
+func (*AppendRequest) ProtoMessage()    {}
+func (*AppendRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{10}
+}
+func (m *AppendRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AppendRequest.Unmarshal(m, b)
+}
+func (m *AppendRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AppendRequest.Marshal(b, m, deterministic)
+}
+func (m *AppendRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AppendRequest.Merge(m, src)
+}
+func (m *AppendRequest) XXX_Size() int {
+	return xxx_messageInfo_AppendRequest.Size(m)
+}
+func (m *AppendRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AppendRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AppendRequest proto.InternalMessageInfo
+
+func (m *AppendRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *AppendRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *AppendRequest) GetMaxValueSize() int64 {
+	if m != nil {
+		return m.MaxValueSize
+	}
+	return 0
+}
+
+func (m *AppendRequest) GetCreateIfMissing() bool {
+	if m != nil {
+		return m.CreateIfMissing
+	}
+	return false
+}
+
+func (m *AppendRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+type AppendResponse struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value_size is the length in bytes of the resulting value.
+	ValueSize int64 `protobuf:"varint,2,opt,name=value_size,json=valueSize,proto3" json:"value_size,omitempty"`
+	// version identifies the revision of the entry that was stored.
+	Version uint64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// expires_at is the time at which the entry expires. It is unset for
+	// entries that never expire.
+	ExpiresAt            *types.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *AppendResponse) Reset()         { *m = AppendResponse{} }
+func (m *AppendResponse) String() string { return proto.CompactTextString(m) }
+func (*AppendResponse) ProtoMessage()    {}
+func (*AppendResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{11}
+}
+func (m *AppendResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AppendResponse.Unmarshal(m, b)
+}
+func (m *AppendResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AppendResponse.Marshal(b, m, deterministic)
+}
+func (m *AppendResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AppendResponse.Merge(m, src)
+}
+func (m *AppendResponse) XXX_Size() int {
+	return xxx_messageInfo_AppendResponse.Size(m)
+}
+func (m *AppendResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AppendResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AppendResponse proto.InternalMessageInfo
+
+func (m *AppendResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *AppendResponse) GetValueSize() int64 {
+	if m != nil {
+		return m.ValueSize
+	}
+	return 0
+}
+
+func (m *AppendResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *AppendResponse) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+type PrependRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value is added to the start of the stored value.
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// max_value_size, create_if_missing and ttl are as in AppendRequest.
+	MaxValueSize         int64           `protobuf:"varint,3,opt,name=max_value_size,json=maxValueSize,proto3" json:"max_value_size,omitempty"`
+	CreateIfMissing      bool            `protobuf:"varint,4,opt,name=create_if_missing,json=createIfMissing,proto3" json:"create_if_missing,omitempty"`
+	Ttl                  *types.Duration `protobuf:"bytes,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *PrependRequest) Reset()         { *m = PrependRequest{} }
+func (m *PrependRequest) String() string { return proto.CompactTextString(m) }
+func (*PrependRequest) ProtoMessage()    {}
+func (*PrependRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{12}
+}
+func (m *PrependRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PrependRequest.Unmarshal(m, b)
+}
+func (m *PrependRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PrependRequest.Marshal(b, m, deterministic)
+}
+func (m *PrependRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PrependRequest.Merge(m, src)
+}
+func (m *PrependRequest) XXX_Size() int {
+	return xxx_messageInfo_PrependRequest.Size(m)
+}
+func (m *PrependRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PrependRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PrependRequest proto.InternalMessageInfo
+
+func (m *PrependRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *PrependRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *PrependRequest) GetMaxValueSize() int64 {
+	if m != nil {
+		return m.MaxValueSize
+	}
+	return 0
+}
+
+func (m *PrependRequest) GetCreateIfMissing() bool {
+	if m != nil {
+		return m.CreateIfMissing
+	}
+	return false
+}
+
+func (m *PrependRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+type PrependResponse struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value_size, version and expires_at describe the entry stored, as in
+	// AppendResponse.
+	ValueSize            int64            `protobuf:"varint,2,opt,name=value_size,json=valueSize,proto3" json:"value_size,omitempty"`
+	Version              uint64           `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	ExpiresAt            *types.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *PrependResponse) Reset()         { *m = PrependResponse{} }
+func (m *PrependResponse) String() string { return proto.CompactTextString(m) }
+func (*PrependResponse) ProtoMessage()    {}
+func (*PrependResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{13}
+}
+func (m *PrependResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PrependResponse.Unmarshal(m, b)
+}
+func (m *PrependResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PrependResponse.Marshal(b, m, deterministic)
+}
+func (m *PrependResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PrependResponse.Merge(m, src)
+}
+func (m *PrependResponse) XXX_Size() int {
+	return xxx_messageInfo_PrependResponse.Size(m)
+}
+func (m *PrependResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PrependResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PrependResponse proto.InternalMessageInfo
+
+func (m *PrependResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *PrependResponse) GetValueSize() int64 {
+	if m != nil {
+		return m.ValueSize
+	}
+	return 0
+}
+
+func (m *PrependResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *PrependResponse) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+type PutStreamRequest struct {
+	// header is the write the value belongs to, and must only be set in the
+	// first message. Its value must be empty.
+	Header *PutRequest `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// chunk is the next part of the value.
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	// checksum must be set in the last message.
+	Checksum             *Checksum `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *PutStreamRequest) Reset()         { *m = PutStreamRequest{} }
+func (m *PutStreamRequest) String() string { return proto.CompactTextString(m) }
+func (*PutStreamRequest) ProtoMessage()    {}
+func (*PutStreamRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{14}
+}
+func (m *PutStreamRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutStreamRequest.Unmarshal(m, b)
+}
+func (m *PutStreamRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutStreamRequest.Marshal(b, m, deterministic)
+}
+func (m *PutStreamRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutStreamRequest.Merge(m, src)
+}
+func (m *PutStreamRequest) XXX_Size() int {
+	return xxx_messageInfo_PutStreamRequest.Size(m)
+}
+func (m *PutStreamRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutStreamRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutStreamRequest proto.InternalMessageInfo
+
+func (m *PutStreamRequest) GetHeader() *PutRequest {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *PutStreamRequest) GetChunk() []byte {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+func (m *PutStreamRequest) GetChecksum() *Checksum {
+	if m != nil {
+		return m.Checksum
+	}
+	return nil
+}
+
+type GetStreamRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// chunk_size is the maximum number of bytes of the value sent in each
+	// message. It defaults to 64 KiB and may not exceed 1 MiB.
+	ChunkSize int32 `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	// offset and length optionally restrict the part of the value sent, as in
+	// GetRequest.
+	Offset               int64    `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length               int64    `protobuf:"varint,4,opt,name=length,proto3" json:"length,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStreamRequest) Reset()         { *m = GetStreamRequest{} }
+func (m *GetStreamRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStreamRequest) ProtoMessage()    {}
+func (*GetStreamRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{15}
+}
+func (m *GetStreamRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStreamRequest.Unmarshal(m, b)
+}
+func (m *GetStreamRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStreamRequest.Marshal(b, m, deterministic)
+}
+func (m *GetStreamRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStreamRequest.Merge(m, src)
+}
+func (m *GetStreamRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStreamRequest.Size(m)
+}
+func (m *GetStreamRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStreamRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStreamRequest proto.InternalMessageInfo
+
+func (m *GetStreamRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetStreamRequest) GetChunkSize() int32 {
+	if m != nil {
+		return m.ChunkSize
+	}
+	return 0
+}
+
+func (m *GetStreamRequest) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *GetStreamRequest) GetLength() int64 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
+type GetStreamResponse struct {
+	// header is the entry read, without its value. It is only set in the first
+	// message.
+	Header *GetResponse `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// value_size is the length of the whole value in bytes, even when only part
+	// of it is sent, and is only set in the first message.
+	ValueSize int64 `protobuf:"varint,2,opt,name=value_size,json=valueSize,proto3" json:"value_size,omitempty"`
+	// chunk is the next part of the value.
+	Chunk []byte `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	// checksum is only set in the last message, and covers the part of the
+	// value sent.
+	Checksum             *Checksum `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *GetStreamResponse) Reset()         { *m = GetStreamResponse{} }
+func (m *GetStreamResponse) String() string { return proto.CompactTextString(m) }
+func (*GetStreamResponse) ProtoMessage()    {}
+func (*GetStreamResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{16}
+}
+func (m *GetStreamResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStreamResponse.Unmarshal(m, b)
+}
+func (m *GetStreamResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStreamResponse.Marshal(b, m, deterministic)
+}
+func (m *GetStreamResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStreamResponse.Merge(m, src)
+}
+func (m *GetStreamResponse) XXX_Size() int {
+	return xxx_messageInfo_GetStreamResponse.Size(m)
+}
+func (m *GetStreamResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStreamResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStreamResponse proto.InternalMessageInfo
+
+func (m *GetStreamResponse) GetHeader() *GetResponse {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *GetStreamResponse) GetValueSize() int64 {
+	if m != nil {
+		return m.ValueSize
+	}
+	return 0
+}
+
+func (m *GetStreamResponse) GetChunk() []byte {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+func (m *GetStreamResponse) GetChecksum() *Checksum {
+	if m != nil {
+		return m.Checksum
+	}
+	return nil
+}
+
+// Checksum ends a value sent in chunks.
+type Checksum struct {
+	// crc32c is the CRC-32C (Castagnoli) of the whole value.
+	Crc32C               uint32   `protobuf:"varint,1,opt,name=crc32c,proto3" json:"crc32c,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Checksum) Reset()         { *m = Checksum{} }
+func (m *Checksum) String() string { return proto.CompactTextString(m) }
+func (*Checksum) ProtoMessage()    {}
+func (*Checksum) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{17}
+}
+func (m *Checksum) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Checksum.Unmarshal(m, b)
+}
+func (m *Checksum) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Checksum.Marshal(b, m, deterministic)
+}
+func (m *Checksum) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Checksum.Merge(m, src)
+}
+func (m *Checksum) XXX_Size() int {
+	return xxx_messageInfo_Checksum.Size(m)
+}
+func (m *Checksum) XXX_DiscardUnknown() {
+	xxx_messageInfo_Checksum.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Checksum proto.InternalMessageInfo
+
+func (m *Checksum) GetCrc32C() uint32 {
+	if m != nil {
+		return m.Crc32C
+	}
+	return 0
+}
+
+type DeleteRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// expected_version, when set, only deletes the entry if it is at this
+	// version, failing with FAILED_PRECONDITION otherwise.
+	ExpectedVersion      uint64   `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{18}
+}
+func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
+}
+func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteRequest.Merge(m, src)
+}
+func (m *DeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteRequest.Size(m)
+}
+func (m *DeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
+
+func (m *DeleteRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *DeleteRequest) GetExpectedVersion() uint64 {
+	if m != nil {
+		return m.ExpectedVersion
+	}
+	return 0
+}
+
+type DeleteResponse struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteResponse) Reset()         { *m = DeleteResponse{} }
+func (m *DeleteResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteResponse) ProtoMessage()    {}
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{19}
+}
+func (m *DeleteResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteResponse.Unmarshal(m, b)
+}
+func (m *DeleteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteResponse.Merge(m, src)
+}
+func (m *DeleteResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteResponse.Size(m)
+}
+func (m *DeleteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteResponse proto.InternalMessageInfo
+
+func (m *DeleteResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+// ItemError describes why a single item of a batch failed.
+type ItemError struct {
+	// code is the canonical gRPC status code of the failure.
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ItemError) Reset()         { *m = ItemError{} }
+func (m *ItemError) String() string { return proto.CompactTextString(m) }
+func (*ItemError) ProtoMessage()    {}
+func (*ItemError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{20}
+}
+func (m *ItemError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ItemError.Unmarshal(m, b)
+}
+func (m *ItemError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ItemError.Marshal(b, m, deterministic)
+}
+func (m *ItemError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ItemError.Merge(m, src)
+}
+func (m *ItemError) XXX_Size() int {
+	return xxx_messageInfo_ItemError.Size(m)
+}
+func (m *ItemError) XXX_DiscardUnknown() {
+	xxx_messageInfo_ItemError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ItemError proto.InternalMessageInfo
+
+func (m *ItemError) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *ItemError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type BatchGetRequest struct {
+	Keys                 []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchGetRequest) Reset()         { *m = BatchGetRequest{} }
+func (m *BatchGetRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchGetRequest) ProtoMessage()    {}
+func (*BatchGetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{21}
+}
+func (m *BatchGetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchGetRequest.Unmarshal(m, b)
+}
+func (m *BatchGetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchGetRequest.Marshal(b, m, deterministic)
+}
+func (m *BatchGetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchGetRequest.Merge(m, src)
+}
+func (m *BatchGetRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchGetRequest.Size(m)
+}
+func (m *BatchGetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchGetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchGetRequest proto.InternalMessageInfo
+
+func (m *BatchGetRequest) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type BatchGetResponse struct {
+	// results holds a result for each requested key, in request order.
+	Results              []*BatchGetResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *BatchGetResponse) Reset()         { *m = BatchGetResponse{} }
+func (m *BatchGetResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchGetResponse) ProtoMessage()    {}
+func (*BatchGetResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{22}
+}
+func (m *BatchGetResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchGetResponse.Unmarshal(m, b)
+}
+func (m *BatchGetResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchGetResponse.Marshal(b, m, deterministic)
+}
+func (m *BatchGetResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchGetResponse.Merge(m, src)
+}
+func (m *BatchGetResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchGetResponse.Size(m)
+}
+func (m *BatchGetResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchGetResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchGetResponse proto.InternalMessageInfo
+
+func (m *BatchGetResponse) GetResults() []*BatchGetResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type BatchGetResult struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// entry is set when the key was read.
+	Entry *GetResponse `protobuf:"bytes,2,opt,name=entry,proto3" json:"entry,omitempty"`
+	// error is set when the key could not be read.
+	Error                *ItemError `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *BatchGetResult) Reset()         { *m = BatchGetResult{} }
+func (m *BatchGetResult) String() string { return proto.CompactTextString(m) }
+func (*BatchGetResult) ProtoMessage()    {}
+func (*BatchGetResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{23}
+}
+func (m *BatchGetResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchGetResult.Unmarshal(m, b)
+}
+func (m *BatchGetResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchGetResult.Marshal(b, m, deterministic)
+}
+func (m *BatchGetResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchGetResult.Merge(m, src)
+}
+func (m *BatchGetResult) XXX_Size() int {
+	return xxx_messageInfo_BatchGetResult.Size(m)
+}
+func (m *BatchGetResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchGetResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchGetResult proto.InternalMessageInfo
+
+func (m *BatchGetResult) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *BatchGetResult) GetEntry() *GetResponse {
+	if m != nil {
+		return m.Entry
+	}
+	return nil
+}
+
+func (m *BatchGetResult) GetError() *ItemError {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type BatchPutRequest struct {
+	// items are applied in order, so later writes to a key see earlier ones.
+	Items                []*PutRequest `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *BatchPutRequest) Reset()         { *m = BatchPutRequest{} }
+func (m *BatchPutRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchPutRequest) ProtoMessage()    {}
+func (*BatchPutRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{24}
+}
+func (m *BatchPutRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchPutRequest.Unmarshal(m, b)
+}
+func (m *BatchPutRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchPutRequest.Marshal(b, m, deterministic)
+}
+func (m *BatchPutRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchPutRequest.Merge(m, src)
+}
+func (m *BatchPutRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchPutRequest.Size(m)
+}
+func (m *BatchPutRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchPutRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchPutRequest proto.InternalMessageInfo
+
+func (m *BatchPutRequest) GetItems() []*PutRequest {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type BatchPutResponse struct {
+	// results holds a result for each item, in request order.
+	Results              []*BatchPutResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *BatchPutResponse) Reset()         { *m = BatchPutResponse{} }
+func (m *BatchPutResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchPutResponse) ProtoMessage()    {}
+func (*BatchPutResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{25}
+}
+func (m *BatchPutResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchPutResponse.Unmarshal(m, b)
+}
+func (m *BatchPutResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchPutResponse.Marshal(b, m, deterministic)
+}
+func (m *BatchPutResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchPutResponse.Merge(m, src)
+}
+func (m *BatchPutResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchPutResponse.Size(m)
+}
+func (m *BatchPutResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchPutResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchPutResponse proto.InternalMessageInfo
+
+func (m *BatchPutResponse) GetResults() []*BatchPutResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type BatchPutResult struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// entry is set when the item was stored.
+	Entry *PutResponse `protobuf:"bytes,2,opt,name=entry,proto3" json:"entry,omitempty"`
+	// error is set when the item could not be stored.
+	Error                *ItemError `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *BatchPutResult) Reset()         { *m = BatchPutResult{} }
+func (m *BatchPutResult) String() string { return proto.CompactTextString(m) }
+func (*BatchPutResult) ProtoMessage()    {}
+func (*BatchPutResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{26}
+}
+func (m *BatchPutResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchPutResult.Unmarshal(m, b)
+}
+func (m *BatchPutResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchPutResult.Marshal(b, m, deterministic)
+}
+func (m *BatchPutResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchPutResult.Merge(m, src)
+}
+func (m *BatchPutResult) XXX_Size() int {
+	return xxx_messageInfo_BatchPutResult.Size(m)
+}
+func (m *BatchPutResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchPutResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchPutResult proto.InternalMessageInfo
+
+func (m *BatchPutResult) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *BatchPutResult) GetEntry() *PutResponse {
+	if m != nil {
+		return m.Entry
+	}
+	return nil
+}
+
+func (m *BatchPutResult) GetError() *ItemError {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type BatchDeleteRequest struct {
+	Keys                 []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchDeleteRequest) Reset()         { *m = BatchDeleteRequest{} }
+func (m *BatchDeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchDeleteRequest) ProtoMessage()    {}
+func (*BatchDeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{27}
+}
+func (m *BatchDeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchDeleteRequest.Unmarshal(m, b)
+}
+func (m *BatchDeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchDeleteRequest.Marshal(b, m, deterministic)
+}
+func (m *BatchDeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchDeleteRequest.Merge(m, src)
+}
+func (m *BatchDeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchDeleteRequest.Size(m)
+}
+func (m *BatchDeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchDeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchDeleteRequest proto.InternalMessageInfo
+
+func (m *BatchDeleteRequest) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type BatchDeleteResponse struct {
+	// results holds a result for each requested key, in request order.
+	Results              []*BatchDeleteResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *BatchDeleteResponse) Reset()         { *m = BatchDeleteResponse{} }
+func (m *BatchDeleteResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchDeleteResponse) ProtoMessage()    {}
+func (*BatchDeleteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{28}
+}
+func (m *BatchDeleteResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchDeleteResponse.Unmarshal(m, b)
+}
+func (m *BatchDeleteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchDeleteResponse.Marshal(b, m, deterministic)
+}
+func (m *BatchDeleteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchDeleteResponse.Merge(m, src)
+}
+func (m *BatchDeleteResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchDeleteResponse.Size(m)
+}
+func (m *BatchDeleteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchDeleteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchDeleteResponse proto.InternalMessageInfo
+
+func (m *BatchDeleteResponse) GetResults() []*BatchDeleteResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type BatchDeleteResult struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// error is set when the key could not be deleted.
+	Error                *ItemError `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *BatchDeleteResult) Reset()         { *m = BatchDeleteResult{} }
+func (m *BatchDeleteResult) String() string { return proto.CompactTextString(m) }
+func (*BatchDeleteResult) ProtoMessage()    {}
+func (*BatchDeleteResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{29}
+}
+func (m *BatchDeleteResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchDeleteResult.Unmarshal(m, b)
+}
+func (m *BatchDeleteResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchDeleteResult.Marshal(b, m, deterministic)
+}
+func (m *BatchDeleteResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchDeleteResult.Merge(m, src)
+}
+func (m *BatchDeleteResult) XXX_Size() int {
+	return xxx_messageInfo_BatchDeleteResult.Size(m)
+}
+func (m *BatchDeleteResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchDeleteResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchDeleteResult proto.InternalMessageInfo
+
+func (m *BatchDeleteResult) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *BatchDeleteResult) GetError() *ItemError {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type ListRequest struct {
+	// prefix restricts the listing to keys that start with it.
+	Prefix string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// page_size is the maximum number of entries to return. It defaults to 100
+	// and may not exceed 1000.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token is the next_page_token of a previous response, to continue
+	// the listing where that page ended.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// include_values returns the values along with the keys.
+	IncludeValues        bool     `protobuf:"varint,4,opt,name=include_values,json=includeValues,proto3" json:"include_values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRequest) Reset()         { *m = ListRequest{} }
+func (m *ListRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRequest) ProtoMessage()    {}
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{30}
+}
+func (m *ListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRequest.Unmarshal(m, b)
+}
+func (m *ListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRequest.Marshal(b, m, deterministic)
+}
+func (m *ListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRequest.Merge(m, src)
+}
+func (m *ListRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRequest.Size(m)
+}
+func (m *ListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRequest proto.InternalMessageInfo
+
+func (m *ListRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ListRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+func (m *ListRequest) GetIncludeValues() bool {
+	if m != nil {
+		return m.IncludeValues
+	}
+	return false
+}
+
+type ListResponse struct {
+	Entries []*ListEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// next_page_token is set when there are more entries to list.
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListResponse) Reset()         { *m = ListResponse{} }
+func (m *ListResponse) String() string { return proto.CompactTextString(m) }
+func (*ListResponse) ProtoMessage()    {}
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{31}
+}
+func (m *ListResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListResponse.Unmarshal(m, b)
+}
+func (m *ListResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListResponse.Marshal(b, m, deterministic)
+}
+func (m *ListResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListResponse.Merge(m, src)
+}
+func (m *ListResponse) XXX_Size() int {
+	return xxx_messageInfo_ListResponse.Size(m)
+}
+func (m *ListResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListResponse proto.InternalMessageInfo
+
+func (m *ListResponse) GetEntries() []*ListEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *ListResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type ListEntry struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value_size is the length of the value in bytes.
+	ValueSize int64 `protobuf:"varint,2,opt,name=value_size,json=valueSize,proto3" json:"value_size,omitempty"`
+	// value is only set when the request asked for values.
+	Value                []byte           `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	ExpiresAt            *types.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Version              uint64           `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *ListEntry) Reset()         { *m = ListEntry{} }
+func (m *ListEntry) String() string { return proto.CompactTextString(m) }
+func (*ListEntry) ProtoMessage()    {}
+func (*ListEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{32}
+}
+func (m *ListEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListEntry.Unmarshal(m, b)
+}
+func (m *ListEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListEntry.Marshal(b, m, deterministic)
+}
+func (m *ListEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListEntry.Merge(m, src)
+}
+func (m *ListEntry) XXX_Size() int {
+	return xxx_messageInfo_ListEntry.Size(m)
+}
+func (m *ListEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListEntry proto.InternalMessageInfo
+
+func (m *ListEntry) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *ListEntry) GetValueSize() int64 {
+	if m != nil {
+		return m.ValueSize
+	}
+	return 0
+}
+
+func (m *ListEntry) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *ListEntry) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *ListEntry) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+type WatchRequest struct {
+	// key restricts the watch to a single key.
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// prefix restricts the watch to the keys that start with it. Only one of
+	// key and prefix may be set; when neither is, every key is watched.
+	Prefix string `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// resume_revision is the revision of the last event the client received.
+	// The events that followed it are replayed before new ones are streamed.
+	// Watches fail with OUT_OF_RANGE when those events are no longer held by
+	// the server. When zero, only new events are streamed.
+	ResumeRevision       uint64   `protobuf:"varint,3,opt,name=resume_revision,json=resumeRevision,proto3" json:"resume_revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{33}
+}
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetResumeRevision() uint64 {
+	if m != nil {
+		return m.ResumeRevision
+	}
+	return 0
+}
+
+type WatchEvent struct {
+	// revision identifies the event, and increases by one with every change
+	// made to the cache.
+	Revision uint64    `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Type     EventType `protobuf:"varint,2,opt,name=type,proto3,enum=cachely.v1.EventType" json:"type,omitempty"`
+	Key      string    `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	// version is the version of the entry written or removed.
+	Version              uint64           `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	ExpiresAt            *types.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *WatchEvent) Reset()         { *m = WatchEvent{} }
+func (m *WatchEvent) String() string { return proto.CompactTextString(m) }
+func (*WatchEvent) ProtoMessage()    {}
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1a7b39a1e3392aa2, []int{34}
+}
+func (m *WatchEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchEvent.Unmarshal(m, b)
+}
+func (m *WatchEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchEvent.Marshal(b, m, deterministic)
+}
+func (m *WatchEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchEvent.Merge(m, src)
+}
+func (m *WatchEvent) XXX_Size() int {
+	return xxx_messageInfo_WatchEvent.Size(m)
+}
+func (m *WatchEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchEvent proto.InternalMessageInfo
+
+func (m *WatchEvent) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *WatchEvent) GetType() EventType {
+	if m != nil {
+		return m.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (m *WatchEvent) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *WatchEvent) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *WatchEvent) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("cachely.v1.WriteMode", WriteMode_name, WriteMode_value)
+	proto.RegisterEnum("cachely.v1.EventType", EventType_name, EventType_value)
+	proto.RegisterType((*GetRequest)(nil), "cachely.v1.GetRequest")
+	proto.RegisterType((*GetResponse)(nil), "cachely.v1.GetResponse")
+	proto.RegisterMapType((map[string]string)(nil), "cachely.v1.GetResponse.MetadataEntry")
+	proto.RegisterType((*PutRequest)(nil), "cachely.v1.PutRequest")
+	proto.RegisterMapType((map[string]string)(nil), "cachely.v1.PutRequest.MetadataEntry")
+	proto.RegisterType((*PutResponse)(nil), "cachely.v1.PutResponse")
+	proto.RegisterType((*CompareAndSwapRequest)(nil), "cachely.v1.CompareAndSwapRequest")
+	proto.RegisterMapType((map[string]string)(nil), "cachely.v1.CompareAndSwapRequest.MetadataEntry")
+	proto.RegisterType((*CompareAndSwapResponse)(nil), "cachely.v1.CompareAndSwapResponse")
+	proto.RegisterType((*IncrementRequest)(nil), "cachely.v1.IncrementRequest")
+	proto.RegisterType((*IncrementResponse)(nil), "cachely.v1.IncrementResponse")
+	proto.RegisterType((*DecrementRequest)(nil), "cachely.v1.DecrementRequest")
+	proto.RegisterType((*DecrementResponse)(nil), "cachely.v1.DecrementResponse")
+	proto.RegisterType((*AppendRequest)(nil), "cachely.v1.AppendRequest")
+	proto.RegisterType((*AppendResponse)(nil), "cachely.v1.AppendResponse")
+	proto.RegisterType((*PrependRequest)(nil), "cachely.v1.PrependRequest")
+	proto.RegisterType((*PrependResponse)(nil), "cachely.v1.PrependResponse")
+	proto.RegisterType((*PutStreamRequest)(nil), "cachely.v1.PutStreamRequest")
+	proto.RegisterType((*GetStreamRequest)(nil), "cachely.v1.GetStreamRequest")
+	proto.RegisterType((*GetStreamResponse)(nil), "cachely.v1.GetStreamResponse")
+	proto.RegisterType((*Checksum)(nil), "cachely.v1.Checksum")
+	proto.RegisterType((*DeleteRequest)(nil), "cachely.v1.DeleteRequest")
+	proto.RegisterType((*DeleteResponse)(nil), "cachely.v1.DeleteResponse")
+	proto.RegisterType((*ItemError)(nil), "cachely.v1.ItemError")
+	proto.RegisterType((*BatchGetRequest)(nil), "cachely.v1.BatchGetRequest")
+	proto.RegisterType((*BatchGetResponse)(nil), "cachely.v1.BatchGetResponse")
+	proto.RegisterType((*BatchGetResult)(nil), "cachely.v1.BatchGetResult")
+	proto.RegisterType((*BatchPutRequest)(nil), "cachely.v1.BatchPutRequest")
+	proto.RegisterType((*BatchPutResponse)(nil), "cachely.v1.BatchPutResponse")
+	proto.RegisterType((*BatchPutResult)(nil), "cachely.v1.BatchPutResult")
+	proto.RegisterType((*BatchDeleteRequest)(nil), "cachely.v1.BatchDeleteRequest")
+	proto.RegisterType((*BatchDeleteResponse)(nil), "cachely.v1.BatchDeleteResponse")
+	proto.RegisterType((*BatchDeleteResult)(nil), "cachely.v1.BatchDeleteResult")
+	proto.RegisterType((*ListRequest)(nil), "cachely.v1.ListRequest")
+	proto.RegisterType((*ListResponse)(nil), "cachely.v1.ListResponse")
+	proto.RegisterType((*ListEntry)(nil), "cachely.v1.ListEntry")
+	proto.RegisterType((*WatchRequest)(nil), "cachely.v1.WatchRequest")
+	proto.RegisterType((*WatchEvent)(nil), "cachely.v1.WatchEvent")
+}
+
+func init() { proto.RegisterFile("cachely/v1/cache_api.proto", fileDescriptor_1a7b39a1e3392aa2) }
+
+var fileDescriptor_1a7b39a1e3392aa2 = []byte{
+	// 1930 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe4, 0x58, 0xcf, 0x6f, 0xe3, 0xc6,
+	0xf5, 0x5f, 0x8a, 0x92, 0x2d, 0x3e, 0xd9, 0xb2, 0x3c, 0xd9, 0x75, 0x14, 0x7a, 0xbd, 0xeb, 0x65,
+	0xbc, 0x89, 0x57, 0xfb, 0xfd, 0x4a, 0x6b, 0x27, 0x48, 0x1b, 0x07, 0x41, 0xab, 0xb5, 0x98, 0xad,
+	0x50, 0xaf, 0xcb, 0xd2, 0x5a, 0xed, 0xb6, 0x08, 0x20, 0x70, 0xa9, 0xb1, 0xcd, 0x5a, 0xfc, 0x51,
+	0x72, 0xe8, 0xd8, 0xbb, 0x68, 0x0f, 0x3d, 0x15, 0xbd, 0xb4, 0x40, 0x81, 0xf4, 0x9e, 0x4b, 0xd1,
+	0xf4, 0xde, 0x02, 0xbd, 0xf4, 0xde, 0x6b, 0xff, 0x85, 0x1e, 0x0b, 0xf4, 0xd8, 0x6b, 0xc1, 0xe1,
+	0x90, 0x26, 0x25, 0x52, 0xfe, 0x95, 0x02, 0x09, 0x7a, 0xe3, 0xbc, 0xf7, 0xe6, 0xfd, 0xf8, 0xbc,
+	0x37, 0xf3, 0xe6, 0x11, 0x44, 0x5d, 0xd3, 0x0f, 0xf1, 0xe8, 0xb4, 0x75, 0xbc, 0xd1, 0xa2, 0x9f,
+	0x03, 0xcd, 0x31, 0x9a, 0x8e, 0x6b, 0x13, 0x1b, 0x01, 0xe3, 0x35, 0x8f, 0x37, 0xc4, 0xdb, 0x07,
+	0xb6, 0x7d, 0x30, 0xc2, 0x2d, 0xcd, 0x31, 0x5a, 0x9a, 0x65, 0xd9, 0x44, 0x23, 0x86, 0x6d, 0x79,
+	0xa1, 0xa4, 0x78, 0x87, 0x71, 0xe9, 0xea, 0xa5, 0xbf, 0xdf, 0x1a, 0xfa, 0x2e, 0x15, 0x60, 0xfc,
+	0xbb, 0xe3, 0x7c, 0x62, 0x98, 0xd8, 0x23, 0x9a, 0xe9, 0xe4, 0x29, 0xf8, 0xcc, 0xd5, 0x1c, 0x07,
+	0xbb, 0xcc, 0x80, 0xb4, 0x0b, 0xf0, 0x04, 0x13, 0x15, 0xff, 0xd4, 0xc7, 0x1e, 0x41, 0x35, 0xe0,
+	0x8f, 0xf0, 0x69, 0x9d, 0x5b, 0xe5, 0xd6, 0x05, 0x35, 0xf8, 0x44, 0x4b, 0x30, 0x63, 0xef, 0xef,
+	0x7b, 0x98, 0xd4, 0x0b, 0xab, 0xdc, 0x3a, 0xaf, 0xb2, 0x55, 0x40, 0x1f, 0x61, 0xeb, 0x80, 0x1c,
+	0xd6, 0xf9, 0x90, 0x1e, 0xae, 0xa4, 0x7f, 0xf1, 0x50, 0xa1, 0x0a, 0x3d, 0xc7, 0xb6, 0x3c, 0x9c,
+	0xa1, 0xf1, 0x26, 0x94, 0x8e, 0xb5, 0x91, 0x8f, 0xa9, 0xc2, 0x39, 0x35, 0x5c, 0xa0, 0x0f, 0x01,
+	0xf0, 0x89, 0x63, 0xb8, 0xd8, 0x1b, 0x68, 0x84, 0xea, 0xac, 0x6c, 0x8a, 0xcd, 0xd0, 0xf9, 0x66,
+	0xe4, 0x7c, 0xb3, 0x17, 0x45, 0xa7, 0x0a, 0x4c, 0xba, 0x4d, 0x50, 0x1d, 0x66, 0x8f, 0xb1, 0xeb,
+	0x19, 0xb6, 0x55, 0x2f, 0xae, 0x72, 0xeb, 0x45, 0x35, 0x5a, 0xa2, 0x7b, 0x30, 0xa7, 0xdb, 0x16,
+	0xc1, 0x16, 0x19, 0x90, 0x53, 0x07, 0xd7, 0x4b, 0xd4, 0x8b, 0x0a, 0xa3, 0xf5, 0x4e, 0x1d, 0x8c,
+	0x1e, 0x40, 0x2d, 0x12, 0xc1, 0x96, 0x6e, 0x0f, 0x0d, 0xeb, 0xa0, 0x3e, 0x43, 0xc5, 0x16, 0x18,
+	0x5d, 0x66, 0x64, 0xd4, 0x86, 0xb2, 0x89, 0x89, 0x36, 0xd4, 0x88, 0x56, 0x9f, 0x5d, 0xe5, 0xd7,
+	0x2b, 0x9b, 0xf7, 0x9b, 0x67, 0x89, 0x6c, 0x26, 0xa2, 0x6e, 0x3e, 0x65, 0x72, 0xb2, 0x45, 0xdc,
+	0x53, 0x35, 0xde, 0x16, 0x44, 0xa9, 0xbb, 0x58, 0x23, 0x78, 0x18, 0x44, 0x59, 0x3e, 0x3f, 0x4a,
+	0x26, 0xdd, 0x26, 0xe8, 0x23, 0xa8, 0x98, 0xf6, 0xd0, 0xd8, 0x37, 0xc2, 0xbd, 0xc2, 0xb9, 0x7b,
+	0x21, 0x12, 0x6f, 0x13, 0xb4, 0x02, 0x40, 0x61, 0x1e, 0x78, 0xc6, 0x2b, 0x5c, 0x07, 0x9a, 0x31,
+	0x81, 0x52, 0xf6, 0x8c, 0x57, 0x58, 0xfc, 0x08, 0xe6, 0x53, 0x1e, 0x9f, 0x97, 0x35, 0x81, 0x65,
+	0x6d, 0xab, 0xf0, 0x6d, 0x4e, 0xfa, 0x2b, 0x0f, 0xa0, 0xf8, 0x53, 0x4a, 0x28, 0x3b, 0xe1, 0x0f,
+	0x81, 0x27, 0x64, 0xc4, 0x32, 0xfd, 0xd6, 0x44, 0x1c, 0x1d, 0x56, 0xe7, 0x6a, 0x20, 0x35, 0x56,
+	0x1d, 0xc5, 0xcb, 0x54, 0xc7, 0x03, 0x28, 0x9a, 0xf6, 0x30, 0xcc, 0x7d, 0x75, 0xf3, 0x56, 0x32,
+	0x63, 0xcf, 0x5d, 0x83, 0xe0, 0xa7, 0xf6, 0x10, 0xab, 0x54, 0x64, 0xa2, 0x5c, 0x66, 0x2e, 0x56,
+	0x2e, 0xb3, 0xd9, 0xe5, 0xf2, 0xdd, 0x44, 0xb9, 0x94, 0x69, 0xb9, 0xac, 0x25, 0x8d, 0x9f, 0x41,
+	0x96, 0x5b, 0x2d, 0x0f, 0xa0, 0x86, 0x4f, 0x1c, 0xac, 0x07, 0xe5, 0x12, 0x55, 0xb8, 0x40, 0x2b,
+	0x7c, 0x21, 0xa2, 0xf7, 0x43, 0xf2, 0xf5, 0x32, 0xf8, 0x4f, 0x0e, 0x2a, 0x8a, 0x1f, 0x57, 0x6f,
+	0xc6, 0xde, 0x34, 0xfe, 0x85, 0x2b, 0x9e, 0x4e, 0x3e, 0x7d, 0x3a, 0xd3, 0x87, 0xa1, 0x78, 0x8d,
+	0xc3, 0x50, 0xba, 0xcc, 0x61, 0x90, 0xbe, 0xe4, 0xe1, 0xd6, 0xb6, 0x6d, 0x3a, 0x9a, 0x8b, 0xdb,
+	0xd6, 0x70, 0xef, 0x33, 0xcd, 0xc9, 0xaf, 0xdd, 0xac, 0x14, 0x14, 0x32, 0x53, 0x70, 0x86, 0x2f,
+	0x9f, 0x51, 0xe6, 0xc5, 0x2b, 0x94, 0x79, 0xe9, 0x32, 0x30, 0x7f, 0xb5, 0xb5, 0xfb, 0xfd, 0x89,
+	0xda, 0x6d, 0x25, 0x6b, 0x37, 0x13, 0xbd, 0xbc, 0x32, 0xbe, 0x5e, 0x6d, 0xfe, 0x9b, 0x83, 0xa5,
+	0x71, 0x73, 0xff, 0x1b, 0x65, 0xfa, 0x07, 0x0e, 0x6a, 0x5d, 0x4b, 0x77, 0xb1, 0x89, 0xad, 0x29,
+	0xb7, 0xeb, 0x06, 0x94, 0x86, 0x78, 0x44, 0x34, 0x16, 0xee, 0xf2, 0x84, 0xf6, 0xae, 0x45, 0x3e,
+	0x78, 0xbf, 0x1f, 0x00, 0xaa, 0x86, 0x92, 0xe8, 0x6d, 0x98, 0x37, 0x2c, 0x83, 0x18, 0xda, 0x68,
+	0x70, 0x56, 0xb1, 0xbc, 0x3a, 0xc7, 0x88, 0xfd, 0x4b, 0x17, 0xae, 0xf4, 0x1b, 0x0e, 0x16, 0x13,
+	0xbe, 0x5e, 0xac, 0xf7, 0xf3, 0xd1, 0x19, 0x99, 0x8a, 0xfd, 0x15, 0xef, 0x7d, 0x0a, 0x5f, 0x07,
+	0x7f, 0x73, 0xe0, 0xeb, 0xe0, 0xaf, 0x15, 0x7c, 0x7f, 0xe2, 0x60, 0xbe, 0xed, 0x38, 0xd8, 0x1a,
+	0x5e, 0xb6, 0xb1, 0xaf, 0x41, 0xd5, 0xd4, 0x4e, 0x06, 0x89, 0xf7, 0x06, 0xc3, 0xc7, 0xd4, 0x4e,
+	0xfa, 0xd1, 0x93, 0x03, 0x35, 0x60, 0x31, 0x3c, 0x27, 0x03, 0x63, 0x7f, 0x60, 0x1a, 0x9e, 0x17,
+	0xdc, 0x46, 0x81, 0x87, 0x65, 0x75, 0x21, 0x64, 0x74, 0xf7, 0x9f, 0x86, 0xe4, 0x08, 0xcb, 0xd2,
+	0x85, 0xb0, 0xfc, 0x9c, 0x83, 0x6a, 0xe4, 0x78, 0x2e, 0x90, 0xe9, 0xf7, 0x50, 0x61, 0xec, 0x3d,
+	0xf4, 0xdf, 0x41, 0xf4, 0xcf, 0x1c, 0x54, 0x15, 0x17, 0x7f, 0x03, 0x21, 0xfd, 0x1d, 0x07, 0x0b,
+	0xb1, 0xe7, 0x5f, 0x2b, 0x4c, 0x7f, 0xc5, 0x41, 0x4d, 0xf1, 0xc9, 0x1e, 0x71, 0xb1, 0x66, 0x46,
+	0xa8, 0x36, 0x61, 0xe6, 0x10, 0x6b, 0x43, 0xec, 0x52, 0xef, 0x2a, 0x9b, 0x4b, 0xd9, 0xcf, 0x2e,
+	0x95, 0x49, 0x05, 0x98, 0xeb, 0x87, 0xbe, 0x75, 0x14, 0x61, 0x4e, 0x17, 0xe8, 0x11, 0x94, 0xf5,
+	0x43, 0xac, 0x1f, 0x79, 0xbe, 0xc9, 0x1e, 0xa9, 0x37, 0x53, 0x2d, 0x90, 0xf1, 0xd4, 0x58, 0x4a,
+	0xf2, 0xa0, 0xf6, 0x04, 0x8f, 0xf9, 0x92, 0x09, 0x13, 0x35, 0x70, 0x06, 0x53, 0x49, 0x15, 0x28,
+	0x85, 0xc2, 0x74, 0x36, 0x6f, 0xf1, 0x39, 0xf3, 0x56, 0x31, 0x35, 0x6f, 0xfd, 0x91, 0x83, 0xc5,
+	0x84, 0x55, 0x96, 0x9d, 0xd6, 0x18, 0x04, 0x6f, 0xe6, 0x0c, 0x2a, 0x31, 0x06, 0xe7, 0x24, 0x2f,
+	0x86, 0x88, 0xcf, 0x83, 0xa8, 0x78, 0x21, 0x88, 0x24, 0x28, 0x47, 0xd4, 0x20, 0x22, 0xdd, 0xd5,
+	0xdf, 0xdb, 0xd4, 0xa9, 0x8f, 0xf3, 0x2a, 0x5b, 0x49, 0x3b, 0x30, 0xdf, 0xc1, 0x23, 0x4c, 0xf0,
+	0x57, 0xf1, 0x2a, 0x93, 0x24, 0xa8, 0x46, 0xda, 0xf2, 0x2a, 0x57, 0xfa, 0x10, 0x84, 0x2e, 0xc1,
+	0xa6, 0xec, 0xba, 0xb6, 0x8b, 0x10, 0x14, 0xf5, 0x60, 0x5e, 0xe0, 0x68, 0x66, 0xe8, 0x77, 0x50,
+	0xbb, 0x26, 0xf6, 0x3c, 0xed, 0x20, 0x7a, 0xa0, 0x44, 0x4b, 0xe9, 0x3e, 0x2c, 0x3c, 0xd6, 0x88,
+	0x7e, 0x98, 0x98, 0xa1, 0x11, 0x14, 0x8f, 0xf0, 0xa9, 0x57, 0xe7, 0x56, 0xf9, 0x75, 0x41, 0xa5,
+	0xdf, 0xd2, 0xf7, 0xa0, 0x76, 0x26, 0xc6, 0xfc, 0x78, 0x1f, 0x66, 0x5d, 0xec, 0xf9, 0x23, 0x12,
+	0x8a, 0x06, 0x35, 0x9f, 0x00, 0x2f, 0x21, 0xee, 0x8f, 0x88, 0x1a, 0x89, 0x4a, 0x3f, 0x87, 0x6a,
+	0x9a, 0x95, 0x01, 0xcf, 0xff, 0x43, 0x09, 0x07, 0x0f, 0xad, 0x7a, 0x61, 0x7a, 0xf2, 0x43, 0x29,
+	0xf4, 0x10, 0x4a, 0x38, 0x08, 0x9d, 0x95, 0x79, 0x6a, 0x44, 0x8a, 0x71, 0x51, 0x43, 0x19, 0xe9,
+	0x3b, 0x2c, 0xe0, 0xc4, 0xc4, 0xf7, 0x7f, 0x50, 0x32, 0x08, 0x36, 0xa3, 0x30, 0xf2, 0x8e, 0x5b,
+	0x28, 0x14, 0x43, 0xa1, 0xf8, 0x97, 0x82, 0x42, 0xf1, 0x73, 0xa1, 0x50, 0xfc, 0xab, 0x41, 0xa1,
+	0xf8, 0xd7, 0x83, 0x62, 0x1d, 0x10, 0xb5, 0x9f, 0xae, 0xd6, 0xac, 0xf4, 0xef, 0xc2, 0x1b, 0x29,
+	0x49, 0x16, 0xf6, 0xb7, 0xc6, 0xc3, 0x5e, 0x99, 0x08, 0x3b, 0xde, 0x91, 0x8a, 0x5c, 0x85, 0xc5,
+	0x09, 0x6e, 0x46, 0xf0, 0x71, 0x34, 0x85, 0x0b, 0x44, 0xf3, 0x4b, 0x0e, 0x2a, 0x3b, 0x86, 0x17,
+	0x67, 0x75, 0x09, 0x66, 0x1c, 0x17, 0xef, 0x1b, 0x27, 0x4c, 0x23, 0x5b, 0xa1, 0x65, 0x10, 0x1c,
+	0xed, 0x00, 0x27, 0xaf, 0xaf, 0x72, 0x40, 0xa0, 0xf7, 0xc4, 0x0a, 0x00, 0x65, 0x12, 0xfb, 0x08,
+	0x87, 0xf7, 0xbc, 0xa0, 0x52, 0xf1, 0x5e, 0x40, 0x40, 0xf7, 0xa1, 0x6a, 0x58, 0xfa, 0xc8, 0x1f,
+	0xe2, 0xb0, 0x97, 0x79, 0xac, 0x3d, 0xcd, 0x33, 0x2a, 0xed, 0x65, 0x9e, 0x74, 0x00, 0x73, 0xa1,
+	0x27, 0xf1, 0x6d, 0x36, 0x1b, 0xa4, 0xc7, 0xc0, 0x11, 0x4e, 0xa9, 0x48, 0x02, 0xd1, 0x70, 0xe4,
+	0x88, 0xa4, 0xd0, 0x3b, 0xb0, 0x60, 0xe1, 0x13, 0x32, 0x48, 0xf8, 0x12, 0x9e, 0xdb, 0xf9, 0x80,
+	0xac, 0x44, 0xfe, 0x48, 0xbf, 0xe7, 0x40, 0x88, 0xb7, 0x5f, 0xbe, 0xa5, 0x65, 0x4f, 0x7c, 0xd7,
+	0xf8, 0x57, 0x91, 0xe8, 0x91, 0xa5, 0x54, 0x8f, 0x94, 0x34, 0x98, 0x7b, 0x1e, 0x64, 0x7c, 0xea,
+	0x8f, 0x3a, 0x96, 0xaf, 0x42, 0x2a, 0x5f, 0xef, 0xc2, 0x42, 0x50, 0x36, 0x26, 0x1e, 0xb8, 0xf8,
+	0xd8, 0x48, 0xf4, 0xdf, 0x6a, 0x48, 0x56, 0x19, 0x55, 0xfa, 0x0b, 0x07, 0x40, 0x6d, 0xc8, 0xc7,
+	0xd8, 0x22, 0x48, 0x84, 0x72, 0xbc, 0x81, 0xa3, 0x1b, 0xe2, 0x75, 0xf0, 0x4f, 0x85, 0x0e, 0x99,
+	0x85, 0xc9, 0x7f, 0x2a, 0xf2, 0x31, 0x1b, 0x37, 0x55, 0x2a, 0x12, 0x39, 0xca, 0x9f, 0x39, 0x9a,
+	0xff, 0xbb, 0xee, 0xea, 0xe3, 0x6f, 0xe3, 0x87, 0x20, 0xc4, 0x7f, 0x73, 0xd0, 0x2d, 0x58, 0x7c,
+	0xae, 0x76, 0x7b, 0xf2, 0xe0, 0xe9, 0x0f, 0x3a, 0xf2, 0xa0, 0xbb, 0xbb, 0x27, 0xab, 0xbd, 0xda,
+	0x0d, 0xb4, 0x04, 0x28, 0x41, 0x56, 0x65, 0x65, 0xa7, 0xbd, 0x2d, 0xd7, 0xb8, 0x31, 0xf1, 0x67,
+	0x0a, 0x15, 0x2f, 0x34, 0x3e, 0xe7, 0x40, 0x88, 0xa3, 0x41, 0x22, 0x2c, 0xc9, 0x7d, 0x79, 0xb7,
+	0x37, 0xe8, 0xfd, 0x48, 0x91, 0x07, 0xcf, 0x76, 0xf7, 0x14, 0x79, 0xbb, 0xfb, 0x49, 0x57, 0xee,
+	0xd4, 0x6e, 0x20, 0x04, 0xd5, 0x04, 0x4f, 0x79, 0xd6, 0x0b, 0x95, 0x26, 0xe5, 0x95, 0x4e, 0xbb,
+	0x27, 0xd7, 0x0a, 0x63, 0xe4, 0x8e, 0xbc, 0x23, 0xf7, 0xe4, 0x1a, 0x3f, 0x46, 0x96, 0x5f, 0x28,
+	0x5d, 0x55, 0xae, 0x15, 0xd1, 0x4d, 0xa8, 0x25, 0xc9, 0xfd, 0xee, 0x76, 0xaf, 0x56, 0xda, 0xfc,
+	0x62, 0x0e, 0xca, 0xdb, 0x01, 0xe2, 0x6d, 0xa5, 0x8b, 0x3e, 0x05, 0xfe, 0x49, 0xf0, 0x3c, 0x98,
+	0xb8, 0xe3, 0x69, 0x9d, 0x88, 0x79, 0x77, 0xbf, 0xb4, 0xf6, 0x8b, 0xbf, 0xff, 0xe3, 0xb7, 0x85,
+	0x3b, 0xe8, 0x76, 0x2b, 0xf1, 0x9f, 0xda, 0x7e, 0xf9, 0x13, 0xac, 0x13, 0xaf, 0xf5, 0xfa, 0x08,
+	0x9f, 0x7e, 0xdc, 0x68, 0xfc, 0x0c, 0xf5, 0x81, 0x57, 0xfc, 0x31, 0xed, 0x8a, 0x9f, 0xad, 0x3d,
+	0x71, 0x9d, 0x4a, 0x77, 0xa8, 0xf6, 0xba, 0xf4, 0x46, 0x86, 0xf6, 0x2d, 0xae, 0x81, 0x7e, 0xcd,
+	0x41, 0x35, 0x3d, 0xd5, 0xa3, 0x7b, 0xe7, 0xfe, 0x60, 0x10, 0xa5, 0x69, 0x22, 0xcc, 0xf2, 0x07,
+	0xd4, 0xf2, 0xa3, 0x2d, 0xae, 0x21, 0x3d, 0x9c, 0x16, 0xda, 0x96, 0x9e, 0x36, 0xff, 0x1a, 0x84,
+	0x78, 0x80, 0x45, 0xb7, 0x53, 0x37, 0xe5, 0xd8, 0x0c, 0x2e, 0xae, 0xe4, 0x70, 0x99, 0x07, 0x1b,
+	0xd4, 0x83, 0x87, 0x81, 0x07, 0xef, 0x4c, 0xf5, 0xc0, 0x88, 0xed, 0xbd, 0x06, 0xa1, 0x83, 0x33,
+	0x8d, 0x77, 0xf0, 0x34, 0xe3, 0x1d, 0x9c, 0x63, 0xfc, 0x1c, 0xcb, 0xc3, 0x68, 0x5f, 0x90, 0x0b,
+	0x1b, 0x66, 0xc2, 0x79, 0x09, 0xbd, 0x95, 0xd4, 0x9d, 0x1a, 0xfe, 0x44, 0x31, 0x8b, 0xc5, 0x6c,
+	0x36, 0xa9, 0xcd, 0x75, 0xe9, 0xed, 0xa9, 0x36, 0x35, 0xba, 0x29, 0x30, 0xe8, 0xc1, 0x2c, 0x9b,
+	0x26, 0x50, 0x4a, 0x6d, 0x7a, 0x38, 0x12, 0x97, 0x33, 0x79, 0xcc, 0x66, 0x8b, 0xda, 0x7c, 0x20,
+	0xad, 0x4d, 0xb5, 0xe9, 0xb8, 0x38, 0x32, 0xfa, 0x09, 0x08, 0xf1, 0xa0, 0x90, 0x86, 0x78, 0x7c,
+	0x7e, 0xc8, 0xaf, 0xea, 0x1b, 0xeb, 0x1c, 0xda, 0x01, 0xe1, 0x09, 0xce, 0xd4, 0x33, 0xfe, 0xf6,
+	0x17, 0x57, 0x72, 0xb8, 0x91, 0xb6, 0x47, 0x1c, 0xc2, 0x30, 0x13, 0xf6, 0xf0, 0x34, 0xf6, 0xa9,
+	0x17, 0x85, 0x28, 0x66, 0xb1, 0xd2, 0xc7, 0xb8, 0x71, 0xde, 0x31, 0x2e, 0x06, 0x6d, 0x0e, 0xbd,
+	0x39, 0xde, 0x37, 0x23, 0x13, 0xf5, 0x49, 0x06, 0x33, 0xb0, 0x4c, 0x0d, 0xdc, 0x42, 0x59, 0x27,
+	0x19, 0x7d, 0x0c, 0x25, 0xda, 0x31, 0x50, 0x6a, 0x7f, 0xb2, 0x51, 0x89, 0x4b, 0x13, 0x1c, 0x7a,
+	0xa7, 0xd2, 0xe8, 0x2d, 0x28, 0x47, 0x6f, 0x59, 0xb4, 0x9c, 0xfd, 0xf8, 0x0d, 0x95, 0xdc, 0xce,
+	0x66, 0x32, 0x17, 0xdf, 0xa5, 0x2e, 0xde, 0x93, 0xb2, 0x30, 0xd8, 0x7a, 0xc9, 0xa4, 0x83, 0x1a,
+	0x88, 0xec, 0x29, 0x7e, 0x96, 0x3d, 0xc5, 0x9f, 0x62, 0x4f, 0xf1, 0x2f, 0x63, 0x4f, 0xf1, 0xa9,
+	0xbd, 0x57, 0x50, 0x49, 0x3c, 0xd3, 0xd0, 0x9d, 0xdc, 0xd7, 0x5d, 0x68, 0xf5, 0x6e, 0x2e, 0x9f,
+	0x19, 0x6e, 0x50, 0xc3, 0x6b, 0xd2, 0xdd, 0x5c, 0xc3, 0xe1, 0x86, 0x2d, 0xae, 0xf1, 0x78, 0x07,
+	0xaa, 0xba, 0x6d, 0x26, 0x34, 0x3e, 0x9e, 0x0f, 0x7b, 0x86, 0x63, 0x28, 0x41, 0x27, 0x55, 0xb8,
+	0x1f, 0x0b, 0x8c, 0x79, 0xbc, 0xf1, 0x45, 0x81, 0xdf, 0x7e, 0xf1, 0xe2, 0xcb, 0x02, 0x6c, 0x33,
+	0xf1, 0xfe, 0xc6, 0xdf, 0xe2, 0xc5, 0xa7, 0xfd, 0x8d, 0x97, 0x33, 0xb4, 0xfb, 0xbe, 0xf7, 0x9f,
+	0x01, 0x00, 0x6c, 0xf5, 0x85, 0xac, 0xd5, 0x1c, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// CacheAPIClient is the client API for CacheAPI service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CacheAPIClient interface {
+	// Get retrieves a value from the cache. The HTTP gateway returns the raw
+	// value instead of a JSON GetResponse when the Accept header prefers
+	// application/octet-stream or the content type the value was stored with.
+	// Either way, the metadata of the entry is also sent as HTTP headers:
+	// Last-Modified, Expires, Cachely-Version, Cachely-Created-At, and a
+	// Cachely-Meta- prefixed header for each user-defined attribute, along
+	// with Content-Type and Content-Encoding for raw values. Responses carry an
+	// ETag derived from the version of the entry, which differs between the
+	// raw value and the JSON GetResponse and changes when the server restarts,
+	// since versions are numbered afresh then. Conditional requests using
+	// If-None-Match or If-Modified-Since are answered with 304 Not Modified
+	// when the entry has not changed. Raw values can be read in part with a
+	// Range header, which is answered with 206 Partial Content, using a
+	// multipart/byteranges body when several ranges are requested; the JSON
+	// GetResponse is restricted with the offset and length query parameters
+	// instead. Servers in read-through mode load missing keys from their
+	// origin, as described by OriginAPI, and fail with UNAVAILABLE when the
+	// origin cannot be read.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Put adds a value to the cache. By default it only inserts new keys; see
+	// WriteMode for replacing existing ones. The HTTP gateway also serves
+	// PUT /cachely/v1/objects/{key}, which upserts. Its body is a JSON
+	// PutRequest when its Content-Type is application/json, and the raw value
+	// otherwise, stored along with its Content-Type, Content-Encoding and
+	// Cachely-Meta- prefixed headers; the other options of the write are then
+	// taken from the query parameters. An If-Match header naming the ETag of
+	// the entry, or *, makes the write conditional on the entry not having
+	// changed, or existing; If-None-Match: * only lets it create the entry.
+	// Writes whose condition fails are answered with 412 Precondition Failed.
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
+	// CompareAndSwap replaces a cached value only if it is still at the version
+	// the caller expects, so that concurrent writers cannot lose each other's
+	// updates.
+	CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error)
+	// Increment atomically adds to a counter, a value holding a signed 64-bit
+	// integer in decimal, and returns its new value. A missing key is created
+	// as a counter starting at the initial value of the request. Values that
+	// are not counters fail with INVALID_ARGUMENT, and results that do not fit
+	// in 64 bits with OUT_OF_RANGE.
+	Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error)
+	// Decrement atomically subtracts from a counter, as Increment adds to it.
+	Decrement(ctx context.Context, in *DecrementRequest, opts ...grpc.CallOption) (*DecrementResponse, error)
+	// Append atomically adds bytes to the end of a value. Missing keys fail
+	// with NOT_FOUND, unless the request asks for them to be created holding
+	// the bytes appended. Values that would grow past the maximum size of the
+	// request fail with OUT_OF_RANGE and are left as they are.
+	Append(ctx context.Context, in *AppendRequest, opts ...grpc.CallOption) (*AppendResponse, error)
+	// Prepend atomically adds bytes to the start of a value, as Append adds
+	// them to its end.
+	Prepend(ctx context.Context, in *PrependRequest, opts ...grpc.CallOption) (*PrependResponse, error)
+	// PutStream stores a value sent in chunks, for values too large to fit in a
+	// single message. The first message carries the key and options of the
+	// write as a PutRequest, and the last one the checksum of the whole value.
+	// The value is only stored once it has been received in full and its
+	// checksum verified; a mismatch fails with DATA_LOSS. The HTTP gateway
+	// serves PUT /cachely/v1/objects/{key}:stream, which upserts the raw request
+	// body, taking the other options of the write from the query parameters.
+	PutStream(ctx context.Context, opts ...grpc.CallOption) (CacheAPI_PutStreamClient, error)
+	// GetStream retrieves a value in chunks, for values too large to fit in a
+	// single message. The first message carries the entry without its value,
+	// and the last one the checksum of the whole value. The HTTP gateway serves
+	// GET /cachely/v1/objects/{key}:stream, which returns the raw value. Missing
+	// keys are loaded from the origin of read-through servers, as with Get.
+	GetStream(ctx context.Context, in *GetStreamRequest, opts ...grpc.CallOption) (CacheAPI_GetStreamClient, error)
+	// Delete removes a cached value from the cache. On the HTTP gateway, an
+	// If-Match header makes the delete conditional as for Put.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// List enumerates the cached keys in lexical order, optionally restricted to
+	// those starting with a prefix. Results are paginated. Only the entries held
+	// by the cache are listed: read-through servers do not list the keys of
+	// their origin that have not been loaded.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Watch streams the changes made to a key, or to every key starting with a
+	// prefix. A client that reconnects can pass the revision of the last event
+	// it received to replay the changes it missed, as long as they are still
+	// held in the server's change log. The latest revision at the time the
+	// watch starts is sent in the cachely-revision response header.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CacheAPI_WatchClient, error)
+	// BatchGet retrieves several values from the cache in one round trip. Keys
+	// that cannot be read are reported individually rather than failing the
+	// whole batch. Missing keys are loaded from the origin of read-through
+	// servers, as with Get.
+	BatchGet(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetResponse, error)
+	// BatchPut stores several values in the cache in one round trip. Each write
+	// succeeds or fails on its own.
+	BatchPut(ctx context.Context, in *BatchPutRequest, opts ...grpc.CallOption) (*BatchPutResponse, error)
+	// BatchDelete removes several values from the cache in one round trip. Each
+	// delete succeeds or fails on its own.
+	BatchDelete(ctx context.Context, in *BatchDeleteRequest, opts ...grpc.CallOption) (*BatchDeleteResponse, error)
+}
+
+type cacheAPIClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCacheAPIClient(cc *grpc.ClientConn) CacheAPIClient {
+	return &cacheAPIClient{cc}
+}
+
+func (c *cacheAPIClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error) {
+	out := new(PutResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Put", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error) {
+	out := new(CompareAndSwapResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/CompareAndSwap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error) {
+	out := new(IncrementResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Increment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) Decrement(ctx context.Context, in *DecrementRequest, opts ...grpc.CallOption) (*DecrementResponse, error) {
+	out := new(DecrementResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Decrement", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) Append(ctx context.Context, in *AppendRequest, opts ...grpc.CallOption) (*AppendResponse, error) {
+	out := new(AppendResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Append", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) Prepend(ctx context.Context, in *PrependRequest, opts ...grpc.CallOption) (*PrependResponse, error) {
+	out := new(PrependResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Prepend", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) PutStream(ctx context.Context, opts ...grpc.CallOption) (CacheAPI_PutStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CacheAPI_serviceDesc.Streams[0], "/cachely.v1.CacheAPI/PutStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheAPIPutStreamClient{stream}
+	return x, nil
+}
+
+type CacheAPI_PutStreamClient interface {
+	Send(*PutStreamRequest) error
+	CloseAndRecv() (*PutResponse, error)
+	grpc.ClientStream
+}
+
+type cacheAPIPutStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheAPIPutStreamClient) Send(m *PutStreamRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *cacheAPIPutStreamClient) CloseAndRecv() (*PutResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PutResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cacheAPIClient) GetStream(ctx context.Context, in *GetStreamRequest, opts ...grpc.CallOption) (CacheAPI_GetStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CacheAPI_serviceDesc.Streams[1], "/cachely.v1.CacheAPI/GetStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheAPIGetStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CacheAPI_GetStreamClient interface {
+	Recv() (*GetStreamResponse, error)
+	grpc.ClientStream
+}
+
+type cacheAPIGetStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheAPIGetStreamClient) Recv() (*GetStreamResponse, error) {
+	m := new(GetStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cacheAPIClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CacheAPI_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CacheAPI_serviceDesc.Streams[2], "/cachely.v1.CacheAPI/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheAPIWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CacheAPI_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type cacheAPIWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheAPIWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cacheAPIClient) BatchGet(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetResponse, error) {
+	out := new(BatchGetResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/BatchGet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) BatchPut(ctx context.Context, in *BatchPutRequest, opts ...grpc.CallOption) (*BatchPutResponse, error) {
+	out := new(BatchPutResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/BatchPut", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAPIClient) BatchDelete(ctx context.Context, in *BatchDeleteRequest, opts ...grpc.CallOption) (*BatchDeleteResponse, error) {
+	out := new(BatchDeleteResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.CacheAPI/BatchDelete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CacheAPIServer is the server API for CacheAPI service.
+type CacheAPIServer interface {
+	// Get retrieves a value from the cache. The HTTP gateway returns the raw
+	// value instead of a JSON GetResponse when the Accept header prefers
+	// application/octet-stream or the content type the value was stored with.
+	// Either way, the metadata of the entry is also sent as HTTP headers:
+	// Last-Modified, Expires, Cachely-Version, Cachely-Created-At, and a
+	// Cachely-Meta- prefixed header for each user-defined attribute, along
+	// with Content-Type and Content-Encoding for raw values. Responses carry an
+	// ETag derived from the version of the entry, which differs between the
+	// raw value and the JSON GetResponse and changes when the server restarts,
+	// since versions are numbered afresh then. Conditional requests using
+	// If-None-Match or If-Modified-Since are answered with 304 Not Modified
+	// when the entry has not changed. Raw values can be read in part with a
+	// Range header, which is answered with 206 Partial Content, using a
+	// multipart/byteranges body when several ranges are requested; the JSON
+	// GetResponse is restricted with the offset and length query parameters
+	// instead. Servers in read-through mode load missing keys from their
+	// origin, as described by OriginAPI, and fail with UNAVAILABLE when the
+	// origin cannot be read.
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// Put adds a value to the cache. By default it only inserts new keys; see
+	// WriteMode for replacing existing ones. The HTTP gateway also serves
+	// PUT /cachely/v1/objects/{key}, which upserts. Its body is a JSON
+	// PutRequest when its Content-Type is application/json, and the raw value
+	// otherwise, stored along with its Content-Type, Content-Encoding and
+	// Cachely-Meta- prefixed headers; the other options of the write are then
+	// taken from the query parameters. An If-Match header naming the ETag of
+	// the entry, or *, makes the write conditional on the entry not having
+	// changed, or existing; If-None-Match: * only lets it create the entry.
+	// Writes whose condition fails are answered with 412 Precondition Failed.
+	Put(context.Context, *PutRequest) (*PutResponse, error)
+	// CompareAndSwap replaces a cached value only if it is still at the version
+	// the caller expects, so that concurrent writers cannot lose each other's
+	// updates.
+	CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error)
+	// Increment atomically adds to a counter, a value holding a signed 64-bit
+	// integer in decimal, and returns its new value. A missing key is created
+	// as a counter starting at the initial value of the request. Values that
+	// are not counters fail with INVALID_ARGUMENT, and results that do not fit
+	// in 64 bits with OUT_OF_RANGE.
+	Increment(context.Context, *IncrementRequest) (*IncrementResponse, error)
+	// Decrement atomically subtracts from a counter, as Increment adds to it.
+	Decrement(context.Context, *DecrementRequest) (*DecrementResponse, error)
+	// Append atomically adds bytes to the end of a value. Missing keys fail
+	// with NOT_FOUND, unless the request asks for them to be created holding
+	// the bytes appended. Values that would grow past the maximum size of the
+	// request fail with OUT_OF_RANGE and are left as they are.
+	Append(context.Context, *AppendRequest) (*AppendResponse, error)
+	// Prepend atomically adds bytes to the start of a value, as Append adds
+	// them to its end.
+	Prepend(context.Context, *PrependRequest) (*PrependResponse, error)
+	// PutStream stores a value sent in chunks, for values too large to fit in a
+	// single message. The first message carries the key and options of the
+	// write as a PutRequest, and the last one the checksum of the whole value.
+	// The value is only stored once it has been received in full and its
+	// checksum verified; a mismatch fails with DATA_LOSS. The HTTP gateway
+	// serves PUT /cachely/v1/objects/{key}:stream, which upserts the raw request
+	// body, taking the other options of the write from the query parameters.
+	PutStream(CacheAPI_PutStreamServer) error
+	// GetStream retrieves a value in chunks, for values too large to fit in a
+	// single message. The first message carries the entry without its value,
+	// and the last one the checksum of the whole value. The HTTP gateway serves
+	// GET /cachely/v1/objects/{key}:stream, which returns the raw value. Missing
+	// keys are loaded from the origin of read-through servers, as with Get.
+	GetStream(*GetStreamRequest, CacheAPI_GetStreamServer) error
+	// Delete removes a cached value from the cache. On the HTTP gateway, an
+	// If-Match header makes the delete conditional as for Put.
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// List enumerates the cached keys in lexical order, optionally restricted to
+	// those starting with a prefix. Results are paginated. Only the entries held
+	// by the cache are listed: read-through servers do not list the keys of
+	// their origin that have not been loaded.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Watch streams the changes made to a key, or to every key starting with a
+	// prefix. A client that reconnects can pass the revision of the last event
+	// it received to replay the changes it missed, as long as they are still
+	// held in the server's change log. The latest revision at the time the
+	// watch starts is sent in the cachely-revision response header.
+	Watch(*WatchRequest, CacheAPI_WatchServer) error
+	// BatchGet retrieves several values from the cache in one round trip. Keys
+	// that cannot be read are reported individually rather than failing the
+	// whole batch. Missing keys are loaded from the origin of read-through
+	// servers, as with Get.
+	BatchGet(context.Context, *BatchGetRequest) (*BatchGetResponse, error)
+	// BatchPut stores several values in the cache in one round trip. Each write
+	// succeeds or fails on its own.
+	BatchPut(context.Context, *BatchPutRequest) (*BatchPutResponse, error)
+	// BatchDelete removes several values from the cache in one round trip. Each
+	// delete succeeds or fails on its own.
+	BatchDelete(context.Context, *BatchDeleteRequest) (*BatchDeleteResponse, error)
+}
+
+// UnimplementedCacheAPIServer can be embedded to have forward compatible implementations.
+type UnimplementedCacheAPIServer struct {
+}
+
+func (*UnimplementedCacheAPIServer) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (*UnimplementedCacheAPIServer) Put(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+}
+func (*UnimplementedCacheAPIServer) CompareAndSwap(ctx context.Context, req *CompareAndSwapRequest) (*CompareAndSwapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareAndSwap not implemented")
+}
+func (*UnimplementedCacheAPIServer) Increment(ctx context.Context, req *IncrementRequest) (*IncrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Increment not implemented")
+}
+func (*UnimplementedCacheAPIServer) Decrement(ctx context.Context, req *DecrementRequest) (*DecrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decrement not implemented")
+}
+func (*UnimplementedCacheAPIServer) Append(ctx context.Context, req *AppendRequest) (*AppendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Append not implemented")
+}
+func (*UnimplementedCacheAPIServer) Prepend(ctx context.Context, req *PrependRequest) (*PrependResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Prepend not implemented")
+}
+func (*UnimplementedCacheAPIServer) PutStream(srv CacheAPI_PutStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method PutStream not implemented")
+}
+func (*UnimplementedCacheAPIServer) GetStream(req *GetStreamRequest, srv CacheAPI_GetStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetStream not implemented")
+}
+func (*UnimplementedCacheAPIServer) Delete(ctx context.Context, req *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (*UnimplementedCacheAPIServer) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (*UnimplementedCacheAPIServer) Watch(req *WatchRequest, srv CacheAPI_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (*UnimplementedCacheAPIServer) BatchGet(ctx context.Context, req *BatchGetRequest) (*BatchGetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGet not implemented")
+}
+func (*UnimplementedCacheAPIServer) BatchPut(ctx context.Context, req *BatchPutRequest) (*BatchPutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchPut not implemented")
+}
+func (*UnimplementedCacheAPIServer) BatchDelete(ctx context.Context, req *BatchDeleteRequest) (*BatchDeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchDelete not implemented")
 }
 
 func RegisterCacheAPIServer(s *grpc.Server, srv CacheAPIServer) {
@@ -425,6 +2946,143 @@ func _CacheAPI_Put_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CacheAPI_CompareAndSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareAndSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).CompareAndSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/CompareAndSwap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).CompareAndSwap(ctx, req.(*CompareAndSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_Increment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).Increment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/Increment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).Increment(ctx, req.(*IncrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_Decrement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecrementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).Decrement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/Decrement",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).Decrement(ctx, req.(*DecrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_Append_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).Append(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/Append",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).Append(ctx, req.(*AppendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_Prepend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrependRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).Prepend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/Prepend",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).Prepend(ctx, req.(*PrependRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_PutStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CacheAPIServer).PutStream(&cacheAPIPutStreamServer{stream})
+}
+
+type CacheAPI_PutStreamServer interface {
+	SendAndClose(*PutResponse) error
+	Recv() (*PutStreamRequest, error)
+	grpc.ServerStream
+}
+
+type cacheAPIPutStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheAPIPutStreamServer) SendAndClose(m *PutResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *cacheAPIPutStreamServer) Recv() (*PutStreamRequest, error) {
+	m := new(PutStreamRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _CacheAPI_GetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CacheAPIServer).GetStream(m, &cacheAPIGetStreamServer{stream})
+}
+
+type CacheAPI_GetStreamServer interface {
+	Send(*GetStreamResponse) error
+	grpc.ServerStream
+}
+
+type cacheAPIGetStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheAPIGetStreamServer) Send(m *GetStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _CacheAPI_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteRequest)
 	if err := dec(in); err != nil {
@@ -443,6 +3101,99 @@ func _CacheAPI_Delete_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CacheAPI_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CacheAPIServer).Watch(m, &cacheAPIWatchServer{stream})
+}
+
+type CacheAPI_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type cacheAPIWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheAPIWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CacheAPI_BatchGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).BatchGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/BatchGet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).BatchGet(ctx, req.(*BatchGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_BatchPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchPutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).BatchPut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/BatchPut",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).BatchPut(ctx, req.(*BatchPutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAPI_BatchDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchDeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAPIServer).BatchDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.CacheAPI/BatchDelete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAPIServer).BatchDelete(ctx, req.(*BatchDeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _CacheAPI_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "cachely.v1.CacheAPI",
 	HandlerType: (*CacheAPIServer)(nil),
@@ -455,11 +3206,63 @@ var _CacheAPI_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Put",
 			Handler:    _CacheAPI_Put_Handler,
 		},
+		{
+			MethodName: "CompareAndSwap",
+			Handler:    _CacheAPI_CompareAndSwap_Handler,
+		},
+		{
+			MethodName: "Increment",
+			Handler:    _CacheAPI_Increment_Handler,
+		},
+		{
+			MethodName: "Decrement",
+			Handler:    _CacheAPI_Decrement_Handler,
+		},
+		{
+			MethodName: "Append",
+			Handler:    _CacheAPI_Append_Handler,
+		},
+		{
+			MethodName: "Prepend",
+			Handler:    _CacheAPI_Prepend_Handler,
+		},
 		{
 			MethodName: "Delete",
 			Handler:    _CacheAPI_Delete_Handler,
 		},
+		{
+			MethodName: "List",
+			Handler:    _CacheAPI_List_Handler,
+		},
+		{
+			MethodName: "BatchGet",
+			Handler:    _CacheAPI_BatchGet_Handler,
+		},
+		{
+			MethodName: "BatchPut",
+			Handler:    _CacheAPI_BatchPut_Handler,
+		},
+		{
+			MethodName: "BatchDelete",
+			Handler:    _CacheAPI_BatchDelete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PutStream",
+			Handler:       _CacheAPI_PutStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetStream",
+			Handler:       _CacheAPI_GetStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _CacheAPI_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "cachely/v1/cache_api.proto",
 }
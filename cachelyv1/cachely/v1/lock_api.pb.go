@@ -0,0 +1,766 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: cachely/v1/lock_api.proto
+
+package cachelyv1
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/gogo/protobuf/proto"
+	types "github.com/gogo/protobuf/types"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
+
+// Lease is the hold of an owner over a lock.
+type Lease struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// owner identifies the holder to other clients, and may be empty.
+	Owner string `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	// token is the fencing token issued when the lock was acquired. Renewing
+	// the lease keeps it.
+	Token uint64 `protobuf:"varint,3,opt,name=token,proto3" json:"token,omitempty"`
+	// expires_at is the time at which the lock is released unless the lease is
+	// renewed.
+	ExpiresAt            *types.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *Lease) Reset()         { *m = Lease{} }
+func (m *Lease) String() string { return proto.CompactTextString(m) }
+func (*Lease) ProtoMessage()    {}
+func (*Lease) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{0}
+}
+func (m *Lease) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Lease.Unmarshal(m, b)
+}
+func (m *Lease) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Lease.Marshal(b, m, deterministic)
+}
+func (m *Lease) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Lease.Merge(m, src)
+}
+func (m *Lease) XXX_Size() int {
+	return xxx_messageInfo_Lease.Size(m)
+}
+func (m *Lease) XXX_DiscardUnknown() {
+	xxx_messageInfo_Lease.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Lease proto.InternalMessageInfo
+
+func (m *Lease) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Lease) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *Lease) GetToken() uint64 {
+	if m != nil {
+		return m.Token
+	}
+	return 0
+}
+
+func (m *Lease) GetExpiresAt() *types.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+type AcquireRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// owner optionally identifies the holder to other clients.
+	Owner string `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	// ttl is the duration of the lease, and must be set.
+	Ttl                  *types.Duration `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *AcquireRequest) Reset()         { *m = AcquireRequest{} }
+func (m *AcquireRequest) String() string { return proto.CompactTextString(m) }
+func (*AcquireRequest) ProtoMessage()    {}
+func (*AcquireRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{1}
+}
+func (m *AcquireRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcquireRequest.Unmarshal(m, b)
+}
+func (m *AcquireRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcquireRequest.Marshal(b, m, deterministic)
+}
+func (m *AcquireRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcquireRequest.Merge(m, src)
+}
+func (m *AcquireRequest) XXX_Size() int {
+	return xxx_messageInfo_AcquireRequest.Size(m)
+}
+func (m *AcquireRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcquireRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcquireRequest proto.InternalMessageInfo
+
+func (m *AcquireRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AcquireRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *AcquireRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+type AcquireResponse struct {
+	Lease                *Lease   `protobuf:"bytes,1,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcquireResponse) Reset()         { *m = AcquireResponse{} }
+func (m *AcquireResponse) String() string { return proto.CompactTextString(m) }
+func (*AcquireResponse) ProtoMessage()    {}
+func (*AcquireResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{2}
+}
+func (m *AcquireResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcquireResponse.Unmarshal(m, b)
+}
+func (m *AcquireResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcquireResponse.Marshal(b, m, deterministic)
+}
+func (m *AcquireResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcquireResponse.Merge(m, src)
+}
+func (m *AcquireResponse) XXX_Size() int {
+	return xxx_messageInfo_AcquireResponse.Size(m)
+}
+func (m *AcquireResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcquireResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcquireResponse proto.InternalMessageInfo
+
+func (m *AcquireResponse) GetLease() *Lease {
+	if m != nil {
+		return m.Lease
+	}
+	return nil
+}
+
+type AcquireWaitRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// owner and ttl describe the lease to acquire, as in AcquireRequest.
+	Owner string          `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	Ttl   *types.Duration `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// timeout optionally bounds how long to wait for the lock. Without it, the
+	// wait lasts until the lock is acquired or the call is cancelled.
+	Timeout              *types.Duration `protobuf:"bytes,4,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *AcquireWaitRequest) Reset()         { *m = AcquireWaitRequest{} }
+func (m *AcquireWaitRequest) String() string { return proto.CompactTextString(m) }
+func (*AcquireWaitRequest) ProtoMessage()    {}
+func (*AcquireWaitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{3}
+}
+func (m *AcquireWaitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcquireWaitRequest.Unmarshal(m, b)
+}
+func (m *AcquireWaitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcquireWaitRequest.Marshal(b, m, deterministic)
+}
+func (m *AcquireWaitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcquireWaitRequest.Merge(m, src)
+}
+func (m *AcquireWaitRequest) XXX_Size() int {
+	return xxx_messageInfo_AcquireWaitRequest.Size(m)
+}
+func (m *AcquireWaitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcquireWaitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcquireWaitRequest proto.InternalMessageInfo
+
+func (m *AcquireWaitRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AcquireWaitRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *AcquireWaitRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+func (m *AcquireWaitRequest) GetTimeout() *types.Duration {
+	if m != nil {
+		return m.Timeout
+	}
+	return nil
+}
+
+type AcquireWaitResponse struct {
+	// holder is the lease of the current holder of the lock, sent while
+	// waiting for it.
+	Holder *Lease `protobuf:"bytes,1,opt,name=holder,proto3" json:"holder,omitempty"`
+	// lease is the lease acquired, set in the last message only.
+	Lease                *Lease   `protobuf:"bytes,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcquireWaitResponse) Reset()         { *m = AcquireWaitResponse{} }
+func (m *AcquireWaitResponse) String() string { return proto.CompactTextString(m) }
+func (*AcquireWaitResponse) ProtoMessage()    {}
+func (*AcquireWaitResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{4}
+}
+func (m *AcquireWaitResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcquireWaitResponse.Unmarshal(m, b)
+}
+func (m *AcquireWaitResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcquireWaitResponse.Marshal(b, m, deterministic)
+}
+func (m *AcquireWaitResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcquireWaitResponse.Merge(m, src)
+}
+func (m *AcquireWaitResponse) XXX_Size() int {
+	return xxx_messageInfo_AcquireWaitResponse.Size(m)
+}
+func (m *AcquireWaitResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcquireWaitResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcquireWaitResponse proto.InternalMessageInfo
+
+func (m *AcquireWaitResponse) GetHolder() *Lease {
+	if m != nil {
+		return m.Holder
+	}
+	return nil
+}
+
+func (m *AcquireWaitResponse) GetLease() *Lease {
+	if m != nil {
+		return m.Lease
+	}
+	return nil
+}
+
+type RenewRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// token is the fencing token of the lease to renew.
+	Token uint64 `protobuf:"varint,2,opt,name=token,proto3" json:"token,omitempty"`
+	// ttl is the new duration of the lease, from now, and must be set.
+	Ttl                  *types.Duration `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *RenewRequest) Reset()         { *m = RenewRequest{} }
+func (m *RenewRequest) String() string { return proto.CompactTextString(m) }
+func (*RenewRequest) ProtoMessage()    {}
+func (*RenewRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{5}
+}
+func (m *RenewRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenewRequest.Unmarshal(m, b)
+}
+func (m *RenewRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenewRequest.Marshal(b, m, deterministic)
+}
+func (m *RenewRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenewRequest.Merge(m, src)
+}
+func (m *RenewRequest) XXX_Size() int {
+	return xxx_messageInfo_RenewRequest.Size(m)
+}
+func (m *RenewRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenewRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenewRequest proto.InternalMessageInfo
+
+func (m *RenewRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RenewRequest) GetToken() uint64 {
+	if m != nil {
+		return m.Token
+	}
+	return 0
+}
+
+func (m *RenewRequest) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+type RenewResponse struct {
+	Lease                *Lease   `protobuf:"bytes,1,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenewResponse) Reset()         { *m = RenewResponse{} }
+func (m *RenewResponse) String() string { return proto.CompactTextString(m) }
+func (*RenewResponse) ProtoMessage()    {}
+func (*RenewResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{6}
+}
+func (m *RenewResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenewResponse.Unmarshal(m, b)
+}
+func (m *RenewResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenewResponse.Marshal(b, m, deterministic)
+}
+func (m *RenewResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenewResponse.Merge(m, src)
+}
+func (m *RenewResponse) XXX_Size() int {
+	return xxx_messageInfo_RenewResponse.Size(m)
+}
+func (m *RenewResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenewResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenewResponse proto.InternalMessageInfo
+
+func (m *RenewResponse) GetLease() *Lease {
+	if m != nil {
+		return m.Lease
+	}
+	return nil
+}
+
+type ReleaseRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// token is the fencing token of the lease to release.
+	Token                uint64   `protobuf:"varint,2,opt,name=token,proto3" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseRequest) Reset()         { *m = ReleaseRequest{} }
+func (m *ReleaseRequest) String() string { return proto.CompactTextString(m) }
+func (*ReleaseRequest) ProtoMessage()    {}
+func (*ReleaseRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{7}
+}
+func (m *ReleaseRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseRequest.Unmarshal(m, b)
+}
+func (m *ReleaseRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseRequest.Marshal(b, m, deterministic)
+}
+func (m *ReleaseRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseRequest.Merge(m, src)
+}
+func (m *ReleaseRequest) XXX_Size() int {
+	return xxx_messageInfo_ReleaseRequest.Size(m)
+}
+func (m *ReleaseRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseRequest proto.InternalMessageInfo
+
+func (m *ReleaseRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ReleaseRequest) GetToken() uint64 {
+	if m != nil {
+		return m.Token
+	}
+	return 0
+}
+
+type ReleaseResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseResponse) Reset()         { *m = ReleaseResponse{} }
+func (m *ReleaseResponse) String() string { return proto.CompactTextString(m) }
+func (*ReleaseResponse) ProtoMessage()    {}
+func (*ReleaseResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e02f26aaef74df20, []int{8}
+}
+func (m *ReleaseResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseResponse.Unmarshal(m, b)
+}
+func (m *ReleaseResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseResponse.Marshal(b, m, deterministic)
+}
+func (m *ReleaseResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseResponse.Merge(m, src)
+}
+func (m *ReleaseResponse) XXX_Size() int {
+	return xxx_messageInfo_ReleaseResponse.Size(m)
+}
+func (m *ReleaseResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*Lease)(nil), "cachely.v1.Lease")
+	proto.RegisterType((*AcquireRequest)(nil), "cachely.v1.AcquireRequest")
+	proto.RegisterType((*AcquireResponse)(nil), "cachely.v1.AcquireResponse")
+	proto.RegisterType((*AcquireWaitRequest)(nil), "cachely.v1.AcquireWaitRequest")
+	proto.RegisterType((*AcquireWaitResponse)(nil), "cachely.v1.AcquireWaitResponse")
+	proto.RegisterType((*RenewRequest)(nil), "cachely.v1.RenewRequest")
+	proto.RegisterType((*RenewResponse)(nil), "cachely.v1.RenewResponse")
+	proto.RegisterType((*ReleaseRequest)(nil), "cachely.v1.ReleaseRequest")
+	proto.RegisterType((*ReleaseResponse)(nil), "cachely.v1.ReleaseResponse")
+}
+
+func init() { proto.RegisterFile("cachely/v1/lock_api.proto", fileDescriptor_e02f26aaef74df20) }
+
+var fileDescriptor_e02f26aaef74df20 = []byte{
+	// 570 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x54, 0x41, 0x6e, 0xd3, 0x40,
+	0x14, 0xd5, 0x38, 0x49, 0xa3, 0xfc, 0x94, 0x54, 0x1d, 0x58, 0x24, 0x2e, 0x6a, 0x83, 0x91, 0x68,
+	0x08, 0x95, 0x4d, 0xd2, 0x0d, 0x58, 0x62, 0x91, 0x96, 0x0d, 0xa2, 0x8b, 0xc8, 0x42, 0xa5, 0x42,
+	0x48, 0xd5, 0xd4, 0x1d, 0x52, 0x13, 0xc7, 0xe3, 0x8e, 0x27, 0x29, 0x08, 0xb1, 0xa9, 0xb8, 0x01,
+	0x17, 0x40, 0x2c, 0x39, 0x0a, 0x5b, 0xae, 0xd0, 0x83, 0x20, 0xcf, 0x4c, 0x52, 0xa7, 0xa4, 0x29,
+	0x59, 0x74, 0xe7, 0x99, 0xf7, 0xff, 0xbc, 0xf7, 0xdf, 0xfb, 0x32, 0xd4, 0x7c, 0xe2, 0x9f, 0xd0,
+	0xf0, 0xb3, 0x33, 0x6a, 0x39, 0x21, 0xf3, 0xfb, 0x87, 0x24, 0x0e, 0xec, 0x98, 0x33, 0xc1, 0x30,
+	0x68, 0xc8, 0x1e, 0xb5, 0xcc, 0xfb, 0x3d, 0xc6, 0x7a, 0x21, 0x75, 0x48, 0x1c, 0x38, 0x24, 0x8a,
+	0x98, 0x20, 0x22, 0x60, 0x51, 0xa2, 0x2a, 0xcd, 0x75, 0x8d, 0xca, 0xd3, 0xd1, 0xf0, 0x83, 0x73,
+	0x3c, 0xe4, 0xb2, 0x40, 0xe3, 0x1b, 0x57, 0x71, 0x11, 0x0c, 0x68, 0x22, 0xc8, 0x20, 0x56, 0x05,
+	0xd6, 0x39, 0x82, 0xc2, 0x1e, 0x25, 0x09, 0xc5, 0x18, 0xf2, 0x11, 0x19, 0xd0, 0x2a, 0xaa, 0xa3,
+	0x46, 0xc9, 0x93, 0xdf, 0xf8, 0x1e, 0x14, 0xd8, 0x59, 0x44, 0x79, 0xd5, 0x90, 0x97, 0xea, 0x90,
+	0xde, 0x0a, 0xd6, 0xa7, 0x51, 0x35, 0x57, 0x47, 0x8d, 0xbc, 0xa7, 0x0e, 0xf8, 0x39, 0x00, 0xfd,
+	0x14, 0x07, 0x9c, 0x26, 0x87, 0x44, 0x54, 0xf3, 0x75, 0xd4, 0x28, 0xb7, 0x4d, 0x5b, 0xf1, 0xdb,
+	0x63, 0x7e, 0xfb, 0xcd, 0x98, 0xdf, 0x2b, 0xe9, 0xea, 0x8e, 0xb0, 0x7a, 0x50, 0xe9, 0xf8, 0xa7,
+	0xc3, 0x80, 0x53, 0x8f, 0x9e, 0x0e, 0x69, 0x22, 0x16, 0x10, 0xf3, 0x04, 0x72, 0x42, 0x84, 0x52,
+	0x4a, 0xb9, 0x5d, 0xfb, 0x87, 0xef, 0xa5, 0xf6, 0xc3, 0x4b, 0xab, 0x2c, 0x17, 0x56, 0x26, 0x44,
+	0x49, 0xcc, 0xa2, 0x84, 0xe2, 0x4d, 0x28, 0x84, 0xe9, 0xfc, 0x92, 0xaa, 0xdc, 0x5e, 0xb5, 0x2f,
+	0xbd, 0xb7, 0xa5, 0x31, 0x9e, 0xc2, 0xad, 0x1f, 0x08, 0xb0, 0x6e, 0x7e, 0x4b, 0x02, 0x71, 0xbb,
+	0x4a, 0xf1, 0x36, 0x14, 0xd3, 0xa8, 0xd8, 0x70, 0x6c, 0xe5, 0x9c, 0x86, 0x71, 0xa5, 0x15, 0xc0,
+	0xdd, 0x29, 0x85, 0x7a, 0xc4, 0xc7, 0xb0, 0x74, 0xc2, 0xc2, 0x63, 0xca, 0xaf, 0x9f, 0x51, 0x17,
+	0x5c, 0xba, 0x61, 0xdc, 0xe0, 0x06, 0x85, 0x65, 0x8f, 0x46, 0xf4, 0xec, 0x06, 0x1b, 0xd4, 0x9e,
+	0x18, 0xd9, 0x3d, 0x59, 0x28, 0xb0, 0x67, 0x70, 0x47, 0xd3, 0x2c, 0x1a, 0x97, 0x0b, 0x15, 0x8f,
+	0xca, 0xcf, 0x85, 0x25, 0x5a, 0xab, 0xb0, 0x32, 0xe9, 0x55, 0xbc, 0xed, 0x8b, 0x1c, 0x14, 0xf7,
+	0x98, 0xdf, 0xef, 0x74, 0x5f, 0x61, 0x0e, 0x45, 0x6d, 0x33, 0x36, 0xb3, 0xfc, 0xd3, 0x3b, 0x6c,
+	0xae, 0xcd, 0xc4, 0xd4, 0x7b, 0x96, 0x7d, 0xfe, 0xe7, 0xe2, 0xbb, 0xd1, 0xb0, 0x1e, 0x3a, 0x57,
+	0x7e, 0x03, 0x89, 0xf3, 0x25, 0x55, 0xf6, 0xa2, 0xd9, 0xfc, 0xea, 0x12, 0xd5, 0xe4, 0xa2, 0x26,
+	0xfe, 0x86, 0xa0, 0x9c, 0xc9, 0x16, 0xaf, 0xcf, 0x78, 0x3c, 0xb3, 0x96, 0xe6, 0xc6, 0xb5, 0xb8,
+	0x16, 0xd0, 0x96, 0x02, 0xb6, 0xac, 0xcd, 0xff, 0x10, 0x90, 0x36, 0xba, 0xa8, 0xf9, 0x14, 0xe1,
+	0x8f, 0x50, 0x90, 0x79, 0xe0, 0x6a, 0xf6, 0xfd, 0xec, 0x26, 0x98, 0xb5, 0x19, 0x88, 0xe6, 0xdc,
+	0x92, 0x9c, 0x8f, 0xac, 0x07, 0xf3, 0x38, 0x79, 0xda, 0x92, 0x8e, 0xcc, 0xa1, 0xa8, 0x53, 0x98,
+	0xb6, 0x79, 0x3a, 0x56, 0x73, 0x6d, 0x26, 0x36, 0x6d, 0xb3, 0x8b, 0x9a, 0xf3, 0x9d, 0xe6, 0xaa,
+	0x6f, 0xe7, 0x35, 0x54, 0x7c, 0x36, 0xc8, 0xbc, 0xb8, 0xb3, 0x2c, 0x53, 0x8f, 0x83, 0x6e, 0xba,
+	0xa0, 0x5d, 0xf4, 0xae, 0xa4, 0xb1, 0x51, 0xeb, 0xa7, 0x91, 0xdb, 0x3d, 0x38, 0xf8, 0x65, 0xc0,
+	0xae, 0xae, 0xde, 0x6f, 0xfd, 0x9e, 0x1c, 0xde, 0xef, 0xb7, 0x8e, 0x96, 0xe4, 0x52, 0x6f, 0xff,
+	0x1d, 0x00, 0x32, 0x5e, 0x36, 0x35, 0xea, 0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// LockAPIClient is the client API for LockAPI service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type LockAPIClient interface {
+	// Acquire takes a lock if it is free, and fails with ABORTED otherwise.
+	Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*AcquireResponse, error)
+	// AcquireWait takes a lock, waiting for it to be released or for the lease
+	// of its holder to expire. While it waits, the lease of the holder is sent
+	// every time the lock is found held under a new lease. The lease acquired
+	// is sent in the last message. The wait fails with DEADLINE_EXCEEDED once
+	// the timeout of the request has elapsed.
+	AcquireWait(ctx context.Context, in *AcquireWaitRequest, opts ...grpc.CallOption) (LockAPI_AcquireWaitClient, error)
+	// Renew extends the lease of a lock from now. It fails with
+	// FAILED_PRECONDITION if the lease has expired or been released, in which
+	// case the lock may have been acquired by another holder.
+	Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*RenewResponse, error)
+	// Release frees a lock. It fails with FAILED_PRECONDITION if the lease has
+	// expired or been released already.
+	Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error)
+}
+
+type lockAPIClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewLockAPIClient(cc *grpc.ClientConn) LockAPIClient {
+	return &lockAPIClient{cc}
+}
+
+func (c *lockAPIClient) Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*AcquireResponse, error) {
+	out := new(AcquireResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.LockAPI/Acquire", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lockAPIClient) AcquireWait(ctx context.Context, in *AcquireWaitRequest, opts ...grpc.CallOption) (LockAPI_AcquireWaitClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_LockAPI_serviceDesc.Streams[0], "/cachely.v1.LockAPI/AcquireWait", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &lockAPIAcquireWaitClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LockAPI_AcquireWaitClient interface {
+	Recv() (*AcquireWaitResponse, error)
+	grpc.ClientStream
+}
+
+type lockAPIAcquireWaitClient struct {
+	grpc.ClientStream
+}
+
+func (x *lockAPIAcquireWaitClient) Recv() (*AcquireWaitResponse, error) {
+	m := new(AcquireWaitResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *lockAPIClient) Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*RenewResponse, error) {
+	out := new(RenewResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.LockAPI/Renew", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lockAPIClient) Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error) {
+	out := new(ReleaseResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.LockAPI/Release", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LockAPIServer is the server API for LockAPI service.
+type LockAPIServer interface {
+	// Acquire takes a lock if it is free, and fails with ABORTED otherwise.
+	Acquire(context.Context, *AcquireRequest) (*AcquireResponse, error)
+	// AcquireWait takes a lock, waiting for it to be released or for the lease
+	// of its holder to expire. While it waits, the lease of the holder is sent
+	// every time the lock is found held under a new lease. The lease acquired
+	// is sent in the last message. The wait fails with DEADLINE_EXCEEDED once
+	// the timeout of the request has elapsed.
+	AcquireWait(*AcquireWaitRequest, LockAPI_AcquireWaitServer) error
+	// Renew extends the lease of a lock from now. It fails with
+	// FAILED_PRECONDITION if the lease has expired or been released, in which
+	// case the lock may have been acquired by another holder.
+	Renew(context.Context, *RenewRequest) (*RenewResponse, error)
+	// Release frees a lock. It fails with FAILED_PRECONDITION if the lease has
+	// expired or been released already.
+	Release(context.Context, *ReleaseRequest) (*ReleaseResponse, error)
+}
+
+// UnimplementedLockAPIServer can be embedded to have forward compatible implementations.
+type UnimplementedLockAPIServer struct {
+}
+
+func (*UnimplementedLockAPIServer) Acquire(ctx context.Context, req *AcquireRequest) (*AcquireResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acquire not implemented")
+}
+func (*UnimplementedLockAPIServer) AcquireWait(req *AcquireWaitRequest, srv LockAPI_AcquireWaitServer) error {
+	return status.Errorf(codes.Unimplemented, "method AcquireWait not implemented")
+}
+func (*UnimplementedLockAPIServer) Renew(ctx context.Context, req *RenewRequest) (*RenewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Renew not implemented")
+}
+func (*UnimplementedLockAPIServer) Release(ctx context.Context, req *ReleaseRequest) (*ReleaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+
+func RegisterLockAPIServer(s *grpc.Server, srv LockAPIServer) {
+	s.RegisterService(&_LockAPI_serviceDesc, srv)
+}
+
+func _LockAPI_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LockAPIServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.LockAPI/Acquire",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LockAPIServer).Acquire(ctx, req.(*AcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LockAPI_AcquireWait_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AcquireWaitRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LockAPIServer).AcquireWait(m, &lockAPIAcquireWaitServer{stream})
+}
+
+type LockAPI_AcquireWaitServer interface {
+	Send(*AcquireWaitResponse) error
+	grpc.ServerStream
+}
+
+type lockAPIAcquireWaitServer struct {
+	grpc.ServerStream
+}
+
+func (x *lockAPIAcquireWaitServer) Send(m *AcquireWaitResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _LockAPI_Renew_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LockAPIServer).Renew(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.LockAPI/Renew",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LockAPIServer).Renew(ctx, req.(*RenewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LockAPI_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LockAPIServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.LockAPI/Release",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LockAPIServer).Release(ctx, req.(*ReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _LockAPI_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cachely.v1.LockAPI",
+	HandlerType: (*LockAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acquire",
+			Handler:    _LockAPI_Acquire_Handler,
+		},
+		{
+			MethodName: "Renew",
+			Handler:    _LockAPI_Renew_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _LockAPI_Release_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AcquireWait",
+			Handler:       _LockAPI_AcquireWait_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cachely/v1/lock_api.proto",
+}
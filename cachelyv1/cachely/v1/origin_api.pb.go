@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: cachely/v1/origin_api.proto
+
+package cachelyv1
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/gogo/protobuf/proto"
+	types "github.com/gogo/protobuf/types"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
+
+type LoadRequest struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoadRequest) Reset()         { *m = LoadRequest{} }
+func (m *LoadRequest) String() string { return proto.CompactTextString(m) }
+func (*LoadRequest) ProtoMessage()    {}
+func (*LoadRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_be8e5d40111759ca, []int{0}
+}
+func (m *LoadRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoadRequest.Unmarshal(m, b)
+}
+func (m *LoadRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoadRequest.Marshal(b, m, deterministic)
+}
+func (m *LoadRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoadRequest.Merge(m, src)
+}
+func (m *LoadRequest) XXX_Size() int {
+	return xxx_messageInfo_LoadRequest.Size(m)
+}
+func (m *LoadRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoadRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoadRequest proto.InternalMessageInfo
+
+func (m *LoadRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type LoadResponse struct {
+	Value []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// content_type, content_encoding and metadata are stored along with the
+	// value, as set by PutRequest. They must be valid as for PutRequest, or the
+	// load fails.
+	ContentType     string            `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ContentEncoding string            `protobuf:"bytes,3,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
+	Metadata        map[string]string `protobuf:"bytes,4,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// ttl optionally sets how long the value is cached for. Without it, the
+	// value is cached for as long as the server is configured to cache loaded
+	// values.
+	Ttl                  *types.Duration `protobuf:"bytes,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *LoadResponse) Reset()         { *m = LoadResponse{} }
+func (m *LoadResponse) String() string { return proto.CompactTextString(m) }
+func (*LoadResponse) ProtoMessage()    {}
+func (*LoadResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_be8e5d40111759ca, []int{1}
+}
+func (m *LoadResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoadResponse.Unmarshal(m, b)
+}
+func (m *LoadResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoadResponse.Marshal(b, m, deterministic)
+}
+func (m *LoadResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoadResponse.Merge(m, src)
+}
+func (m *LoadResponse) XXX_Size() int {
+	return xxx_messageInfo_LoadResponse.Size(m)
+}
+func (m *LoadResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoadResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoadResponse proto.InternalMessageInfo
+
+func (m *LoadResponse) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *LoadResponse) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *LoadResponse) GetContentEncoding() string {
+	if m != nil {
+		return m.ContentEncoding
+	}
+	return ""
+}
+
+func (m *LoadResponse) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *LoadResponse) GetTtl() *types.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*LoadRequest)(nil), "cachely.v1.LoadRequest")
+	proto.RegisterType((*LoadResponse)(nil), "cachely.v1.LoadResponse")
+	proto.RegisterMapType((map[string]string)(nil), "cachely.v1.LoadResponse.MetadataEntry")
+}
+
+func init() { proto.RegisterFile("cachely/v1/origin_api.proto", fileDescriptor_be8e5d40111759ca) }
+
+var fileDescriptor_be8e5d40111759ca = []byte{
+	// 350 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x91, 0xcd, 0x4a, 0xfb, 0x40,
+	0x14, 0xc5, 0x49, 0xd2, 0xfe, 0xf9, 0xf7, 0xa6, 0xd6, 0x32, 0x08, 0xc6, 0x0a, 0x5a, 0xbb, 0x90,
+	0x8a, 0x30, 0x21, 0x75, 0xe3, 0xc7, 0xca, 0xd6, 0x0a, 0x82, 0xc5, 0x12, 0xa4, 0x14, 0x11, 0xca,
+	0x34, 0x19, 0x63, 0x30, 0x9d, 0x89, 0xe9, 0x24, 0x90, 0xb7, 0x11, 0x97, 0x3e, 0x8a, 0x4f, 0x25,
+	0x49, 0xa6, 0x1f, 0x52, 0xdc, 0xe5, 0xde, 0xf3, 0xcb, 0xe5, 0x9c, 0x33, 0xb0, 0xef, 0x10, 0xe7,
+	0x95, 0x06, 0xa9, 0x99, 0x58, 0x26, 0x8f, 0x7c, 0xcf, 0x67, 0x13, 0x12, 0xfa, 0x38, 0x8c, 0xb8,
+	0xe0, 0x08, 0xa4, 0x88, 0x13, 0xab, 0x71, 0xe0, 0x71, 0xee, 0x05, 0xd4, 0xcc, 0x95, 0x69, 0xfc,
+	0x62, 0xba, 0x71, 0x44, 0x84, 0xcf, 0x59, 0xc1, 0xb6, 0x0e, 0x41, 0xbf, 0xe7, 0xc4, 0xb5, 0xe9,
+	0x7b, 0x4c, 0xe7, 0x02, 0xd5, 0x41, 0x7b, 0xa3, 0xa9, 0xa1, 0x34, 0x95, 0x76, 0xc5, 0xce, 0x3e,
+	0x5b, 0x1f, 0x2a, 0x54, 0x0b, 0x62, 0x1e, 0x72, 0x36, 0xa7, 0x68, 0x07, 0xca, 0x09, 0x09, 0x62,
+	0x9a, 0x43, 0x55, 0xbb, 0x18, 0xd0, 0x11, 0x54, 0x1d, 0xce, 0x04, 0x65, 0x62, 0x22, 0xd2, 0x90,
+	0x1a, 0x6a, 0x7e, 0x41, 0x97, 0xbb, 0xc7, 0x34, 0xa4, 0xe8, 0x04, 0xea, 0x0b, 0x84, 0x32, 0x87,
+	0xbb, 0x3e, 0xf3, 0x0c, 0x2d, 0xc7, 0xb6, 0xe5, 0xbe, 0x2f, 0xd7, 0xa8, 0x0b, 0xff, 0x67, 0x54,
+	0x10, 0x97, 0x08, 0x62, 0x94, 0x9a, 0x5a, 0x5b, 0xef, 0x1c, 0xe3, 0x55, 0x28, 0xbc, 0xee, 0x07,
+	0x0f, 0x24, 0xd8, 0x67, 0x22, 0x4a, 0xed, 0xe5, 0x7f, 0xe8, 0x14, 0x34, 0x21, 0x02, 0xa3, 0xdc,
+	0x54, 0xda, 0x7a, 0x67, 0x0f, 0x17, 0x3d, 0xe0, 0x45, 0x0f, 0xf8, 0x46, 0xf6, 0x60, 0x67, 0x54,
+	0xe3, 0x0a, 0xb6, 0x7e, 0xdd, 0xd9, 0x2c, 0x62, 0x95, 0xbb, 0x88, 0x56, 0x0c, 0x97, 0xea, 0xb9,
+	0xd2, 0xb9, 0x85, 0xca, 0x43, 0xfe, 0x06, 0xd7, 0xc3, 0x3b, 0x74, 0x01, 0xa5, 0xcc, 0x1e, 0xda,
+	0xdd, 0x34, 0x9c, 0x57, 0xdc, 0x30, 0xfe, 0x4a, 0xd2, 0x1d, 0x40, 0xcd, 0xe1, 0xb3, 0x35, 0xb9,
+	0x5b, 0x93, 0x77, 0x43, 0x7f, 0x98, 0xf9, 0x1e, 0x2a, 0x4f, 0x15, 0xa9, 0x26, 0xd6, 0xa7, 0xaa,
+	0xf5, 0xc6, 0xe3, 0x2f, 0x15, 0x7a, 0x92, 0x1f, 0x59, 0xdf, 0xcb, 0xe1, 0x79, 0x64, 0x4d, 0xff,
+	0xe5, 0x59, 0xcf, 0x7e, 0x06, 0x00, 0x3c, 0xd4, 0x10, 0x14, 0x2c, 0x02, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// OriginAPIClient is the client API for OriginAPI service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type OriginAPIClient interface {
+	// Load returns the value at a key, or fails with NOT_FOUND if there is
+	// none.
+	Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*LoadResponse, error)
+}
+
+type originAPIClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewOriginAPIClient(cc *grpc.ClientConn) OriginAPIClient {
+	return &originAPIClient{cc}
+}
+
+func (c *originAPIClient) Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*LoadResponse, error) {
+	out := new(LoadResponse)
+	err := c.cc.Invoke(ctx, "/cachely.v1.OriginAPI/Load", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OriginAPIServer is the server API for OriginAPI service.
+type OriginAPIServer interface {
+	// Load returns the value at a key, or fails with NOT_FOUND if there is
+	// none.
+	Load(context.Context, *LoadRequest) (*LoadResponse, error)
+}
+
+// UnimplementedOriginAPIServer can be embedded to have forward compatible implementations.
+type UnimplementedOriginAPIServer struct {
+}
+
+func (*UnimplementedOriginAPIServer) Load(ctx context.Context, req *LoadRequest) (*LoadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Load not implemented")
+}
+
+func RegisterOriginAPIServer(s *grpc.Server, srv OriginAPIServer) {
+	s.RegisterService(&_OriginAPI_serviceDesc, srv)
+}
+
+func _OriginAPI_Load_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OriginAPIServer).Load(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cachely.v1.OriginAPI/Load",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OriginAPIServer).Load(ctx, req.(*LoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _OriginAPI_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cachely.v1.OriginAPI",
+	HandlerType: (*OriginAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Load",
+			Handler:    _OriginAPI_Load_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cachely/v1/origin_api.proto",
+}
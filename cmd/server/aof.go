@@ -0,0 +1,161 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/timraymond/cachely/aof"
+	"github.com/timraymond/cachely/store"
+)
+
+// maxMessageSize is the largest message the gRPC server accepts, which is the
+// gRPC default. Every part of an entry but a value streamed with PutStream or
+// built by Append and Prepend arrives in a single message.
+const maxMessageSize = 4 << 20
+
+// maxRecordSize bounds the size of the records in the append-only log of a
+// server accepting values of up to maxValueSize bytes.
+func maxRecordSize(maxValueSize int64) int64 {
+	if maxValueSize < maxMessageSize {
+		maxValueSize = maxMessageSize
+	}
+	return maxValueSize + maxMessageSize
+}
+
+// replayLog restores the entries recorded in the append-only log at path into
+// st, and reports whether there was a log to replay.
+func replayLog(path string, st store.Store, maxValueSize int64) bool {
+	n, truncated, err := aof.Replay(path, st, maxRecordSize(maxValueSize))
+	switch {
+	case os.IsNotExist(err):
+		log.Printf("no append-only log found at %s\n", path)
+		return false
+	case isCorrupt(err):
+		log.Fatalf("append-only log %s is corrupt, repair or remove it to start: %v", path, err)
+	case err != nil && n == 0:
+		log.Fatalf("failed to replay append-only log: %v", err)
+	case err != nil:
+		log.Printf("replayed %d keys from %s, some could not be restored: %v\n", n, path, err)
+	default:
+		log.Printf("replayed %d keys from %s\n", n, path)
+	}
+	if truncated > 0 {
+		log.Printf("discarded %d bytes of incomplete records at the end of %s\n", truncated, path)
+	}
+	return true
+}
+
+// isCorrupt reports whether err reports a corrupt append-only log.
+func isCorrupt(err error) bool {
+	_, ok := err.(*aof.CorruptError)
+	return ok
+}
+
+// compactLog checks every interval whether l has grown enough to be worth
+// compacting, and compacts it if so. It returns once done is closed.
+func compactLog(l *aof.Log, st store.Store, minSize int64, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if !l.NeedsCompaction(minSize) {
+				continue
+			}
+			before := l.Size()
+			if err := l.Compact(st); err != nil {
+				log.Printf("failed to compact append-only log: %v\n", err)
+				continue
+			}
+			log.Printf("compacted append-only log from %d to %d bytes\n", before, l.Size())
+		case <-done:
+			return
+		}
+	}
+}
+
+// loggedStore is a Store whose changes are recorded in an append-only log by
+// one of its observers. Writes are only acknowledged once the log has
+// committed them, outside of the locks of the store. Once the log fails, the
+// write it failed on and every later write fail with a *logError, while reads
+// are still served.
+type loggedStore struct {
+	store.Store
+	log  *aof.Log
+	once sync.Once // reports the failure of the log
+}
+
+// logError is returned by the writes to a loggedStore whose append-only log
+// has failed.
+type logError struct {
+	err error
+}
+
+func (e *logError) Error() string {
+	return "append-only log failed: " + e.err.Error()
+}
+
+// check fails if the log has failed.
+func (s *loggedStore) check() error {
+	if err := s.log.Commit(); err != nil {
+		s.once.Do(func() {
+			log.Printf("failed to write append-only log, writes are disabled: %v\n", err)
+		})
+		return &logError{err: err}
+	}
+	return nil
+}
+
+// Put stores e at key once the log is known to be working, and returns once
+// the write is committed to the log.
+func (s *loggedStore) Put(key string, e store.Entry) (store.Entry, error) {
+	if err := s.check(); err != nil {
+		return store.Entry{}, err
+	}
+	e, err := s.Store.Put(key, e)
+	if err != nil {
+		return store.Entry{}, err
+	}
+	return e, s.check()
+}
+
+// Update replaces the entry at key once the log is known to be working, and
+// returns once the write is committed to the log.
+func (s *loggedStore) Update(key string, fn store.UpdateFunc) (store.Entry, error) {
+	if err := s.check(); err != nil {
+		return store.Entry{}, err
+	}
+	e, err := s.Store.Update(key, fn)
+	if err != nil {
+		return store.Entry{}, err
+	}
+	return e, s.check()
+}
+
+// Delete removes the entry at key once the log is known to be working, and
+// returns once the deletion is committed to the log.
+func (s *loggedStore) Delete(key string) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if err := s.Store.Delete(key); err != nil {
+		return err
+	}
+	return s.check()
+}
+
+// CompareAndDelete removes the entry at key if it is at version, once the log
+// is known to be working, and returns once the deletion is committed to the
+// log.
+func (s *loggedStore) CompareAndDelete(key string, version uint64) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if err := store.CompareAndDelete(s.Store, key, version); err != nil {
+		return err
+	}
+	return s.check()
+}
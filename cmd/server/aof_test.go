@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/timraymond/cachely/aof"
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+)
+
+func TestLoggedStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cachely")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cachely.aof")
+
+	wal, err := aof.Open(path, aof.SyncAlways)
+	if err != nil {
+		t.Fatalf("Open: unexpected error: %v", err)
+	}
+	st := store.NewMemory(0, nil)
+	st.Notify(func(ev store.Event) { wal.Append(ev) })
+	srv := newTestServer()
+	srv.store = &loggedStore{Store: st, log: wal}
+	client, stop := serveCache(t, srv)
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "a", Value: []byte("v")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "b", Value: []byte("v")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if _, err := client.Delete(ctx, &cachelyv1.DeleteRequest{Key: "b"}); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	// the acknowledged writes are in the log
+	replayed := store.NewMemory(0, nil)
+	if n, _, err := aof.Replay(path, replayed, maxRecordSize(srv.maxValueSize)); err != nil || n != 1 {
+		t.Fatalf("Replay: want 1 entry, got %d and %v", n, err)
+	}
+	if _, err := replayed.Get("a"); err != nil {
+		t.Errorf("Get(a) after Replay: unexpected error: %v", err)
+	}
+
+	// once the log cannot be written, writes fail but reads are served
+	wal.Close()
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "c", Value: []byte("v")}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Put with a failed log: want Unavailable, got %v", err)
+	}
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "d", Value: []byte("v")}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Put after the log failed: want Unavailable, got %v", err)
+	}
+	if _, err := client.Delete(ctx, &cachelyv1.DeleteRequest{Key: "a"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Delete after the log failed: want Unavailable, got %v", err)
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "a"}); err != nil {
+		t.Errorf("Get after the log failed: unexpected error: %v", err)
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "d"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get of a write refused before it was made: want NotFound, got %v", err)
+	}
+}
+
+func TestMaxRecordSize(t *testing.T) {
+	tests := []struct {
+		maxValueSize int64
+		want         int64
+	}{
+		{1 << 10, 8 << 20},
+		{4 << 20, 8 << 20},
+		{64 << 20, 68 << 20},
+	}
+	for _, tt := range tests {
+		if got := maxRecordSize(tt.maxValueSize); got != tt.want {
+			t.Errorf("maxRecordSize(%d): want %d, got %d", tt.maxValueSize, tt.want, got)
+		}
+	}
+}
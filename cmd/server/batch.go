@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+)
+
+// BatchGet retrieves every requested key, reporting keys that cannot be read
+// individually. The keys missing from the store are loaded from the origin of
+// the server, if it is read-through, as with Get.
+func (s *server) BatchGet(ctx context.Context, req *cachelyv1.BatchGetRequest) (*cachelyv1.BatchGetResponse, error) {
+	keys := req.GetKeys()
+	log.Printf("looking up %d keys\n", len(keys))
+
+	resp := &cachelyv1.BatchGetResponse{
+		Results: make([]*cachelyv1.BatchGetResult, len(keys)),
+	}
+	results := store.GetBatch(s.store, keys)
+	if s.origin != nil {
+		s.loadMisses(ctx, keys, results)
+	}
+	for i, r := range results {
+		result := &cachelyv1.BatchGetResult{Key: keys[i]}
+		if r.Err != nil {
+			result.Error = itemError(readError(r.Err, keys[i]))
+		} else {
+			result.Entry = getResponse(keys[i], r.Entry)
+		}
+		resp.Results[i] = result
+	}
+	return resp, nil
+}
+
+// loadMisses loads the keys whose results are store.ErrNotFound from the
+// origin of the server, once for every distinct key and at most originLoads at
+// a time, and replaces their results with those of the loads.
+func (s *server) loadMisses(ctx context.Context, keys []string, results []store.Result) {
+	misses := make(map[string][]int)
+	for i, r := range results {
+		if r.Err == store.ErrNotFound {
+			misses[keys[i]] = append(misses[keys[i]], i)
+		}
+	}
+
+	limit := s.originLoads
+	if limit <= 0 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for key, pos := range misses {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key string, pos []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			e, err := s.origin.GetOrLoad(ctx, key)
+			for _, i := range pos {
+				results[i] = store.Result{Entry: e, Err: err}
+			}
+		}(key, pos)
+	}
+	wg.Wait()
+}
+
+// BatchPut applies every write in the request. Invalid writes are reported
+// individually and do not prevent the others from being applied.
+func (s *server) BatchPut(ctx context.Context, req *cachelyv1.BatchPutRequest) (*cachelyv1.BatchPutResponse, error) {
+	items := req.GetItems()
+	log.Printf("Writing %d values\n", len(items))
+
+	resp := &cachelyv1.BatchPutResponse{
+		Results: make([]*cachelyv1.BatchPutResult, len(items)),
+	}
+
+	// only valid writes are sent to the store; pos maps each of them back to
+	// its item
+	now := time.Now()
+	writes := make([]store.Write, 0, len(items))
+	pos := make([]int, 0, len(items))
+	for i, item := range items {
+		resp.Results[i] = &cachelyv1.BatchPutResult{Key: item.GetKey()}
+		w, err := putWrite(item, now)
+		if err != nil {
+			resp.Results[i].Error = itemError(err)
+			continue
+		}
+		writes = append(writes, w)
+		pos = append(pos, i)
+	}
+
+	for j, r := range store.PutBatch(s.store, writes) {
+		result := resp.Results[pos[j]]
+		if r.Err != nil {
+			result.Error = itemError(storeError(r.Err, result.Key))
+		} else {
+			result.Entry = putResponse(result.Key, r.Entry)
+		}
+	}
+	return resp, nil
+}
+
+// BatchDelete removes every requested key, reporting keys that cannot be
+// deleted individually.
+func (s *server) BatchDelete(ctx context.Context, req *cachelyv1.BatchDeleteRequest) (*cachelyv1.BatchDeleteResponse, error) {
+	keys := req.GetKeys()
+	log.Printf("Deleting %d keys\n", len(keys))
+
+	resp := &cachelyv1.BatchDeleteResponse{
+		Results: make([]*cachelyv1.BatchDeleteResult, len(keys)),
+	}
+	for i, err := range store.DeleteBatch(s.store, keys) {
+		result := &cachelyv1.BatchDeleteResult{Key: keys[i]}
+		if err != nil {
+			result.Error = itemError(storeError(err, keys[i]))
+		}
+		resp.Results[i] = result
+	}
+	return resp, nil
+}
+
+// itemError describes the gRPC status err for a single item of a batch.
+func itemError(err error) *cachelyv1.ItemError {
+	st := status.Convert(err)
+	return &cachelyv1.ItemError{
+		Code:    int32(st.Code()),
+		Message: st.Message(),
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+func TestBatches(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	put, err := client.BatchPut(ctx, &cachelyv1.BatchPutRequest{
+		Items: []*cachelyv1.PutRequest{
+			{Key: "a", Value: []byte("alpha")},
+			{Key: "b", Value: []byte("bravo")},
+			{Key: "a", Value: []byte("again")},
+			{Key: "bad", Value: []byte("ttl"), Ttl: &types.Duration{Seconds: -1}},
+			{Key: "c", Value: []byte("charlie")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BatchPut: unexpected error: %v", err)
+	}
+	wantPut := []struct {
+		key  string
+		code codes.Code
+	}{
+		{"a", codes.OK},
+		{"b", codes.OK},
+		{"a", codes.AlreadyExists},
+		{"bad", codes.InvalidArgument},
+		{"c", codes.OK},
+	}
+	if len(put.GetResults()) != len(wantPut) {
+		t.Fatalf("BatchPut: want %d results, got %d", len(wantPut), len(put.GetResults()))
+	}
+	for i, want := range wantPut {
+		r := put.GetResults()[i]
+		if r.GetKey() != want.key || codes.Code(r.GetError().GetCode()) != want.code {
+			t.Errorf("BatchPut result %d: want %s with code %s, got %s with %v", i, want.key, want.code, r.GetKey(), r.GetError())
+		}
+		if (want.code == codes.OK) != (r.GetEntry() != nil) {
+			t.Errorf("BatchPut result %d: entry %v does not match code %s", i, r.GetEntry(), want.code)
+		}
+	}
+
+	get, err := client.BatchGet(ctx, &cachelyv1.BatchGetRequest{Keys: []string{"c", "bad", "a"}})
+	if err != nil {
+		t.Fatalf("BatchGet: unexpected error: %v", err)
+	}
+	wantGet := []struct {
+		key   string
+		value string
+		code  codes.Code
+	}{
+		{"c", "charlie", codes.OK},
+		{"bad", "", codes.NotFound},
+		{"a", "alpha", codes.OK},
+	}
+	for i, want := range wantGet {
+		r := get.GetResults()[i]
+		if r.GetKey() != want.key || codes.Code(r.GetError().GetCode()) != want.code || string(r.GetEntry().GetValue()) != want.value {
+			t.Errorf("BatchGet result %d: want %s=%q with code %s, got %s=%q with %v",
+				i, want.key, want.value, want.code, r.GetKey(), r.GetEntry().GetValue(), r.GetError())
+		}
+	}
+
+	del, err := client.BatchDelete(ctx, &cachelyv1.BatchDeleteRequest{Keys: []string{"a", "missing", "a"}})
+	if err != nil {
+		t.Fatalf("BatchDelete: unexpected error: %v", err)
+	}
+	for i, want := range []codes.Code{codes.OK, codes.NotFound, codes.NotFound} {
+		if code := codes.Code(del.GetResults()[i].GetError().GetCode()); code != want {
+			t.Errorf("BatchDelete result %d: want code %s, got %s", i, want, code)
+		}
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "b"}); err != nil {
+		t.Errorf("Get of a key left out of BatchDelete: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+)
+
+// concatRequest holds the fields shared by AppendRequest and PrependRequest.
+type concatRequest interface {
+	GetKey() string
+	GetValue() []byte
+	GetMaxValueSize() int64
+	GetCreateIfMissing() bool
+	GetTtl() *types.Duration
+}
+
+// Append adds the requested bytes to the end of the value at key.
+func (s *server) Append(ctx context.Context, req *cachelyv1.AppendRequest) (*cachelyv1.AppendResponse, error) {
+	log.Printf("Appending to value at key: %q\n", req.GetKey())
+
+	e, err := s.concat(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachelyv1.AppendResponse{
+		Key:       req.GetKey(),
+		ValueSize: int64(len(e.Value)),
+		Version:   e.Version,
+		ExpiresAt: timestamp(e.ExpiresAt),
+	}, nil
+}
+
+// Prepend adds the requested bytes to the start of the value at key.
+func (s *server) Prepend(ctx context.Context, req *cachelyv1.PrependRequest) (*cachelyv1.PrependResponse, error) {
+	log.Printf("Prepending to value at key: %q\n", req.GetKey())
+
+	e, err := s.concat(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachelyv1.PrependResponse{
+		Key:       req.GetKey(),
+		ValueSize: int64(len(e.Value)),
+		Version:   e.Version,
+		ExpiresAt: timestamp(e.ExpiresAt),
+	}, nil
+}
+
+// concat atomically joins the value of req to the value at its key, before it
+// when prepend is set and after it otherwise, and returns the entry stored.
+// The result may not exceed the maximum size of the request, nor the largest
+// value the server accepts. Existing entries keep their expiration and
+// metadata.
+func (s *server) concat(req concatRequest, prepend bool) (store.Entry, error) {
+	key := req.GetKey()
+	maxSize := req.GetMaxValueSize()
+	if maxSize < 0 {
+		return store.Entry{}, status.Errorf(codes.InvalidArgument, "max_value_size must not be negative, got %d", maxSize)
+	}
+	expiresAt, err := expiration(req.GetTtl(), nil, time.Now())
+	if err != nil {
+		return store.Entry{}, err
+	}
+
+	value := req.GetValue()
+	e, err := s.store.Update(key, func(cur store.Entry, exists bool) (store.Entry, error) {
+		if !exists {
+			if !req.GetCreateIfMissing() {
+				return store.Entry{}, status.Errorf(codes.NotFound, "could not find key %s", key)
+			}
+			cur = store.Entry{ExpiresAt: expiresAt}
+		}
+
+		size := int64(len(cur.Value) + len(value))
+		switch {
+		case maxSize > 0 && size > maxSize:
+			return store.Entry{}, status.Errorf(codes.OutOfRange, "value at %s would grow to %d bytes, past the maximum of %d", key, size, maxSize)
+		case size > s.maxValueSize:
+			return store.Entry{}, status.Errorf(codes.ResourceExhausted, "value at %s would exceed %d bytes", key, s.maxValueSize)
+		}
+
+		// the current value is shared with the store, so a new one is built
+		joined := make([]byte, 0, size)
+		if prepend {
+			joined = append(append(joined, value...), cur.Value...)
+		} else {
+			joined = append(append(joined, cur.Value...), value...)
+		}
+		cur.Value = joined
+		return cur, nil
+	})
+	if err != nil {
+		return store.Entry{}, updateError(err, key)
+	}
+	return e, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// concat appends or prepends value at key, and returns the size of the
+// resulting value.
+func concat(client cachelyv1.CacheAPIClient, prepend bool, req *cachelyv1.AppendRequest) (int64, error) {
+	ctx := context.Background()
+	if prepend {
+		resp, err := client.Prepend(ctx, &cachelyv1.PrependRequest{
+			Key:             req.Key,
+			Value:           req.Value,
+			MaxValueSize:    req.MaxValueSize,
+			CreateIfMissing: req.CreateIfMissing,
+			Ttl:             req.Ttl,
+		})
+		return resp.GetValueSize(), err
+	}
+	resp, err := client.Append(ctx, req)
+	return resp.GetValueSize(), err
+}
+
+func TestConcat(t *testing.T) {
+	srv := newTestServer()
+	srv.maxValueSize = 16
+	client, stop := serveCache(t, srv)
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "k", Value: []byte("mid"), ContentType: "text/plain", Metadata: map[string]string{"owner": "a"}}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		prepend bool
+		req     *cachelyv1.AppendRequest
+		want    string
+	}{
+		{"append", false, &cachelyv1.AppendRequest{Key: "k", Value: []byte("-end")}, "mid-end"},
+		{"prepend", true, &cachelyv1.AppendRequest{Key: "k", Value: []byte("start-")}, "start-mid-end"},
+		{"append of nothing", false, &cachelyv1.AppendRequest{Key: "k"}, "start-mid-end"},
+		{"append up to the maximum", false, &cachelyv1.AppendRequest{Key: "k", Value: []byte("!"), MaxValueSize: 14}, "start-mid-end!"},
+		{"append up to the server maximum", false, &cachelyv1.AppendRequest{Key: "k", Value: []byte("!!")}, "start-mid-end!!!"},
+		{"append creating a value", false, &cachelyv1.AppendRequest{Key: "a", Value: []byte("x"), CreateIfMissing: true}, "x"},
+		{"prepend creating a value", true, &cachelyv1.AppendRequest{Key: "p", Value: []byte("y"), CreateIfMissing: true}, "y"},
+		{"prepend of an existing value asked to be created", true, &cachelyv1.AppendRequest{Key: "p", Value: []byte("x"), CreateIfMissing: true}, "xy"},
+	}
+	for _, tt := range tests {
+		size, err := concat(client, tt.prepend, tt.req)
+		if err != nil || size != int64(len(tt.want)) {
+			t.Errorf("%s: want a %d byte value, got %d and %v", tt.name, len(tt.want), size, err)
+			continue
+		}
+		got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: tt.req.Key})
+		if err != nil || string(got.GetValue()) != tt.want {
+			t.Errorf("%s: want %q stored, got %q and %v", tt.name, tt.want, got.GetValue(), err)
+		}
+	}
+	got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "k"})
+	if err != nil || got.GetContentType() != "text/plain" || !reflect.DeepEqual(got.GetMetadata(), map[string]string{"owner": "a"}) {
+		t.Errorf("Get: want the metadata kept, got %+v and %v", got, err)
+	}
+
+	failures := []struct {
+		name    string
+		prepend bool
+		req     *cachelyv1.AppendRequest
+		code    codes.Code
+	}{
+		{"append to a missing key", false, &cachelyv1.AppendRequest{Key: "missing", Value: []byte("x")}, codes.NotFound},
+		{"prepend to a missing key", true, &cachelyv1.AppendRequest{Key: "missing", Value: []byte("x")}, codes.NotFound},
+		{"append past the maximum", false, &cachelyv1.AppendRequest{Key: "a", Value: []byte("xx"), MaxValueSize: 2}, codes.OutOfRange},
+		{"prepend past the maximum", true, &cachelyv1.AppendRequest{Key: "a", Value: []byte("xx"), MaxValueSize: 2}, codes.OutOfRange},
+		{"creation past the maximum", false, &cachelyv1.AppendRequest{Key: "missing", Value: []byte("xx"), MaxValueSize: 1, CreateIfMissing: true}, codes.OutOfRange},
+		{"append past the server maximum", false, &cachelyv1.AppendRequest{Key: "k", Value: []byte("x")}, codes.ResourceExhausted},
+		{"prepend past the server maximum", true, &cachelyv1.AppendRequest{Key: "k", Value: []byte("x")}, codes.ResourceExhausted},
+		{"negative maximum", false, &cachelyv1.AppendRequest{Key: "a", Value: []byte("x"), MaxValueSize: -1}, codes.InvalidArgument},
+		{"negative ttl", false, &cachelyv1.AppendRequest{Key: "a", Value: []byte("x"), Ttl: &types.Duration{Seconds: -1}}, codes.InvalidArgument},
+	}
+	for _, tt := range failures {
+		if _, err := concat(client, tt.prepend, tt.req); status.Code(err) != tt.code {
+			t.Errorf("%s: want %s, got %v", tt.name, tt.code, err)
+		}
+	}
+	// failed writes leave values as they were
+	for key, want := range map[string]string{"k": "start-mid-end!!!", "a": "x"} {
+		if got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: key}); err != nil || string(got.GetValue()) != want {
+			t.Errorf("Get(%s) after the failed writes: want %q, got %q and %v", key, want, got.GetValue(), err)
+		}
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get of a key the failed writes did not create: want NotFound, got %v", err)
+	}
+}
+
+func TestConcatExpiration(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+
+	ttl := &types.Duration{Seconds: 60}
+	created, err := client.Append(context.Background(), &cachelyv1.AppendRequest{Key: "k", Value: []byte("a"), CreateIfMissing: true, Ttl: ttl})
+	if err != nil || created.GetExpiresAt() == nil {
+		t.Fatalf("Append creating a value with a ttl: want an expiration, got %+v and %v", created, err)
+	}
+	resp, err := client.Prepend(context.Background(), &cachelyv1.PrependRequest{Key: "k", Value: []byte("b"), Ttl: &types.Duration{Seconds: 3600}})
+	if err != nil || !resp.GetExpiresAt().Equal(created.GetExpiresAt()) || resp.GetVersion() == created.GetVersion() {
+		t.Errorf("Prepend with a ttl: want the expiration kept and a new version, got %+v and %v", resp, err)
+	}
+}
+
+func TestConcatGateway(t *testing.T) {
+	_, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	url := base + objectsPath + "/log"
+
+	resp, body := do(t, "POST", url+":append", `{"value": "YQ=="}`, "Content-Type", "application/json")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST :append to a missing key: want status 404, got %d: %s", resp.StatusCode, body)
+	}
+	for _, tt := range []struct{ verb, body, size string }{
+		{"append", `{"value": "YQ==", "create_if_missing": true}`, "1"},
+		{"append", `{"value": "Yg=="}`, "2"},
+		{"prepend", `{"value": "Yw=="}`, "3"},
+	} {
+		resp, body := do(t, "POST", url+":"+tt.verb, tt.body, "Content-Type", "application/json")
+		var concat struct {
+			ValueSize string `json:"value_size"`
+		}
+		if err := json.Unmarshal(body, &concat); resp.StatusCode != http.StatusOK || err != nil || concat.ValueSize != tt.size {
+			t.Errorf("POST :%s %s: want status 200 and %s bytes, got %d: %s", tt.verb, tt.body, tt.size, resp.StatusCode, body)
+		}
+	}
+	if resp, body := do(t, "GET", url, "", "Accept", "application/octet-stream"); string(body) != "cab" {
+		t.Errorf("GET: want cab, got %d and %q", resp.StatusCode, body)
+	}
+	resp, body = do(t, "POST", url+":append", `{"value": "YQ==", "max_value_size": 3}`, "Content-Type", "application/json")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST :append past the maximum: want status 400, got %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// jsonTagSuffix distinguishes the ETag of the JSON GetResponse describing an
+// entry from the ETag of its raw value.
+const jsonTagSuffix = "-json"
+
+// tagEpoch starts the ETags made by this process. Stores number their
+// versions afresh when they start, even when their entries are restored from
+// a snapshot or the append-only log, so the same version may name different
+// values before and after a restart; the epoch keeps their tags apart.
+var tagEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// entityTag returns the strong ETag of the entry at version, either of its
+// raw value or, when json is set, of the JSON GetResponse describing it.
+// Versions are never reused by a running store, so the tag changes every time
+// the entry is written, even with the same value.
+func entityTag(version uint64, json bool) string {
+	tag := tagEpoch + "." + strconv.FormatUint(version, 10)
+	if json {
+		tag += jsonTagSuffix
+	}
+	return `"` + tag + `"`
+}
+
+// tagVersion returns the version of the entry a strong ETag made by entityTag
+// was computed from. Weak and foreign tags, and the tags made before the
+// process started, are reported as not ok.
+func tagVersion(tag string) (uint64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	tag = strings.TrimSuffix(tag[1:len(tag)-1], jsonTagSuffix)
+	if !strings.HasPrefix(tag, tagEpoch+".") {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(tag[len(tagEpoch)+1:], 10, 64)
+	return version, err == nil && version != 0
+}
+
+// tagList returns the comma separated entity tags of the header name.
+func tagList(h http.Header, name string) []string {
+	var tags []string
+	for _, value := range h[name] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// notModified reports whether a GET whose response would have the ETag etag
+// and describe an entry last modified at modifiedAt can be answered with 304
+// Not Modified. If-None-Match, which compares tags weakly, takes precedence
+// over If-Modified-Since.
+func notModified(req *http.Request, etag string, modifiedAt *types.Timestamp) bool {
+	if tags := tagList(req.Header, "If-None-Match"); len(tags) > 0 {
+		for _, tag := range tags {
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || modifiedAt == nil {
+		return false
+	}
+	t, err := types.TimestampFromProto(modifiedAt)
+	// HTTP dates only have a precision of a second
+	return err == nil && !t.Truncate(time.Second).After(since)
+}
+
+// writeNotModified answers a conditional GET of the entry described by resp
+// with 304 Not Modified.
+func writeNotModified(w http.ResponseWriter, resp *cachelyv1.GetResponse, etag string) {
+	setEntryHeaders(w.Header(), resp, etag)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// precondition is the condition the If-Match or If-None-Match header of a
+// write puts on the entry it replaces or deletes.
+type precondition struct {
+	set     bool                // whether the request has a condition
+	version uint64              // version the entry must be at, zero for any
+	mode    cachelyv1.WriteMode // mode of a PUT enforcing a condition of *
+}
+
+// putPrecondition reads the condition a PUT of the entry at key is under: the
+// one set by its If-Match header, or else by an If-None-Match header of *,
+// which only lets the write create the entry. Other If-None-Match values are
+// ignored.
+func putPrecondition(ctx context.Context, client cachelyv1.CacheAPIClient, req *http.Request, key string) (precondition, error) {
+	if p, err := parseIfMatch(ctx, client, req, key); p.set || err != nil {
+		return p, err
+	}
+	for _, tag := range tagList(req.Header, "If-None-Match") {
+		if tag == "*" {
+			return precondition{set: true, mode: cachelyv1.WriteMode_WRITE_MODE_INSERT}, nil
+		}
+	}
+	return precondition{}, nil
+}
+
+// parseIfMatch reads the condition on the entry at key set by the If-Match
+// header of req. Writes can only expect a single version, so when If-Match
+// names several the entry is read to find which one it is at.
+func parseIfMatch(ctx context.Context, client cachelyv1.CacheAPIClient, req *http.Request, key string) (precondition, error) {
+	tags := tagList(req.Header, "If-Match")
+	if len(tags) == 0 {
+		return precondition{}, nil
+	}
+
+	p := precondition{set: true, mode: cachelyv1.WriteMode_WRITE_MODE_REPLACE}
+	versions := make(map[uint64]bool)
+	for _, tag := range tags {
+		if tag == "*" {
+			return p, nil
+		}
+		if version, ok := tagVersion(tag); ok {
+			versions[version] = true
+			p.version = version
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return p, status.Errorf(codes.FailedPrecondition, "cached item at %s does not match If-Match", key)
+	case 1:
+		return p, nil
+	}
+	current, err := currentVersion(ctx, client, key)
+	if err != nil {
+		return p, p.check(err)
+	}
+	if !versions[current] {
+		return p, status.Errorf(codes.FailedPrecondition, "cached item at %s does not match If-Match", key)
+	}
+	p.version = current
+	return p, nil
+}
+
+// currentVersion reads the version of the entry at key without its value.
+func currentVersion(ctx context.Context, client cachelyv1.CacheAPIClient, key string) (uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	_, first, err := openDownload(ctx, client, &cachelyv1.GetStreamRequest{Key: key})
+	if err != nil {
+		return 0, err
+	}
+	return first.GetHeader().GetVersion(), nil
+}
+
+// applyPut makes the write protoReq enforce p.
+func (p precondition) applyPut(protoReq *cachelyv1.PutRequest) {
+	switch {
+	case p.version != 0:
+		protoReq.ExpectedVersion = p.version
+	case p.set:
+		protoReq.Mode = p.mode
+	}
+}
+
+// check converts the errors of a write that mean p did not hold, because the
+// entry is absent or present when it should not be, into FAILED_PRECONDITION,
+// which the gateway answers with 412 Precondition Failed.
+func (p precondition) check(err error) error {
+	if !p.set {
+		return err
+	}
+	switch s := status.Convert(err); s.Code() {
+	case codes.NotFound, codes.AlreadyExists:
+		return status.Error(codes.FailedPrecondition, s.Message())
+	}
+	return err
+}
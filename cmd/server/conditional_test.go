@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntityTag(t *testing.T) {
+	raw, json := entityTag(42, false), entityTag(42, true)
+	if raw == json || raw == entityTag(43, false) {
+		t.Errorf("entityTag: want distinct tags, got %s, %s and %s", raw, json, entityTag(43, false))
+	}
+	for _, tag := range []string{raw, json} {
+		if version, ok := tagVersion(tag); !ok || version != 42 {
+			t.Errorf("tagVersion(%s): want 42, got %d and %v", tag, version, ok)
+		}
+	}
+
+	// the same version has another tag once the server restarts
+	epoch := tagEpoch
+	tagEpoch = strings.ToUpper(epoch) + "x"
+	restarted := entityTag(42, false)
+	tagEpoch = epoch
+	if restarted == raw {
+		t.Errorf("entityTag: want a new tag after a restart, got %s again", raw)
+	}
+
+	for _, tag := range []string{restarted, "W/" + raw, `"42"`, "42", `""`, `"` + epoch + `.0"`, `"` + epoch + `.x"`, `"`} {
+		if version, ok := tagVersion(tag); ok {
+			t.Errorf("tagVersion(%s): want the tag rejected, got %d", tag, version)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	_, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	url := base + "/cachely/v1/objects/k"
+
+	if resp, body := do(t, "PUT", url, "v1", "Content-Type", "text/plain"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	raw, _ := do(t, "GET", url, "", "Accept", "text/plain")
+	json, _ := do(t, "GET", url, "", "Accept", "application/json")
+	rawTag, jsonTag := raw.Header.Get("ETag"), json.Header.Get("ETag")
+	if rawTag == "" || jsonTag == "" || rawTag == jsonTag {
+		t.Fatalf("GET: want distinct ETags for the value and the JSON entry, got %q and %q", rawTag, jsonTag)
+	}
+	lastModified := raw.Header.Get("Last-Modified")
+
+	tests := []struct {
+		name   string
+		accept string
+		header []string
+		code   int
+	}{
+		{"If-None-Match of the value", "text/plain", []string{"If-None-Match", rawTag}, http.StatusNotModified},
+		{"weak If-None-Match", "text/plain", []string{"If-None-Match", "W/" + rawTag}, http.StatusNotModified},
+		{"If-None-Match listing the value", "text/plain", []string{"If-None-Match", `"other", ` + rawTag}, http.StatusNotModified},
+		{"If-None-Match: *", "text/plain", []string{"If-None-Match", "*"}, http.StatusNotModified},
+		{"If-None-Match of the JSON entry", "text/plain", []string{"If-None-Match", jsonTag}, http.StatusOK},
+		{"If-None-Match of the JSON entry as JSON", "application/json", []string{"If-None-Match", jsonTag}, http.StatusNotModified},
+		{"If-None-Match of another tag", "text/plain", []string{"If-None-Match", `"other"`}, http.StatusOK},
+		{"If-Modified-Since", "text/plain", []string{"If-Modified-Since", lastModified}, http.StatusNotModified},
+		{"If-Modified-Since earlier", "text/plain", []string{"If-Modified-Since", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}, http.StatusOK},
+		{"If-None-Match over If-Modified-Since", "text/plain", []string{"If-None-Match", `"other"`, "If-Modified-Since", lastModified}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, "GET", url, "", append([]string{"Accept", tt.accept}, tt.header...)...)
+		if resp.StatusCode != tt.code {
+			t.Errorf("GET with %s: want status %d, got %d", tt.name, tt.code, resp.StatusCode)
+			continue
+		}
+		if tt.code == http.StatusNotModified && (len(body) != 0 || resp.Header.Get("ETag") == "") {
+			t.Errorf("GET with %s: want an ETag and no body, got %q and %q", tt.name, resp.Header.Get("ETag"), body)
+		}
+	}
+
+	// the stream route is conditional too
+	resp, _ := do(t, "GET", url+":stream", "", "If-None-Match", rawTag)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("GET :stream with If-None-Match: want status 304, got %d", resp.StatusCode)
+	}
+
+	// writing the same value gives a new tag
+	do(t, "PUT", url, "v1", "Content-Type", "text/plain")
+	resp, _ = do(t, "GET", url, "", "Accept", "text/plain", "If-None-Match", rawTag)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == rawTag {
+		t.Errorf("GET after a write: want status 200 and a new ETag, got %d and %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
+
+func TestConditionalWrite(t *testing.T) {
+	_, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	url := base + "/cachely/v1/objects/k"
+
+	resp, body := do(t, "PUT", url, "v1", "Content-Type", "text/plain", "If-Match", "*")
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("PUT with If-Match: * of a missing key: want status 412, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = do(t, "PUT", url, "v1", "Content-Type", "text/plain", "If-None-Match", "*")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT with If-None-Match: * of a missing key: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	first := resp.Header.Get("ETag")
+	resp, body = do(t, "PUT", url, "v2", "Content-Type", "text/plain", "If-None-Match", "*")
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("PUT with If-None-Match: * of a present key: want status 412, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = do(t, "PUT", url, "v2", "Content-Type", "text/plain", "If-Match", first)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT with If-Match of the current tag: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	second := resp.Header.Get("ETag")
+	if second == first {
+		t.Errorf("PUT: want a new ETag, got %s again", second)
+	}
+	resp, body = do(t, "PUT", url, "v3", "Content-Type", "text/plain", "If-Match", first)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("PUT with If-Match of a stale tag: want status 412, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = do(t, "PUT", url, "v3", "Content-Type", "text/plain", "If-Match", first+", "+second)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("PUT with If-Match listing the current tag: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	third := resp.Header.Get("ETag")
+
+	// tags from before a restart match nothing
+	epoch := tagEpoch
+	tagEpoch = strings.ToUpper(epoch) + "x"
+	resp, body = do(t, "PUT", url, "v4", "Content-Type", "text/plain", "If-Match", third)
+	tagEpoch = epoch
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("PUT with If-Match of a tag from before a restart: want status 412, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = do(t, "DELETE", url, "", "If-Match", first)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with If-Match of a stale tag: want status 412, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = do(t, "DELETE", url, "", "If-Match", third)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("DELETE with If-Match of the current tag: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = do(t, "DELETE", url, "", "If-Match", "*")
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with If-Match: * of a missing key: want status 412, got %d: %s", resp.StatusCode, body)
+	}
+}
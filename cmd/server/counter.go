@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+)
+
+// Increment adds the requested delta to the counter at key, creating it if it
+// is missing.
+func (s *server) Increment(ctx context.Context, req *cachelyv1.IncrementRequest) (*cachelyv1.IncrementResponse, error) {
+	key := req.GetKey()
+	log.Printf("Incrementing counter at key: %q\n", key)
+
+	e, value, err := s.add(key, counterDelta(req.GetDelta()), req.GetInitialValue(), req.GetTtl())
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachelyv1.IncrementResponse{
+		Key:       key,
+		Value:     value,
+		Version:   e.Version,
+		ExpiresAt: timestamp(e.ExpiresAt),
+	}, nil
+}
+
+// Decrement subtracts the requested delta from the counter at key, creating it
+// if it is missing.
+func (s *server) Decrement(ctx context.Context, req *cachelyv1.DecrementRequest) (*cachelyv1.DecrementResponse, error) {
+	key := req.GetKey()
+	log.Printf("Decrementing counter at key: %q\n", key)
+
+	delta := counterDelta(req.GetDelta())
+	if delta == math.MinInt64 {
+		return nil, status.Errorf(codes.OutOfRange, "delta %d cannot be subtracted", delta)
+	}
+	e, value, err := s.add(key, -delta, req.GetInitialValue(), req.GetTtl())
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachelyv1.DecrementResponse{
+		Key:       key,
+		Value:     value,
+		Version:   e.Version,
+		ExpiresAt: timestamp(e.ExpiresAt),
+	}, nil
+}
+
+// counterDelta returns the delta of a counter request, which is 1 when it is
+// unset.
+func counterDelta(delta *types.Int64Value) int64 {
+	if delta == nil {
+		return 1
+	}
+	return delta.GetValue()
+}
+
+// add atomically adds delta to the counter at key, which starts at initial
+// when it is missing, and returns the entry stored along with the new value
+// of the counter. Counters are stored as decimal text, so that they can be
+// read like any other value. Counters created by add expire after ttl, if it
+// is set, while existing ones keep their expiration and metadata.
+func (s *server) add(key string, delta, initial int64, ttl *types.Duration) (store.Entry, int64, error) {
+	expiresAt, err := expiration(ttl, nil, time.Now())
+	if err != nil {
+		return store.Entry{}, 0, err
+	}
+
+	var value int64
+	e, err := s.store.Update(key, func(cur store.Entry, exists bool) (store.Entry, error) {
+		n := initial
+		if exists {
+			var err error
+			if n, err = strconv.ParseInt(string(cur.Value), 10, 64); err != nil {
+				return store.Entry{}, status.Errorf(codes.InvalidArgument, "value at %s is not a counter", key)
+			}
+		}
+
+		value = n + delta
+		if (delta > 0 && value < n) || (delta < 0 && value > n) {
+			return store.Entry{}, status.Errorf(codes.OutOfRange, "counter at %s would overflow", key)
+		}
+		if !exists {
+			cur = store.Entry{ExpiresAt: expiresAt}
+		}
+		cur.Value = strconv.AppendInt(nil, value, 10)
+		return cur, nil
+	})
+	if err != nil {
+		return store.Entry{}, 0, updateError(err, key)
+	}
+	return e, value, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+func TestCounters(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	delta := func(n int64) *types.Int64Value { return &types.Int64Value{Value: n} }
+	tests := []struct {
+		name      string
+		decrement bool
+		req       *cachelyv1.IncrementRequest
+		want      int64
+	}{
+		{"increment of a missing counter", false, &cachelyv1.IncrementRequest{Key: "a"}, 1},
+		{"default delta", false, &cachelyv1.IncrementRequest{Key: "a"}, 2},
+		{"delta", false, &cachelyv1.IncrementRequest{Key: "a", Delta: delta(10)}, 12},
+		{"negative delta", false, &cachelyv1.IncrementRequest{Key: "a", Delta: delta(-20)}, -8},
+		{"delta of 0", false, &cachelyv1.IncrementRequest{Key: "a", Delta: delta(0)}, -8},
+		{"initial value of an existing counter", false, &cachelyv1.IncrementRequest{Key: "a", InitialValue: 100}, -7},
+		{"initial value", false, &cachelyv1.IncrementRequest{Key: "b", InitialValue: 100, Delta: delta(5)}, 105},
+		{"delta of 0 of a missing counter", false, &cachelyv1.IncrementRequest{Key: "c", InitialValue: 7, Delta: delta(0)}, 7},
+		{"decrement", true, &cachelyv1.IncrementRequest{Key: "b"}, 104},
+		{"decrement by a delta", true, &cachelyv1.IncrementRequest{Key: "b", Delta: delta(200)}, -96},
+		{"decrement by 0", true, &cachelyv1.IncrementRequest{Key: "b", Delta: delta(0)}, -96},
+		{"decrement of a missing counter", true, &cachelyv1.IncrementRequest{Key: "d", InitialValue: 1}, 0},
+		{"largest value", false, &cachelyv1.IncrementRequest{Key: "max", InitialValue: math.MaxInt64 - 1}, math.MaxInt64},
+		{"smallest value", true, &cachelyv1.IncrementRequest{Key: "min", InitialValue: math.MinInt64 + 1}, math.MinInt64},
+	}
+	for _, tt := range tests {
+		var value int64
+		var err error
+		if tt.decrement {
+			var resp *cachelyv1.DecrementResponse
+			resp, err = client.Decrement(ctx, &cachelyv1.DecrementRequest{Key: tt.req.Key, Delta: tt.req.Delta, InitialValue: tt.req.InitialValue})
+			value = resp.GetValue()
+		} else {
+			var resp *cachelyv1.IncrementResponse
+			resp, err = client.Increment(ctx, tt.req)
+			value = resp.GetValue()
+		}
+		if err != nil || value != tt.want {
+			t.Errorf("%s: want %d, got %d and %v", tt.name, tt.want, value, err)
+			continue
+		}
+		// counters are stored as decimal text
+		got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: tt.req.Key})
+		if err != nil || string(got.GetValue()) != strconv.FormatInt(tt.want, 10) {
+			t.Errorf("%s: want %d stored, got %q and %v", tt.name, tt.want, got.GetValue(), err)
+		}
+	}
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "text", Value: []byte("ten")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	failures := []struct {
+		name      string
+		decrement bool
+		req       *cachelyv1.IncrementRequest
+		code      codes.Code
+	}{
+		{"increment past the largest value", false, &cachelyv1.IncrementRequest{Key: "max"}, codes.OutOfRange},
+		{"increment past the smallest value", false, &cachelyv1.IncrementRequest{Key: "min", Delta: delta(-1)}, codes.OutOfRange},
+		{"decrement past the smallest value", true, &cachelyv1.IncrementRequest{Key: "min"}, codes.OutOfRange},
+		{"decrement by the smallest delta", true, &cachelyv1.IncrementRequest{Key: "new", Delta: delta(math.MinInt64)}, codes.OutOfRange},
+		{"increment of a value that is not a counter", false, &cachelyv1.IncrementRequest{Key: "text"}, codes.InvalidArgument},
+		{"delta of 0 of a value that is not a counter", false, &cachelyv1.IncrementRequest{Key: "text", Delta: delta(0)}, codes.InvalidArgument},
+		{"negative ttl", false, &cachelyv1.IncrementRequest{Key: "new", Ttl: &types.Duration{Seconds: -1}}, codes.InvalidArgument},
+	}
+	for _, tt := range failures {
+		var err error
+		if tt.decrement {
+			_, err = client.Decrement(ctx, &cachelyv1.DecrementRequest{Key: tt.req.Key, Delta: tt.req.Delta})
+		} else {
+			_, err = client.Increment(ctx, tt.req)
+		}
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: want %s, got %v", tt.name, tt.code, err)
+		}
+	}
+	got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "max"})
+	if err != nil || string(got.GetValue()) != strconv.FormatInt(math.MaxInt64, 10) {
+		t.Errorf("Get after an overflow: want the counter left as it was, got %q and %v", got.GetValue(), err)
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "new"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get after failed increments: want NotFound, got %v", err)
+	}
+}
+
+func TestCounterExpiration(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	before := time.Now()
+	resp, err := client.Increment(ctx, &cachelyv1.IncrementRequest{Key: "c", Ttl: &types.Duration{Seconds: 60}})
+	if err != nil {
+		t.Fatalf("Increment: unexpected error: %v", err)
+	}
+	expires, err := types.TimestampFromProto(resp.GetExpiresAt())
+	if err != nil || expires.Before(before.Add(59*time.Second)) || expires.After(time.Now().Add(61*time.Second)) {
+		t.Fatalf("Increment with a ttl: want an expiration a minute away, got %v", resp.GetExpiresAt())
+	}
+
+	// existing counters keep their expiration
+	again, err := client.Increment(ctx, &cachelyv1.IncrementRequest{Key: "c", Ttl: &types.Duration{Seconds: 3600}})
+	if err != nil || !again.GetExpiresAt().Equal(resp.GetExpiresAt()) || again.GetVersion() == resp.GetVersion() {
+		t.Errorf("Increment of an existing counter: want the expiration kept and a new version, got %+v and %v", again, err)
+	}
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "forever", Value: []byte("1")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	dec, err := client.Decrement(ctx, &cachelyv1.DecrementRequest{Key: "forever", Ttl: &types.Duration{Seconds: 60}})
+	if err != nil || dec.GetValue() != 0 || dec.GetExpiresAt() != nil {
+		t.Errorf("Decrement of a counter that never expires: want 0 without an expiration, got %+v and %v", dec, err)
+	}
+}
+
+func TestCounterGateway(t *testing.T) {
+	_, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	url := base + objectsPath + "/hits"
+
+	tests := []struct {
+		verb string
+		body string
+		want string
+	}{
+		{"increment", `{}`, "1"},
+		{"increment", `{"delta": 5}`, "6"},
+		{"increment", `{"delta": "4"}`, "10"},
+		{"increment", `{"delta": 0}`, "10"},
+		{"decrement", `{}`, "9"},
+		{"decrement", `{"delta": 0}`, "9"},
+		{"decrement", `{"delta": 10}`, "-1"},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, "POST", url+":"+tt.verb, tt.body, "Content-Type", "application/json")
+		var counter struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(body, &counter); resp.StatusCode != http.StatusOK || err != nil {
+			t.Errorf("POST :%s %s: want status 200, got %d: %s", tt.verb, tt.body, resp.StatusCode, body)
+			continue
+		}
+		if counter.Value != tt.want {
+			t.Errorf("POST :%s %s: want %s, got %s", tt.verb, tt.body, tt.want, body)
+		}
+	}
+
+	do(t, "PUT", url, "ten", "Content-Type", "text/plain")
+	resp, body := do(t, "POST", url+":increment", `{}`, "Content-Type", "application/json")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST :increment of a value that is not a counter: want status 400, got %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// patternWatch matches /cachely/v1/objects:watch.
+var patternWatch = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"cachely", "v1", "objects"}, "watch"))
+
+const (
+	// watchBuffer is the number of events buffered for an HTTP watcher. A
+	// client that lets more events pile up is disconnected, and has to resume
+	// from the revision of the last event it received.
+	watchBuffer = 256
+
+	// heartbeatInterval is how often watch connections are probed, so that
+	// proxies do not close idle connections and dead clients are noticed.
+	heartbeatInterval = 15 * time.Second
+
+	// writeTimeout bounds the time spent writing a message to a WebSocket
+	// client.
+	writeTimeout = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// watchObjects serves GET /cachely/v1/objects:watch, relaying the Watch
+// stream selected by the query parameters as Server-Sent Events, or as
+// WebSocket text messages when the client asks for an upgrade. Each event is
+// the JSON form of a WatchEvent.
+func watchObjects(mux *runtime.ServeMux, client cachelyv1.CacheAPIClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		relay, err := openWatch(rctx, client, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		defer relay.Close()
+
+		if websocket.IsWebSocketUpgrade(req) {
+			relayWebSocket(w, req, outboundMarshaler, relay)
+		} else {
+			relayEventStream(w, outboundMarshaler, relay)
+		}
+	}
+}
+
+// openWatch starts the Watch requested by the query parameters of req, and
+// waits for the server to acknowledge it so that invalid requests are
+// reported with a regular HTTP error. Event stream clients resume after a
+// disconnection by sending the revision of the last event they received as
+// Last-Event-ID, which is used unless resume_revision is set.
+func openWatch(ctx context.Context, client cachelyv1.CacheAPIClient, req *http.Request) (*watchRelay, error) {
+	var protoReq cachelyv1.WatchRequest
+
+	if err := req.ParseForm(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if err := runtime.PopulateQueryParameters(&protoReq, req.Form, utilities.NewDoubleArray(nil)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if id := req.Header.Get("Last-Event-ID"); id != "" && protoReq.ResumeRevision == 0 {
+		rev, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid Last-Event-ID %q", id)
+		}
+		protoReq.ResumeRevision = rev
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := client.Watch(ctx, &protoReq)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	md, err := stream.Header()
+	if err == nil && len(md.Get(revisionHeader)) == 0 {
+		// the watch failed before it started, so its status is all that is
+		// left in the stream
+		if _, err = stream.Recv(); err == nil {
+			err = status.Errorf(codes.Internal, "watch was not acknowledged")
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	relay := &watchRelay{
+		events: make(chan *cachelyv1.WatchEvent, watchBuffer),
+		cancel: cancel,
+	}
+	go relay.pump(stream)
+	return relay, nil
+}
+
+// watchRelay buffers the events of a Watch stream for an HTTP client. The
+// stream is only read as fast as the client keeps up with the buffer, so a
+// slow client pushes back on the gRPC stream until the server gives up on it;
+// the relay does the same once its own buffer is full.
+type watchRelay struct {
+	events chan *cachelyv1.WatchEvent // closed once the stream has ended
+	err    error                      // why the stream ended, set before events is closed
+	cancel context.CancelFunc
+}
+
+// pump reads stream into the buffer until it ends.
+func (r *watchRelay) pump(stream cachelyv1.CacheAPI_WatchClient) {
+	defer close(r.events)
+
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			r.err = err
+			return
+		}
+
+		select {
+		case r.events <- e:
+		default:
+			r.cancel()
+			r.err = status.Errorf(codes.Aborted, "watcher fell too far behind")
+			return
+		}
+	}
+}
+
+// Close ends the stream.
+func (r *watchRelay) Close() {
+	r.cancel()
+}
+
+// relayEventStream writes the events of relay to w as Server-Sent Events,
+// using their revisions as event IDs. Comments are sent as heartbeats while
+// the watch is idle. When the stream ends, its status is sent in an error
+// event.
+func relayEventStream(w http.ResponseWriter, marshaler runtime.Marshaler, relay *watchRelay) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case e, ok := <-relay.events:
+			if !ok {
+				if data, ok := watchStatus(relay.err); ok {
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+					flusher.Flush()
+				}
+				return
+			}
+
+			var data []byte
+			data, err = marshaler.Marshal(e)
+			if err != nil {
+				return
+			}
+			_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.GetRevision(), data)
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": heartbeat\n\n")
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// relayWebSocket upgrades the connection and writes the events of relay as
+// text messages. Pings are sent as heartbeats, and the connection is dropped
+// if the client stops answering them or stops reading. When the stream ends,
+// its status is sent in the close message.
+func relayWebSocket(w http.ResponseWriter, req *http.Request, marshaler runtime.Marshaler, relay *watchRelay) {
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// the upgrader has already replied with an HTTP error
+		return
+	}
+	defer conn.Close()
+
+	// clients are not expected to send anything, but control messages are
+	// only processed while reading
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	})
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				relay.Close()
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-relay.events:
+			if !ok {
+				closeWebSocket(conn, relay.err)
+				return
+			}
+
+			data, err := marshaler.Marshal(e)
+			if err != nil {
+				closeWebSocket(conn, status.Errorf(codes.Internal, "%v", err))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// closeWebSocket sends a close message describing why the watch ended.
+// Clients that fell behind are asked to try again later.
+func closeWebSocket(conn *websocket.Conn, err error) {
+	st := status.Convert(err)
+	code := websocket.CloseInternalServerErr
+	switch st.Code() {
+	case codes.OK, codes.Canceled:
+		code = websocket.CloseNormalClosure
+	case codes.Aborted:
+		code = websocket.CloseTryAgainLater
+	}
+
+	// close messages only have room for 123 bytes of text
+	reason := st.Message()
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+}
+
+// watchStatus encodes the status of a watch that ended with err, reporting
+// false if the client went away and there is no one left to tell.
+func watchStatus(err error) ([]byte, bool) {
+	st := status.Convert(err)
+	if st.Code() == codes.Canceled {
+		return nil, false
+	}
+
+	data, err := json.Marshal(struct {
+		Code    codes.Code `json:"code"`
+		Message string     `json:"message"`
+	}{st.Code(), st.Message()})
+	return data, err == nil
+}
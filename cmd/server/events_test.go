@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// readEvent reads the next Server-Sent Event from r, skipping comments, and
+// returns its fields by name.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && len(fields) > 0:
+			return fields
+		case line == "", strings.HasPrefix(line, ":"):
+			continue
+		}
+		i := strings.Index(line, ": ")
+		if i < 0 {
+			t.Fatalf("malformed event line %q", line)
+		}
+		fields[line[:i]] = line[i+2:]
+	}
+}
+
+// watchEventData decodes the JSON form of a WatchEvent.
+func watchEventData(t *testing.T, data string) *cachelyv1.WatchEvent {
+	t.Helper()
+	var e cachelyv1.WatchEvent
+	if err := newGogoJSONPb().Unmarshal([]byte(data), &e); err != nil {
+		t.Fatalf("decoding event %q: %v", data, err)
+	}
+	return &e
+}
+
+func TestWatchEventStream(t *testing.T) {
+	client, url, stop := serveGateway(t, newWatchedServer())
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", url+"/cachely/v1/objects:watch?prefix=users/", nil)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("GET: unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("want a 200 event stream, got %s of %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+
+	client.Put(ctx, &cachelyv1.PutRequest{Key: "groups/1", Value: []byte("a")})
+	client.Put(ctx, &cachelyv1.PutRequest{Key: "users/1", Value: []byte("a")})
+	r := bufio.NewReader(resp.Body)
+	ev := readEvent(t, r)
+	if ev["id"] != "2" {
+		t.Errorf("want the event ID to be the revision 2, got %q", ev["id"])
+	}
+	if e := watchEventData(t, ev["data"]); e.GetKey() != "users/1" || e.GetType() != cachelyv1.EventType_EVENT_TYPE_PUT {
+		t.Errorf("want the put of users/1, got %v", e)
+	}
+
+	// a client reconnecting with the ID of the last event it got is sent
+	// what it missed
+	client.Delete(ctx, &cachelyv1.DeleteRequest{Key: "users/1"})
+	req, _ = http.NewRequest("GET", url+"/cachely/v1/objects:watch?key=users/1", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resumed, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("GET: unexpected error: %v", err)
+	}
+	defer resumed.Body.Close()
+	r = bufio.NewReader(resumed.Body)
+	for _, want := range []string{"2", "3"} {
+		if ev := readEvent(t, r); ev["id"] != want {
+			t.Errorf("resumed stream: want event %s, got %v", want, ev)
+		}
+	}
+}
+
+func TestWatchEventStreamErrors(t *testing.T) {
+	_, url, stop := serveGateway(t, newWatchedServer())
+	defer stop()
+
+	tests := []struct {
+		query       string
+		lastEventID string
+		code        int
+	}{
+		{"?key=a&prefix=b", "", http.StatusBadRequest},
+		{"?resume_revision=10", "", http.StatusBadRequest},
+		{"", "10", http.StatusBadRequest},
+		{"", "not a revision", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", url+"/cachely/v1/objects:watch"+tt.query, nil)
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("GET %q with Last-Event-ID %q: want %d, got %s", tt.query, tt.lastEventID, tt.code, resp.Status)
+		}
+	}
+}
+
+func TestWatchWebSocket(t *testing.T) {
+	client, url, stop := serveGateway(t, newWatchedServer())
+	defer stop()
+	ctx := context.Background()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/cachely/v1/objects:watch?key=k", nil)
+	if err != nil {
+		t.Fatalf("Dial: unexpected error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	client.Put(ctx, &cachelyv1.PutRequest{Key: "other", Value: []byte("a")})
+	client.Put(ctx, &cachelyv1.PutRequest{Key: "k", Value: []byte("a")})
+	client.Delete(ctx, &cachelyv1.DeleteRequest{Key: "k"})
+	for _, want := range []cachelyv1.EventType{cachelyv1.EventType_EVENT_TYPE_PUT, cachelyv1.EventType_EVENT_TYPE_DELETE} {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: unexpected error: %v", err)
+		}
+		if typ != websocket.TextMessage {
+			t.Errorf("want a text message, got type %d", typ)
+		}
+		if e := watchEventData(t, string(data)); e.GetKey() != "k" || e.GetType() != want {
+			t.Errorf("want a %s event for k, got %v", want, e)
+		}
+	}
+}
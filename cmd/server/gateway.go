@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+var (
+	// patternObject matches /cachely/v1/objects/{key=**}, binding the rest of
+	// the path to the key; see gatewayHandler. It also matches
+	// /cachely/v1/objects itself, which names no key; see keyed.
+	patternObject = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2, 3, 0, 4, 1, 5, 3}, []string{"cachely", "v1", "objects", "key"}, ""))
+
+	// patternObjects matches /cachely/v1/objects.
+	patternObjects = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"cachely", "v1", "objects"}, ""))
+)
+
+// objectsPath is the path of the collection of objects, under which the path
+// of every key lies.
+const objectsPath = "/cachely/v1/objects"
+
+// newGatewayMux returns the mux serving the gateway routes, which relays
+// requests to the services served on conn.
+func newGatewayMux(ctx context.Context, conn *grpc.ClientConn) (*runtime.ServeMux, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, newGogoJSONPb()),
+		runtime.WithForwardResponseOption(forwardEntryHeaders),
+	)
+	client := cachelyv1.NewCacheAPIClient(conn)
+	registerObjectRoutes(mux, client)
+	if err := cachelyv1.RegisterCacheAPIHandlerClient(ctx, mux, client); err != nil {
+		return nil, err
+	}
+	if err := cachelyv1.RegisterLockAPIHandlerClient(ctx, mux, cachelyv1.NewLockAPIClient(conn)); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// gatewayHandler serves the paths of the gateway routes with mux, and every
+// other path with other. Keys are bound to the rest of the path, and may hold
+// empty and dot segments, so the paths of the gateway routes are not cleaned
+// as an http.ServeMux would, which would redirect requests for such keys to
+// other keys.
+func gatewayHandler(mux *runtime.ServeMux, other http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/cachely/") {
+			mux.ServeHTTP(w, escapeColons(req))
+			return
+		}
+		other.ServeHTTP(w, req)
+	})
+}
+
+// escapeColons returns req with a colon added to its path when the last
+// segment of the path holds colons that were escaped as %3A. The mux takes
+// the last colon of the decoded path to start a custom method, so escaped
+// colons would otherwise be taken for one, while the added colon starts an
+// empty custom method, which is the same as none.
+func escapeColons(req *http.Request) *http.Request {
+	if strings.Contains(lastSegment(req.URL.EscapedPath()), ":") || !strings.Contains(lastSegment(req.URL.Path), ":") {
+		return req
+	}
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	u.Path += ":"
+	u.RawPath = ""
+	r.URL = &u
+	return r
+}
+
+// lastSegment returns the part of path after its last slash.
+func lastSegment(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// registerObjectRoutes adds the gateway routes that cannot be expressed as
+// google.api.http annotations. The mux serves a request with the first route
+// registered that matches it, so they must be registered before the generated
+// routes, some of which they replace. The list route comes first, since the
+// object routes match its path too.
+func registerObjectRoutes(mux *runtime.ServeMux, client cachelyv1.CacheAPIClient) {
+	mux.Handle("GET", patternObjects, listObjects(mux, client))
+	mux.Handle("GET", patternObject, keyed(mux, getObject(mux, client)))
+	mux.Handle("PUT", patternObject, keyed(mux, uploadObject(mux, client, true)))
+	mux.Handle("DELETE", patternObject, keyed(mux, deleteObject(mux, client)))
+	mux.Handle("GET", patternObjectStream, keyed(mux, downloadObject(mux, client)))
+	mux.Handle("PUT", patternObjectStream, keyed(mux, uploadObject(mux, client, false)))
+	mux.Handle("GET", patternWatch, watchObjects(mux, client))
+}
+
+// keyed wraps the handler of a route binding the rest of the path to the key,
+// so that paths which name no key, such as /cachely/v1/objects:stream, are not
+// found rather than taken for the empty key, whose path is
+// /cachely/v1/objects/.
+func keyed(mux *runtime.ServeMux, h runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		if !strings.HasPrefix(req.URL.Path, objectsPath+"/") {
+			_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+			runtime.HTTPError(req.Context(), mux, outboundMarshaler, w, req, status.Error(codes.NotFound, http.StatusText(http.StatusNotFound)))
+			return
+		}
+		h(w, req, pathParams)
+	}
+}
+
+// listObjects serves GET /cachely/v1/objects as the generated List route
+// would, which the object routes registered ahead of it would otherwise
+// shadow.
+func listObjects(mux *runtime.ServeMux, client cachelyv1.CacheAPIClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		protoReq := &cachelyv1.ListRequest{}
+		if err := runtime.PopulateQueryParameters(protoReq, req.URL.Query(), &utilities.DoubleArray{}); err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+
+		var md runtime.ServerMetadata
+		resp, err := client.List(rctx, protoReq, grpc.Header(&md.HeaderMD), grpc.Trailer(&md.TrailerMD))
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+	}
+}
+
+// getObject serves GET /cachely/v1/objects/{key} in place of the generated
+// route. The value is returned raw rather than in a JSON GetResponse when the
+// Accept header prefers it; see rawContentType, and may then be read in part
+// with a Range header. The JSON GetResponse is restricted to part of the
+// value by the offset and length query parameters instead, as with Get.
+// Conditional requests are answered with 304 Not Modified when the entry has
+// not changed; see notModified.
+func getObject(mux *runtime.ServeMux, client cachelyv1.CacheAPIClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		protoReq := &cachelyv1.GetRequest{Key: pathParams["key"]}
+		if err := runtime.PopulateQueryParameters(protoReq, req.URL.Query(), utilities.NewDoubleArray([][]string{{"key"}})); err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+
+		// the response depends on the Accept header, which HTTP caches must
+		// key it by
+		w.Header().Set("Vary", "Accept")
+
+		ranges := parseAccept(req.Header.Get("Accept"))
+		if !mayPreferRaw(ranges) {
+			resp, md, err := getEntry(rctx, client, protoReq)
+			ctx = runtime.NewServerMetadataContext(ctx, md)
+			if err != nil {
+				runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+				return
+			}
+			if etag := entityTag(resp.GetVersion(), true); notModified(req, etag, resp.GetModifiedAt()) {
+				writeNotModified(w, resp, etag)
+				return
+			}
+
+			runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+			return
+		}
+
+		// the content type of the value is needed to pick the response
+		dctx, stop := context.WithCancel(rctx)
+		defer stop()
+		stream, first, err := openDownload(dctx, client, &cachelyv1.GetStreamRequest{Key: pathParams["key"]})
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		header := first.GetHeader()
+		contentType, raw := rawContentType(ranges, header.GetContentType())
+		if etag := entityTag(header.GetVersion(), !raw); notModified(req, etag, header.GetModifiedAt()) {
+			writeNotModified(w, header, etag)
+			return
+		}
+		if raw {
+			if err := sendValue(rctx, w, req, client, stream, first, stop, contentType); err != nil {
+				runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			}
+			return
+		}
+		resp, err := readValue(stream, first)
+		if err == nil {
+			resp.Value, err = valueRange(resp.Value, protoReq.GetOffset(), protoReq.GetLength())
+		}
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+	}
+}
+
+// getEntry reads the entry requested by protoReq with Get.
+func getEntry(ctx context.Context, client cachelyv1.CacheAPIClient, protoReq *cachelyv1.GetRequest) (*cachelyv1.GetResponse, runtime.ServerMetadata, error) {
+	var metadata runtime.ServerMetadata
+
+	msg, err := client.Get(ctx, protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	return msg, metadata, err
+}
+
+// uploadObject serves PUT /cachely/v1/objects/{key} and its :stream variant.
+// The body is the raw value, stored along with its Content-Type, and the
+// other options of the write are taken from the query parameters. When
+// jsonBody is set, a body whose Content-Type is application/json is instead
+// read as a PutRequest without its key. Unlike the POST route, existing
+// entries are replaced unless the request asks for a different write mode or
+// sets a precondition; see putPrecondition.
+func uploadObject(mux *runtime.ServeMux, client cachelyv1.CacheAPIClient, jsonBody bool) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		p, err := putPrecondition(rctx, client, req, pathParams["key"])
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		var resp *cachelyv1.PutResponse
+		var md runtime.ServerMetadata
+		if jsonBody && isJSON(req.Header.Get("Content-Type")) {
+			resp, md, err = upsertObject(rctx, inboundMarshaler, client, req, pathParams, p)
+		} else {
+			resp, md, err = upsertRaw(rctx, client, req, pathParams, p)
+		}
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, p.check(err))
+			return
+		}
+
+		runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+	}
+}
+
+// isJSON reports whether contentType is application/json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == jsonType
+}
+
+// upsertObject writes the PutRequest in the body of req to the key in the
+// path under the precondition p, upserting unless the body asks for a
+// different write mode.
+func upsertObject(ctx context.Context, marshaler runtime.Marshaler, client cachelyv1.CacheAPIClient, req *http.Request, pathParams map[string]string, p precondition) (*cachelyv1.PutResponse, runtime.ServerMetadata, error) {
+	var protoReq cachelyv1.PutRequest
+	var metadata runtime.ServerMetadata
+
+	if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
+		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	protoReq.Key = pathParams["key"]
+	if protoReq.Mode == cachelyv1.WriteMode_WRITE_MODE_INSERT {
+		protoReq.Mode = cachelyv1.WriteMode_WRITE_MODE_UPSERT
+	}
+	p.applyPut(&protoReq)
+
+	msg, err := client.Put(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	return msg, metadata, err
+}
+
+// deleteObject serves DELETE /cachely/v1/objects/{key} in place of the
+// generated route, making the delete conditional on the If-Match header of
+// the request when it has one.
+func deleteObject(mux *runtime.ServeMux, client cachelyv1.CacheAPIClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		protoReq := &cachelyv1.DeleteRequest{Key: pathParams["key"]}
+		if err := runtime.PopulateQueryParameters(protoReq, req.URL.Query(), utilities.NewDoubleArray([][]string{{"key"}})); err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+		p, err := parseIfMatch(rctx, client, req, protoReq.Key)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		if p.version != 0 {
+			protoReq.ExpectedVersion = p.version
+		}
+
+		var md runtime.ServerMetadata
+		resp, err := client.Delete(rctx, protoReq, grpc.Header(&md.HeaderMD), grpc.Trailer(&md.TrailerMD))
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, p.check(err))
+			return
+		}
+
+		runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// do sends a request with the given body, and headers given as name and
+// value pairs, and returns the response along with its body.
+func do(t *testing.T, method, url, body string, header ...string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: unexpected error: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", method, url, err)
+	}
+	return resp, b
+}
+
+func TestRawValues(t *testing.T) {
+	client, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+	url := base + "/cachely/v1/objects/image"
+
+	png := "\x89PNG\r\n\x1a\n\x00\x01"
+	resp, body := do(t, "PUT", url, png, "Content-Type", "image/png")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "image"})
+	if err != nil || string(got.GetValue()) != png || got.GetContentType() != "image/png" {
+		t.Fatalf("Get: want the raw body stored as image/png, got %+v and %v", got, err)
+	}
+
+	tests := []struct {
+		accept      string
+		contentType string // of the response, JSON if empty
+	}{
+		{"", ""},
+		{"*/*", ""},
+		{"application/json", ""},
+		{"image/png", "image/png"},
+		{"image/*", "image/png"},
+		{"application/octet-stream", octetType},
+		{"text/html, image/png;q=0.9, */*;q=0.8", "image/png"},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, "GET", url, "", "Accept", tt.accept)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET with Accept %q: want status 200, got %d: %s", tt.accept, resp.StatusCode, body)
+			continue
+		}
+		if vary := resp.Header.Get("Vary"); vary != "Accept" {
+			t.Errorf("GET with Accept %q: want Vary: Accept, got %q", tt.accept, vary)
+		}
+		if tt.contentType != "" {
+			if ct := resp.Header.Get("Content-Type"); ct != tt.contentType || string(body) != png {
+				t.Errorf("GET with Accept %q: want the raw value as %s, got %q as %s", tt.accept, tt.contentType, body, ct)
+			}
+			continue
+		}
+		var entry struct {
+			Value       []byte `json:"value"`
+			ContentType string `json:"content_type"`
+		}
+		if err := json.Unmarshal(body, &entry); err != nil || string(entry.Value) != png || entry.ContentType != "image/png" {
+			t.Errorf("GET with Accept %q: want the entry in JSON, got %s and %v", tt.accept, body, err)
+		}
+	}
+
+	// a JSON body is a PutRequest rather than the value
+	resp, body = do(t, "PUT", url, `{"value": "aGVsbG8=", "content_type": "text/plain"}`, "Content-Type", "application/json")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT of JSON: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = do(t, "GET", url, "", "Accept", "text/plain")
+	if string(body) != "hello" || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("GET after PUT of JSON: want hello as text/plain, got %q as %s", body, resp.Header.Get("Content-Type"))
+	}
+	resp, body = do(t, "PUT", url, `{"value": `, "Content-Type", "application/json")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PUT of malformed JSON: want status 400, got %d: %s", resp.StatusCode, body)
+	}
+
+	// a body without a content type is stored without one, and returned as
+	// application/octet-stream
+	req, err := http.NewRequest("PUT", base+"/cachely/v1/objects/untyped", bytes.NewReader([]byte("raw")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT without Content-Type: want status 200, got %v", err)
+	} else {
+		resp.Body.Close()
+	}
+	resp, body = do(t, "GET", base+"/cachely/v1/objects/untyped", "", "Accept", "application/octet-stream")
+	if string(body) != "raw" || resp.Header.Get("Content-Type") != octetType {
+		t.Errorf("GET of an untyped value: want raw as %s, got %q as %s", octetType, body, resp.Header.Get("Content-Type"))
+	}
+
+	// the write mode is taken from the query
+	resp, body = do(t, "PUT", url+"?mode=WRITE_MODE_INSERT", "new", "Content-Type", "text/plain")
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("PUT inserting over an entry: want status 409, got %d: %s", resp.StatusCode, body)
+	}
+	resp, body = do(t, "PUT", url+"?mode=bogus", "new", "Content-Type", "text/plain")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PUT with an unknown mode: want status 400, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, _ = do(t, "GET", base+"/cachely/v1/objects/missing", "", "Accept", "image/png")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of a missing key: want status 404, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/protobuf/proto"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+const (
+	// versionHeader carries the version of an entry.
+	versionHeader = "Cachely-Version"
+
+	// createdAtHeader carries the creation time of an entry.
+	createdAtHeader = "Cachely-Created-At"
+
+	// metadataHeaderPrefix starts the names of the headers carrying the
+	// user-defined attributes of an entry, which follow it.
+	metadataHeaderPrefix = "Cachely-Meta-"
+)
+
+// setEntryHeaders describes the entry read in resp with HTTP headers, along
+// with the ETag of the response body. The content type and encoding are left
+// to the caller, since they only describe the body when it is the raw value.
+func setEntryHeaders(h http.Header, resp *cachelyv1.GetResponse, etag string) {
+	h.Set("ETag", etag)
+	h.Set(versionHeader, strconv.FormatUint(resp.GetVersion(), 10))
+	setTimeHeader(h, "Expires", resp.GetExpiresAt())
+	setTimeHeader(h, "Last-Modified", resp.GetModifiedAt())
+	setTimeHeader(h, createdAtHeader, resp.GetCreatedAt())
+	for name, value := range resp.GetMetadata() {
+		h.Set(metadataHeaderPrefix+name, value)
+	}
+}
+
+// setTimeHeader sets the header name to ts in the HTTP date format, unless ts
+// is unset.
+func setTimeHeader(h http.Header, name string, ts *types.Timestamp) {
+	if ts == nil {
+		return
+	}
+	if t, err := types.TimestampFromProto(ts); err == nil {
+		h.Set(name, t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// forwardEntryHeaders is a gateway option that adds the headers set by
+// setEntryHeaders to the JSON responses describing an entry, and the ETag of
+// the value stored to the responses of writes.
+func forwardEntryHeaders(ctx context.Context, w http.ResponseWriter, msg proto.Message) error {
+	switch resp := msg.(type) {
+	case *cachelyv1.GetResponse:
+		setEntryHeaders(w.Header(), resp, entityTag(resp.GetVersion(), true))
+	case *cachelyv1.PutResponse:
+		w.Header().Set("ETag", entityTag(resp.GetVersion(), false))
+		w.Header().Set(versionHeader, strconv.FormatUint(resp.GetVersion(), 10))
+	}
+	return nil
+}
+
+// requestMetadata collects the user-defined attributes sent in the
+// Cachely-Meta- prefixed headers of a request. Their names are lower cased,
+// since HTTP header names are case insensitive.
+func requestMetadata(h http.Header) map[string]string {
+	var metadata map[string]string
+	for name, values := range h {
+		if len(name) <= len(metadataHeaderPrefix) || !strings.EqualFold(name[:len(metadataHeaderPrefix)], metadataHeaderPrefix) {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[strings.ToLower(name[len(metadataHeaderPrefix):])] = strings.Join(values, ", ")
+	}
+	return metadata
+}
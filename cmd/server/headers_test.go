@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+func TestMetadata(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	metadata := map[string]string{"owner": "team-a", "x-1": "a b"}
+	before := time.Now()
+	put, err := client.Put(ctx, &cachelyv1.PutRequest{
+		Key:             "k",
+		Value:           []byte("compressed"),
+		ContentType:     "text/plain; charset=utf-8",
+		ContentEncoding: "gzip",
+		Metadata:        metadata,
+	})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	created, err := types.TimestampFromProto(put.GetCreatedAt())
+	if err != nil || created.Before(before.Add(-time.Second)) || !put.GetModifiedAt().Equal(put.GetCreatedAt()) {
+		t.Errorf("Put: want creation and modification times of the write, got %v and %v", put.GetCreatedAt(), put.GetModifiedAt())
+	}
+
+	got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "k"})
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if got.GetContentType() != "text/plain; charset=utf-8" || got.GetContentEncoding() != "gzip" ||
+		!reflect.DeepEqual(got.GetMetadata(), metadata) || got.GetValueSize() != int64(len("compressed")) ||
+		!got.GetCreatedAt().Equal(put.GetCreatedAt()) || !got.GetModifiedAt().Equal(put.GetModifiedAt()) {
+		t.Errorf("Get: want the metadata written, got %+v", got)
+	}
+
+	// replacing the entry keeps its creation time and replaces its metadata
+	time.Sleep(time.Millisecond)
+	swap, err := client.CompareAndSwap(ctx, &cachelyv1.CompareAndSwapRequest{
+		Key:             "k",
+		ExpectedVersion: put.GetVersion(),
+		Value:           []byte("plain"),
+		Metadata:        map[string]string{"owner": "team-b"},
+	})
+	if err != nil {
+		t.Fatalf("CompareAndSwap: unexpected error: %v", err)
+	}
+	got, err = client.Get(ctx, &cachelyv1.GetRequest{Key: "k"})
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if got.GetContentType() != "" || got.GetContentEncoding() != "" || !reflect.DeepEqual(got.GetMetadata(), map[string]string{"owner": "team-b"}) {
+		t.Errorf("Get after CompareAndSwap: want only the new metadata, got %+v", got)
+	}
+	modified, _ := types.TimestampFromProto(got.GetModifiedAt())
+	if !got.GetCreatedAt().Equal(put.GetCreatedAt()) || !got.GetModifiedAt().Equal(swap.GetModifiedAt()) || !modified.After(created) {
+		t.Errorf("Get after CompareAndSwap: want created at %v and modified later, got %v and %v",
+			put.GetCreatedAt(), got.GetCreatedAt(), got.GetModifiedAt())
+	}
+}
+
+func TestMetadataValidation(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		req  *cachelyv1.PutRequest
+		code codes.Code
+	}{
+		{"no metadata", &cachelyv1.PutRequest{}, codes.OK},
+		{"content type", &cachelyv1.PutRequest{ContentType: "application/json"}, codes.OK},
+		{"invalid content type", &cachelyv1.PutRequest{ContentType: "text/"}, codes.InvalidArgument},
+		{"content encoding", &cachelyv1.PutRequest{ContentEncoding: "br"}, codes.OK},
+		{"invalid content encoding", &cachelyv1.PutRequest{ContentEncoding: "gzip\n"}, codes.InvalidArgument},
+		{"upper case name", &cachelyv1.PutRequest{Metadata: map[string]string{"Owner": "a"}}, codes.InvalidArgument},
+		{"empty name", &cachelyv1.PutRequest{Metadata: map[string]string{"": "a"}}, codes.InvalidArgument},
+		{"leading dash", &cachelyv1.PutRequest{Metadata: map[string]string{"-a": "a"}}, codes.InvalidArgument},
+		{"underscore", &cachelyv1.PutRequest{Metadata: map[string]string{"a_b": "a"}}, codes.InvalidArgument},
+		{"control character", &cachelyv1.PutRequest{Metadata: map[string]string{"a": "a\r\nb"}}, codes.InvalidArgument},
+		{"non-ASCII value", &cachelyv1.PutRequest{Metadata: map[string]string{"a": "é"}}, codes.InvalidArgument},
+		{"empty value", &cachelyv1.PutRequest{Metadata: map[string]string{"a": ""}}, codes.OK},
+		{"largest metadata", &cachelyv1.PutRequest{Metadata: map[string]string{"a": strings.Repeat("x", maxMetadataSize-1)}}, codes.OK},
+		{"metadata too large", &cachelyv1.PutRequest{Metadata: map[string]string{"a": strings.Repeat("x", maxMetadataSize)}}, codes.InvalidArgument},
+	}
+	for i, tt := range tests {
+		tt.req.Key = strconv.Itoa(i)
+		if _, err := client.Put(ctx, tt.req); status.Code(err) != tt.code {
+			t.Errorf("Put with %s: want %s, got %v", tt.name, tt.code, err)
+		}
+	}
+
+	// CompareAndSwap validates its metadata the same way
+	_, err := client.CompareAndSwap(ctx, &cachelyv1.CompareAndSwapRequest{Key: "cas", Metadata: map[string]string{"A": "a"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CompareAndSwap with an invalid name: want InvalidArgument, got %v", err)
+	}
+}
+
+func TestMetadataHeaders(t *testing.T) {
+	client, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	url := base + "/cachely/v1/objects/k"
+
+	resp, body := do(t, "PUT", url, "compressed",
+		"Content-Type", "text/plain",
+		"Content-Encoding", "gzip",
+		"Cachely-Meta-Owner", "team-a",
+		"cachely-meta-x-1", "a b")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	put, err := client.Get(context.Background(), &cachelyv1.GetRequest{Key: "k"})
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	want := map[string]string{"owner": "team-a", "x-1": "a b"}
+	if put.GetContentType() != "text/plain" || put.GetContentEncoding() != "gzip" || !reflect.DeepEqual(put.GetMetadata(), want) {
+		t.Errorf("Get: want the metadata sent in headers, got %+v", put)
+	}
+	if v := resp.Header.Get(versionHeader); v != strconv.FormatUint(put.GetVersion(), 10) {
+		t.Errorf("PUT: want %s %d, got %q", versionHeader, put.GetVersion(), v)
+	}
+
+	created, _ := types.TimestampFromProto(put.GetCreatedAt())
+	modified, _ := types.TimestampFromProto(put.GetModifiedAt())
+	for _, accept := range []string{"application/json", "text/plain"} {
+		// asking for gzip keeps the client from decompressing the value
+		resp, body := do(t, "GET", url, "", "Accept", accept, "Accept-Encoding", "gzip")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET with Accept %s: want status 200, got %d: %s", accept, resp.StatusCode, body)
+		}
+		contentType, contentEncoding := "text/plain", "gzip"
+		if accept == "application/json" {
+			// the headers describe the body, which is not the value
+			contentType, contentEncoding = "application/json", ""
+		}
+		for name, value := range map[string]string{
+			versionHeader:        strconv.FormatUint(put.GetVersion(), 10),
+			"Last-Modified":      modified.UTC().Format(http.TimeFormat),
+			createdAtHeader:      created.UTC().Format(http.TimeFormat),
+			"Cachely-Meta-Owner": "team-a",
+			"Cachely-Meta-X-1":   "a b",
+			"Expires":            "",
+			"Content-Type":       contentType,
+			"Content-Encoding":   contentEncoding,
+		} {
+			if got := resp.Header.Get(name); got != value {
+				t.Errorf("GET with Accept %s: want %s %q, got %q", accept, name, value, got)
+			}
+		}
+	}
+
+	// entries that expire say when
+	expires := time.Now().Add(time.Hour)
+	resp, body = do(t, "PUT", url+"?ttl=3600s", "v", "Content-Type", "text/plain")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT with a ttl: want status 200, got %d: %s", resp.StatusCode, body)
+	}
+	resp, _ = do(t, "GET", url, "", "Accept", "text/plain")
+	if got, err := http.ParseTime(resp.Header.Get("Expires")); err != nil || got.Sub(expires) > 2*time.Second || expires.Sub(got) > 2*time.Second {
+		t.Errorf("GET: want Expires around %v, got %q", expires, resp.Header.Get("Expires"))
+	}
+
+	resp, body = do(t, "PUT", url, "v", "Content-Type", "text/plain", "Cachely-Meta-Bad_Name", "a")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PUT with an invalid attribute name: want status 400, got %d: %s", resp.StatusCode, body)
+	}
+}
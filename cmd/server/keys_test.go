@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// keys holds keys with characters that need care in URLs.
+var keys = []string{
+	"users/42/profile",
+	"héllo wörld",
+	"日本語/キー",
+	"a+b",
+	"100%",
+	"what?",
+	"a#b",
+	"a:b",
+	"x:y/z",
+	"a//b",
+	"a/../b",
+	"dir/",
+	"",
+}
+
+func TestKeys(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	for _, key := range keys {
+		if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: key, Value: []byte(key)}); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+	for _, key := range keys {
+		resp, err := client.Get(ctx, &cachelyv1.GetRequest{Key: key})
+		if err != nil || string(resp.GetValue()) != key {
+			t.Errorf("Get(%q): want the value written at the key, got %q and %v", key, resp.GetValue(), err)
+		}
+	}
+	resp, err := client.List(ctx, &cachelyv1.ListRequest{Prefix: "a"})
+	if err != nil {
+		t.Fatalf("List: unexpected error: %v", err)
+	}
+	var listed []string
+	for _, entry := range resp.GetEntries() {
+		listed = append(listed, entry.GetKey())
+	}
+	if want := []string{"a#b", "a+b", "a/../b", "a//b", "a:b"}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("List(a): want %q, got %q", want, listed)
+	}
+}
+
+func TestGatewayKeys(t *testing.T) {
+	client, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+	objects := base + objectsPath + "/"
+
+	tests := []struct {
+		key  string
+		path string
+	}{
+		{"users/42/profile", "users/42/profile"},
+		{"users/42/profile", "users%2F42%2Fprofile"},
+		{"héllo wörld", "h%C3%A9llo%20w%C3%B6rld"},
+		{"日本語/キー", "日本語/キー"},
+		{"a+b", "a+b"},
+		{"100%", "100%25"},
+		{"what?", "what%3F"},
+		{"a#b", "a%23b"},
+		{"a:b", "a%3Ab"},
+		{"a:b", "a%3ab"},
+		{"a:b", "a:b:"},
+		{":a", "%3Aa"},
+		{"x:y/z", "x:y/z"},
+		{"a//b", "a//b"},
+		{"a/../b", "a/../b"},
+		{"dir/", "dir/"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		path := objects + tt.path
+		resp, body := do(t, "PUT", path, tt.path, "Content-Type", "text/plain")
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("PUT %s: want status 200, got %d: %s", tt.path, resp.StatusCode, body)
+			continue
+		}
+		got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: tt.key})
+		if err != nil || string(got.GetValue()) != tt.path {
+			t.Errorf("PUT %s: want the value stored at %q, got %q and %v", tt.path, tt.key, got.GetValue(), err)
+		}
+
+		resp, body = do(t, "GET", path, "", "Accept", "text/plain")
+		if resp.StatusCode != http.StatusOK || string(body) != tt.path {
+			t.Errorf("GET %s: want status 200 and the value, got %d and %q", tt.path, resp.StatusCode, body)
+		}
+		// a custom method follows the key, unless the key ends with a slash
+		// or the path with the extra colon
+		if !strings.HasSuffix(tt.path, "/") && !strings.HasSuffix(tt.path, ":") && tt.path != "" {
+			resp, body = do(t, "GET", path+":stream", "")
+			if resp.StatusCode != http.StatusOK || string(body) != tt.path {
+				t.Errorf("GET %s:stream: want status 200 and the value, got %d and %q", tt.path, resp.StatusCode, body)
+			}
+		}
+
+		resp, body = do(t, "DELETE", path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("DELETE %s: want status 200, got %d: %s", tt.path, resp.StatusCode, body)
+		}
+		if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: tt.key}); status.Code(err) != codes.NotFound {
+			t.Errorf("DELETE %s: want %q deleted, got %v", tt.path, tt.key, err)
+		}
+	}
+
+	// an unescaped colon in the last segment starts a custom method
+	resp, _ := do(t, "PUT", objects+"a:b", "v", "Content-Type", "text/plain")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("PUT a:b: want status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestGatewayList(t *testing.T) {
+	client, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+
+	for _, key := range []string{"users/1", "users/2", "other", ""} {
+		if _, err := client.Put(context.Background(), &cachelyv1.PutRequest{Key: key, Value: []byte("v")}); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+
+	// the collection is listed rather than taken for the empty key
+	resp, body := do(t, "GET", base+objectsPath+"?prefix="+url.QueryEscape("users/"), "")
+	var list struct {
+		Entries []struct {
+			Key string `json:"key"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(body, &list); resp.StatusCode != http.StatusOK || err != nil {
+		t.Fatalf("GET of the objects: want status 200 and a listing, got %d: %s", resp.StatusCode, body)
+	}
+	var listed []string
+	for _, entry := range list.Entries {
+		listed = append(listed, entry.Key)
+	}
+	if want := []string{"users/1", "users/2"}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("GET of the objects with a prefix: want %q, got %q", want, listed)
+	}
+	resp, body = do(t, "GET", base+objectsPath+"?page_size=x", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET of the objects with a malformed page size: want status 400, got %d: %s", resp.StatusCode, body)
+	}
+
+	// paths naming no key leave the empty key alone
+	for _, tt := range []struct{ method, path string }{
+		{"PUT", objectsPath},
+		{"DELETE", objectsPath},
+		{"GET", objectsPath + ":stream"},
+		{"PUT", objectsPath + ":stream"},
+	} {
+		resp, body := do(t, tt.method, base+tt.path, "v", "Content-Type", "text/plain")
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s %s: want status 404, got %d: %s", tt.method, tt.path, resp.StatusCode, body)
+		}
+	}
+	if _, err := client.Get(context.Background(), &cachelyv1.GetRequest{Key: ""}); err != nil {
+		t.Errorf("Get of the empty key: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// List enumerates the keys starting with the requested prefix in lexical
+// order. The page token handed back to clients is the last key of the page,
+// so listings stay consistent while keys are added or removed between pages.
+func (s *server) List(ctx context.Context, req *cachelyv1.ListRequest) (*cachelyv1.ListResponse, error) {
+	log.Printf("listing keys with prefix %q\n", req.GetPrefix())
+
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must not be negative, got %d", size)
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		return nil, status.Errorf(codes.InvalidArgument, "page_size may not exceed %d, got %d", maxPageSize, size)
+	}
+
+	// without a page token the listing starts from the first key, which may
+	// be the empty key
+	var start string
+	if token := req.GetPageToken(); token != "" {
+		after, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page_token %q", token)
+		}
+		start = store.KeyAfter(string(after))
+	}
+
+	page, more := store.List(s.store, req.GetPrefix(), start, size)
+
+	resp := &cachelyv1.ListResponse{
+		Entries: make([]*cachelyv1.ListEntry, 0, len(page)),
+	}
+	for _, ke := range page {
+		entry := &cachelyv1.ListEntry{
+			Key:       ke.Key,
+			ValueSize: int64(len(ke.Entry.Value)),
+			ExpiresAt: timestamp(ke.Entry.ExpiresAt),
+			Version:   ke.Entry.Version,
+		}
+		if req.GetIncludeValues() {
+			entry.Value = ke.Entry.Value
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	if more {
+		resp.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(page[len(page)-1].Key))
+	}
+	return resp, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+func TestList(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	want := []string{""}
+	for i := 0; i < 5; i++ {
+		want = append(want, fmt.Sprintf("k%d", i))
+	}
+	for _, key := range want {
+		if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: key, Value: []byte("v:" + key)}); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+
+	var got []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatalf("List did not finish after %d pages", pages)
+		}
+		resp, err := client.List(ctx, &cachelyv1.ListRequest{PageSize: 2, PageToken: token, IncludeValues: true})
+		if err != nil {
+			t.Fatalf("List: unexpected error: %v", err)
+		}
+		for _, e := range resp.GetEntries() {
+			if string(e.GetValue()) != "v:"+e.GetKey() || e.GetValueSize() != int64(len(e.GetValue())) {
+				t.Errorf("List: unexpected value %q of size %d at %q", e.GetValue(), e.GetValueSize(), e.GetKey())
+			}
+			got = append(got, e.GetKey())
+		}
+		if token = resp.GetNextPageToken(); token == "" {
+			break
+		}
+	}
+	if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+		t.Errorf("List: want keys %q, got %q", want, got)
+	}
+
+	resp, err := client.List(ctx, &cachelyv1.ListRequest{Prefix: "k", PageSize: 10})
+	if err != nil {
+		t.Fatalf("List: unexpected error: %v", err)
+	}
+	if n := len(resp.GetEntries()); n != 5 || resp.GetNextPageToken() != "" {
+		t.Errorf("List with a prefix: want 5 keys in a single page, got %d and token %q", n, resp.GetNextPageToken())
+	}
+	if resp.GetEntries()[0].GetValue() != nil {
+		t.Errorf("List: values included without include_values")
+	}
+
+	for _, req := range []*cachelyv1.ListRequest{
+		{PageSize: -1},
+		{PageSize: maxPageSize + 1},
+		{PageToken: "not base64!"},
+	} {
+		if _, err := client.List(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("List(%v): want InvalidArgument, got %v", req, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/lock"
+)
+
+// lockServer serves the LockAPI from the locks held in a lock.Table.
+type lockServer struct {
+	locks *lock.Table
+}
+
+// Acquire takes the requested lock if it is free.
+func (s *lockServer) Acquire(ctx context.Context, req *cachelyv1.AcquireRequest) (*cachelyv1.AcquireResponse, error) {
+	name := req.GetName()
+	log.Printf("acquiring lock %q\n", name)
+
+	ttl, err := leaseDuration(req.GetTtl())
+	if err != nil {
+		return nil, err
+	}
+	l, err := s.locks.TryAcquire(name, req.GetOwner(), ttl)
+	if err != nil {
+		return nil, lockError(err, name)
+	}
+	return &cachelyv1.AcquireResponse{Lease: leaseProto(l)}, nil
+}
+
+// AcquireWait takes the requested lock, waiting for it to be freed for as long
+// as the timeout of the request allows.
+func (s *lockServer) AcquireWait(req *cachelyv1.AcquireWaitRequest, stream cachelyv1.LockAPI_AcquireWaitServer) error {
+	name := req.GetName()
+	log.Printf("waiting for lock %q\n", name)
+
+	ttl, err := leaseDuration(req.GetTtl())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	if pb := req.GetTimeout(); pb != nil {
+		timeout, err := types.DurationFromProto(pb)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid timeout: %v", err)
+		}
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// a client that stops reading cannot take the lock, so failing to tell it
+	// about the holder ends the wait
+	var sendErr error
+	l, err := s.locks.Acquire(ctx, name, req.GetOwner(), ttl, func(holder lock.Lease) {
+		if sendErr = stream.Send(&cachelyv1.AcquireWaitResponse{Holder: leaseProto(holder)}); sendErr != nil {
+			cancel()
+		}
+	})
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case err == context.DeadlineExceeded:
+		return status.Errorf(codes.DeadlineExceeded, "timed out waiting for lock %s", name)
+	case err == context.Canceled:
+		return status.Errorf(codes.Canceled, "gave up waiting for lock %s", name)
+	case err != nil:
+		return lockError(err, name)
+	}
+
+	// the lease is freed right away if it cannot reach the client, rather than
+	// keeping others waiting until it expires
+	if err := stream.Send(&cachelyv1.AcquireWaitResponse{Lease: leaseProto(l)}); err != nil {
+		s.locks.Release(name, l.Token)
+		return err
+	}
+	return nil
+}
+
+// Renew extends the requested lease.
+func (s *lockServer) Renew(ctx context.Context, req *cachelyv1.RenewRequest) (*cachelyv1.RenewResponse, error) {
+	name := req.GetName()
+	log.Printf("renewing lock %q\n", name)
+
+	ttl, err := leaseDuration(req.GetTtl())
+	if err != nil {
+		return nil, err
+	}
+	l, err := s.locks.Renew(name, req.GetToken(), ttl)
+	if err != nil {
+		return nil, lockError(err, name)
+	}
+	return &cachelyv1.RenewResponse{Lease: leaseProto(l)}, nil
+}
+
+// Release frees the requested lock.
+func (s *lockServer) Release(ctx context.Context, req *cachelyv1.ReleaseRequest) (*cachelyv1.ReleaseResponse, error) {
+	name := req.GetName()
+	log.Printf("releasing lock %q\n", name)
+
+	if err := s.locks.Release(name, req.GetToken()); err != nil {
+		return nil, lockError(err, name)
+	}
+	return &cachelyv1.ReleaseResponse{}, nil
+}
+
+// leaseDuration converts the ttl of a lease, which must be set and positive.
+func leaseDuration(pb *types.Duration) (time.Duration, error) {
+	if pb == nil {
+		return 0, status.Errorf(codes.InvalidArgument, "ttl must be set")
+	}
+	ttl, err := types.DurationFromProto(pb)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid ttl: %v", err)
+	}
+	if ttl <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "ttl must be positive, got %s", ttl)
+	}
+	return ttl, nil
+}
+
+// lockError converts an error from the lock table into a gRPC status.
+func lockError(err error, name string) error {
+	switch err {
+	case lock.ErrHeld:
+		return status.Errorf(codes.Aborted, "lock %s is held", name)
+	case lock.ErrNotHeld:
+		return status.Errorf(codes.FailedPrecondition, "lock %s is not held with this token", name)
+	default:
+		return status.Errorf(codes.Internal, "lock %s: %v", name, err)
+	}
+}
+
+// leaseProto converts a lease to its protobuf form.
+func leaseProto(l lock.Lease) *cachelyv1.Lease {
+	return &cachelyv1.Lease{
+		Name:      l.Name,
+		Owner:     l.Owner,
+		Token:     l.Token,
+		ExpiresAt: timestamp(l.ExpiresAt),
+	}
+}
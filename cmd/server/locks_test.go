@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/lock"
+)
+
+// serveLocks serves a LockAPI with no lock held, and returns a client of it
+// along with a function stopping it.
+func serveLocks(t *testing.T) (cachelyv1.LockAPIClient, func()) {
+	t.Helper()
+	conn, stop := serve(t, func(s *grpc.Server) {
+		cachelyv1.RegisterLockAPIServer(s, &lockServer{locks: lock.NewTable(1)})
+	})
+	return cachelyv1.NewLockAPIClient(conn), stop
+}
+
+func TestLocks(t *testing.T) {
+	client, stop := serveLocks(t)
+	defer stop()
+	ctx := context.Background()
+	minute := types.DurationProto(time.Minute)
+
+	resp, err := client.Acquire(ctx, &cachelyv1.AcquireRequest{Name: "jobs/a", Owner: "alice", Ttl: minute})
+	if err != nil {
+		t.Fatalf("Acquire: unexpected error: %v", err)
+	}
+	l := resp.GetLease()
+	if l.GetName() != "jobs/a" || l.GetOwner() != "alice" || l.GetToken() <= 1 || l.GetExpiresAt() == nil {
+		t.Errorf("Acquire: want a lease of alice over jobs/a, got %+v", l)
+	}
+
+	renewed, err := client.Renew(ctx, &cachelyv1.RenewRequest{Name: "jobs/a", Token: l.GetToken(), Ttl: types.DurationProto(time.Hour)})
+	if err != nil || renewed.GetLease().GetToken() != l.GetToken() || renewed.GetLease().GetExpiresAt().Compare(l.GetExpiresAt()) <= 0 {
+		t.Errorf("Renew: want the same token expiring later, got %+v and %v", renewed, err)
+	}
+
+	failures := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"Acquire of a held lock", func() error {
+			_, err := client.Acquire(ctx, &cachelyv1.AcquireRequest{Name: "jobs/a", Owner: "bob", Ttl: minute})
+			return err
+		}, codes.Aborted},
+		{"Acquire without a ttl", func() error {
+			_, err := client.Acquire(ctx, &cachelyv1.AcquireRequest{Name: "b"})
+			return err
+		}, codes.InvalidArgument},
+		{"Acquire with a negative ttl", func() error {
+			_, err := client.Acquire(ctx, &cachelyv1.AcquireRequest{Name: "b", Ttl: types.DurationProto(-time.Second)})
+			return err
+		}, codes.InvalidArgument},
+		{"Renew with another token", func() error {
+			_, err := client.Renew(ctx, &cachelyv1.RenewRequest{Name: "jobs/a", Token: l.GetToken() + 1, Ttl: minute})
+			return err
+		}, codes.FailedPrecondition},
+		{"Renew without a ttl", func() error {
+			_, err := client.Renew(ctx, &cachelyv1.RenewRequest{Name: "jobs/a", Token: l.GetToken()})
+			return err
+		}, codes.InvalidArgument},
+		{"Release with another token", func() error {
+			_, err := client.Release(ctx, &cachelyv1.ReleaseRequest{Name: "jobs/a", Token: l.GetToken() + 1})
+			return err
+		}, codes.FailedPrecondition},
+	}
+	for _, tt := range failures {
+		if err := tt.call(); status.Code(err) != tt.code {
+			t.Errorf("%s: want %s, got %v", tt.name, tt.code, err)
+		}
+	}
+
+	if _, err := client.Release(ctx, &cachelyv1.ReleaseRequest{Name: "jobs/a", Token: l.GetToken()}); err != nil {
+		t.Fatalf("Release: unexpected error: %v", err)
+	}
+	if _, err := client.Release(ctx, &cachelyv1.ReleaseRequest{Name: "jobs/a", Token: l.GetToken()}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Release of a released lease: want FailedPrecondition, got %v", err)
+	}
+	next, err := client.Acquire(ctx, &cachelyv1.AcquireRequest{Name: "jobs/a", Owner: "bob", Ttl: minute})
+	if err != nil || next.GetLease().GetToken() <= l.GetToken() {
+		t.Errorf("Acquire of a released lock: want a token above %d, got %+v and %v", l.GetToken(), next, err)
+	}
+}
+
+func TestAcquireWait(t *testing.T) {
+	client, stop := serveLocks(t)
+	defer stop()
+	ctx := context.Background()
+	minute := types.DurationProto(time.Minute)
+
+	held, err := client.Acquire(ctx, &cachelyv1.AcquireRequest{Name: "a", Owner: "alice", Ttl: minute})
+	if err != nil {
+		t.Fatalf("Acquire: unexpected error: %v", err)
+	}
+	stream, err := client.AcquireWait(ctx, &cachelyv1.AcquireWaitRequest{Name: "a", Owner: "bob", Ttl: minute})
+	if err != nil {
+		t.Fatalf("AcquireWait: unexpected error: %v", err)
+	}
+	msg, err := stream.Recv()
+	if err != nil || msg.GetHolder().GetToken() != held.GetLease().GetToken() || msg.GetLease() != nil {
+		t.Fatalf("AcquireWait of a held lock: want the lease of the holder first, got %+v and %v", msg, err)
+	}
+	if _, err := client.Release(ctx, &cachelyv1.ReleaseRequest{Name: "a", Token: held.GetLease().GetToken()}); err != nil {
+		t.Fatalf("Release: unexpected error: %v", err)
+	}
+	msg, err = stream.Recv()
+	if l := msg.GetLease(); err != nil || l.GetOwner() != "bob" || l.GetToken() <= held.GetLease().GetToken() {
+		t.Fatalf("AcquireWait of a released lock: want a lease of bob, got %+v and %v", msg, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("AcquireWait: want the stream to end with the lease, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *cachelyv1.AcquireWaitRequest
+		code codes.Code
+	}{
+		{"a free lock", &cachelyv1.AcquireWaitRequest{Name: "b", Ttl: minute}, codes.OK},
+		{"a held lock past the timeout", &cachelyv1.AcquireWaitRequest{Name: "a", Ttl: minute, Timeout: types.DurationProto(10 * time.Millisecond)}, codes.DeadlineExceeded},
+		{"no ttl", &cachelyv1.AcquireWaitRequest{Name: "c"}, codes.InvalidArgument},
+		{"an invalid timeout", &cachelyv1.AcquireWaitRequest{Name: "c", Ttl: minute, Timeout: &types.Duration{Seconds: 1, Nanos: -1}}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		stream, err := client.AcquireWait(ctx, tt.req)
+		if err != nil {
+			t.Fatalf("AcquireWait of %s: unexpected error: %v", tt.name, err)
+		}
+		var last *cachelyv1.AcquireWaitResponse
+		for {
+			msg, err = stream.Recv()
+			if err != nil {
+				break
+			}
+			last = msg
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		if status.Code(err) != tt.code {
+			t.Errorf("AcquireWait of %s: want %s, got %v", tt.name, tt.code, err)
+		}
+		if tt.code == codes.OK && last.GetLease() == nil {
+			t.Errorf("AcquireWait of %s: want the lease acquired last, got %+v", tt.name, last)
+		}
+	}
+}
+
+func TestLockGateway(t *testing.T) {
+	_, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	url := base + "/cachely/v1/locks/jobs/a"
+
+	type lease struct {
+		Lease struct {
+			Name  string `json:"name"`
+			Owner string `json:"owner"`
+			Token string `json:"token"`
+		} `json:"lease"`
+	}
+	resp, body := do(t, "POST", url+":acquire", `{"owner": "alice", "ttl": "60s"}`, "Content-Type", "application/json")
+	var acquired lease
+	if err := json.Unmarshal(body, &acquired); resp.StatusCode != http.StatusOK || err != nil {
+		t.Fatalf("POST :acquire: want status 200 and a lease, got %d: %s", resp.StatusCode, body)
+	}
+	if acquired.Lease.Name != "jobs/a" || acquired.Lease.Owner != "alice" || acquired.Lease.Token == "" {
+		t.Errorf("POST :acquire: want a lease of alice over jobs/a, got %s", body)
+	}
+	token := acquired.Lease.Token
+
+	tests := []struct {
+		verb string
+		body string
+		code int
+	}{
+		{"acquire", `{"owner": "bob", "ttl": "60s"}`, http.StatusConflict},
+		{"acquire", `{"owner": "bob"}`, http.StatusBadRequest},
+		{"renew", `{"token": "1", "ttl": "60s"}`, http.StatusPreconditionFailed},
+		{"renew", `{"token": "` + token + `", "ttl": "120s"}`, http.StatusOK},
+		{"release", `{"token": "` + token + `"}`, http.StatusOK},
+		{"release", `{"token": "` + token + `"}`, http.StatusPreconditionFailed},
+		{"acquire", `{"owner": "bob", "ttl": "60s"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, "POST", url+":"+tt.verb, tt.body, "Content-Type", "application/json")
+		if resp.StatusCode != tt.code {
+			t.Errorf("POST :%s %s: want status %d, got %d: %s", tt.verb, tt.body, tt.code, resp.StatusCode, body)
+		}
+	}
+}
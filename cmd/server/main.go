@@ -2,78 +2,434 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"flag"
 	"log"
+	"mime"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 
-	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/gogo/protobuf/types"
+	"github.com/timraymond/cachely/aof"
+	"github.com/timraymond/cachely/backend"
+	"github.com/timraymond/cachely/backend/sqlite"
 	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/eviction"
+	"github.com/timraymond/cachely/lock"
+	"github.com/timraymond/cachely/origin"
+	"github.com/timraymond/cachely/store"
+	"github.com/timraymond/cachely/watch"
 )
 
 type server struct {
-	data sync.Map
+	store        store.Store
+	changes      *watch.Log          // recent changes followed by watchers, nil when watching is disabled
+	maxValueSize int64               // largest value accepted by PutStream, Append and Prepend
+	origin       *origin.ReadThrough // fills the misses of reads, nil when the server is not read-through
+	originLoads  int                 // largest number of loads from the origin a BatchGet makes at once
 }
 
 func (s *server) Get(ctx context.Context, req *cachelyv1.GetRequest) (*cachelyv1.GetResponse, error) {
 	key := req.GetKey()
 	log.Printf("looking up key %q\n", key)
-	if v, ok := s.data.Load(key); ok {
-		log.Printf("found key %q\n", key)
-		return &cachelyv1.GetResponse{
-			Key:   key,
-			Value: v.([]byte),
-		}, status.New(codes.OK, "").Err()
+
+	e, err := s.read(ctx, key)
+	if err != nil {
+		log.Printf("key not found %q\n", key)
+		return nil, readError(err, key)
+	}
+
+	value, err := valueRange(e.Value, req.GetOffset(), req.GetLength())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("found key %q\n", key)
+	resp := getResponse(key, e)
+	resp.Value = value
+	return resp, status.New(codes.OK, "").Err()
+}
+
+// read returns the entry at key, loading it from the origin of the server if
+// it is missing and the server is read-through.
+func (s *server) read(ctx context.Context, key string) (store.Entry, error) {
+	if s.origin == nil {
+		return s.store.Get(key)
+	}
+	return s.origin.GetOrLoad(ctx, key)
+}
+
+// readError converts an error returned by read into a gRPC status.
+func readError(err error, key string) error {
+	if lerr, ok := err.(*origin.LoadError); ok {
+		return status.Errorf(codes.Unavailable, "could not load key %s from the origin: %v", key, lerr.Err)
+	}
+	switch err {
+	case context.DeadlineExceeded:
+		return status.Errorf(codes.DeadlineExceeded, "timed out loading key %s", key)
+	case context.Canceled:
+		return status.Errorf(codes.Canceled, "gave up loading key %s", key)
+	}
+	return storeError(err, key)
+}
+
+// valueRange returns the length bytes of value starting at offset, or those up
+// to its end when length is zero or goes past it.
+func valueRange(value []byte, offset, length int64) ([]byte, error) {
+	switch {
+	case offset < 0 || length < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "offset and length may not be negative")
+	case offset > int64(len(value)):
+		return nil, status.Errorf(codes.OutOfRange, "offset %d is past the end of the %d byte value", offset, len(value))
+	}
+	value = value[offset:]
+	if length > 0 && length < int64(len(value)) {
+		value = value[:length]
+	}
+	return value, nil
+}
+
+// getResponse describes the entry e read from key.
+func getResponse(key string, e store.Entry) *cachelyv1.GetResponse {
+	return &cachelyv1.GetResponse{
+		Key:             key,
+		Value:           e.Value,
+		ExpiresAt:       timestamp(e.ExpiresAt),
+		Version:         e.Version,
+		ContentType:     e.ContentType,
+		ContentEncoding: e.ContentEncoding,
+		Metadata:        e.Metadata,
+		CreatedAt:       timestamp(e.CreatedAt),
+		ModifiedAt:      timestamp(e.ModifiedAt),
+		ValueSize:       int64(len(e.Value)),
 	}
-	log.Printf("key not found %q\n", key)
-	return nil, status.Errorf(codes.NotFound, "could not find key %s", key)
 }
 
 // Delete will remove the cached value located at key from the cache. If there
-// is no value at the provided key, an error will be produced.
+// is no value at the provided key, an error will be produced. An expected
+// version makes the delete fail with FailedPrecondition if the entry has been
+// written since.
 func (s *server) Delete(ctx context.Context, req *cachelyv1.DeleteRequest) (*cachelyv1.DeleteResponse, error) {
 	key := req.GetKey()
 	log.Printf("Storing key: %q\n", key)
 
-	if _, ok := s.data.Load(key); ok {
-		s.data.Delete(key)
-
-		return &cachelyv1.DeleteResponse{
-			Key: key,
-		}, nil
+	var err error
+	if version := req.GetExpectedVersion(); version != 0 {
+		err = store.CompareAndDelete(s.store, key, version)
+	} else {
+		err = s.store.Delete(key)
+	}
+	if err != nil {
+		return nil, storeError(err, key)
 	}
 
-	return nil, status.Errorf(codes.NotFound, "could not find key %s", key)
+	return &cachelyv1.DeleteResponse{
+		Key: key,
+	}, nil
 }
 
-// Put stores the provided value at the key specified. If there is an existing
-// entry, it will return an error. Delete should be called on that entry first.
+// Put stores the provided value at the key specified. By default, if there is
+// an existing entry, it will return an error; the request's write mode can ask
+// to replace existing entries instead, and its expected version to replace
+// only the entry at that version. Entries may optionally carry a TTL or an
+// absolute expiration time, after which they are no longer served.
 func (s *server) Put(ctx context.Context, req *cachelyv1.PutRequest) (*cachelyv1.PutResponse, error) {
 	key := req.GetKey()
-	val := req.GetValue()
 
 	log.Printf("Writing value at key: %q\n", key)
 
-	if _, ok := s.data.Load(key); !ok {
-		s.data.Store(key, val)
+	w, err := putWrite(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := store.Apply(s.store, w)
+	if err != nil {
+		return nil, storeError(err, key)
+	}
+
+	return putResponse(key, e), nil
+}
+
+// putWrite converts req into a write to the store.
+func putWrite(req *cachelyv1.PutRequest, now time.Time) (store.Write, error) {
+	expiresAt, err := expiration(req.GetTtl(), req.GetExpiresAt(), now)
+	if err != nil {
+		return store.Write{}, err
+	}
+	if err := validateMetadata(req.GetContentType(), req.GetContentEncoding(), req.GetMetadata()); err != nil {
+		return store.Write{}, err
+	}
+
+	w := store.Write{
+		Key: req.GetKey(),
+		Entry: store.Entry{
+			Value:           req.GetValue(),
+			ExpiresAt:       expiresAt,
+			ContentType:     req.GetContentType(),
+			ContentEncoding: req.GetContentEncoding(),
+			Metadata:        req.GetMetadata(),
+		},
+		Version: req.GetExpectedVersion(),
+	}
+	switch req.GetMode() {
+	case cachelyv1.WriteMode_WRITE_MODE_INSERT:
+		w.Mode = store.ModeInsert
+	case cachelyv1.WriteMode_WRITE_MODE_REPLACE:
+		w.Mode = store.ModeReplace
+	case cachelyv1.WriteMode_WRITE_MODE_UPSERT:
+		w.Mode = store.ModeUpsert
+	default:
+		return store.Write{}, status.Errorf(codes.InvalidArgument, "unknown write mode %v", req.GetMode())
+	}
+	return w, nil
+}
+
+// putResponse describes the entry e stored at key.
+func putResponse(key string, e store.Entry) *cachelyv1.PutResponse {
+	return &cachelyv1.PutResponse{
+		Key:        key,
+		ExpiresAt:  timestamp(e.ExpiresAt),
+		Version:    e.Version,
+		CreatedAt:  timestamp(e.CreatedAt),
+		ModifiedAt: timestamp(e.ModifiedAt),
+	}
+}
+
+// CompareAndSwap replaces the value at key only if the entry is still at the
+// version the caller expects, failing with FailedPrecondition otherwise. An
+// expected version of zero inserts the key if it is absent.
+func (s *server) CompareAndSwap(ctx context.Context, req *cachelyv1.CompareAndSwapRequest) (*cachelyv1.CompareAndSwapResponse, error) {
+	key := req.GetKey()
+
+	log.Printf("Swapping value at key: %q\n", key)
+
+	expiresAt, err := expiration(req.GetTtl(), req.GetExpiresAt(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMetadata(req.GetContentType(), req.GetContentEncoding(), req.GetMetadata()); err != nil {
+		return nil, err
+	}
+
+	e, err := store.CompareAndSwap(s.store, key, req.GetExpectedVersion(), store.Entry{
+		Value:           req.GetValue(),
+		ExpiresAt:       expiresAt,
+		ContentType:     req.GetContentType(),
+		ContentEncoding: req.GetContentEncoding(),
+		Metadata:        req.GetMetadata(),
+	})
+	if err != nil {
+		return nil, storeError(err, key)
+	}
+
+	return &cachelyv1.CompareAndSwapResponse{
+		Key:        key,
+		ExpiresAt:  timestamp(e.ExpiresAt),
+		Version:    e.Version,
+		CreatedAt:  timestamp(e.CreatedAt),
+		ModifiedAt: timestamp(e.ModifiedAt),
+	}, nil
+}
+
+// storeError converts an error returned by the store for key into a gRPC
+// status.
+func storeError(err error, key string) error {
+	switch err {
+	case store.ErrNotFound:
+		return status.Errorf(codes.NotFound, "could not find key %s", key)
+	case store.ErrExists:
+		return status.Errorf(codes.AlreadyExists, "existing cached item located at %s", key)
+	case store.ErrTooLarge:
+		return status.Errorf(codes.ResourceExhausted, "item at %s does not fit in the cache", key)
+	case store.ErrVersionMismatch:
+		return status.Errorf(codes.FailedPrecondition, "cached item at %s is not at the expected version", key)
+	case backend.ErrQueueFull:
+		return status.Errorf(codes.ResourceExhausted, "too many writes are waiting for the backend to write key %s", key)
+	}
+	if lerr, ok := err.(*logError); ok {
+		return status.Errorf(codes.Unavailable, "writes are disabled: %v", lerr)
+	}
+	if werr, ok := err.(*backend.WriteError); ok {
+		return status.Errorf(codes.Unavailable, "could not write key %s to the backend: %v", key, werr.Err)
+	}
+	return status.Errorf(codes.Internal, "could not access key %s: %v", key, err)
+}
+
+// updateError converts an error returned by the store from an update of key
+// into a gRPC status. Errors returned by the update function itself are
+// expected to be statuses already.
+func updateError(err error, key string) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return storeError(err, key)
+}
+
+// expiration determines the absolute expiration time requested by a write
+// through either a ttl or an expires_at field. The zero time is returned for
+// requests without an expiration.
+func expiration(ttlpb *types.Duration, expiresAtpb *types.Timestamp, now time.Time) (time.Time, error) {
+	var expiresAt time.Time
+	switch {
+	case ttlpb != nil && expiresAtpb != nil:
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "only one of ttl and expires_at may be set")
+	case ttlpb != nil:
+		ttl, err := types.DurationFromProto(ttlpb)
+		if err != nil {
+			return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid ttl: %v", err)
+		}
+		if ttl <= 0 {
+			return time.Time{}, status.Errorf(codes.InvalidArgument, "ttl must be positive, got %s", ttl)
+		}
+		expiresAt = now.Add(ttl)
+	case expiresAtpb != nil:
+		t, err := types.TimestampFromProto(expiresAtpb)
+		if err != nil {
+			return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid expires_at: %v", err)
+		}
+		if !t.After(now) {
+			return time.Time{}, status.Errorf(codes.InvalidArgument, "expires_at %s is in the past", t.Format(time.RFC3339))
+		}
+		expiresAt = t
+	default:
+		return time.Time{}, nil
+	}
+
+	if _, err := types.TimestampProto(expiresAt); err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid expiration: %v", err)
+	}
+	return expiresAt, nil
+}
+
+// maxMetadataSize bounds the total size of the names and values of the
+// user-defined attributes of an entry.
+const maxMetadataSize = 8 << 10
+
+// validateMetadata checks the metadata a write sets on an entry, which the
+// gateway sends as HTTP headers.
+func validateMetadata(contentType, contentEncoding string, metadata map[string]string) error {
+	if contentType != "" {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid content_type %q: %v", contentType, err)
+		}
+	}
+	if !isHeaderValue(contentEncoding) {
+		return status.Errorf(codes.InvalidArgument, "invalid content_encoding %q", contentEncoding)
+	}
+
+	size := 0
+	for name, value := range metadata {
+		if !isMetadataName(name) {
+			return status.Errorf(codes.InvalidArgument, "invalid metadata name %q: only lower case letters, digits and dashes are allowed", name)
+		}
+		if !isHeaderValue(value) {
+			return status.Errorf(codes.InvalidArgument, "invalid value for metadata %q: only printable ASCII characters are allowed", name)
+		}
+		size += len(name) + len(value)
+	}
+	if size > maxMetadataSize {
+		return status.Errorf(codes.InvalidArgument, "metadata takes %d bytes, more than the %d allowed", size, maxMetadataSize)
+	}
+	return nil
+}
 
-		return &cachelyv1.PutResponse{
-			Key: key,
-		}, nil
+// isMetadataName reports whether name is a valid name for a user-defined
+// attribute.
+func isMetadataName(name string) bool {
+	if name == "" || name[0] == '-' {
+		return false
 	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
 
-	return nil, status.Errorf(codes.AlreadyExists, "existing cached item located at %s", key)
+// isHeaderValue reports whether s can be sent as an HTTP header value as is.
+func isHeaderValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// timestamp converts an expiration time to its protobuf form, leaving it unset
+// for entries that never expire. Expirations are validated when they are
+// written, so the conversion cannot fail.
+func timestamp(t time.Time) *types.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts, _ := types.TimestampProto(t)
+	return ts
 }
 
 func main() {
+	reapInterval := flag.Duration("reap-interval", time.Second, "how often expired entries are removed from memory")
+	maxBytes := flag.Int64("max-bytes", 0, "memory budget for keys and values in bytes, unlimited when zero")
+	shards := flag.Int("shards", 16, "number of lock stripes the store is split into; the memory budget is divided between them")
+	policyName := flag.String("eviction", "lru", "eviction policy used when over the memory budget: "+strings.Join(eviction.Names, ", "))
+	snapshotPath := flag.String("snapshot", "", "file the cache is saved to on shutdown and restored from on startup, disabled when empty")
+	snapshotInterval := flag.Duration("snapshot-interval", 5*time.Minute, "how often the cache is saved to the snapshot file, only on shutdown and on demand when zero")
+	aofPath := flag.String("aof", "", "append-only log of writes, replayed on startup in place of the snapshot, disabled when empty")
+	aofSync := flag.String("aof-fsync", "everysec", "how often the append-only log is flushed to disk: "+strings.Join(aof.SyncPolicies, ", "))
+	aofCompactSize := flag.Int64("aof-compact-size", 64<<20, "size in bytes the append-only log has to reach before it is compacted")
+	watchHistory := flag.Int("watch-history", 10000, "number of recent changes kept for watchers to resume from, zero disables watching")
+	maxValueSize := flag.Int64("max-value-size", 256<<20, "largest value in bytes that can be streamed into the cache or built by appends")
+	diskDir := flag.String("disk-dir", "", "directory entries evicted from memory are moved to instead of being dropped, and entries too large for memory are written to, disabled when empty; requires -max-bytes")
+	diskMaxBytes := flag.Int64("disk-max-bytes", 1<<30, "disk budget for entries evicted from memory in bytes")
+	diskSegmentSize := flag.Int64("disk-segment-size", 64<<20, "size in bytes of the files entries on disk are appended to")
+	originURL := flag.String("origin-url", "", "URL template, with {key} standing for the key, of an HTTP origin misses are loaded from, disabled when empty")
+	originAddr := flag.String("origin-grpc", "", "address of a gRPC origin implementing the OriginAPI misses are loaded from, disabled when empty")
+	originTimeout := flag.Duration("origin-timeout", 5*time.Second, "how long loads from the origin may take before they fail")
+	originBatchLoads := flag.Int("origin-batch-loads", 8, "largest number of keys missing from a BatchGet loaded from the origin at once")
+	originTTL := flag.Duration("origin-ttl", 0, "lifetime of the entries loaded from the origin that do not set one, unlimited when zero")
+	backendDir := flag.String("backend-dir", "", "directory of a filesystem backend writes are forwarded to, disabled when empty")
+	backendSQLite := flag.String("backend-sqlite", "", "SQLite database file of a backend writes are forwarded to, disabled when empty")
+	backendMode := flag.String("backend-mode", "write-through", "how writes are forwarded to the backend: write-through, before they are acknowledged, or write-behind, from a queue in the background")
+	backendBatchSize := flag.Int("backend-batch-size", 100, "largest number of writes applied to the backend at once in write-behind mode")
+	backendFlushInterval := flag.Duration("backend-flush-interval", time.Second, "how often queued writes are applied to the backend in write-behind mode")
+	backendQueueSize := flag.Int("backend-queue-size", 100000, "number of queued writes past which writes fail in write-behind mode, unlimited when zero")
+	originNegativeTTL := flag.Duration("origin-negative-ttl", 0, "how long failed loads from the origin, including those of missing keys, are remembered, not at all when zero")
+	flag.Parse()
+
+	if _, err := eviction.New(*policyName); err != nil {
+		log.Fatal(err)
+	}
+	syncPolicy, err := aof.ParseSyncPolicy(*aofSync)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *diskDir != "" && *maxBytes <= 0 {
+		log.Fatal("-disk-dir requires a memory budget set with -max-bytes")
+	}
+	if *originURL != "" && *originAddr != "" {
+		log.Fatal("only one of -origin-url and -origin-grpc may be set")
+	}
+	if *backendDir != "" && *backendSQLite != "" {
+		log.Fatal("only one of -backend-dir and -backend-sqlite may be set")
+	}
+	if *backendMode != "write-through" && *backendMode != "write-behind" {
+		log.Fatalf("unknown backend mode %q", *backendMode)
+	}
+
 	sock, err := net.Listen("tcp", ":5051")
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
@@ -82,13 +438,124 @@ func main() {
 	// create a new grpc server
 	s := grpc.NewServer()
 
-	// #TODO: create our new server. Make sure to provide it a sync.Map{}
+	// #TODO: create our new server. Make sure to provide it a store
+	sharded := store.NewSharded(*shards, *maxBytes, func() eviction.Policy {
+		policy, _ := eviction.New(*policyName)
+		return policy
+	})
+	var st cache = sharded
+	var tiered *store.Tiered
+	if *diskDir != "" {
+		tiered, err = store.NewTiered(sharded, *diskDir, *diskMaxBytes, *diskSegmentSize)
+		if err != nil {
+			log.Fatalf("failed to create disk tier: %v", err)
+		}
+		st = tiered
+		expvar.Publish("tiers", expvar.Func(func() interface{} {
+			return tiered.TierStats()
+		}))
+	}
 	srv := &server{
-		data: sync.Map{},
+		store:        st,
+		maxValueSize: *maxValueSize,
+		originLoads:  *originBatchLoads,
+	}
+
+	var loader origin.Loader
+	switch {
+	case *originURL != "":
+		loader, err = origin.NewHTTP(*originURL, *originTimeout, *maxValueSize)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case *originAddr != "":
+		originConn, err := grpc.Dial(*originAddr, grpc.WithInsecure())
+		if err != nil {
+			log.Fatalf("failed to dial origin: %v", err)
+		}
+		defer originConn.Close()
+		loader = origin.NewGRPC(cachelyv1.NewOriginAPIClient(originConn), *originTimeout)
+	}
+	if loader != nil {
+		srv.origin = origin.NewReadThrough(st, checkedLoader{loader}, *originTTL, *originNegativeTTL)
+		expvar.Publish("origin", expvar.Func(func() interface{} {
+			return srv.origin.Stats()
+		}))
 	}
 
+	// only the writes made by clients are forwarded to the backend, while
+	// those restoring the cache or filling it from the origin are not
+	var b backend.Backend
+	switch {
+	case *backendDir != "":
+		b, err = backend.OpenDir(*backendDir)
+		if err != nil {
+			log.Fatalf("failed to open backend: %v", err)
+		}
+	case *backendSQLite != "":
+		db, err := sqlite.Open(*backendSQLite)
+		if err != nil {
+			log.Fatalf("failed to open backend: %v", err)
+		}
+		defer db.Close()
+		b = db
+	}
+	var queue *backend.Queue
+	switch {
+	case b == nil:
+	case *backendMode == "write-behind":
+		queue = backend.NewQueue(b, *backendBatchSize, *backendFlushInterval, *backendQueueSize)
+		srv.store = backend.WriteBehind(st, queue)
+		expvar.Publish("backend", expvar.Func(func() interface{} {
+			return queue.Stats()
+		}))
+	default:
+		srv.store = backend.WriteThrough(st, b)
+	}
+
+	// the append-only log holds every write, so the snapshot is only needed
+	// when there is no log yet
+	replayed := *aofPath != "" && replayLog(*aofPath, st, *maxValueSize)
+	var sn *snapshotter
+	if *snapshotPath != "" {
+		sn = &snapshotter{path: *snapshotPath, store: st}
+		if !replayed {
+			loadSnapshot(*snapshotPath, st)
+		}
+	}
+
+	var wal *aof.Log
+	if *aofPath != "" {
+		wal, err = aof.Open(*aofPath, syncPolicy)
+		if err != nil {
+			log.Fatalf("failed to open append-only log: %v", err)
+		}
+		if !replayed {
+			// start the log with the entries restored from the snapshot
+			if err := wal.Compact(st); err != nil {
+				log.Fatalf("failed to write append-only log: %v", err)
+			}
+		}
+		// failures are reported to writers by the loggedStore, which does
+		// not acknowledge a write before the log has committed it
+		st.Notify(func(ev store.Event) {
+			wal.Append(ev)
+		})
+		srv.store = &loggedStore{Store: srv.store, log: wal}
+	}
+
+	if *watchHistory > 0 {
+		srv.changes = watch.NewLog(*watchHistory)
+		st.Notify(srv.changes.Record)
+	}
+	expvar.Publish("store", expvar.Func(func() interface{} {
+		return st.Stats()
+	}))
+
 	// #TODO: Register the new server by calling `cachely.RegisterCacheServer`
 	cachelyv1.RegisterCacheAPIServer(s, srv)
+	locks := lock.NewTable(uint64(time.Now().UnixNano()))
+	cachelyv1.RegisterLockAPIServer(s, &lockServer{locks: locks})
 
 	// enable reflection
 	reflection.Register(s)
@@ -97,9 +564,13 @@ func main() {
 	log.Printf("starting gRPC service on %s\n", sock.Addr())
 
 	// setup the gateway
-	mux := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithInsecure()}
-	err = cachelyv1.RegisterCacheAPIHandlerFromEndpoint(context.TODO(), mux, sock.Addr().String(), opts)
+	conn, err := grpc.Dial(sock.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		log.Println("err starting grpc gateway: err:", err)
+		return
+	}
+	defer conn.Close()
+	mux, err := newGatewayMux(context.TODO(), conn)
 	if err != nil {
 		log.Println("err starting grpc gateway: err:", err)
 		return
@@ -109,8 +580,8 @@ func main() {
 	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
 	wg := sync.WaitGroup{}
 
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 		// start listening and responding
 		if err := s.Serve(sock); err != nil {
@@ -118,16 +589,73 @@ func main() {
 		}
 	}()
 
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 
-		err := http.ListenAndServe(":8080", mux)
+		// expose store statistics such as evictions next to the gateway
+		httpMux := http.NewServeMux()
+		httpMux.Handle("/debug/vars", expvar.Handler())
+		if sn != nil {
+			httpMux.Handle("/debug/snapshot", sn)
+		}
+		httpMux.Handle("/", mux)
+
+		err := http.ListenAndServe(":8080", gatewayHandler(mux, httpMux))
 		if err != http.ErrServerClosed {
 			log.Fatalf("failed to serve: %v\n", err)
 		}
 	}()
 
+	done := make(chan struct{})
+	go reap(st, *reapInterval, done)
+	go reap(locks, *reapInterval, done)
+	if srv.origin != nil {
+		go reap(srv.origin, *reapInterval, done)
+	}
+	if sn != nil && *snapshotInterval > 0 {
+		go sn.run(*snapshotInterval, done)
+	}
+	if wal != nil {
+		go compactLog(wal, st, *aofCompactSize, 10*time.Second, done)
+	}
+	if tiered != nil {
+		go collectGarbage(tiered, 10*time.Second, done)
+	}
+
 	<-sig
 	log.Println("Shutdown signal received. Starting graceful shutdown")
+	close(done)
+	if sn != nil {
+		sn.Save()
+	}
+	if wal != nil {
+		wal.Close()
+	}
+	if tiered != nil {
+		tiered.Close()
+	}
+	if queue != nil {
+		if n := queue.Close(30 * time.Second); n > 0 {
+			log.Printf("gave up on %d writes that could not be applied to the backend\n", n)
+		}
+	}
+}
+
+// reap periodically removes expired entries so that their memory is reclaimed
+// even if they are never read again. It returns once done is closed.
+func reap(r store.Reaper, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case now := <-t.C:
+			if n := r.ReapExpired(now); n > 0 {
+				log.Printf("reaped %d expired entries\n", n)
+			}
+		case <-done:
+			return
+		}
+	}
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/lock"
+	"github.com/timraymond/cachely/store"
+)
+
+// serve starts a gRPC server on a local port with the services registered by
+// register, and returns a connection to it along with a function stopping
+// both.
+func serve(t *testing.T, register func(*grpc.Server)) (*grpc.ClientConn, func()) {
+	t.Helper()
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	go s.Serve(sock)
+
+	conn, err := grpc.Dial(sock.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		s.Stop()
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return conn, func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+// newTestServer returns a server over an empty unbounded store.
+func newTestServer() *server {
+	return &server{
+		store:        store.NewMemory(0, nil),
+		maxValueSize: 1 << 20,
+	}
+}
+
+// serveCache serves srv as the CacheAPI, and returns a client of it along
+// with a function stopping it.
+func serveCache(t *testing.T, srv *server) (cachelyv1.CacheAPIClient, func()) {
+	t.Helper()
+	conn, stop := serve(t, func(s *grpc.Server) {
+		cachelyv1.RegisterCacheAPIServer(s, srv)
+	})
+	return cachelyv1.NewCacheAPIClient(conn), stop
+}
+
+// serveGateway serves srv as the CacheAPI, along with a LockAPI, and the
+// gateway relaying HTTP requests to both. It returns a client of the CacheAPI
+// and the base URL of the gateway, along with a function stopping them.
+func serveGateway(t *testing.T, srv *server) (cachelyv1.CacheAPIClient, string, func()) {
+	t.Helper()
+	conn, stop := serve(t, func(s *grpc.Server) {
+		cachelyv1.RegisterCacheAPIServer(s, srv)
+		cachelyv1.RegisterLockAPIServer(s, &lockServer{locks: lock.NewTable(1)})
+	})
+	mux, err := newGatewayMux(context.Background(), conn)
+	if err != nil {
+		stop()
+		t.Fatalf("newGatewayMux: unexpected error: %v", err)
+	}
+	hs := httptest.NewServer(gatewayHandler(mux, http.NotFoundHandler()))
+	return cachelyv1.NewCacheAPIClient(conn), hs.URL, func() {
+		hs.Close()
+		stop()
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		ttl       *types.Duration
+		expiresAt *types.Timestamp
+		want      time.Time
+		code      codes.Code
+	}{
+		{"none", nil, nil, time.Time{}, codes.OK},
+		{"ttl", types.DurationProto(time.Minute), nil, now.Add(time.Minute), codes.OK},
+		{"expires_at", nil, &types.Timestamp{Seconds: now.Unix() + 3600}, now.Add(time.Hour), codes.OK},
+		{"both", types.DurationProto(time.Minute), &types.Timestamp{Seconds: now.Unix() + 3600}, time.Time{}, codes.InvalidArgument},
+		{"zero ttl", types.DurationProto(0), nil, time.Time{}, codes.InvalidArgument},
+		{"negative ttl", types.DurationProto(-time.Second), nil, time.Time{}, codes.InvalidArgument},
+		{"past expires_at", nil, &types.Timestamp{Seconds: now.Unix()}, time.Time{}, codes.InvalidArgument},
+		{"invalid ttl", &types.Duration{Seconds: 1, Nanos: -1}, nil, time.Time{}, codes.InvalidArgument},
+		{"overflowing ttl", &types.Duration{Seconds: 315576000000}, nil, time.Time{}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		got, err := expiration(tt.ttl, tt.expiresAt, now)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: want code %s, got %v", tt.name, tt.code, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: want expiration %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	// an expected version of zero inserts absent keys only
+	first, err := client.CompareAndSwap(ctx, &cachelyv1.CompareAndSwapRequest{Key: "k", Value: []byte("v1")})
+	if err != nil {
+		t.Fatalf("CompareAndSwap inserting: unexpected error: %v", err)
+	}
+	if first.GetVersion() == 0 {
+		t.Fatalf("CompareAndSwap inserting: want a version assigned")
+	}
+	_, err = client.CompareAndSwap(ctx, &cachelyv1.CompareAndSwapRequest{Key: "k", Value: []byte("v2")})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("CompareAndSwap inserting over an entry: want FailedPrecondition, got %v", err)
+	}
+
+	second, err := client.CompareAndSwap(ctx, &cachelyv1.CompareAndSwapRequest{Key: "k", ExpectedVersion: first.GetVersion(), Value: []byte("v2")})
+	if err != nil {
+		t.Fatalf("CompareAndSwap: unexpected error: %v", err)
+	}
+	if second.GetVersion() <= first.GetVersion() {
+		t.Errorf("CompareAndSwap: want a version above %d, got %d", first.GetVersion(), second.GetVersion())
+	}
+	if !second.GetCreatedAt().Equal(first.GetCreatedAt()) {
+		t.Errorf("CompareAndSwap: creation time changed from %v to %v", first.GetCreatedAt(), second.GetCreatedAt())
+	}
+
+	// the first version is stale now
+	_, err = client.CompareAndSwap(ctx, &cachelyv1.CompareAndSwapRequest{Key: "k", ExpectedVersion: first.GetVersion(), Value: []byte("v3")})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("CompareAndSwap at a stale version: want FailedPrecondition, got %v", err)
+	}
+	_, err = client.CompareAndSwap(ctx, &cachelyv1.CompareAndSwapRequest{Key: "missing", ExpectedVersion: 1})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("CompareAndSwap of a missing key: want NotFound, got %v", err)
+	}
+
+	got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "k"})
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if string(got.GetValue()) != "v2" || got.GetVersion() != second.GetVersion() {
+		t.Errorf("Get: want v2 at version %d, got %q at version %d", second.GetVersion(), got.GetValue(), got.GetVersion())
+	}
+}
+
+func TestDeleteExpectedVersion(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	put, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "k", Value: []byte("v")})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	_, err = client.Delete(ctx, &cachelyv1.DeleteRequest{Key: "k", ExpectedVersion: put.GetVersion() + 1})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("Delete at another version: want FailedPrecondition, got %v", err)
+	}
+	if _, err := client.Delete(ctx, &cachelyv1.DeleteRequest{Key: "k", ExpectedVersion: put.GetVersion()}); err != nil {
+		t.Errorf("Delete at the current version: unexpected error: %v", err)
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "k"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get after Delete: want NotFound, got %v", err)
+	}
+}
+
+func TestPutModes(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "present", Value: []byte("old")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		mode cachelyv1.WriteMode
+		code codes.Code
+	}{
+		{"present", cachelyv1.WriteMode_WRITE_MODE_INSERT, codes.AlreadyExists},
+		{"present", cachelyv1.WriteMode_WRITE_MODE_REPLACE, codes.OK},
+		{"present", cachelyv1.WriteMode_WRITE_MODE_UPSERT, codes.OK},
+		{"absent-insert", cachelyv1.WriteMode_WRITE_MODE_INSERT, codes.OK},
+		{"absent-replace", cachelyv1.WriteMode_WRITE_MODE_REPLACE, codes.NotFound},
+		{"absent-upsert", cachelyv1.WriteMode_WRITE_MODE_UPSERT, codes.OK},
+		{"present", cachelyv1.WriteMode(42), codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		value := []byte(tt.mode.String())
+		_, err := client.Put(ctx, &cachelyv1.PutRequest{Key: tt.key, Value: value, Mode: tt.mode})
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("Put(%q) with mode %s: want code %s, got %v", tt.key, tt.mode, tt.code, err)
+			continue
+		}
+
+		got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: tt.key})
+		switch {
+		case tt.code == codes.OK && err != nil:
+			t.Errorf("Get(%q): unexpected error: %v", tt.key, err)
+		case tt.code == codes.OK && string(got.GetValue()) != string(value):
+			t.Errorf("Put(%q) with mode %s: want value %q stored, got %q", tt.key, tt.mode, value, got.GetValue())
+		case tt.code != codes.OK && err == nil && string(got.GetValue()) == string(value):
+			t.Errorf("Put(%q) with mode %s failed but stored its value", tt.key, tt.mode)
+		}
+	}
+}
+
+func TestPutReplaceKeepsCreationTime(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	first, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "k", Value: []byte("v1"), Ttl: types.DurationProto(time.Hour)})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	second, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "k", Value: []byte("v2"), Mode: cachelyv1.WriteMode_WRITE_MODE_UPSERT})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if !second.GetCreatedAt().Equal(first.GetCreatedAt()) {
+		t.Errorf("upsert changed the creation time from %v to %v", first.GetCreatedAt(), second.GetCreatedAt())
+	}
+	if second.GetExpiresAt() != nil {
+		t.Errorf("upsert without a ttl kept the expiration %v", second.GetExpiresAt())
+	}
+	if second.GetVersion() <= first.GetVersion() {
+		t.Errorf("upsert: want a version above %d, got %d", first.GetVersion(), second.GetVersion())
+	}
+}
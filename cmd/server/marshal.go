@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+// gogoJSONPb is a gateway marshaler built on the gogo jsonpb package. The
+// gateway's default marshaler uses golang/protobuf's jsonpb, which cannot find
+// the enums registered by our gogo generated code, so enum names in request
+// bodies would be rejected.
+type gogoJSONPb struct {
+	m jsonpb.Marshaler
+	u jsonpb.Unmarshaler
+}
+
+func newGogoJSONPb() *gogoJSONPb {
+	return &gogoJSONPb{
+		m: jsonpb.Marshaler{OrigName: true},
+	}
+}
+
+// ContentType always returns "application/json".
+func (*gogoJSONPb) ContentType() string {
+	return "application/json"
+}
+
+// Marshal marshals v into JSON. Values that are not protobuf messages are
+// encoded with encoding/json.
+func (j *gogoJSONPb) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := j.encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal unmarshals JSON data into v.
+func (j *gogoJSONPb) Unmarshal(data []byte, v interface{}) error {
+	return j.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// NewDecoder returns a Decoder which reads a stream of JSON values from r.
+func (j *gogoJSONPb) NewDecoder(r io.Reader) runtime.Decoder {
+	d := json.NewDecoder(r)
+	return runtime.DecoderFunc(func(v interface{}) error {
+		if pb, ok := v.(proto.Message); ok {
+			return j.u.UnmarshalNext(d, pb)
+		}
+		return d.Decode(v)
+	})
+}
+
+// NewEncoder returns an Encoder which writes JSON values to w.
+func (j *gogoJSONPb) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		return j.encode(w, v)
+	})
+}
+
+// Delimiter separates the messages of a streamed response.
+func (*gogoJSONPb) Delimiter() []byte {
+	return []byte("\n")
+}
+
+func (j *gogoJSONPb) encode(w io.Writer, v interface{}) error {
+	if pb, ok := v.(proto.Message); ok {
+		return j.m.Marshal(w, pb)
+	}
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonType  = "application/json"
+	octetType = "application/octet-stream"
+)
+
+// mediaRange is one of the media ranges listed in an Accept header.
+type mediaRange struct {
+	typ, subtype string // either may be "*"
+	q            float64
+}
+
+// parseAccept parses the media ranges of an Accept header, skipping the ones
+// that are malformed. An empty header accepts anything.
+func parseAccept(header string) []mediaRange {
+	if strings.TrimSpace(header) == "" {
+		return []mediaRange{{typ: "*", subtype: "*", q: 1}}
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		slash := strings.IndexByte(mediaType, '/')
+		if slash < 0 {
+			continue
+		}
+		r := mediaRange{typ: mediaType[:slash], subtype: mediaType[slash+1:], q: 1}
+		if q, ok := params["q"]; ok {
+			if r.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality returns the quality the ranges give to contentType, taken from the
+// most specific range matching it. Content types that are not acceptable have
+// a quality of zero.
+func quality(ranges []mediaRange, contentType string) float64 {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0
+	}
+	slash := strings.IndexByte(mediaType, '/')
+	if slash < 0 {
+		return 0
+	}
+	typ, subtype := mediaType[:slash], mediaType[slash+1:]
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// mayPreferRaw reports whether the ranges could prefer a raw value over a
+// JSON response, that is whether they name any specific type other than JSON.
+func mayPreferRaw(ranges []mediaRange) bool {
+	for _, r := range ranges {
+		if r.q > 0 && r.typ != "*" && !(r.typ == "application" && r.subtype == "json") {
+			return true
+		}
+	}
+	return false
+}
+
+// rawContentType decides whether a value stored with contentType should be
+// returned raw to a client accepting ranges, rather than in a JSON response,
+// and if so with which content type. The raw value is preferred when it is
+// strictly more acceptable than JSON, so that clients accepting anything keep
+// getting JSON. Values are returned as application/octet-stream when their
+// own content type is unknown or less acceptable.
+func rawContentType(ranges []mediaRange, contentType string) (string, bool) {
+	qStored := 0.0
+	if contentType != "" {
+		qStored = quality(ranges, contentType)
+	}
+	qOctet := quality(ranges, octetType)
+	qJSON := quality(ranges, jsonType)
+
+	switch {
+	case qStored > qJSON && qStored >= qOctet:
+		return contentType, true
+	case qOctet > qJSON:
+		return octetType, true
+	}
+	return "", false
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestRawContentType(t *testing.T) {
+	tests := []struct {
+		accept, stored string
+		want           string
+		raw            bool
+	}{
+		{"", "image/png", "", false},
+		{"*/*", "image/png", "", false},
+		{"application/json", "image/png", "", false},
+		{"application/octet-stream", "", octetType, true},
+		{"application/octet-stream", "image/png", octetType, true},
+		{"image/png", "image/png", "image/png", true},
+		{"image/*", "image/png", "image/png", true},
+		{"image/png, application/octet-stream", "image/png", "image/png", true},
+		{"image/png;q=0.5, application/octet-stream", "image/png", octetType, true},
+		{"image/png;q=0.5, application/json", "image/png", "", false},
+		{"image/png, application/json;q=0.5", "image/png", "image/png", true},
+		{"image/png;q=0", "image/png", "", false},
+		{"text/plain", "image/png", "", false},
+		{"application/octet-stream;q=0.1, */*;q=0.2", "image/png", "", false},
+		{"not a media range, image/png", "image/png", "image/png", true},
+	}
+	for _, tt := range tests {
+		ranges := parseAccept(tt.accept)
+		got, raw := rawContentType(ranges, tt.stored)
+		if got != tt.want || raw != tt.raw {
+			t.Errorf("rawContentType(%q, %q): want %q and %v, got %q and %v", tt.accept, tt.stored, tt.want, tt.raw, got, raw)
+		}
+		if tt.raw && !mayPreferRaw(ranges) {
+			t.Errorf("mayPreferRaw(%q): want true, got false", tt.accept)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/status"
+
+	"github.com/timraymond/cachely/origin"
+	"github.com/timraymond/cachely/store"
+)
+
+// checkedLoader is an origin.Loader rejecting the entries whose metadata a
+// write could not set, since they are served back as HTTP headers just the
+// same.
+type checkedLoader struct {
+	origin.Loader
+}
+
+// Load loads the entry at key, failing if its metadata is invalid.
+func (l checkedLoader) Load(ctx context.Context, key string) (store.Entry, error) {
+	e, err := l.Loader.Load(ctx, key)
+	if err != nil {
+		return store.Entry{}, err
+	}
+	if err := validateMetadata(e.ContentType, e.ContentEncoding, e.Metadata); err != nil {
+		return store.Entry{}, fmt.Errorf("invalid entry: %s", status.Convert(err).Message())
+	}
+	return e, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/origin"
+	"github.com/timraymond/cachely/store"
+)
+
+// mapOrigin loads the values of a map, failing the keys it does not hold
+// with err, and counts the loads made.
+type mapOrigin struct {
+	values map[string]string
+	err    error
+	loads  int32
+}
+
+func (o *mapOrigin) Load(ctx context.Context, key string) (store.Entry, error) {
+	atomic.AddInt32(&o.loads, 1)
+	if value, ok := o.values[key]; ok {
+		return store.Entry{Value: []byte(value)}, nil
+	}
+	return store.Entry{}, o.err
+}
+
+// slowOrigin loads every key after a delay, and records the largest number
+// of loads it served at once.
+type slowOrigin struct {
+	active, peak int32
+}
+
+func (o *slowOrigin) Load(ctx context.Context, key string) (store.Entry, error) {
+	n := atomic.AddInt32(&o.active, 1)
+	defer atomic.AddInt32(&o.active, -1)
+	for {
+		peak := atomic.LoadInt32(&o.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&o.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return store.Entry{Value: []byte(key)}, nil
+}
+
+// entryOrigin loads the entries of a map.
+type entryOrigin map[string]store.Entry
+
+func (o entryOrigin) Load(ctx context.Context, key string) (store.Entry, error) {
+	e, ok := o[key]
+	if !ok {
+		return store.Entry{}, store.ErrNotFound
+	}
+	return e, nil
+}
+
+// newReadThroughServer returns a test server loading its misses from o.
+func newReadThroughServer(o origin.Loader) *server {
+	srv := newTestServer()
+	srv.origin = origin.NewReadThrough(srv.store, checkedLoader{o}, 0, 0)
+	return srv
+}
+
+func TestReadThrough(t *testing.T) {
+	o := &mapOrigin{values: map[string]string{"a": "1", "b": "2"}, err: store.ErrNotFound}
+	client, stop := serveCache(t, newReadThroughServer(o))
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "cached", Value: []byte("c")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "a"})
+	if err != nil || string(got.GetValue()) != "1" {
+		t.Errorf("Get of a key of the origin: want 1, got %q and %v", got.GetValue(), err)
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get of a key missing from the origin: want NotFound, got %v", err)
+	}
+	msgs, err := getStream(client, &cachelyv1.GetStreamRequest{Key: "b"})
+	if err != nil || len(msgs) != 1 || string(msgs[0].GetChunk()) != "2" {
+		t.Errorf("GetStream of a key of the origin: want 2, got %v and %v", msgs, err)
+	}
+
+	// List only sees the keys loaded so far
+	list, err := client.List(ctx, &cachelyv1.ListRequest{})
+	if err != nil {
+		t.Fatalf("List: unexpected error: %v", err)
+	}
+	var keys []string
+	for _, e := range list.GetEntries() {
+		keys = append(keys, e.GetKey())
+	}
+	if want := []string{"a", "b", "cached"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List: want %q, got %q", want, keys)
+	}
+	if o.loads != 3 {
+		t.Errorf("reads: want 3 loads, got %d", o.loads)
+	}
+}
+
+func TestBatchGetReadThrough(t *testing.T) {
+	o := &mapOrigin{values: map[string]string{"a": "1", "b": "2"}, err: store.ErrNotFound}
+	client, stop := serveCache(t, newReadThroughServer(o))
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "a", Value: []byte("cached")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	resp, err := client.BatchGet(ctx, &cachelyv1.BatchGetRequest{Keys: []string{"a", "b", "missing", "b"}})
+	if err != nil {
+		t.Fatalf("BatchGet: unexpected error: %v", err)
+	}
+	want := []struct {
+		value string
+		code  codes.Code
+	}{{"cached", codes.OK}, {"2", codes.OK}, {"", codes.NotFound}, {"2", codes.OK}}
+	for i, r := range resp.GetResults() {
+		if string(r.GetEntry().GetValue()) != want[i].value || codes.Code(r.GetError().GetCode()) != want[i].code {
+			t.Errorf("BatchGet result %d: want %q and %s, got %+v", i, want[i].value, want[i].code, r)
+		}
+	}
+	// keys held by the cache are not loaded, and repeated misses are loaded
+	// once
+	if o.loads != 2 {
+		t.Errorf("BatchGet: want 2 loads, got %d", o.loads)
+	}
+	if got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "b"}); err != nil || string(got.GetValue()) != "2" || o.loads != 2 {
+		t.Errorf("Get after BatchGet: want the loaded value stored, got %q, %v and %d loads", got.GetValue(), err, o.loads)
+	}
+}
+
+func TestBatchGetLoadLimit(t *testing.T) {
+	o := &slowOrigin{}
+	srv := newReadThroughServer(o)
+	srv.originLoads = 3
+	client, stop := serveCache(t, srv)
+	defer stop()
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		keys = append(keys, strconv.Itoa(i))
+	}
+	resp, err := client.BatchGet(context.Background(), &cachelyv1.BatchGetRequest{Keys: keys})
+	if err != nil {
+		t.Fatalf("BatchGet: unexpected error: %v", err)
+	}
+	for i, r := range resp.GetResults() {
+		if string(r.GetEntry().GetValue()) != keys[i] {
+			t.Errorf("BatchGet result %d: want %q, got %+v", i, keys[i], r)
+		}
+	}
+	if o.peak != 3 {
+		t.Errorf("BatchGet: want at most 3 loads at once, got %d", o.peak)
+	}
+}
+
+func TestReadThroughFailure(t *testing.T) {
+	o := &mapOrigin{err: errors.New("connection refused")}
+	client, stop := serveCache(t, newReadThroughServer(o))
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "k"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Get from an origin that is down: want Unavailable, got %v", err)
+	}
+	resp, err := client.BatchGet(ctx, &cachelyv1.BatchGetRequest{Keys: []string{"k"}})
+	if err != nil || codes.Code(resp.GetResults()[0].GetError().GetCode()) != codes.Unavailable {
+		t.Errorf("BatchGet from an origin that is down: want Unavailable for the key, got %+v and %v", resp, err)
+	}
+}
+
+func TestReadThroughInvalidEntry(t *testing.T) {
+	o := entryOrigin{
+		"valid":        {Value: []byte("v"), ContentType: "text/plain", Metadata: map[string]string{"owner": "team-a"}},
+		"content-type": {Value: []byte("v"), ContentType: "text/"},
+		"encoding":     {Value: []byte("v"), ContentEncoding: "gzip\r\nX-Injected: 1"},
+		"name":         {Value: []byte("v"), Metadata: map[string]string{"Owner": "team-a"}},
+		"value":        {Value: []byte("v"), Metadata: map[string]string{"owner": "team\na"}},
+		"size":         {Value: []byte("v"), Metadata: map[string]string{"owner": strings.Repeat("a", maxMetadataSize)}},
+	}
+	client, stop := serveCache(t, newReadThroughServer(o))
+	defer stop()
+	ctx := context.Background()
+
+	if got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "valid"}); err != nil || got.GetMetadata()["owner"] != "team-a" {
+		t.Errorf("Get of a valid entry: want its metadata, got %+v and %v", got, err)
+	}
+	for _, key := range []string{"content-type", "encoding", "name", "value", "size"} {
+		if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: key}); status.Code(err) != codes.Unavailable {
+			t.Errorf("Get of an entry with an invalid %s: want Unavailable, got %v", key, err)
+		}
+	}
+
+	// the entries rejected are not stored
+	list, err := client.List(ctx, &cachelyv1.ListRequest{})
+	if err != nil || len(list.GetEntries()) != 1 {
+		t.Errorf("List: want only the valid entry stored, got %+v and %v", list, err)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// maxRanges bounds the number of ranges served for a single request. Requests
+// for more are answered with the whole value.
+const maxRanges = 64
+
+// errUnsatisfiable is returned by parseRange when none of the requested
+// ranges overlap the value.
+var errUnsatisfiable = errors.New("no requested range overlaps the value")
+
+// byteRange is a part of a value, as the offset of its first byte and its
+// length.
+type byteRange struct {
+	start, length int64
+}
+
+// contentRange formats r as the value of a Content-Range header, for a value
+// of size bytes.
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// parseRange parses the Range header s for a value of size bytes. Ranges that
+// start past the end of the value are dropped, and those that go past it are
+// cut short. An error other than errUnsatisfiable means s is malformed, and
+// should be ignored.
+func parseRange(s string, size int64) ([]byteRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return nil, errors.New("invalid range unit")
+	}
+
+	var ranges []byteRange
+	specs := 0
+	for _, spec := range strings.Split(s[len(prefix):], ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		specs++
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid range %q", spec)
+		}
+		first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+		if first == "" {
+			// a suffix range, holding the last bytes of the value
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid range %q", spec)
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			ranges = append(ranges, byteRange{start: size - n, length: n})
+			continue
+		}
+
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid range %q", spec)
+		}
+		end := size - 1
+		if last != "" {
+			if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+				return nil, fmt.Errorf("invalid range %q", spec)
+			}
+			if end >= size {
+				end = size - 1
+			}
+		}
+		if start >= size {
+			continue
+		}
+		ranges = append(ranges, byteRange{start: start, length: end - start + 1})
+	}
+
+	switch {
+	case specs == 0:
+		return nil, errors.New("no range requested")
+	case len(ranges) == 0:
+		return nil, errUnsatisfiable
+	}
+	return ranges, nil
+}
+
+// requestedRanges returns the ranges of the raw value described by header
+// that req asks for, or none if the whole value should be sent. The Range
+// header is ignored when it is malformed, when it asks for more of the value
+// than there is, or when the If-Range header names another revision of the
+// entry than the one with the ETag etag. It returns errUnsatisfiable if none
+// of the ranges overlap the value.
+func requestedRanges(req *http.Request, header *cachelyv1.GetResponse, etag string) ([]byteRange, error) {
+	s := req.Header.Get("Range")
+	if s == "" || !ifRange(req, etag, header.GetModifiedAt()) {
+		return nil, nil
+	}
+
+	size := header.GetValueSize()
+	ranges, err := parseRange(s, size)
+	if err == errUnsatisfiable {
+		return nil, err
+	}
+	if err != nil || len(ranges) > maxRanges {
+		return nil, nil
+	}
+
+	// overlapping ranges are sent as they are, as long as they do not add up
+	// to more than the whole value
+	var total int64
+	for _, r := range ranges {
+		total += r.length
+	}
+	if total > size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// ifRange reports whether the If-Range header of req, if any, names the
+// revision of the entry with the ETag etag and last modified at modifiedAt.
+// Only strong ETags and exact modification times match.
+func ifRange(req *http.Request, etag string, modifiedAt *types.Timestamp) bool {
+	v := req.Header.Get("If-Range")
+	switch {
+	case v == "":
+		return true
+	case strings.HasPrefix(v, `"`):
+		return v == etag
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil || modifiedAt == nil {
+		return false
+	}
+	mod, err := types.TimestampFromProto(modifiedAt)
+	return err == nil && mod.Truncate(time.Second).Equal(t)
+}
+
+// sendValue answers req with the raw value of the given content type whose
+// GetStream has started with first, either whole or in the ranges asked for
+// by its Range header. Ranges are read with streams of their own, so stop is
+// called to cancel stream when they are sent instead. Errors are only
+// returned before the response has started.
+func sendValue(ctx context.Context, w http.ResponseWriter, req *http.Request, client cachelyv1.CacheAPIClient, stream cachelyv1.CacheAPI_GetStreamClient, first *cachelyv1.GetStreamResponse, stop context.CancelFunc, contentType string) error {
+	header := first.GetHeader()
+	ranges, err := requestedRanges(req, header, entityTag(header.GetVersion(), false))
+	switch {
+	case err == errUnsatisfiable:
+		stop()
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", header.GetValueSize()))
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return nil
+	case ranges == nil:
+		relayValue(w, stream, first, contentType)
+		return nil
+	}
+	stop()
+	return sendRanges(ctx, w, client, header, ranges, contentType)
+}
+
+// sendRanges answers a request for ranges of the raw value of the entry
+// described by header with 206 Partial Content. Several ranges are sent as a
+// multipart/byteranges body, each in a part of the given content type.
+func sendRanges(ctx context.Context, w http.ResponseWriter, client cachelyv1.CacheAPIClient, header *cachelyv1.GetResponse, ranges []byteRange, contentType string) error {
+	size := header.GetValueSize()
+	stream, first, err := openRange(ctx, client, header, ranges[0])
+	if err != nil {
+		return err
+	}
+
+	h := w.Header()
+	setEntryHeaders(h, header, entityTag(header.GetVersion(), false))
+	h.Set("Accept-Ranges", "bytes")
+	if encoding := header.GetContentEncoding(); encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+
+	if len(ranges) == 1 {
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Range", ranges[0].contentRange(size))
+		h.Set("Content-Length", strconv.FormatInt(ranges[0].length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		if err := copyValue(w, stream, first); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		return nil
+	}
+
+	mw := multipart.NewWriter(w)
+	h.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusPartialContent)
+	for i, r := range ranges {
+		if i > 0 {
+			// the status has been sent, so failures can only abort the
+			// response
+			if stream, first, err = openRange(ctx, client, header, r); err != nil {
+				panic(http.ErrAbortHandler)
+			}
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {contentType},
+			"Content-Range": {r.contentRange(size)},
+		})
+		if err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		if err := copyValue(part, stream, first); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+// openRange starts a GetStream of the range r of the value of the entry
+// described by header, failing with ABORTED if the entry has been written
+// since.
+func openRange(ctx context.Context, client cachelyv1.CacheAPIClient, header *cachelyv1.GetResponse, r byteRange) (cachelyv1.CacheAPI_GetStreamClient, *cachelyv1.GetStreamResponse, error) {
+	stream, first, err := openDownload(ctx, client, &cachelyv1.GetStreamRequest{
+		Key:    header.GetKey(),
+		Offset: r.start,
+		Length: r.length,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if first.GetHeader().GetVersion() != header.GetVersion() {
+		return nil, nil, status.Errorf(codes.Aborted, "cached item at %s changed while being read", header.GetKey())
+	}
+	return stream, first, nil
+}
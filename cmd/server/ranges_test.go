@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []byteRange
+		err    bool
+	}{
+		{"bytes=0-4", []byteRange{{0, 5}}, false},
+		{"bytes=2-", []byteRange{{2, 8}}, false},
+		{"bytes=-3", []byteRange{{7, 3}}, false},
+		{"bytes=-30", []byteRange{{0, 10}}, false},
+		{"bytes=5-100", []byteRange{{5, 5}}, false},
+		{"bytes=0-0, 2-3 ,-1", []byteRange{{0, 1}, {2, 2}, {9, 1}}, false},
+		{"bytes=0-1, 20-30", []byteRange{{0, 2}}, false},
+		{"bytes=10-", nil, true},
+		{"bytes=-0", nil, true},
+		{"bytes=3-2", nil, true},
+		{"bytes=a-b", nil, true},
+		{"bytes=-1-2", nil, true},
+		{"bytes=", nil, true},
+		{"items=0-1", nil, true},
+		{"bytes=1", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRange(tt.header, 10)
+		if (err != nil) != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRange(%q): want %v and an error %v, got %v and %v", tt.header, tt.want, tt.err, got, err)
+		}
+	}
+	if _, err := parseRange("bytes=10-", 10); err != errUnsatisfiable {
+		t.Errorf("parseRange past the end: want errUnsatisfiable, got %v", err)
+	}
+	if _, err := parseRange("bytes=0-", 0); err != errUnsatisfiable {
+		t.Errorf("parseRange of an empty value: want errUnsatisfiable, got %v", err)
+	}
+}
+
+func TestGetOffset(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "k", Value: []byte("0123456789")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	tests := []struct {
+		offset, length int64
+		want           string
+		code           codes.Code
+	}{
+		{0, 0, "0123456789", codes.OK},
+		{2, 3, "234", codes.OK},
+		{8, 0, "89", codes.OK},
+		{8, 5, "89", codes.OK},
+		{10, 0, "", codes.OK},
+		{11, 0, "", codes.OutOfRange},
+		{-1, 0, "", codes.InvalidArgument},
+		{0, -1, "", codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		resp, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "k", Offset: tt.offset, Length: tt.length})
+		if status.Code(err) != tt.code {
+			t.Errorf("Get(offset %d, length %d): want %s, got %v", tt.offset, tt.length, tt.code, err)
+			continue
+		}
+		if err == nil && (string(resp.GetValue()) != tt.want || resp.GetValueSize() != 10) {
+			t.Errorf("Get(offset %d, length %d): want %q of 10 bytes, got %q of %d", tt.offset, tt.length, tt.want, resp.GetValue(), resp.GetValueSize())
+		}
+	}
+}
+
+func TestGatewayOffset(t *testing.T) {
+	client, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	if _, err := client.Put(context.Background(), &cachelyv1.PutRequest{Key: "k", Value: []byte("0123456789"), ContentType: "text/plain"}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+
+	// the JSON entry is returned whether or not the Accept header names
+	// another type
+	for _, accept := range []string{"", "text/plain;q=0.5, application/json"} {
+		resp, body := do(t, "GET", base+"/cachely/v1/objects/k?offset=2&length=3", "", "Accept", accept)
+		var entry struct {
+			Value     []byte `json:"value"`
+			ValueSize string `json:"value_size"`
+		}
+		if err := json.Unmarshal(body, &entry); resp.StatusCode != http.StatusOK || err != nil {
+			t.Fatalf("GET with Accept %q: want status 200 and a JSON entry, got %d: %s", accept, resp.StatusCode, body)
+		}
+		if string(entry.Value) != "234" || entry.ValueSize != "10" {
+			t.Errorf("GET with Accept %q: want 234 of a 10 byte value, got %q of %s", accept, entry.Value, entry.ValueSize)
+		}
+
+		resp, body = do(t, "GET", base+"/cachely/v1/objects/k?offset=11", "", "Accept", accept)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET with Accept %q past the end: want status 400, got %d: %s", accept, resp.StatusCode, body)
+		}
+		resp, body = do(t, "GET", base+"/cachely/v1/objects/k?offset=x", "", "Accept", accept)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET with Accept %q and a malformed offset: want status 400, got %d: %s", accept, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	client, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+	put, err := client.Put(context.Background(), &cachelyv1.PutRequest{Key: "k", Value: []byte("0123456789"), ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	etag := entityTag(put.GetVersion(), false)
+
+	for _, path := range []string{"/cachely/v1/objects/k", "/cachely/v1/objects/k:stream"} {
+		url := base + path
+
+		resp, body := do(t, "GET", url, "", "Accept", "text/plain", "Range", "bytes=2-4")
+		if resp.StatusCode != http.StatusPartialContent || string(body) != "234" {
+			t.Errorf("GET %s of a range: want status 206 and 234, got %d and %q", path, resp.StatusCode, body)
+		}
+		if cr := resp.Header.Get("Content-Range"); cr != "bytes 2-4/10" {
+			t.Errorf("GET %s of a range: want Content-Range bytes 2-4/10, got %q", path, cr)
+		}
+		if resp.Header.Get("Accept-Ranges") != "bytes" || resp.Header.Get("Content-Type") != "text/plain" {
+			t.Errorf("GET %s of a range: want Accept-Ranges bytes and the stored type, got %v", path, resp.Header)
+		}
+
+		resp, body = do(t, "GET", url, "", "Accept", "text/plain", "Range", "bytes=0-1,-2")
+		mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if resp.StatusCode != http.StatusPartialContent || err != nil || mediaType != "multipart/byteranges" {
+			t.Fatalf("GET %s of ranges: want status 206 and multipart/byteranges, got %d and %q", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		mr := multipart.NewReader(strings.NewReader(string(body)), params["boundary"])
+		var parts []string
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			b, _ := ioutil.ReadAll(part)
+			parts = append(parts, part.Header.Get("Content-Range")+" "+part.Header.Get("Content-Type")+" "+string(b))
+		}
+		if want := []string{"bytes 0-1/10 text/plain 01", "bytes 8-9/10 text/plain 89"}; !reflect.DeepEqual(parts, want) {
+			t.Errorf("GET %s of ranges: want parts %q, got %q", path, want, parts)
+		}
+
+		resp, _ = do(t, "GET", url, "", "Accept", "text/plain", "Range", "bytes=10-")
+		if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable || resp.Header.Get("Content-Range") != "bytes */10" {
+			t.Errorf("GET %s of an unsatisfiable range: want status 416 and bytes */10, got %d and %q", path, resp.StatusCode, resp.Header.Get("Content-Range"))
+		}
+
+		lastModified := resp.Header.Get("Last-Modified")
+		whole := []struct {
+			name   string
+			header []string
+		}{
+			{"a malformed range", []string{"Range", "bytes=4-2"}},
+			{"ranges adding up to more than the value", []string{"Range", "bytes=0-8,1-9"}},
+			{"If-Range of another tag", []string{"Range", "bytes=2-4", "If-Range", entityTag(put.GetVersion()+1, false)}},
+			{"If-Range of a weak tag", []string{"Range", "bytes=2-4", "If-Range", "W/" + etag}},
+			{"If-Range of another date", []string{"Range", "bytes=2-4", "If-Range", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}},
+		}
+		for _, tt := range whole {
+			resp, body := do(t, "GET", url, "", append([]string{"Accept", "text/plain"}, tt.header...)...)
+			if resp.StatusCode != http.StatusOK || string(body) != "0123456789" {
+				t.Errorf("GET %s with %s: want status 200 and the whole value, got %d and %q", path, tt.name, resp.StatusCode, body)
+			}
+		}
+		for _, ifRange := range []string{etag, lastModified} {
+			resp, body := do(t, "GET", url, "", "Accept", "text/plain", "Range", "bytes=2-4", "If-Range", ifRange)
+			if resp.StatusCode != http.StatusPartialContent || string(body) != "234" {
+				t.Errorf("GET %s with If-Range %s: want status 206 and 234, got %d and %q", path, ifRange, resp.StatusCode, body)
+			}
+		}
+	}
+
+	// ranges only apply to raw values
+	resp, body := do(t, "GET", base+"/cachely/v1/objects/k", "", "Accept", "application/json", "Range", "bytes=2-4")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"value"`) {
+		t.Errorf("GET of the JSON entry with a range: want status 200 and the entry, got %d: %s", resp.StatusCode, body)
+	}
+}
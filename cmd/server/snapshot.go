@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/timraymond/cachely/snapshot"
+	"github.com/timraymond/cachely/store"
+)
+
+// loadSnapshot restores the snapshot at path into st, if there is one.
+func loadSnapshot(path string, st store.Store) {
+	n, err := snapshot.Load(path, st)
+	switch {
+	case os.IsNotExist(err):
+		log.Printf("no snapshot found at %s, starting empty\n", path)
+	case err != nil && n == 0:
+		log.Fatalf("failed to load snapshot: %v", err)
+	case err != nil:
+		log.Printf("restored %d keys from %s, some could not be restored: %v\n", n, path, err)
+	default:
+		log.Printf("restored %d keys from %s\n", n, path)
+	}
+}
+
+// snapshotter saves snapshots of a store to a single file, one at a time.
+type snapshotter struct {
+	mu    sync.Mutex
+	path  string
+	store store.Store
+}
+
+// Save writes a snapshot of the store, replacing the previous one.
+func (sn *snapshotter) Save() error {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+
+	start := time.Now()
+	n, err := snapshot.Save(sn.path, sn.store)
+	if err != nil {
+		log.Printf("failed to save snapshot to %s: %v\n", sn.path, err)
+		return err
+	}
+	log.Printf("saved %d keys to %s in %s\n", n, sn.path, time.Since(start))
+	return nil
+}
+
+// run saves a snapshot every interval until done is closed.
+func (sn *snapshotter) run(interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			sn.Save()
+		case <-done:
+			return
+		}
+	}
+}
+
+// ServeHTTP saves a snapshot on demand when sent a POST request.
+func (sn *snapshotter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err := sn.Save(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to save snapshot: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+)
+
+const (
+	// defaultChunkSize is the size of the chunks GetStream sends unless the
+	// request asks for another.
+	defaultChunkSize = 64 << 10
+
+	// maxChunkSize bounds the chunks GetStream sends, keeping its messages
+	// well under gRPC's default 4 MB limit.
+	maxChunkSize = 1 << 20
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// PutStream stores a value received in chunks once its checksum has been
+// verified. Values are buffered in full before they are stored, and may not
+// exceed the server's maximum value size.
+func (s *server) PutStream(stream cachelyv1.CacheAPI_PutStreamServer) error {
+	msg, err := stream.Recv()
+	if err == io.EOF {
+		return status.Errorf(codes.InvalidArgument, "stream ended before its header")
+	}
+	if err != nil {
+		return err
+	}
+	header := msg.GetHeader()
+	if header == nil {
+		return status.Errorf(codes.InvalidArgument, "the first message must carry the header")
+	}
+	if len(header.GetValue()) > 0 {
+		return status.Errorf(codes.InvalidArgument, "the value must be sent in chunks, not in the header")
+	}
+	key := header.GetKey()
+
+	log.Printf("Streaming value at key: %q\n", key)
+
+	// reject invalid writes before receiving their value
+	if _, err := putWrite(header, time.Now()); err != nil {
+		return err
+	}
+
+	var value bytes.Buffer
+	var sum *cachelyv1.Checksum
+	for first := true; ; first = false {
+		if !first && msg.GetHeader() != nil {
+			return status.Errorf(codes.InvalidArgument, "only the first message may carry a header")
+		}
+		if sum != nil {
+			return status.Errorf(codes.InvalidArgument, "the checksum must be sent in the last message")
+		}
+		if int64(value.Len()+len(msg.GetChunk())) > s.maxValueSize {
+			return status.Errorf(codes.ResourceExhausted, "value at %s exceeds %d bytes", key, s.maxValueSize)
+		}
+		value.Write(msg.GetChunk())
+		sum = msg.GetChecksum()
+
+		msg, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if sum == nil {
+		return status.Errorf(codes.InvalidArgument, "stream ended without a checksum")
+	}
+	if crc32.Checksum(value.Bytes(), castagnoli) != sum.GetCrc32C() {
+		return status.Errorf(codes.DataLoss, "value at %s does not match its checksum", key)
+	}
+
+	// the expiration is measured from when the value is stored
+	header.Value = value.Bytes()
+	w, err := putWrite(header, time.Now())
+	if err != nil {
+		return err
+	}
+	e, err := store.Apply(s.store, w)
+	if err != nil {
+		return storeError(err, key)
+	}
+	return stream.SendAndClose(putResponse(key, e))
+}
+
+// GetStream sends the value at the requested key, or the requested part of
+// it, in chunks, followed by its checksum.
+func (s *server) GetStream(req *cachelyv1.GetStreamRequest, stream cachelyv1.CacheAPI_GetStreamServer) error {
+	key := req.GetKey()
+	chunkSize := int(req.GetChunkSize())
+	switch {
+	case chunkSize == 0:
+		chunkSize = defaultChunkSize
+	case chunkSize < 0 || chunkSize > maxChunkSize:
+		return status.Errorf(codes.InvalidArgument, "chunk_size must be between 1 and %d, got %d", maxChunkSize, chunkSize)
+	}
+
+	log.Printf("streaming key %q\n", key)
+
+	e, err := s.read(stream.Context(), key)
+	if err != nil {
+		return readError(err, key)
+	}
+	value, err := valueRange(e.Value, req.GetOffset(), req.GetLength())
+	if err != nil {
+		return err
+	}
+
+	header := getResponse(key, e)
+	header.Value = nil
+	resp := &cachelyv1.GetStreamResponse{
+		Header:    header,
+		ValueSize: int64(len(e.Value)),
+	}
+	rest := value
+	for {
+		n := len(rest)
+		if n > chunkSize {
+			n = chunkSize
+		}
+		resp.Chunk, rest = rest[:n], rest[n:]
+		if len(rest) == 0 {
+			resp.Checksum = &cachelyv1.Checksum{Crc32C: crc32.Checksum(value, castagnoli)}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		if len(rest) == 0 {
+			return nil
+		}
+		resp = &cachelyv1.GetStreamResponse{}
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// putStream sends msgs over a PutStream call and returns its outcome.
+func putStream(client cachelyv1.CacheAPIClient, msgs ...*cachelyv1.PutStreamRequest) (*cachelyv1.PutResponse, error) {
+	stream, err := client.PutStream(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		// the server may fail the call before every message is sent, which
+		// CloseAndRecv reports
+		if err := stream.Send(msg); err != nil {
+			break
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+// chunked returns the messages sending value at key in chunks of n bytes,
+// ending with sum.
+func chunked(key string, value []byte, n int, sum uint32) []*cachelyv1.PutStreamRequest {
+	msgs := []*cachelyv1.PutStreamRequest{{Header: &cachelyv1.PutRequest{Key: key, ContentType: "text/plain"}}}
+	for len(value) > 0 {
+		if n > len(value) {
+			n = len(value)
+		}
+		msgs = append(msgs, &cachelyv1.PutStreamRequest{Chunk: value[:n]})
+		value = value[n:]
+	}
+	msgs[len(msgs)-1].Checksum = &cachelyv1.Checksum{Crc32C: sum}
+	return msgs
+}
+
+func checksum(b []byte) uint32 {
+	return crc32.Checksum(b, castagnoli)
+}
+
+func TestPutStream(t *testing.T) {
+	srv := newTestServer()
+	srv.maxValueSize = 16
+	client, stop := serveCache(t, srv)
+	defer stop()
+	ctx := context.Background()
+
+	value := []byte("0123456789")
+	resp, err := putStream(client, chunked("k", value, 3, checksum(value))...)
+	if err != nil {
+		t.Fatalf("PutStream: unexpected error: %v", err)
+	}
+	got, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "k"})
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if string(got.GetValue()) != string(value) || got.GetVersion() != resp.GetVersion() || got.GetContentType() != "text/plain" {
+		t.Errorf("Get: want %q of type text/plain at version %d, got %+v", value, resp.GetVersion(), got)
+	}
+
+	// an empty value is sent as a header and a checksum
+	if _, err := putStream(client, chunked("empty", nil, 3, checksum(nil))...); err != nil {
+		t.Errorf("PutStream of an empty value: unexpected error: %v", err)
+	}
+
+	header := func(key string) *cachelyv1.PutStreamRequest {
+		return &cachelyv1.PutStreamRequest{Header: &cachelyv1.PutRequest{Key: key}}
+	}
+	sum := &cachelyv1.Checksum{Crc32C: checksum([]byte("ab"))}
+	tests := []struct {
+		name string
+		msgs []*cachelyv1.PutStreamRequest
+		code codes.Code
+	}{
+		{"no message", nil, codes.InvalidArgument},
+		{"no header", []*cachelyv1.PutStreamRequest{{Chunk: []byte("ab"), Checksum: sum}}, codes.InvalidArgument},
+		{"value in the header", []*cachelyv1.PutStreamRequest{
+			{Header: &cachelyv1.PutRequest{Key: "bad", Value: []byte("ab")}, Checksum: sum},
+		}, codes.InvalidArgument},
+		{"invalid header", []*cachelyv1.PutStreamRequest{
+			{Header: &cachelyv1.PutRequest{Key: "bad", Ttl: &types.Duration{Seconds: -1}}, Checksum: sum},
+		}, codes.InvalidArgument},
+		{"second header", []*cachelyv1.PutStreamRequest{header("bad"), header("bad"), {Chunk: []byte("ab"), Checksum: sum}}, codes.InvalidArgument},
+		{"no checksum", []*cachelyv1.PutStreamRequest{header("bad"), {Chunk: []byte("ab")}}, codes.InvalidArgument},
+		{"checksum before the end", []*cachelyv1.PutStreamRequest{header("bad"), {Chunk: []byte("a"), Checksum: sum}, {Chunk: []byte("b")}}, codes.InvalidArgument},
+		{"checksum mismatch", chunked("bad", []byte("ab"), 1, checksum([]byte("ba"))), codes.DataLoss},
+		{"value too large", chunked("bad", make([]byte, 17), 4, checksum(make([]byte, 17))), codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		if _, err := putStream(client, tt.msgs...); status.Code(err) != tt.code {
+			t.Errorf("PutStream with %s: want %s, got %v", tt.name, tt.code, err)
+		}
+	}
+	if _, err := client.Get(ctx, &cachelyv1.GetRequest{Key: "bad"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get after the failed writes: want NotFound, got %v", err)
+	}
+
+	// the write mode of the header applies
+	msgs := chunked("k", value, 4, checksum(value))
+	msgs[0].Header.Mode = cachelyv1.WriteMode_WRITE_MODE_INSERT
+	if _, err := putStream(client, msgs...); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("PutStream inserting over an entry: want AlreadyExists, got %v", err)
+	}
+}
+
+// getStream reads a GetStream call to its end, and returns its messages.
+func getStream(client cachelyv1.CacheAPIClient, req *cachelyv1.GetStreamRequest) ([]*cachelyv1.GetStreamResponse, error) {
+	stream, err := client.GetStream(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []*cachelyv1.GetStreamResponse
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestGetStream(t *testing.T) {
+	client, stop := serveCache(t, newTestServer())
+	defer stop()
+	ctx := context.Background()
+
+	value := []byte("0123456789")
+	put, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "k", Value: value, ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		req    *cachelyv1.GetStreamRequest
+		chunks []string
+	}{
+		{&cachelyv1.GetStreamRequest{Key: "k"}, []string{"0123456789"}},
+		{&cachelyv1.GetStreamRequest{Key: "k", ChunkSize: 3}, []string{"012", "345", "678", "9"}},
+		{&cachelyv1.GetStreamRequest{Key: "k", ChunkSize: 5}, []string{"01234", "56789"}},
+		{&cachelyv1.GetStreamRequest{Key: "k", ChunkSize: 3, Offset: 2, Length: 5}, []string{"234", "56"}},
+		{&cachelyv1.GetStreamRequest{Key: "k", Offset: 8}, []string{"89"}},
+		{&cachelyv1.GetStreamRequest{Key: "k", Offset: 10}, []string{""}},
+		{&cachelyv1.GetStreamRequest{Key: "k", Length: 100}, []string{"0123456789"}},
+	}
+	for _, tt := range tests {
+		msgs, err := getStream(client, tt.req)
+		if err != nil {
+			t.Errorf("GetStream(%+v): unexpected error: %v", tt.req, err)
+			continue
+		}
+		var chunks []string
+		var sent []byte
+		for i, msg := range msgs {
+			chunks = append(chunks, string(msg.GetChunk()))
+			sent = append(sent, msg.GetChunk()...)
+			if (msg.GetHeader() != nil) != (i == 0) || (msg.GetValueSize() != 0) != (i == 0) {
+				t.Errorf("GetStream(%+v): want the header in the first message only, got it in message %d", tt.req, i)
+			}
+			if (msg.GetChecksum() != nil) != (i == len(msgs)-1) {
+				t.Errorf("GetStream(%+v): want the checksum in the last message only, got it in message %d", tt.req, i)
+			}
+		}
+		if !reflect.DeepEqual(chunks, tt.chunks) {
+			t.Errorf("GetStream(%+v): want chunks %q, got %q", tt.req, tt.chunks, chunks)
+			continue
+		}
+		first, last := msgs[0], msgs[len(msgs)-1]
+		if h := first.GetHeader(); h.GetVersion() != put.GetVersion() || h.GetContentType() != "text/plain" || len(h.GetValue()) != 0 {
+			t.Errorf("GetStream(%+v): want the header of the entry without its value, got %+v", tt.req, h)
+		}
+		if first.GetValueSize() != int64(len(value)) {
+			t.Errorf("GetStream(%+v): want value size %d, got %d", tt.req, len(value), first.GetValueSize())
+		}
+		if last.GetChecksum().GetCrc32C() != checksum(sent) {
+			t.Errorf("GetStream(%+v): want the checksum of the part sent", tt.req)
+		}
+	}
+
+	failures := []struct {
+		req  *cachelyv1.GetStreamRequest
+		code codes.Code
+	}{
+		{&cachelyv1.GetStreamRequest{Key: "missing"}, codes.NotFound},
+		{&cachelyv1.GetStreamRequest{Key: "k", ChunkSize: -1}, codes.InvalidArgument},
+		{&cachelyv1.GetStreamRequest{Key: "k", ChunkSize: maxChunkSize + 1}, codes.InvalidArgument},
+		{&cachelyv1.GetStreamRequest{Key: "k", Offset: -1}, codes.InvalidArgument},
+		{&cachelyv1.GetStreamRequest{Key: "k", Offset: 11}, codes.OutOfRange},
+	}
+	for _, tt := range failures {
+		if _, err := getStream(client, tt.req); status.Code(err) != tt.code {
+			t.Errorf("GetStream(%+v): want %s, got %v", tt.req, tt.code, err)
+		}
+	}
+}
+
+func TestStreamGateway(t *testing.T) {
+	client, base, stop := serveGateway(t, newTestServer())
+	defer stop()
+
+	// the body spans several chunks both ways
+	value := bytes.Repeat([]byte("0123456789abcdef"), 3*uploadChunkSize/16+1)
+	req, err := http.NewRequest("PUT", base+"/cachely/v1/objects/big:stream", bytes.NewReader(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT: unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT: want status 200, got %d", resp.StatusCode)
+	}
+	got, err := client.Get(context.Background(), &cachelyv1.GetRequest{Key: "big"})
+	if err != nil || !bytes.Equal(got.GetValue(), value) {
+		t.Fatalf("Get: want the %d byte value uploaded, got %d bytes and %v", len(value), len(got.GetValue()), err)
+	}
+
+	resp, err = http.Get(base + "/cachely/v1/objects/big:stream?chunk_size=1000")
+	if err != nil {
+		t.Fatalf("GET: unexpected error: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || !bytes.Equal(body, value) {
+		t.Errorf("GET: want status 200 and the %d byte value, got %d, %d bytes and %v", len(value), resp.StatusCode, len(body), err)
+	}
+	if n := resp.Header.Get("Content-Length"); n != strconv.Itoa(len(value)) {
+		t.Errorf("GET: want Content-Length %d, got %q", len(value), n)
+	}
+
+	resp, err = http.Get(base + "/cachely/v1/objects/missing:stream")
+	if err != nil {
+		t.Fatalf("GET: unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of a missing key: want status 404, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// cache is the store served, either a Sharded store or a Tiered store
+// extending it to disk.
+type cache interface {
+	store.Store
+	store.Notifier
+	store.Reaper
+}
+
+// collectGarbage periodically rewrites the disk segments of t that mostly hold
+// dead values. It returns once done is closed.
+func collectGarbage(t *store.Tiered, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := t.CollectGarbage()
+			if err != nil {
+				log.Printf("failed to collect disk tier garbage: %v\n", err)
+			}
+			if n > 0 {
+				log.Printf("reclaimed %d bytes of disk tier\n", n)
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+)
+
+// patternObjectStream matches /cachely/v1/objects/{key=**}:stream.
+var patternObjectStream = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2, 3, 0, 4, 1, 5, 3}, []string{"cachely", "v1", "objects", "key"}, "stream"))
+
+// uploadChunkSize is the size of the chunks request bodies are relayed to
+// PutStream in.
+const uploadChunkSize = 64 << 10
+
+// downloadObject serves GET /cachely/v1/objects/{key}:stream, relaying the
+// value read by GetStream as the raw response body. The chunk_size query
+// parameter is passed on to GetStream.
+func downloadObject(mux *runtime.ServeMux, client cachelyv1.CacheAPIClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		// parts of the value are requested with a Range header instead of
+		// offset and length, so that they are sent as such
+		protoReq := &cachelyv1.GetStreamRequest{Key: pathParams["key"]}
+		if err := runtime.PopulateQueryParameters(protoReq, req.URL.Query(), utilities.NewDoubleArray([][]string{{"key"}, {"offset"}, {"length"}})); err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+
+		dctx, stop := context.WithCancel(rctx)
+		defer stop()
+		stream, first, err := openDownload(dctx, client, protoReq)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		header := first.GetHeader()
+		if etag := entityTag(header.GetVersion(), false); notModified(req, etag, header.GetModifiedAt()) {
+			writeNotModified(w, header, etag)
+			return
+		}
+		contentType := header.GetContentType()
+		if contentType == "" {
+			contentType = octetType
+		}
+		if err := sendValue(rctx, w, req, client, stream, first, stop, contentType); err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+		}
+	}
+}
+
+// openDownload starts a GetStream, and receives its first message so that
+// failures are reported with a regular HTTP error.
+func openDownload(ctx context.Context, client cachelyv1.CacheAPIClient, protoReq *cachelyv1.GetStreamRequest) (cachelyv1.CacheAPI_GetStreamClient, *cachelyv1.GetStreamResponse, error) {
+	stream, err := client.GetStream(ctx, protoReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return nil, nil, err
+	}
+	return stream, first, nil
+}
+
+// relayValue writes the value received from stream, starting with its first
+// message, as the response body of the given content type. The status of the
+// response has been sent by the time the value is checked against its
+// checksum, so a value that is cut short or corrupt aborts the response
+// instead, leaving the client with less than the Content-Length it was
+// promised.
+func relayValue(w http.ResponseWriter, stream cachelyv1.CacheAPI_GetStreamClient, first *cachelyv1.GetStreamResponse, contentType string) {
+	header := first.GetHeader()
+	h := w.Header()
+	setEntryHeaders(h, header, entityTag(header.GetVersion(), false))
+	h.Set("Content-Type", contentType)
+	if encoding := header.GetContentEncoding(); encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	h.Set("Content-Length", strconv.FormatInt(first.GetValueSize(), 10))
+	h.Set("Accept-Ranges", "bytes")
+	w.WriteHeader(http.StatusOK)
+
+	if err := copyValue(w, stream, first); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// copyValue writes the value, or part of a value, received from stream,
+// starting with its first message, to dst, and checks it against its
+// checksum once it has been written.
+func copyValue(dst io.Writer, stream cachelyv1.CacheAPI_GetStreamClient, first *cachelyv1.GetStreamResponse) error {
+	crc := crc32.New(castagnoli)
+	msg := first
+	for {
+		crc.Write(msg.GetChunk())
+		if _, err := dst.Write(msg.GetChunk()); err != nil {
+			return err
+		}
+		if sum := msg.GetChecksum(); sum != nil {
+			if crc.Sum32() != sum.GetCrc32C() {
+				return status.Errorf(codes.DataLoss, "value at %s does not match its checksum", first.GetHeader().GetKey())
+			}
+			return nil
+		}
+
+		var err error
+		if msg, err = stream.Recv(); err == io.EOF {
+			return status.Errorf(codes.DataLoss, "value at %s ended without a checksum", first.GetHeader().GetKey())
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// readValue receives the rest of the value sent by stream, starting with its
+// first message, and returns the entry read.
+func readValue(stream cachelyv1.CacheAPI_GetStreamClient, first *cachelyv1.GetStreamResponse) (*cachelyv1.GetResponse, error) {
+	value := bytes.NewBuffer(make([]byte, 0, first.GetValueSize()))
+	if err := copyValue(value, stream, first); err != nil {
+		return nil, err
+	}
+	resp := first.GetHeader()
+	resp.Value = value.Bytes()
+	return resp, nil
+}
+
+// upsertRaw writes the body of req to the key in the path with PutStream,
+// sending it in chunks followed by its checksum, under the precondition p.
+func upsertRaw(ctx context.Context, client cachelyv1.CacheAPIClient, req *http.Request, pathParams map[string]string, p precondition) (*cachelyv1.PutResponse, runtime.ServerMetadata, error) {
+	var metadata runtime.ServerMetadata
+
+	header := &cachelyv1.PutRequest{
+		Key:             pathParams["key"],
+		Mode:            cachelyv1.WriteMode_WRITE_MODE_UPSERT,
+		ContentType:     req.Header.Get("Content-Type"),
+		ContentEncoding: req.Header.Get("Content-Encoding"),
+		Metadata:        requestMetadata(req.Header),
+	}
+	if err := populatePutQuery(header, req.URL.Query()); err != nil {
+		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	p.applyPut(header)
+
+	stream, err := client.PutStream(ctx, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	crc := crc32.New(castagnoli)
+	buf := make([]byte, uploadChunkSize)
+	msg := &cachelyv1.PutStreamRequest{Header: header}
+	for {
+		n, err := io.ReadFull(req.Body, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return nil, metadata, status.Errorf(codes.InvalidArgument, "reading request body: %v", err)
+		}
+		msg.Chunk = buf[:n]
+		crc.Write(msg.Chunk)
+		if last {
+			msg.Checksum = &cachelyv1.Checksum{Crc32C: crc.Sum32()}
+		}
+
+		if err := stream.Send(msg); err == io.EOF {
+			// the server gave up on the stream, and CloseAndRecv reports why
+			break
+		} else if err != nil {
+			return nil, metadata, err
+		}
+		if last {
+			break
+		}
+		msg = &cachelyv1.PutStreamRequest{}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	return resp, metadata, err
+}
+
+// populatePutQuery sets the options of a write from query parameters. Unlike
+// the generated routes, the mode can be given by name. The key, value and
+// metadata cannot be set from the query.
+func populatePutQuery(protoReq *cachelyv1.PutRequest, query url.Values) error {
+	if name := query.Get("mode"); name != "" {
+		mode, ok := cachelyv1.WriteMode_value[name]
+		if !ok {
+			n, err := strconv.Atoi(name)
+			if _, known := cachelyv1.WriteMode_name[int32(n)]; err != nil || !known {
+				return fmt.Errorf("unknown write mode %q", name)
+			}
+			mode = int32(n)
+		}
+		protoReq.Mode = cachelyv1.WriteMode(mode)
+	}
+	return runtime.PopulateQueryParameters(protoReq, query, utilities.NewDoubleArray([][]string{{"key"}, {"value"}, {"mode"}, {"content_type"}, {"content_encoding"}, {"metadata"}}))
+}
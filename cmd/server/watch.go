@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+	"github.com/timraymond/cachely/watch"
+)
+
+// revisionHeader is the response header in which Watch reports the latest
+// revision at the time the watch started.
+const revisionHeader = "cachely-revision"
+
+// eventTypes maps the changes reported by the store to their protobuf form.
+var eventTypes = map[store.EventType]cachelyv1.EventType{
+	store.EventPut:    cachelyv1.EventType_EVENT_TYPE_PUT,
+	store.EventUpdate: cachelyv1.EventType_EVENT_TYPE_UPDATE,
+	store.EventDelete: cachelyv1.EventType_EVENT_TYPE_DELETE,
+	store.EventExpire: cachelyv1.EventType_EVENT_TYPE_EXPIRE,
+	store.EventEvict:  cachelyv1.EventType_EVENT_TYPE_EVICT,
+}
+
+// Watch streams the changes made to the requested keys until the client goes
+// away. Clients that fall too far behind are disconnected with ABORTED and
+// can resume from the last revision they received.
+func (s *server) Watch(req *cachelyv1.WatchRequest, stream cachelyv1.CacheAPI_WatchServer) error {
+	if s.changes == nil {
+		return status.Errorf(codes.Unimplemented, "watching is disabled on this server")
+	}
+
+	filter, err := watchFilter(req)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("watching %q from revision %d\n", filter.Key, req.GetResumeRevision())
+
+	w, err := s.changes.Watch(filter, req.GetResumeRevision())
+	if err == watch.ErrRevisionUnavailable {
+		return status.Errorf(codes.OutOfRange, "revision %d is no longer available, the latest is %d", req.GetResumeRevision(), s.changes.Revision())
+	} else if err != nil {
+		return status.Errorf(codes.Internal, "could not watch: %v", err)
+	}
+	defer w.Stop()
+
+	// send the headers right away, so that clients know the watch is
+	// established before the first change is streamed
+	md := metadata.Pairs(revisionHeader, strconv.FormatUint(w.Revision(), 10))
+	if err := stream.SendHeader(md); err != nil {
+		return err
+	}
+
+	for {
+		e, err := w.Next(stream.Context())
+		switch {
+		case err == watch.ErrLagging:
+			return status.Errorf(codes.Aborted, "watcher fell too far behind")
+		case err != nil:
+			return status.FromContextError(err).Err()
+		}
+
+		if err := stream.Send(watchEvent(e)); err != nil {
+			return err
+		}
+	}
+}
+
+// watchFilter determines the keys a watch request follows.
+func watchFilter(req *cachelyv1.WatchRequest) (watch.Filter, error) {
+	if req.GetKey() != "" && req.GetPrefix() != "" {
+		return watch.Filter{}, status.Errorf(codes.InvalidArgument, "only one of key and prefix may be set")
+	}
+	if req.GetKey() != "" {
+		return watch.Filter{Key: req.GetKey()}, nil
+	}
+	return watch.Filter{Key: req.GetPrefix(), Prefix: true}, nil
+}
+
+// watchEvent converts a change to its protobuf form.
+func watchEvent(e watch.Event) *cachelyv1.WatchEvent {
+	return &cachelyv1.WatchEvent{
+		Revision:  e.Revision,
+		Type:      eventTypes[e.Type],
+		Key:       e.Key,
+		Version:   e.Version,
+		ExpiresAt: timestamp(e.ExpiresAt),
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+	"github.com/timraymond/cachely/watch"
+)
+
+// newWatchedServer returns a test server recording the changes to its store
+// for watchers.
+func newWatchedServer() *server {
+	srv := newTestServer()
+	srv.changes = watch.NewLog(100)
+	srv.store.(store.Notifier).Notify(srv.changes.Record)
+	return srv
+}
+
+func TestWatch(t *testing.T) {
+	client, stop := serveCache(t, newWatchedServer())
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "users/1", Value: []byte("a")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	stream, err := client.Watch(ctx, &cachelyv1.WatchRequest{Prefix: "users/"})
+	if err != nil {
+		t.Fatalf("Watch: unexpected error: %v", err)
+	}
+	md, err := stream.Header()
+	if err != nil {
+		t.Fatalf("Header: unexpected error: %v", err)
+	}
+	if rev := md.Get(revisionHeader); len(rev) != 1 || rev[0] != "1" {
+		t.Errorf("want revision 1 in the headers, got %q", rev)
+	}
+
+	put, err := client.Put(ctx, &cachelyv1.PutRequest{Key: "users/1", Value: []byte("b"), Mode: cachelyv1.WriteMode_WRITE_MODE_UPSERT})
+	if err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	client.Put(ctx, &cachelyv1.PutRequest{Key: "groups/1", Value: []byte("a")})
+	client.Delete(ctx, &cachelyv1.DeleteRequest{Key: "users/1"})
+
+	want := []*cachelyv1.WatchEvent{
+		{Revision: 2, Type: cachelyv1.EventType_EVENT_TYPE_UPDATE, Key: "users/1", Version: put.GetVersion()},
+		{Revision: 4, Type: cachelyv1.EventType_EVENT_TYPE_DELETE, Key: "users/1", Version: put.GetVersion()},
+	}
+	for _, w := range want {
+		e, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: unexpected error: %v", err)
+		}
+		if !proto.Equal(e, w) {
+			t.Errorf("want event %v, got %v", w, e)
+		}
+	}
+
+	// resuming replays what followed the given revision
+	resumed, err := client.Watch(ctx, &cachelyv1.WatchRequest{Key: "users/1", ResumeRevision: 1})
+	if err != nil {
+		t.Fatalf("Watch: unexpected error: %v", err)
+	}
+	for _, w := range want {
+		if e, err := resumed.Recv(); err != nil || e.GetRevision() != w.GetRevision() {
+			t.Errorf("resumed watch: want revision %d, got %v and %v", w.GetRevision(), e, err)
+		}
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	client, stop := serveCache(t, newWatchedServer())
+	defer stop()
+	ctx := context.Background()
+
+	tests := []struct {
+		req  *cachelyv1.WatchRequest
+		code codes.Code
+	}{
+		{&cachelyv1.WatchRequest{Key: "a", Prefix: "b"}, codes.InvalidArgument},
+		{&cachelyv1.WatchRequest{ResumeRevision: 5}, codes.OutOfRange},
+	}
+	for _, tt := range tests {
+		stream, err := client.Watch(ctx, tt.req)
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != tt.code {
+			t.Errorf("Watch(%v): want %s, got %v", tt.req, tt.code, err)
+		}
+	}
+
+	disabled, stop := serveCache(t, newTestServer())
+	defer stop()
+	stream, err := disabled.Watch(ctx, &cachelyv1.WatchRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Watch with watching disabled: want Unimplemented, got %v", err)
+	}
+}
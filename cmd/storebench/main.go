@@ -0,0 +1,119 @@
+// Command storebench compares the throughput of the store backends under mixed
+// read and write workloads, using a sync.Map backed store as the baseline.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+func main() {
+	keys := flag.Int("keys", 10000, "number of distinct keys in the workload")
+	shards := flag.Int("shards", 16, "number of shards in the sharded store")
+	ratios := flag.String("read-ratios", "90,50,10", "comma separated percentages of reads to benchmark")
+	flag.Parse()
+
+	backends := []struct {
+		name string
+		new  func() kv
+	}{
+		{"syncmap", func() kv { return &syncMap{} }},
+		{"memory", func() kv { return store.NewMemory(0, nil) }},
+		{"sharded", func() kv { return store.NewSharded(*shards, 0, nil) }},
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "READS\tSTORE\tOPS\tNS/OP")
+	for _, r := range strings.Split(*ratios, ",") {
+		reads, err := strconv.Atoi(strings.TrimSpace(r))
+		if err != nil || reads < 0 || reads > 100 {
+			fmt.Fprintf(os.Stderr, "invalid read ratio %q\n", r)
+			os.Exit(2)
+		}
+		for _, b := range backends {
+			res := testing.Benchmark(mixed(b.new(), *keys, reads))
+			fmt.Fprintf(w, "%d%%\t%s\t%d\t%d\n", reads, b.name, res.N, res.NsPerOp())
+		}
+	}
+	w.Flush()
+}
+
+// kv is the subset of store.Store exercised by the benchmarks.
+type kv interface {
+	Get(key string) (store.Entry, error)
+	Put(key string, e store.Entry) (store.Entry, error)
+	Delete(key string) error
+}
+
+// mixed returns a parallel benchmark in which reads percent of the operations
+// are Gets, and the rest alternate between inserting and deleting keys.
+func mixed(s kv, keys, reads int) func(*testing.B) {
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "key-" + strconv.Itoa(i)
+		if i%2 == 0 {
+			s.Put(names[i], store.Entry{Value: []byte(names[i])})
+		}
+	}
+
+	return func(b *testing.B) {
+		var seed int64
+		var mu sync.Mutex
+		b.RunParallel(func(pb *testing.PB) {
+			mu.Lock()
+			seed++
+			rng := rand.New(rand.NewSource(seed))
+			mu.Unlock()
+
+			for pb.Next() {
+				key := names[rng.Intn(len(names))]
+				if rng.Intn(100) < reads {
+					s.Get(key)
+					continue
+				}
+				if _, err := s.Put(key, store.Entry{Value: []byte(key)}); err == store.ErrExists {
+					s.Delete(key)
+				}
+			}
+		})
+	}
+}
+
+// syncMap keeps entries in a sync.Map, the way the server did before the Store
+// interface was introduced. Entries are not versioned.
+type syncMap struct {
+	data sync.Map
+}
+
+func (s *syncMap) Get(key string) (store.Entry, error) {
+	v, ok := s.data.Load(key)
+	if !ok || v.(store.Entry).Expired(time.Now()) {
+		return store.Entry{}, store.ErrNotFound
+	}
+	return v.(store.Entry), nil
+}
+
+func (s *syncMap) Put(key string, e store.Entry) (store.Entry, error) {
+	if _, loaded := s.data.LoadOrStore(key, e); loaded {
+		return store.Entry{}, store.ErrExists
+	}
+	return e, nil
+}
+
+func (s *syncMap) Delete(key string) error {
+	if _, ok := s.data.Load(key); !ok {
+		return store.ErrNotFound
+	}
+	s.data.Delete(key)
+	return nil
+}
@@ -0,0 +1,134 @@
+// Command tracebench replays a recorded key trace against each eviction policy
+// and reports the hit ratio a cache with the given memory budget would see.
+//
+// A trace holds one lookup per line: the key, optionally followed by the size
+// of its value in bytes. Keys that miss are inserted as if they had been
+// loaded from an origin.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/timraymond/cachely/eviction"
+)
+
+type request struct {
+	key  string
+	size int64
+}
+
+type result struct {
+	requests  int
+	hits      int
+	evictions int
+}
+
+func (r result) hitRatio() float64 {
+	if r.requests == 0 {
+		return 0
+	}
+	return float64(r.hits) / float64(r.requests)
+}
+
+func main() {
+	tracePath := flag.String("trace", "", "path to the key trace, - for stdin")
+	budget := flag.Int64("budget", 1000, "cache budget in bytes")
+	valueSize := flag.Int64("value-size", 1, "value size used for trace lines without one")
+	policies := flag.String("policies", strings.Join(eviction.Names, ","), "comma separated policies to replay")
+	flag.Parse()
+
+	if *tracePath == "" {
+		log.Fatal("a trace is required, see -trace")
+	}
+
+	in := os.Stdin
+	if *tracePath != "-" {
+		f, err := os.Open(*tracePath)
+		if err != nil {
+			log.Fatalf("failed to open trace: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	trace, err := readTrace(in, *valueSize)
+	if err != nil {
+		log.Fatalf("failed to read trace: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "POLICY\tREQUESTS\tHITS\tHIT RATIO\tEVICTIONS")
+	for _, name := range strings.Split(*policies, ",") {
+		p, err := eviction.New(strings.TrimSpace(name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		r := replay(p, *budget, trace)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f%%\t%d\n", name, r.requests, r.hits, 100*r.hitRatio(), r.evictions)
+	}
+	w.Flush()
+}
+
+// readTrace parses a trace, skipping blank lines and lines starting with #.
+func readTrace(r io.Reader, defaultSize int64) ([]request, error) {
+	var trace []request
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		req := request{key: fields[0], size: defaultSize}
+		if len(fields) > 1 {
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil || size < 0 {
+				return nil, fmt.Errorf("line %d: invalid size %q", line, fields[1])
+			}
+			req.size = size
+		}
+		trace = append(trace, req)
+	}
+	return trace, sc.Err()
+}
+
+// replay simulates a cache bounded to budget bytes that is governed by p.
+func replay(p eviction.Policy, budget int64, trace []request) result {
+	var r result
+	var used int64
+	cached := make(map[string]int64)
+
+	for _, req := range trace {
+		r.requests++
+		p.Access(req.key)
+		if _, ok := cached[req.key]; ok {
+			r.hits++
+			continue
+		}
+
+		if req.size > budget {
+			continue
+		}
+		for used+req.size > budget {
+			victim, ok := p.Evict()
+			if !ok {
+				break
+			}
+			used -= cached[victim]
+			delete(cached, victim)
+			r.evictions++
+		}
+		cached[req.key] = req.size
+		used += req.size
+		p.Add(req.key)
+	}
+	return r
+}
@@ -0,0 +1,102 @@
+package eviction
+
+// ARC implements the Adaptive Replacement Cache policy. It splits the tracked
+// keys between those seen once recently (t1) and those seen at least twice
+// (t2), and remembers recently evicted keys from each (b1 and b2) to adapt the
+// balance between the two. This keeps a one-off scan from flushing out keys
+// that are used repeatedly.
+//
+// ARC was designed for caches holding a fixed number of entries. Since the
+// size of a byte-bounded cache varies, the number of tracked keys stands in
+// for its capacity.
+type ARC struct {
+	t1, t2 *LRU
+	b1, b2 *LRU // ghost entries: recently evicted keys without values
+	p      int  // target length of t1
+}
+
+// NewARC returns an empty ARC policy.
+func NewARC() *ARC {
+	return &ARC{
+		t1: NewLRU(),
+		t2: NewLRU(),
+		b1: NewLRU(),
+		b2: NewLRU(),
+	}
+}
+
+// Add starts tracking key. Keys that were recently evicted are promoted
+// straight to the frequently used list, and shift the balance towards the list
+// they were evicted from.
+func (a *ARC) Add(key string) {
+	switch {
+	case a.t1.contains(key) || a.t2.contains(key):
+		a.Access(key)
+	case a.b1.contains(key):
+		a.p = min(a.p+max(a.b2.Len()/a.b1.Len(), 1), a.t1.Len()+a.t2.Len()+1)
+		a.b1.remove(key)
+		a.t2.Add(key)
+	case a.b2.contains(key):
+		a.p = max(a.p-max(a.b1.Len()/a.b2.Len(), 1), 0)
+		a.b2.remove(key)
+		a.t2.Add(key)
+	default:
+		a.t1.Add(key)
+	}
+}
+
+// Access promotes key to the frequently used list if it is tracked.
+func (a *ARC) Access(key string) {
+	if a.t1.remove(key) {
+		a.t2.Add(key)
+		return
+	}
+	a.t2.Access(key)
+}
+
+// Remove forgets key.
+func (a *ARC) Remove(key string) {
+	if !a.t1.remove(key) {
+		a.t2.remove(key)
+	}
+}
+
+// Evict gives up a key from whichever list exceeds its target length, and
+// remembers it as a ghost entry.
+func (a *ARC) Evict() (string, bool) {
+	var key string
+	switch {
+	case a.t1.Len() > 0 && (a.t1.Len() > a.p || a.t2.Len() == 0):
+		key, _ = a.t1.Evict()
+		a.b1.Add(key)
+	case a.t2.Len() > 0:
+		key, _ = a.t2.Evict()
+		a.b2.Add(key)
+	default:
+		return "", false
+	}
+
+	// keep no more ghost entries than there are tracked keys
+	for c := a.t1.Len() + a.t2.Len(); a.b1.Len()+a.b2.Len() > c; {
+		if a.b1.Len() > a.b2.Len() {
+			a.b1.Evict()
+		} else {
+			a.b2.Evict()
+		}
+	}
+	return key, true
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,53 @@
+package eviction
+
+import "testing"
+
+func TestARC(t *testing.T) {
+	a := NewARC()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		a.Add(key)
+	}
+	a.Access("a") // a is now frequently used
+
+	// keys seen once go first, even those added after a
+	if key, _ := a.Evict(); key != "b" {
+		t.Fatalf("want the least recently used key seen once evicted, got %q", key)
+	}
+
+	// b was evicted too early, which makes room for keys seen once
+	a.Add("b")
+	if a.p != 1 {
+		t.Errorf("want the target length of t1 raised to 1 by a hit in b1, got %d", a.p)
+	}
+	// t1 is held at its target length, so the frequently used keys go next
+	if got, want := evictAll(a), []string{"c", "a", "b", "d"}; !equal(got, want) {
+		t.Errorf("want eviction order %q, got %q", want, got)
+	}
+}
+
+func TestARCScanResistance(t *testing.T) {
+	a := NewARC()
+	hot := []string{"h1", "h2", "h3"}
+	for _, key := range hot {
+		a.Add(key)
+		a.Access(key)
+	}
+
+	// a scan of keys seen once, bounded to 10 keys, never displaces the keys
+	// used repeatedly
+	for i := 0; i < 100; i++ {
+		a.Add(string(rune('A' + i)))
+		if a.t1.Len()+a.t2.Len() > 10 {
+			key, _ := a.Evict()
+			for _, h := range hot {
+				if key == h {
+					t.Fatalf("scan evicted the frequently used key %q", key)
+				}
+			}
+		}
+	}
+	if a.b1.Len()+a.b2.Len() > a.t1.Len()+a.t2.Len() {
+		t.Errorf("want no more ghost entries than tracked keys, got %d ghosts for %d keys",
+			a.b1.Len()+a.b2.Len(), a.t1.Len()+a.t2.Len())
+	}
+}
@@ -0,0 +1,101 @@
+package eviction
+
+import "container/heap"
+
+// LFU evicts the least frequently used key. Ties are broken in favour of
+// keeping the more recently used key.
+type LFU struct {
+	items map[string]*lfuItem
+	heap  lfuHeap
+	clock uint64
+}
+
+type lfuItem struct {
+	key   string
+	hits  uint64
+	tick  uint64 // value of the clock at the last use
+	index int    // position in the heap
+}
+
+// NewLFU returns an empty LFU policy.
+func NewLFU() *LFU {
+	return &LFU{
+		items: make(map[string]*lfuItem),
+	}
+}
+
+// Add starts tracking key with a single use.
+func (l *LFU) Add(key string) {
+	if _, ok := l.items[key]; ok {
+		l.Access(key)
+		return
+	}
+	l.clock++
+	it := &lfuItem{key: key, hits: 1, tick: l.clock}
+	l.items[key] = it
+	heap.Push(&l.heap, it)
+}
+
+// Access counts a use of key if it is tracked.
+func (l *LFU) Access(key string) {
+	it, ok := l.items[key]
+	if !ok {
+		return
+	}
+	l.clock++
+	it.hits++
+	it.tick = l.clock
+	heap.Fix(&l.heap, it.index)
+}
+
+// Remove forgets key.
+func (l *LFU) Remove(key string) {
+	it, ok := l.items[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&l.heap, it.index)
+	delete(l.items, key)
+}
+
+// Evict gives up the least frequently used key.
+func (l *LFU) Evict() (string, bool) {
+	if len(l.heap) == 0 {
+		return "", false
+	}
+	it := heap.Pop(&l.heap).(*lfuItem)
+	delete(l.items, it.key)
+	return it.key, true
+}
+
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].hits != h[j].hits {
+		return h[i].hits < h[j].hits
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	it := x.(*lfuItem)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return it
+}
@@ -0,0 +1,21 @@
+package eviction
+
+import "testing"
+
+func TestLFU(t *testing.T) {
+	l := NewLFU()
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		l.Add(key)
+	}
+	l.Access("a")
+	l.Access("a")
+	l.Access("c")
+	l.Add("e") // adding a tracked key counts as a use
+	l.Access("missing")
+	l.Remove("d")
+
+	// b is the least used; c and e are tied, and c was used first
+	if got, want := evictAll(l), []string{"b", "c", "e", "a"}; !equal(got, want) {
+		t.Errorf("want eviction order %q, got %q", want, got)
+	}
+}
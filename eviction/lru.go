@@ -0,0 +1,77 @@
+package eviction
+
+import "container/list"
+
+// LRU evicts the least recently used key.
+type LRU struct {
+	ll    *list.List // most recently used keys at the front
+	items map[string]*list.Element
+}
+
+// NewLRU returns an empty LRU policy.
+func NewLRU() *LRU {
+	return &LRU{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Add marks key as the most recently used key.
+func (l *LRU) Add(key string) {
+	if el, ok := l.items[key]; ok {
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(key)
+}
+
+// Access marks key as the most recently used key if it is tracked.
+func (l *LRU) Access(key string) {
+	if el, ok := l.items[key]; ok {
+		l.ll.MoveToFront(el)
+	}
+}
+
+// Remove forgets key.
+func (l *LRU) Remove(key string) {
+	l.remove(key)
+}
+
+// Evict gives up the least recently used key.
+func (l *LRU) Evict() (string, bool) {
+	key, ok := l.back()
+	if ok {
+		l.remove(key)
+	}
+	return key, ok
+}
+
+// Len returns the number of tracked keys.
+func (l *LRU) Len() int {
+	return l.ll.Len()
+}
+
+func (l *LRU) contains(key string) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+// back returns the least recently used key without evicting it.
+func (l *LRU) back() (string, bool) {
+	el := l.ll.Back()
+	if el == nil {
+		return "", false
+	}
+	return el.Value.(string), true
+}
+
+// remove forgets key, reporting whether it was tracked.
+func (l *LRU) remove(key string) bool {
+	el, ok := l.items[key]
+	if !ok {
+		return false
+	}
+	l.ll.Remove(el)
+	delete(l.items, key)
+	return true
+}
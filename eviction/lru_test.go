@@ -0,0 +1,46 @@
+package eviction
+
+import "testing"
+
+// evictAll evicts every key tracked by p, in the order it gives them up.
+func evictAll(p Policy) []string {
+	var keys []string
+	for {
+		key, ok := p.Evict()
+		if !ok {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLRU(t *testing.T) {
+	l := NewLRU()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		l.Add(key)
+	}
+	l.Access("a")
+	l.Access("missing")
+	l.Add("c")
+	l.Remove("b")
+	l.Remove("missing")
+
+	if got, want := evictAll(l), []string{"d", "a", "c"}; !equal(got, want) {
+		t.Errorf("want eviction order %q, got %q", want, got)
+	}
+	if l.Len() != 0 {
+		t.Errorf("want no keys left tracked, got %d", l.Len())
+	}
+}
@@ -0,0 +1,45 @@
+// Package eviction provides the policies a size-bounded cache uses to decide
+// which entries to give up when it runs out of room.
+package eviction
+
+import "fmt"
+
+// Policy tracks the keys held by a cache and picks which of them to evict.
+// Implementations are not safe for concurrent use; the cache is expected to
+// serialize calls, typically under the same lock that guards its entries.
+type Policy interface {
+	// Add records that key has been inserted into the cache.
+	Add(key string)
+
+	// Access records a lookup of key. Lookups of keys that are not in the
+	// cache are reported too, so that policies which estimate popularity can
+	// learn about keys before they are admitted.
+	Access(key string)
+
+	// Remove forgets key after it has been deleted from the cache or has
+	// expired. Removing a key that is not tracked is a no-op.
+	Remove(key string)
+
+	// Evict chooses an entry to give up, stops tracking it and returns its
+	// key. It returns false if no keys are tracked.
+	Evict() (key string, ok bool)
+}
+
+// Names lists the policies that can be built with New.
+var Names = []string{"lru", "lfu", "arc", "wtinylfu"}
+
+// New builds the policy with the given name. See Names for the supported
+// policies.
+func New(name string) (Policy, error) {
+	switch name {
+	case "lru":
+		return NewLRU(), nil
+	case "lfu":
+		return NewLFU(), nil
+	case "arc":
+		return NewARC(), nil
+	case "wtinylfu":
+		return NewWTinyLFU(defaultSketchWidth), nil
+	}
+	return nil, fmt.Errorf("unknown eviction policy %q", name)
+}
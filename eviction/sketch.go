@@ -0,0 +1,80 @@
+package eviction
+
+import "hash/fnv"
+
+const (
+	sketchDepth        = 4
+	sketchMaxCount     = 15
+	defaultSketchWidth = 1 << 16
+)
+
+// sketch is a count-min sketch that estimates how often keys have been seen
+// using a fixed amount of memory. Counters saturate at sketchMaxCount, so
+// callers should periodically age the sketch to favour recent popularity.
+type sketch struct {
+	rows    [sketchDepth][]uint8
+	mask    uint64
+	samples int // increments since the counters were last halved
+}
+
+// newSketch returns a sketch with width counters per row. The width is
+// rounded up to a power of two.
+func newSketch(width int) *sketch {
+	w := 1
+	for w < width {
+		w *= 2
+	}
+	s := &sketch{
+		mask: uint64(w - 1),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+	return s
+}
+
+// index returns the counter used for key in row i. Rows are addressed with
+// double hashing so that key only needs to be hashed once.
+func (s *sketch) index(h uint64, i int) uint64 {
+	h1, h2 := h&0xffffffff, h>>32
+	return (h1 + uint64(i)*h2) & s.mask
+}
+
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// increment records an occurrence of key.
+func (s *sketch) increment(key string) {
+	h := hash(key)
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < sketchMaxCount {
+			*c++
+		}
+	}
+	s.samples++
+}
+
+// estimate returns an upper bound on the number of recent occurrences of key.
+func (s *sketch) estimate(key string) uint8 {
+	h := hash(key)
+	est := uint8(sketchMaxCount)
+	for i := range s.rows {
+		if c := s.rows[i][s.index(h, i)]; c < est {
+			est = c
+		}
+	}
+	return est
+}
+
+// age halves every counter.
+func (s *sketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	s.samples /= 2
+}
@@ -0,0 +1,145 @@
+package eviction
+
+const (
+	windowPercent    = 1  // share of tracked keys held by the admission window
+	protectedPercent = 80 // share of the main region reserved for reused keys
+
+	// the frequency sketch is aged after this many samples per tracked key,
+	// but never more often than every minSamples samples
+	samplesPerKey = 10
+	minSamples    = 1024
+)
+
+// WTinyLFU implements the W-TinyLFU policy. New keys enter a small LRU
+// admission window. Once the window is over its share of the cache, its least
+// recently used key has to compete with the eviction victim of the main
+// region, and it is only admitted if a frequency sketch estimates it to be
+// more popular. The main region is a segmented LRU: keys start on probation
+// and are protected once they are used again.
+//
+// Because every lookup is counted, including misses, keys touched once by a
+// scan rarely displace keys that are used repeatedly.
+type WTinyLFU struct {
+	window    *LRU
+	probation *LRU
+	protected *LRU
+	sketch    *sketch
+}
+
+// NewWTinyLFU returns an empty W-TinyLFU policy whose frequency sketch has
+// width counters per row. The width should be a few times larger than the
+// number of keys the cache is expected to hold.
+func NewWTinyLFU(width int) *WTinyLFU {
+	return &WTinyLFU{
+		window:    NewLRU(),
+		probation: NewLRU(),
+		protected: NewLRU(),
+		sketch:    newSketch(width),
+	}
+}
+
+// Add records an occurrence of key and places it in the admission window.
+func (w *WTinyLFU) Add(key string) {
+	if w.tracked(key) {
+		w.Access(key)
+		return
+	}
+	w.record(key)
+	w.window.Add(key)
+}
+
+// Access records an occurrence of key, and refreshes it if it is tracked. A
+// key on probation is moved to the protected segment.
+func (w *WTinyLFU) Access(key string) {
+	w.record(key)
+
+	switch {
+	case w.window.contains(key):
+		w.window.Access(key)
+	case w.probation.remove(key):
+		w.protected.Add(key)
+		// demote the least recently used protected keys if the segment
+		// has outgrown its share of the main region
+		limit := (w.probation.Len() + w.protected.Len()) * protectedPercent / 100
+		for w.protected.Len() > max(limit, 1) {
+			demoted, _ := w.protected.Evict()
+			w.probation.Add(demoted)
+		}
+	default:
+		w.protected.Access(key)
+	}
+}
+
+// Remove forgets key.
+func (w *WTinyLFU) Remove(key string) {
+	if !w.window.remove(key) && !w.probation.remove(key) {
+		w.protected.remove(key)
+	}
+}
+
+// Evict gives up a key. Once the admission window is over its share, its
+// least recently used key competes with the main region's victim, and the
+// loser is evicted. Otherwise the main region's victim is evicted.
+func (w *WTinyLFU) Evict() (string, bool) {
+	total := w.window.Len() + w.probation.Len() + w.protected.Len()
+	limit := max(total*windowPercent/100, 1)
+
+	// the window only grows far past its share while the cache is filling
+	// up, so hand the surplus over to the main region, keeping a single
+	// candidate for admission
+	for w.window.Len() > limit+1 {
+		key, _ := w.window.Evict()
+		w.probation.Add(key)
+	}
+
+	if w.window.Len() <= limit {
+		if key, ok := w.evictMain(); ok {
+			return key, true
+		}
+		return w.window.Evict()
+	}
+
+	candidate, _ := w.window.back()
+	victim, ok := w.mainVictim()
+	if !ok {
+		return w.window.Evict()
+	}
+
+	w.window.remove(candidate)
+	if w.sketch.estimate(candidate) > w.sketch.estimate(victim) {
+		w.probation.Add(candidate)
+		w.Remove(victim)
+		return victim, true
+	}
+	return candidate, true
+}
+
+// record counts an occurrence of key in the frequency sketch, aging the sketch
+// once it has seen enough samples for the number of tracked keys.
+func (w *WTinyLFU) record(key string) {
+	w.sketch.increment(key)
+
+	total := w.window.Len() + w.probation.Len() + w.protected.Len()
+	if w.sketch.samples >= max(samplesPerKey*total, minSamples) {
+		w.sketch.age()
+	}
+}
+
+func (w *WTinyLFU) tracked(key string) bool {
+	return w.window.contains(key) || w.probation.contains(key) || w.protected.contains(key)
+}
+
+// mainVictim returns the key the main region would evict next.
+func (w *WTinyLFU) mainVictim() (string, bool) {
+	if key, ok := w.probation.back(); ok {
+		return key, true
+	}
+	return w.protected.back()
+}
+
+func (w *WTinyLFU) evictMain() (string, bool) {
+	if key, ok := w.probation.Evict(); ok {
+		return key, true
+	}
+	return w.protected.Evict()
+}
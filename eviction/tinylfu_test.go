@@ -0,0 +1,85 @@
+package eviction
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWTinyLFUScanResistance(t *testing.T) {
+	w := NewWTinyLFU(1024)
+	const capacity = 20
+
+	tracked := 0
+	add := func(key string) {
+		w.Add(key)
+		if tracked++; tracked > capacity {
+			evicted, _ := w.Evict()
+			tracked--
+			if len(evicted) > 3 && evicted[:3] == "hot" {
+				t.Fatalf("evicted the frequently used key %q", evicted)
+			}
+		}
+	}
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("hot%d", i)
+		add(key)
+		for j := 0; j < 10; j++ {
+			w.Access(key)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		add(fmt.Sprintf("scan%d", i))
+	}
+
+	for i := 0; i < 5; i++ {
+		if key := fmt.Sprintf("hot%d", i); !w.tracked(key) {
+			t.Errorf("want %q still tracked after the scan", key)
+		}
+	}
+}
+
+func TestWTinyLFUAdmission(t *testing.T) {
+	w := NewWTinyLFU(1024)
+	for i := 0; i < 10; i++ {
+		w.Add(fmt.Sprintf("k%d", i))
+	}
+	// settle the keys in the main region, leaving a single key in the window
+	for w.window.Len() > 2 {
+		key, _ := w.window.Evict()
+		w.probation.Add(key)
+	}
+
+	// the key waiting in the window was seen more often than any key on
+	// probation, so it is admitted and the probation victim goes instead
+	candidate, _ := w.window.back()
+	for i := 0; i < 5; i++ {
+		w.record(candidate)
+	}
+	victim, _ := w.mainVictim()
+	if key, _ := w.Evict(); key != victim {
+		t.Errorf("want the probation victim %q evicted in favour of %q, got %q", victim, candidate, key)
+	}
+	if !w.probation.contains(candidate) {
+		t.Errorf("want %q admitted to probation", candidate)
+	}
+
+	// a key seen no more often than the victim is turned away
+	w.Add("new")
+	candidate, _ = w.window.back()
+	if key, _ := w.Evict(); key != candidate {
+		t.Errorf("want the window candidate %q evicted, got %q", candidate, key)
+	}
+}
+
+func TestWTinyLFUProtection(t *testing.T) {
+	w := NewWTinyLFU(1024)
+	w.probation.Add("a")
+	w.probation.Add("b")
+	w.Access("a")
+	if !w.protected.contains("a") {
+		t.Errorf("want a key used on probation protected")
+	}
+	if key, _ := w.evictMain(); key != "b" {
+		t.Errorf("want the probation key b evicted before protected ones, got %q", key)
+	}
+}
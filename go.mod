@@ -5,7 +5,9 @@ go 1.12
 require (
 	github.com/gogo/protobuf v1.2.1
 	github.com/golang/protobuf v1.2.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/grpc-ecosystem/grpc-gateway v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	google.golang.org/genproto v0.0.0-20180817151627-c66870c02cf8
 	google.golang.org/grpc v1.21.1
 )
@@ -0,0 +1,199 @@
+// Package lock hands out named locks held under leases, so that a lock whose
+// holder crashes is released once its lease expires instead of being held
+// forever.
+//
+// Every acquisition is issued a fencing token, greater than every token issued
+// before it. A holder passes its token along with the writes it makes to
+// other systems, which reject writes carrying a lower token than one they have
+// already seen, so that a holder whose lease expired while it was paused
+// cannot overwrite the work of the next one.
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHeld is returned when acquiring a lock that is held under a live
+	// lease.
+	ErrHeld = errors.New("lock: lock is held")
+
+	// ErrNotHeld is returned when renewing or releasing a lock with a token
+	// that is not the one of its live lease, either because the lease has
+	// expired or because the lock has been acquired again since.
+	ErrNotHeld = errors.New("lock: lock is not held with this token")
+)
+
+// Lease is the hold of an owner over a lock.
+type Lease struct {
+	Name      string
+	Owner     string // identifies the holder to other clients, may be empty
+	Token     uint64 // fencing token issued when the lock was acquired
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the lease no longer holds its lock at now.
+func (l Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// lease is a Lease held in a Table.
+type lease struct {
+	Lease
+	released chan struct{} // closed once the lease is released or renewed
+}
+
+// Table holds a set of named locks. It is safe for concurrent use. Leases
+// are only kept in memory, so they are lost when the process exits.
+type Table struct {
+	mu     sync.Mutex
+	leases map[string]*lease
+	token  uint64 // last fencing token issued
+}
+
+// NewTable returns a Table with no lock held, whose fencing tokens start
+// after start. Seeding start from the clock, as in
+// uint64(time.Now().UnixNano()), keeps the tokens of a restarted process
+// above those it issued before, as long as the clock does not go backwards.
+func NewTable(start uint64) *Table {
+	return &Table{
+		leases: make(map[string]*lease),
+		token:  start,
+	}
+}
+
+// TryAcquire takes the lock name for owner under a lease of ttl, which must be
+// positive, or returns ErrHeld along with the live lease of its holder. An
+// owner holding the lock already cannot take it again.
+func (t *Table) TryAcquire(name, owner string, ttl time.Duration) (Lease, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, _, err := t.tryAcquire(name, owner, ttl, time.Now())
+	return l, err
+}
+
+// tryAcquire is TryAcquire, also returning a channel closed once the lease of
+// the holder of a lock that is held changes. The caller must hold t.mu.
+func (t *Table) tryAcquire(name, owner string, ttl time.Duration, now time.Time) (Lease, <-chan struct{}, error) {
+	if ttl <= 0 {
+		panic("lock: non-positive lease duration")
+	}
+	if cur, ok := t.leases[name]; ok && !cur.Expired(now) {
+		return cur.Lease, cur.released, ErrHeld
+	}
+
+	t.token++
+	l := &lease{
+		Lease: Lease{
+			Name:      name,
+			Owner:     owner,
+			Token:     t.token,
+			ExpiresAt: now.Add(ttl),
+		},
+		released: make(chan struct{}),
+	}
+	t.release(name)
+	t.leases[name] = l
+	return l.Lease, nil, nil
+}
+
+// Acquire takes the lock name for owner like TryAcquire, waiting for as long
+// as ctx allows for the lock to be released or for the lease of its holder to
+// expire. It returns the error of ctx if it gives up. held, if not nil, is
+// called with the lease of the holder every time the lock is found held under
+// a new lease. Waiters are not served in any particular order.
+func (t *Table) Acquire(ctx context.Context, name, owner string, ttl time.Duration, held func(Lease)) (Lease, error) {
+	var seen uint64
+	for {
+		t.mu.Lock()
+		l, released, err := t.tryAcquire(name, owner, ttl, time.Now())
+		t.mu.Unlock()
+		if err == nil {
+			return l, nil
+		}
+
+		if held != nil && l.Token != seen {
+			held(l)
+			seen = l.Token
+		}
+		timer := time.NewTimer(time.Until(l.ExpiresAt))
+		select {
+		case <-released:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return Lease{}, ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// Renew extends the lease of the lock name held with token to ttl from now,
+// which must be positive, and returns the renewed lease. It returns
+// ErrNotHeld if the lease has expired or been released.
+func (t *Table) Renew(name string, token uint64, ttl time.Duration) (Lease, error) {
+	if ttl <= 0 {
+		panic("lock: non-positive lease duration")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	cur, ok := t.leases[name]
+	if !ok || cur.Token != token || cur.Expired(now) {
+		return Lease{}, ErrNotHeld
+	}
+
+	// waiters wait for the lease to expire, so they are woken up to wait
+	// for the new expiration instead
+	l := &lease{Lease: cur.Lease, released: make(chan struct{})}
+	l.ExpiresAt = now.Add(ttl)
+	t.release(name)
+	t.leases[name] = l
+	return l.Lease, nil
+}
+
+// Release frees the lock name held with token. It returns ErrNotHeld if the
+// lease has expired or been released already.
+func (t *Table) Release(name string, token uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cur, ok := t.leases[name]
+	if !ok || cur.Token != token || cur.Expired(time.Now()) {
+		return ErrNotHeld
+	}
+	t.release(name)
+	return nil
+}
+
+// release drops the lease of the lock name, if any, and wakes up the waiters
+// for it. The caller must hold t.mu.
+func (t *Table) release(name string) {
+	if cur, ok := t.leases[name]; ok {
+		close(cur.released)
+		delete(t.leases, name)
+	}
+}
+
+// ReapExpired drops the leases that have expired as of now, and returns the
+// number of leases dropped. Expired leases no longer hold their lock either
+// way, but are otherwise only dropped when their lock is acquired again.
+func (t *Table) ReapExpired(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for name, cur := range t.leases {
+		if cur.Expired(now) {
+			t.release(name)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,215 @@
+package lock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/lock"
+)
+
+func TestTryAcquire(t *testing.T) {
+	locks := lock.NewTable(100)
+
+	first, err := locks.TryAcquire("a", "alice", time.Minute)
+	if err != nil {
+		t.Fatalf("TryAcquire: unexpected error: %v", err)
+	}
+	if first.Name != "a" || first.Owner != "alice" || first.Token <= 100 || first.Expired(time.Now()) {
+		t.Errorf("TryAcquire: want a live lease of alice with a token above 100, got %+v", first)
+	}
+
+	for _, owner := range []string{"bob", "alice"} {
+		holder, err := locks.TryAcquire("a", owner, time.Minute)
+		if err != lock.ErrHeld || holder != first {
+			t.Errorf("TryAcquire by %s of a held lock: want ErrHeld and the lease of the holder, got %+v and %v", owner, holder, err)
+		}
+	}
+
+	// other locks are independent, and tokens grow across them
+	other, err := locks.TryAcquire("b", "bob", time.Minute)
+	if err != nil || other.Token <= first.Token {
+		t.Errorf("TryAcquire of another lock: want a token above %d, got %+v and %v", first.Token, other, err)
+	}
+
+	// an expired lease no longer holds its lock
+	short, err := locks.TryAcquire("c", "alice", time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire: unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	next, err := locks.TryAcquire("c", "bob", time.Minute)
+	if err != nil || next.Owner != "bob" || next.Token <= short.Token {
+		t.Errorf("TryAcquire of a lock whose lease expired: want a lease of bob with a token above %d, got %+v and %v", short.Token, next, err)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	locks := lock.NewTable(0)
+
+	l, err := locks.TryAcquire("a", "alice", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire: unexpected error: %v", err)
+	}
+	renewed, err := locks.Renew("a", l.Token, time.Minute)
+	if err != nil || renewed.Token != l.Token || !renewed.ExpiresAt.After(l.ExpiresAt) {
+		t.Fatalf("Renew: want the same token expiring later, got %+v and %v", renewed, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := locks.TryAcquire("a", "bob", time.Minute); err != lock.ErrHeld {
+		t.Errorf("TryAcquire past the first lease: want ErrHeld, got %v", err)
+	}
+
+	if _, err := locks.Renew("a", l.Token+1, time.Minute); err != lock.ErrNotHeld {
+		t.Errorf("Renew with another token: want ErrNotHeld, got %v", err)
+	}
+	if _, err := locks.Renew("missing", l.Token, time.Minute); err != lock.ErrNotHeld {
+		t.Errorf("Renew of a free lock: want ErrNotHeld, got %v", err)
+	}
+
+	short, err := locks.TryAcquire("b", "alice", time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire: unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := locks.Renew("b", short.Token, time.Minute); err != lock.ErrNotHeld {
+		t.Errorf("Renew of an expired lease: want ErrNotHeld, got %v", err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	locks := lock.NewTable(0)
+
+	l, err := locks.TryAcquire("a", "alice", time.Minute)
+	if err != nil {
+		t.Fatalf("TryAcquire: unexpected error: %v", err)
+	}
+	if err := locks.Release("a", l.Token+1); err != lock.ErrNotHeld {
+		t.Errorf("Release with another token: want ErrNotHeld, got %v", err)
+	}
+	if err := locks.Release("a", l.Token); err != nil {
+		t.Fatalf("Release: unexpected error: %v", err)
+	}
+	if err := locks.Release("a", l.Token); err != lock.ErrNotHeld {
+		t.Errorf("Release of a released lease: want ErrNotHeld, got %v", err)
+	}
+
+	next, err := locks.TryAcquire("a", "bob", time.Minute)
+	if err != nil || next.Token <= l.Token {
+		t.Fatalf("TryAcquire of a released lock: want a token above %d, got %+v and %v", l.Token, next, err)
+	}
+	// the former holder cannot touch the lease of the next one
+	if err := locks.Release("a", l.Token); err != lock.ErrNotHeld {
+		t.Errorf("Release with the token of a former holder: want ErrNotHeld, got %v", err)
+	}
+	if _, err := locks.Renew("a", l.Token, time.Minute); err != lock.ErrNotHeld {
+		t.Errorf("Renew with the token of a former holder: want ErrNotHeld, got %v", err)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	locks := lock.NewTable(0)
+	ctx := context.Background()
+
+	// a free lock is taken without waiting or seeing a holder
+	l, err := locks.Acquire(ctx, "a", "alice", time.Minute, func(lock.Lease) {
+		t.Error("Acquire of a free lock: want no holder seen")
+	})
+	if err != nil {
+		t.Fatalf("Acquire: unexpected error: %v", err)
+	}
+
+	// waiters are woken up by a release
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		locks.Release("a", l.Token)
+	}()
+	var held []lock.Lease
+	next, err := locks.Acquire(ctx, "a", "bob", time.Minute, func(holder lock.Lease) {
+		held = append(held, holder)
+	})
+	if err != nil || next.Owner != "bob" || next.Token <= l.Token {
+		t.Fatalf("Acquire of a released lock: want a lease of bob with a token above %d, got %+v and %v", l.Token, next, err)
+	}
+	if len(held) != 1 || held[0] != l {
+		t.Errorf("Acquire of a held lock: want the lease of the holder seen once, got %+v", held)
+	}
+
+	// and by the expiration of the lease, however often it is renewed
+	short, err := locks.TryAcquire("b", "alice", 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire: unexpected error: %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		locks.Renew("b", short.Token, 30*time.Millisecond)
+	}()
+	held = nil
+	start := time.Now()
+	if _, err := locks.Acquire(ctx, "b", "bob", time.Minute, func(holder lock.Lease) {
+		held = append(held, holder)
+	}); err != nil {
+		t.Fatalf("Acquire of a lock whose lease expires: unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Acquire of a lock whose lease is renewed: want it taken once the renewed lease expires, got it after %s", elapsed)
+	}
+	if len(held) != 1 || held[0].Token != short.Token {
+		t.Errorf("Acquire of a lock whose lease is renewed: want the holder seen once, got %+v", held)
+	}
+
+	// or give up
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := locks.Acquire(ctx, "b", "carol", time.Minute, nil); err != context.DeadlineExceeded {
+		t.Errorf("Acquire past its deadline: want DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestReapExpired(t *testing.T) {
+	locks := lock.NewTable(0)
+	now := time.Now()
+
+	for _, tt := range []struct {
+		name string
+		ttl  time.Duration
+	}{{"a", time.Second}, {"b", time.Minute}, {"c", time.Hour}} {
+		if _, err := locks.TryAcquire(tt.name, "", tt.ttl); err != nil {
+			t.Fatalf("TryAcquire(%s): unexpected error: %v", tt.name, err)
+		}
+	}
+	if n := locks.ReapExpired(now); n != 0 {
+		t.Errorf("ReapExpired before any lease expires: want 0, got %d", n)
+	}
+	if n := locks.ReapExpired(now.Add(2 * time.Minute)); n != 2 {
+		t.Errorf("ReapExpired: want 2, got %d", n)
+	}
+	if n := locks.ReapExpired(now.Add(2 * time.Minute)); n != 0 {
+		t.Errorf("ReapExpired again: want 0, got %d", n)
+	}
+	if _, err := locks.TryAcquire("c", "", time.Minute); err != lock.ErrHeld {
+		t.Errorf("TryAcquire of a lock that was not reaped: want ErrHeld, got %v", err)
+	}
+}
+
+func TestNonPositiveTTL(t *testing.T) {
+	locks := lock.NewTable(0)
+	l, err := locks.TryAcquire("a", "", time.Minute)
+	if err != nil {
+		t.Fatalf("TryAcquire: unexpected error: %v", err)
+	}
+
+	for name, f := range map[string]func(){
+		"TryAcquire": func() { locks.TryAcquire("b", "", 0) },
+		"Renew":      func() { locks.Renew("a", l.Token, -time.Second) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s with a non-positive ttl: want a panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,59 @@
+package origin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/store"
+)
+
+// GRPC loads entries from a backend implementing the OriginAPI service.
+type GRPC struct {
+	client  cachelyv1.OriginAPIClient
+	timeout time.Duration
+}
+
+// NewGRPC returns a GRPC loader calling client. Calls are given up after
+// timeout, if it is positive.
+func NewGRPC(client cachelyv1.OriginAPIClient, timeout time.Duration) *GRPC {
+	return &GRPC{client: client, timeout: timeout}
+}
+
+// Load calls the backend for the entry at key. A NOT_FOUND status means the
+// key is missing from the origin.
+func (g *GRPC) Load(ctx context.Context, key string) (store.Entry, error) {
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	resp, err := g.client.Load(ctx, &cachelyv1.LoadRequest{Key: key})
+	if status.Code(err) == codes.NotFound {
+		return store.Entry{}, store.ErrNotFound
+	}
+	if err != nil {
+		return store.Entry{}, err
+	}
+
+	e := store.Entry{
+		Value:           resp.GetValue(),
+		ContentType:     resp.GetContentType(),
+		ContentEncoding: resp.GetContentEncoding(),
+		Metadata:        resp.GetMetadata(),
+	}
+	if pb := resp.GetTtl(); pb != nil {
+		ttl, err := types.DurationFromProto(pb)
+		if err != nil || ttl <= 0 {
+			return store.Entry{}, fmt.Errorf("invalid ttl %v", pb)
+		}
+		e.ExpiresAt = time.Now().Add(ttl)
+	}
+	return e, nil
+}
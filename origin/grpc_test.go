@@ -0,0 +1,97 @@
+package origin_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cachelyv1 "github.com/timraymond/cachely/cachelyv1/cachely/v1"
+	"github.com/timraymond/cachely/origin"
+	"github.com/timraymond/cachely/store"
+)
+
+// originServer serves the entries of a map as an OriginAPI.
+type originServer map[string]*cachelyv1.LoadResponse
+
+func (s originServer) Load(ctx context.Context, req *cachelyv1.LoadRequest) (*cachelyv1.LoadResponse, error) {
+	switch req.GetKey() {
+	case "slow":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case "error":
+		return nil, status.Error(codes.Internal, "backend failure")
+	}
+	resp, ok := s[req.GetKey()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no key %s", req.GetKey())
+	}
+	return resp, nil
+}
+
+func TestGRPCLoad(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	cachelyv1.RegisterOriginAPIServer(s, originServer{
+		"users/42": {
+			Value:           []byte("profile"),
+			ContentType:     "text/plain",
+			ContentEncoding: "gzip",
+			Metadata:        map[string]string{"owner": "team-a"},
+			Ttl:             types.DurationProto(time.Minute),
+		},
+		"plain":   {Value: []byte("v")},
+		"bad-ttl": {Value: []byte("v"), Ttl: types.DurationProto(-time.Second)},
+	})
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	g := origin.NewGRPC(cachelyv1.NewOriginAPIClient(conn), 50*time.Millisecond)
+	ctx := context.Background()
+
+	before := time.Now()
+	e, err := g.Load(ctx, "users/42")
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if string(e.Value) != "profile" || e.ContentType != "text/plain" || e.ContentEncoding != "gzip" || !reflect.DeepEqual(e.Metadata, map[string]string{"owner": "team-a"}) {
+		t.Errorf("Load: want the entry of the origin, got %+v", e)
+	}
+	if e.ExpiresAt.Before(before.Add(time.Minute)) || e.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("Load: want the entry to expire after its ttl, got %v", e.ExpiresAt)
+	}
+	if e, err := g.Load(ctx, "plain"); err != nil || string(e.Value) != "v" || !e.ExpiresAt.IsZero() {
+		t.Errorf("Load without a ttl: want an entry that does not expire, got %+v and %v", e, err)
+	}
+
+	if _, err := g.Load(ctx, "missing"); err != store.ErrNotFound {
+		t.Errorf("Load of a missing key: want ErrNotFound, got %v", err)
+	}
+	failures := []struct {
+		key  string
+		code codes.Code
+	}{
+		{"error", codes.Internal},
+		{"slow", codes.DeadlineExceeded},
+		{"bad-ttl", codes.Unknown},
+	}
+	for _, tt := range failures {
+		if _, err := g.Load(ctx, tt.key); err == nil || status.Code(err) != tt.code {
+			t.Errorf("Load(%s): want a failure with code %s, got %v", tt.key, tt.code, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package origin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// keyPlaceholder is replaced by the key to load in the URL templates of HTTP
+// origins.
+const keyPlaceholder = "{key}"
+
+// metadataHeaderPrefix starts the names of the response headers carrying the
+// user-defined attributes of an entry, as served by the cachely gateway.
+const metadataHeaderPrefix = "Cachely-Meta-"
+
+// HTTP loads entries with GET requests to URLs built from a template.
+type HTTP struct {
+	template string
+	maxSize  int64
+	client   *http.Client
+}
+
+// NewHTTP returns an HTTP loader requesting the URL template with every
+// occurrence of {key} replaced by the key to load. Each segment of the key is
+// escaped as a path segment, while the slashes between them are kept, so
+// that the key a/b is loaded from http://origin/objects/a/b given the
+// template http://origin/objects/{key}. Requests are given up after timeout,
+// if it is positive, and values larger than maxSize bytes are rejected.
+//
+// A 200 OK response holds the value, with its Content-Type, Content-Encoding
+// and Cachely-Meta- prefixed headers stored along with it. The entry expires
+// after the max-age of its Cache-Control header, if any. Values are asked for
+// without compression, and those the origin encodes anyway are stored as
+// received, along with their encoding, rather than decoded. A 404 Not Found
+// or 410 Gone response means the key is missing from the origin, and every
+// other response fails the load.
+func NewHTTP(template string, timeout time.Duration, maxSize int64) (*HTTP, error) {
+	if !strings.Contains(template, keyPlaceholder) {
+		return nil, fmt.Errorf("origin: URL template %q does not contain %s", template, keyPlaceholder)
+	}
+	u, err := url.Parse(strings.Replace(template, keyPlaceholder, "key", -1))
+	if err != nil {
+		return nil, fmt.Errorf("origin: invalid URL template %q: %v", template, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("origin: URL template %q is not an http or https URL", template)
+	}
+
+	return &HTTP{
+		template: template,
+		maxSize:  maxSize,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Load requests the entry at key from the origin.
+func (h *HTTP) Load(ctx context.Context, key string) (store.Entry, error) {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	u := strings.Replace(h.template, keyPlaceholder, strings.Join(segments, "/"), -1)
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return store.Entry{}, err
+	}
+	// setting the header keeps the client from asking for gzip itself, and
+	// then decoding the value and dropping its Content-Encoding
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err := h.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return store.Entry{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return store.Entry{}, store.ErrNotFound
+	default:
+		return store.Entry{}, fmt.Errorf("origin responded with %s", resp.Status)
+	}
+	if resp.ContentLength > h.maxSize {
+		return store.Entry{}, errTooLarge(h.maxSize)
+	}
+
+	// one byte past the limit is read to tell values that reach it from those
+	// that exceed it
+	value, err := ioutil.ReadAll(io.LimitReader(resp.Body, h.maxSize+1))
+	if err != nil {
+		return store.Entry{}, err
+	}
+	if int64(len(value)) > h.maxSize {
+		return store.Entry{}, errTooLarge(h.maxSize)
+	}
+
+	e := store.Entry{
+		Value:           value,
+		ContentType:     resp.Header.Get("Content-Type"),
+		ContentEncoding: resp.Header.Get("Content-Encoding"),
+	}
+	for name, values := range resp.Header {
+		if len(name) <= len(metadataHeaderPrefix) || !strings.EqualFold(name[:len(metadataHeaderPrefix)], metadataHeaderPrefix) {
+			continue
+		}
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata[strings.ToLower(name[len(metadataHeaderPrefix):])] = strings.Join(values, ", ")
+	}
+	if maxAge, ok := cacheMaxAge(resp.Header.Get("Cache-Control")); ok {
+		e.ExpiresAt = time.Now().Add(maxAge)
+	}
+	return e, nil
+}
+
+// cacheMaxAge returns the positive max-age directive of the Cache-Control
+// header value s, if any.
+func cacheMaxAge(s string) (time.Duration, bool) {
+	for _, directive := range strings.Split(s, ",") {
+		directive = strings.TrimSpace(directive)
+		if len(directive) < len("max-age=") || !strings.EqualFold(directive[:len("max-age=")], "max-age=") {
+			continue
+		}
+		secs, err := strconv.ParseInt(directive[len("max-age="):], 10, 32)
+		if err != nil || secs <= 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	return 0, false
+}
+
+// errTooLarge is the error of loading a value larger than maxSize bytes.
+func errTooLarge(maxSize int64) error {
+	return fmt.Errorf("value exceeds %d bytes", maxSize)
+}
@@ -0,0 +1,127 @@
+package origin_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/origin"
+	"github.com/timraymond/cachely/store"
+)
+
+func TestNewHTTP(t *testing.T) {
+	tests := []struct {
+		template string
+		ok       bool
+	}{
+		{"http://origin/objects/{key}", true},
+		{"https://origin/{key}?format=raw", true},
+		{"http://origin/objects/", false},
+		{"ftp://origin/{key}", false},
+		{"/objects/{key}", false},
+		{"http://[::1/{key}", false},
+	}
+	for _, tt := range tests {
+		if _, err := origin.NewHTTP(tt.template, time.Second, 10); (err == nil) != tt.ok {
+			t.Errorf("NewHTTP(%q): want success %v, got %v", tt.template, tt.ok, err)
+		}
+	}
+}
+
+func TestHTTPLoad(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		paths = append(paths, req.URL.EscapedPath())
+		mu.Unlock()
+		if enc := req.Header.Get("Accept-Encoding"); enc != "identity" {
+			t.Errorf("Load: want Accept-Encoding: identity, got %q", enc)
+		}
+		switch strings.TrimPrefix(req.URL.Path, "/objects/") {
+		case "users/42 x?":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Header().Set("Cachely-Meta-Owner", "team-a")
+			w.Header().Add("cachely-meta-tags", "a")
+			w.Header().Add("cachely-meta-tags", "b")
+			w.Write([]byte("profile"))
+		case "plain":
+			w.Header().Set("Cache-Control", "max-age=x")
+			w.Write([]byte("v"))
+		case "large":
+			w.Write([]byte("0123456789a"))
+		case "streamed":
+			// flushing before the end leaves the length of the body unknown
+			w.Write([]byte("01234"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("56789a"))
+		case "limit":
+			w.Write([]byte("0123456789"))
+		case "gone":
+			w.WriteHeader(http.StatusGone)
+		case "slow":
+			time.Sleep(100 * time.Millisecond)
+		case "error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	h, err := origin.NewHTTP(ts.URL+"/objects/{key}", 50*time.Millisecond, 10)
+	if err != nil {
+		t.Fatalf("NewHTTP: unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	before := time.Now()
+	e, err := h.Load(ctx, "users/42 x?")
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	mu.Lock()
+	if want := "/objects/users/42%20x%3F"; paths[0] != want {
+		t.Errorf("Load: want the key escaped segment by segment as %s, got %s", want, paths[0])
+	}
+	mu.Unlock()
+	want := map[string]string{"owner": "team-a", "tags": "a, b"}
+	if string(e.Value) != "profile" || e.ContentType != "text/plain" || e.ContentEncoding != "gzip" || !reflect.DeepEqual(e.Metadata, want) {
+		t.Errorf("Load: want the value along with its headers, got %+v", e)
+	}
+	if e.ExpiresAt.Before(before.Add(time.Minute)) || e.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("Load: want the entry to expire after its max-age, got %v", e.ExpiresAt)
+	}
+
+	for _, key := range []string{"plain", "limit"} {
+		e, err := h.Load(ctx, key)
+		if err != nil || !e.ExpiresAt.IsZero() || e.Metadata != nil {
+			t.Errorf("Load(%s): want an entry without expiration or metadata, got %+v and %v", key, e, err)
+		}
+	}
+
+	failures := []struct {
+		key      string
+		notFound bool
+	}{
+		{"missing", true},
+		{"gone", true},
+		{"large", false},
+		{"streamed", false},
+		{"slow", false},
+		{"error", false},
+	}
+	for _, tt := range failures {
+		_, err := h.Load(ctx, tt.key)
+		if err == nil || (err == store.ErrNotFound) != tt.notFound {
+			t.Errorf("Load(%s): want a failure, ErrNotFound %v, got %v", tt.key, tt.notFound, err)
+		}
+	}
+}
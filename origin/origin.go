@@ -0,0 +1,183 @@
+// Package origin fills the misses of a store from the system of record it
+// caches, so that readers of a missing key do not each have to load it and
+// race to store it.
+//
+// Concurrent misses on a key are coalesced into a single load, whose result
+// is shared by every reader waiting for it. Failed loads can be remembered for
+// a while, so that a key missing from the origin, or an origin that is down,
+// is not hit by every read.
+package origin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// Loader loads the entries missing from a store from its origin.
+type Loader interface {
+	// Load returns the entry at key in the origin, or store.ErrNotFound if
+	// there is none. Entries without an expiration are given the default
+	// one of the ReadThrough.
+	Load(ctx context.Context, key string) (store.Entry, error)
+}
+
+// LoadError is returned when an entry could not be loaded from the origin.
+type LoadError struct {
+	Key string
+	Err error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("origin: loading %s: %v", e.Key, e.Err)
+}
+
+// Stats summarizes the activity of a ReadThrough.
+type Stats struct {
+	Loads        uint64 `json:"loads"`         // loads made from the origin
+	Coalesced    uint64 `json:"coalesced"`     // misses that waited for a load already in flight
+	Failures     uint64 `json:"failures"`      // loads that failed, including those of missing keys
+	NegativeHits uint64 `json:"negative_hits"` // misses answered with the remembered failure of a load
+}
+
+// ReadThrough reads entries from a store, loading those that are missing from
+// their origin and storing them before returning them. It is safe for
+// concurrent use.
+type ReadThrough struct {
+	store       store.Store
+	loader      Loader
+	ttl         time.Duration // lifetime of loaded entries without one, forever when zero
+	negativeTTL time.Duration // how long failed loads are remembered, not at all when zero
+
+	mu       sync.Mutex
+	loads    map[string]*load   // loads in flight
+	failures map[string]failure // failed loads being remembered
+	stats    Stats
+}
+
+// load is a load in flight, whose result is set before done is closed.
+type load struct {
+	done  chan struct{}
+	entry store.Entry
+	err   error
+}
+
+// failure is a failed load, remembered until its expiration.
+type failure struct {
+	err       error
+	expiresAt time.Time
+}
+
+// NewReadThrough returns a ReadThrough filling the misses of s with l. Loaded
+// entries without an expiration expire after ttl, or never when it is zero.
+// Failed loads, including those of keys missing from the origin, are
+// remembered for negativeTTL, or not at all when it is zero.
+func NewReadThrough(s store.Store, l Loader, ttl, negativeTTL time.Duration) *ReadThrough {
+	return &ReadThrough{
+		store:       s,
+		loader:      l,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		loads:       make(map[string]*load),
+		failures:    make(map[string]failure),
+	}
+}
+
+// GetOrLoad returns the entry at key, loading it from the origin if it is
+// missing from the store. It returns store.ErrNotFound if the key is missing
+// from the origin as well, and a *LoadError if the origin could not be read.
+// The load is carried on for other readers when ctx is done, in which case the
+// error of ctx is returned.
+func (rt *ReadThrough) GetOrLoad(ctx context.Context, key string) (store.Entry, error) {
+	e, err := rt.store.Get(key)
+	if err != store.ErrNotFound {
+		return e, err
+	}
+
+	rt.mu.Lock()
+	if f, ok := rt.failures[key]; ok {
+		if time.Now().Before(f.expiresAt) {
+			rt.stats.NegativeHits++
+			rt.mu.Unlock()
+			return store.Entry{}, f.err
+		}
+		delete(rt.failures, key)
+	}
+	l, ok := rt.loads[key]
+	if ok {
+		rt.stats.Coalesced++
+	} else {
+		l = &load{done: make(chan struct{})}
+		rt.loads[key] = l
+		rt.stats.Loads++
+		go rt.fill(key, l)
+	}
+	rt.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return l.entry, l.err
+	case <-ctx.Done():
+		return store.Entry{}, ctx.Err()
+	}
+}
+
+// fill loads key from the origin into the store and completes l with the
+// result. The load is not tied to the context of any reader, since it is
+// shared by all of them, so the Loader is expected to bound its duration.
+func (rt *ReadThrough) fill(key string, l *load) {
+	e, err := rt.loader.Load(context.Background(), key)
+	loaded := err == nil
+	switch {
+	case loaded:
+		if e.ExpiresAt.IsZero() && rt.ttl > 0 {
+			e.ExpiresAt = time.Now().Add(rt.ttl)
+		}
+		// the key may have been written since the miss, in which case the
+		// write wins over the origin
+		e, err = rt.store.Put(key, e)
+		if err == store.ErrExists {
+			e, err = rt.store.Get(key)
+		}
+	case err != store.ErrNotFound:
+		err = &LoadError{Key: key, Err: err}
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	delete(rt.loads, key)
+	if !loaded {
+		rt.stats.Failures++
+		if rt.negativeTTL > 0 {
+			rt.failures[key] = failure{err: err, expiresAt: time.Now().Add(rt.negativeTTL)}
+		}
+	}
+	l.entry, l.err = e, err
+	close(l.done)
+}
+
+// ReapExpired drops the failed loads remembered past their expiration as of
+// now, and returns the number of failures dropped.
+func (rt *ReadThrough) ReapExpired(now time.Time) int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	n := 0
+	for key, f := range rt.failures {
+		if !now.Before(f.expiresAt) {
+			delete(rt.failures, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Stats summarizes the activity of the ReadThrough.
+func (rt *ReadThrough) Stats() Stats {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.stats
+}
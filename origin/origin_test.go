@@ -0,0 +1,233 @@
+package origin_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/origin"
+	"github.com/timraymond/cachely/store"
+)
+
+// loaderFunc adapts a function to the origin.Loader interface.
+type loaderFunc func(ctx context.Context, key string) (store.Entry, error)
+
+func (f loaderFunc) Load(ctx context.Context, key string) (store.Entry, error) {
+	return f(ctx, key)
+}
+
+// values loads the values of a map, counting the loads made.
+type values struct {
+	m     map[string]string
+	loads int32
+}
+
+func (v *values) Load(ctx context.Context, key string) (store.Entry, error) {
+	atomic.AddInt32(&v.loads, 1)
+	value, ok := v.m[key]
+	if !ok {
+		return store.Entry{}, store.ErrNotFound
+	}
+	return store.Entry{Value: []byte(value)}, nil
+}
+
+func TestGetOrLoad(t *testing.T) {
+	s := store.NewMemory(0, nil)
+	if _, err := s.Put("cached", store.Entry{Value: []byte("c")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	v := &values{m: map[string]string{"cached": "origin", "a": "1"}}
+	rt := origin.NewReadThrough(s, v, time.Minute, 0)
+	ctx := context.Background()
+
+	e, err := rt.GetOrLoad(ctx, "cached")
+	if err != nil || string(e.Value) != "c" || v.loads != 0 {
+		t.Errorf("GetOrLoad of a cached key: want c without a load, got %q, %v and %d loads", e.Value, err, v.loads)
+	}
+
+	before := time.Now()
+	e, err = rt.GetOrLoad(ctx, "a")
+	if err != nil || string(e.Value) != "1" || e.Version == 0 {
+		t.Fatalf("GetOrLoad of a missing key: want the value of the origin stored, got %+v and %v", e, err)
+	}
+	if e.ExpiresAt.Before(before.Add(time.Minute)) || e.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("GetOrLoad: want the loaded entry to expire after the default ttl, got %v", e.ExpiresAt)
+	}
+	if stored, err := s.Get("a"); err != nil || stored.Version != e.Version {
+		t.Errorf("Get after GetOrLoad: want the loaded entry, got %+v and %v", stored, err)
+	}
+	if _, err := rt.GetOrLoad(ctx, "a"); err != nil || v.loads != 1 {
+		t.Errorf("GetOrLoad of a loaded key: want no other load, got %v and %d loads", err, v.loads)
+	}
+
+	if _, err := rt.GetOrLoad(ctx, "missing"); err != store.ErrNotFound {
+		t.Errorf("GetOrLoad of a key missing from the origin: want ErrNotFound, got %v", err)
+	}
+	// failures are not remembered without a negative ttl
+	rt.GetOrLoad(ctx, "missing")
+	if v.loads != 3 {
+		t.Errorf("GetOrLoad of a missing key again: want it loaded again, got %d loads", v.loads)
+	}
+	if _, err := s.Get("missing"); err != store.ErrNotFound {
+		t.Errorf("Get of a key missing from the origin: want ErrNotFound, got %v", err)
+	}
+
+	want := origin.Stats{Loads: 3, Failures: 2}
+	if got := rt.Stats(); got != want {
+		t.Errorf("Stats: want %+v, got %+v", want, got)
+	}
+}
+
+func TestLoadExpiration(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Round(0)
+	l := loaderFunc(func(ctx context.Context, key string) (store.Entry, error) {
+		return store.Entry{Value: []byte(key), ExpiresAt: expiresAt}, nil
+	})
+
+	// the expiration of the origin wins over the default one
+	e, err := origin.NewReadThrough(store.NewMemory(0, nil), l, time.Minute, 0).GetOrLoad(context.Background(), "k")
+	if err != nil || !e.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("GetOrLoad: want the entry to expire at %v, got %v and %v", expiresAt, e.ExpiresAt, err)
+	}
+
+	// entries never expire without a default ttl
+	v := &values{m: map[string]string{"k": "v"}}
+	e, err = origin.NewReadThrough(store.NewMemory(0, nil), v, 0, 0).GetOrLoad(context.Background(), "k")
+	if err != nil || !e.ExpiresAt.IsZero() {
+		t.Errorf("GetOrLoad without a ttl: want the entry not to expire, got %v and %v", e.ExpiresAt, err)
+	}
+}
+
+func TestLoadCoalescing(t *testing.T) {
+	release := make(chan struct{})
+	var loads int32
+	l := loaderFunc(func(ctx context.Context, key string) (store.Entry, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return store.Entry{Value: []byte("v")}, nil
+	})
+	rt := origin.NewReadThrough(store.NewMemory(0, nil), l, 0, 0)
+
+	const readers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, err := rt.GetOrLoad(context.Background(), "k")
+			if err == nil && string(e.Value) != "v" {
+				err = errors.New("wrong value " + string(e.Value))
+			}
+			errs <- err
+		}()
+	}
+	// the readers all wait for the first load before it completes
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if s := rt.Stats(); s.Loads+s.Coalesced == readers {
+			break
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GetOrLoad: unexpected error: %v", err)
+		}
+	}
+	if want := (origin.Stats{Loads: 1, Coalesced: readers - 1}); loads != 1 || rt.Stats() != want {
+		t.Errorf("GetOrLoad by concurrent readers: want a single load and stats %+v, got %d loads and %+v", want, loads, rt.Stats())
+	}
+}
+
+func TestLoadFailures(t *testing.T) {
+	down := errors.New("connection refused")
+	var loads int32
+	l := loaderFunc(func(ctx context.Context, key string) (store.Entry, error) {
+		atomic.AddInt32(&loads, 1)
+		if key == "missing" {
+			return store.Entry{}, store.ErrNotFound
+		}
+		return store.Entry{}, down
+	})
+	rt := origin.NewReadThrough(store.NewMemory(0, nil), l, 0, time.Hour)
+	ctx := context.Background()
+
+	_, err := rt.GetOrLoad(ctx, "k")
+	lerr, ok := err.(*origin.LoadError)
+	if !ok || lerr.Key != "k" || lerr.Err != down {
+		t.Fatalf("GetOrLoad from an origin that is down: want a LoadError of k, got %v", err)
+	}
+	if _, err := rt.GetOrLoad(ctx, "missing"); err != store.ErrNotFound {
+		t.Errorf("GetOrLoad of a key missing from the origin: want ErrNotFound, got %v", err)
+	}
+
+	// failures are remembered for the negative ttl
+	if _, err := rt.GetOrLoad(ctx, "k"); err != lerr {
+		t.Errorf("GetOrLoad of a failed key: want the remembered failure, got %v", err)
+	}
+	if _, err := rt.GetOrLoad(ctx, "missing"); err != store.ErrNotFound {
+		t.Errorf("GetOrLoad of a missing key: want the remembered ErrNotFound, got %v", err)
+	}
+	if want := (origin.Stats{Loads: 2, Failures: 2, NegativeHits: 2}); loads != 2 || rt.Stats() != want {
+		t.Errorf("GetOrLoad of failed keys: want stats %+v, got %d loads and %+v", want, loads, rt.Stats())
+	}
+
+	if n := rt.ReapExpired(time.Now()); n != 0 {
+		t.Errorf("ReapExpired before the failures expire: want 0, got %d", n)
+	}
+	if n := rt.ReapExpired(time.Now().Add(2 * time.Hour)); n != 2 {
+		t.Errorf("ReapExpired: want 2, got %d", n)
+	}
+	rt.GetOrLoad(ctx, "k")
+	if loads != 3 {
+		t.Errorf("GetOrLoad of a failed key once its failure expired: want it loaded again, got %d loads", loads)
+	}
+}
+
+func TestLoadCancellation(t *testing.T) {
+	release := make(chan struct{})
+	s := store.NewMemory(0, nil)
+	l := loaderFunc(func(ctx context.Context, key string) (store.Entry, error) {
+		<-release
+		return store.Entry{Value: []byte("origin")}, nil
+	})
+	rt := origin.NewReadThrough(s, l, 0, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := rt.GetOrLoad(ctx, "k"); err != context.DeadlineExceeded {
+		t.Errorf("GetOrLoad past its deadline: want DeadlineExceeded, got %v", err)
+	}
+
+	// the load is carried on for the other readers, and a write made
+	// meanwhile wins over it
+	result := make(chan store.Entry)
+	go func() {
+		e, err := rt.GetOrLoad(context.Background(), "k")
+		if err != nil {
+			t.Errorf("GetOrLoad: unexpected error: %v", err)
+		}
+		result <- e
+	}()
+	for deadline := time.Now().Add(time.Second); rt.Stats().Coalesced == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := s.Put("k", store.Entry{Value: []byte("written")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	close(release)
+	if e := <-result; string(e.Value) != "written" {
+		t.Errorf("GetOrLoad after a write raced a load: want the value written, got %q", e.Value)
+	}
+	if e, err := s.Get("k"); err != nil || string(e.Value) != "written" {
+		t.Errorf("Get after the load: want the value written, got %q and %v", e.Value, err)
+	}
+	if want := (origin.Stats{Loads: 1, Coalesced: 1}); rt.Stats() != want {
+		t.Errorf("Stats: want %+v, got %+v", want, rt.Stats())
+	}
+}
@@ -0,0 +1,417 @@
+// Package snapshot saves the contents of a store to disk and restores them,
+// so that a restarted cache does not start cold.
+//
+// A snapshot starts with the 4 byte magic "CSNP" and a big endian uint32
+// format version, followed by one record per entry and an end record:
+//
+//	entry:  tag (1 byte, 1, or 2 if the entry expires)
+//	        key length (uvarint) and key
+//	        value length (uvarint) and value
+//	        expiration seconds (varint) and nanoseconds (uvarint) since the
+//	        Unix epoch, only present for tag 2
+//	        content type and content encoding, each as a length (uvarint)
+//	        followed by the string
+//	        creation time, as seconds (varint) and nanoseconds (uvarint)
+//	        since the Unix epoch
+//	        number of user-defined attributes (uvarint), each as a name and
+//	        a value length-prefixed like the content type
+//	        CRC-32C of the record so far (big endian uint32)
+//	end:    tag (1 byte, 0)
+//	        number of entry records (uvarint)
+//	        CRC-32C of the snapshot before the end record (big endian uint32)
+//
+// Entry versions and modification times are not saved; restored entries are
+// assigned new ones by the store they are loaded into. Snapshots of format
+// version 1, whose entries end after their expiration, can still be read.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+var (
+	// ErrCorrupt is returned when reading a snapshot that is truncated or
+	// fails its checksums.
+	ErrCorrupt = errors.New("snapshot: corrupt snapshot")
+
+	// ErrFormat is returned when reading a file that is not a snapshot.
+	ErrFormat = errors.New("snapshot: not a snapshot")
+)
+
+// Version is the format version written by this package.
+const Version = 2
+
+var magic = []byte("CSNP")
+
+const (
+	tagEnd           = 0
+	tagEntry         = 1
+	tagExpiringEntry = 2
+)
+
+// maxFieldSize bounds the length of keys and values, so that a corrupt
+// length is detected before it is allocated.
+const maxFieldSize = 1 << 30
+
+var table = crc32.MakeTable(crc32.Castagnoli)
+
+// Write encodes the live entries of s to w and returns the number of entries
+// written. The entries are collected before any is written, so that writers
+// are not held up by a slow w, but stores that lock their keys in several
+// parts may be captured at slightly different moments.
+func Write(w io.Writer, s store.Store) (int, error) {
+	var entries []store.KeyEntry
+	s.Range(func(key string, e store.Entry) bool {
+		entries = append(entries, store.KeyEntry{Key: key, Entry: e})
+		return true
+	})
+
+	bw := bufio.NewWriter(w)
+	var sum uint32
+	write := func(p []byte) error {
+		sum = crc32.Update(sum, table, p)
+		_, err := bw.Write(p)
+		return err
+	}
+
+	header := make([]byte, len(magic)+4)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], Version)
+	if err := write(header); err != nil {
+		return 0, err
+	}
+
+	var rec []byte
+	for _, ke := range entries {
+		rec = appendEntry(rec[:0], ke.Key, ke.Entry)
+		if err := write(rec); err != nil {
+			return 0, err
+		}
+	}
+
+	rec = append(rec[:0], tagEnd)
+	rec = appendUvarint(rec, uint64(len(entries)))
+	rec = appendUint32(rec, sum)
+	if err := write(rec); err != nil {
+		return 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// appendEntry appends the record for the entry e at key to rec.
+func appendEntry(rec []byte, key string, e store.Entry) []byte {
+	if e.ExpiresAt.IsZero() {
+		rec = append(rec, tagEntry)
+	} else {
+		rec = append(rec, tagExpiringEntry)
+	}
+	rec = appendUvarint(rec, uint64(len(key)))
+	rec = append(rec, key...)
+	rec = appendUvarint(rec, uint64(len(e.Value)))
+	rec = append(rec, e.Value...)
+	if !e.ExpiresAt.IsZero() {
+		rec = appendTime(rec, e.ExpiresAt)
+	}
+	rec = appendString(rec, e.ContentType)
+	rec = appendString(rec, e.ContentEncoding)
+	rec = appendTime(rec, e.CreatedAt)
+	rec = appendUvarint(rec, uint64(len(e.Metadata)))
+	for name, value := range e.Metadata {
+		rec = appendString(rec, name)
+		rec = appendString(rec, value)
+	}
+	return appendUint32(rec, crc32.Checksum(rec, table))
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendTime(b []byte, t time.Time) []byte {
+	b = appendVarint(b, t.Unix())
+	return appendUvarint(b, uint64(t.Nanosecond()))
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// Read decodes a snapshot from r and stores its entries in s, replacing the
+// entries already at their keys. Entries that have expired as of now are
+// skipped. The whole snapshot is verified before s is modified, so a corrupt
+// snapshot leaves s untouched. Read returns the number of entries restored,
+// along with the first error met restoring them.
+func Read(r io.Reader, s store.Store, now time.Time) (int, error) {
+	writes, err := decode(&reader{r: bufio.NewReader(r)}, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// entries that cannot be restored, for instance because the store has
+	// been given a smaller budget, do not prevent the others from being
+	// restored
+	n := 0
+	for i, res := range store.PutBatch(s, writes) {
+		if res.Err != nil {
+			if err == nil {
+				err = fmt.Errorf("snapshot: restoring %q: %v", writes[i].Key, res.Err)
+			}
+			continue
+		}
+		n++
+	}
+	return n, err
+}
+
+// decode reads every entry of a snapshot that is still live at now.
+func decode(r *reader, now time.Time) ([]store.Write, error) {
+	header := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrFormat
+	}
+	if string(header[:len(magic)]) != string(magic) {
+		return nil, ErrFormat
+	}
+	version := binary.BigEndian.Uint32(header[len(magic):])
+	if version < 1 || version > Version {
+		return nil, fmt.Errorf("snapshot: unsupported format version %d", version)
+	}
+
+	var writes []store.Write
+	for count := uint64(0); ; count++ {
+		fileSum := r.file
+		r.rec = 0
+		tag, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrCorrupt
+		}
+
+		switch tag {
+		case tagEnd:
+			n, err := binary.ReadUvarint(r)
+			if err != nil || n != count {
+				return nil, ErrCorrupt
+			}
+			if sum, err := r.checksum(); err != nil || sum != fileSum {
+				return nil, ErrCorrupt
+			}
+			return writes, nil
+		case tagEntry, tagExpiringEntry:
+			key, err := r.field()
+			if err != nil {
+				return nil, ErrCorrupt
+			}
+			value, err := r.field()
+			if err != nil {
+				return nil, ErrCorrupt
+			}
+			e := store.Entry{Value: value}
+			if tag == tagExpiringEntry {
+				if e.ExpiresAt, err = r.time(); err != nil {
+					return nil, ErrCorrupt
+				}
+			}
+			if version >= 2 {
+				if err := r.metadata(&e); err != nil {
+					return nil, ErrCorrupt
+				}
+			}
+			if sum, err := r.checksum(); err != nil || sum != r.rec {
+				return nil, ErrCorrupt
+			}
+
+			if !e.Expired(now) {
+				writes = append(writes, store.Write{Key: string(key), Entry: e, Mode: store.ModeUpsert})
+			}
+		default:
+			return nil, ErrCorrupt
+		}
+	}
+}
+
+// reader reads a snapshot while computing the checksums of the current
+// record and of the whole snapshot.
+type reader struct {
+	r    *bufio.Reader
+	rec  uint32 // checksum of the current record
+	file uint32 // checksum of the snapshot so far
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.rec = crc32.Update(r.rec, table, p[:n])
+	r.file = crc32.Update(r.file, table, p[:n])
+	return n, err
+}
+
+func (r *reader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.rec = crc32.Update(r.rec, table, []byte{b})
+		r.file = crc32.Update(r.file, table, []byte{b})
+	}
+	return b, err
+}
+
+// field reads a length-prefixed key, value or string.
+func (r *reader) field() ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxFieldSize {
+		return nil, ErrCorrupt
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// time reads a time encoded as seconds and nanoseconds since the Unix epoch.
+func (r *reader) time() (time.Time, error) {
+	sec, err := binary.ReadVarint(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nsec, err := binary.ReadUvarint(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if nsec >= uint64(time.Second) {
+		return time.Time{}, ErrCorrupt
+	}
+	return time.Unix(sec, int64(nsec)), nil
+}
+
+// metadata reads the metadata that follows the expiration of an entry into
+// e.
+func (r *reader) metadata(e *store.Entry) error {
+	contentType, err := r.field()
+	if err != nil {
+		return err
+	}
+	contentEncoding, err := r.field()
+	if err != nil {
+		return err
+	}
+	e.ContentType, e.ContentEncoding = string(contentType), string(contentEncoding)
+	if e.CreatedAt, err = r.time(); err != nil {
+		return err
+	}
+
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if n > maxFieldSize {
+		return ErrCorrupt
+	}
+	for i := uint64(0); i < n; i++ {
+		name, err := r.field()
+		if err != nil {
+			return err
+		}
+		value, err := r.field()
+		if err != nil {
+			return err
+		}
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata[string(name)] = string(value)
+	}
+	return nil
+}
+
+// checksum reads the checksum that ends a record.
+func (r *reader) checksum() (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r.r, b[:]); err != nil {
+		return 0, err
+	}
+	r.file = crc32.Update(r.file, table, b[:])
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+// Save atomically replaces the snapshot at path with one of s: the snapshot
+// is written to a temporary file in the same directory, synced to disk, and
+// renamed over path. It returns the number of entries saved.
+func Save(path string, s store.Store) (n int, err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, base+".tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if n, err = Write(f, s); err != nil {
+		return 0, err
+	}
+	if err = f.Sync(); err != nil {
+		return 0, err
+	}
+	if err = f.Close(); err != nil {
+		return 0, err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return 0, err
+	}
+
+	// sync the directory too, so that the rename itself survives a crash
+	d, err := os.Open(dir)
+	if err != nil {
+		return n, nil
+	}
+	defer d.Close()
+	d.Sync()
+	return n, nil
+}
+
+// Load restores the snapshot at path into s. See Read. If there is no
+// snapshot at path, the error satisfies os.IsNotExist.
+func Load(path string, s store.Store) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return Read(f, s, time.Now())
+}
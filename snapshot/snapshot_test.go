@@ -0,0 +1,209 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+func mustPut(t *testing.T, s store.Store, key string, e store.Entry) {
+	t.Helper()
+	if _, err := s.Put(key, e); err != nil {
+		t.Fatalf("Put(%q): unexpected error: %v", key, err)
+	}
+}
+
+// checkEntries checks that s holds the entries of want, apart from their
+// versions and modification times.
+func checkEntries(t *testing.T, s store.Store, want map[string]store.Entry) {
+	t.Helper()
+	for key, w := range want {
+		got, err := s.Get(key)
+		if err != nil {
+			t.Errorf("Get(%q): unexpected error: %v", key, err)
+			continue
+		}
+		if !bytes.Equal(got.Value, w.Value) || !got.ExpiresAt.Equal(w.ExpiresAt) ||
+			got.ContentType != w.ContentType || got.ContentEncoding != w.ContentEncoding ||
+			len(got.Metadata)+len(w.Metadata) > 0 && !reflect.DeepEqual(got.Metadata, w.Metadata) {
+			t.Errorf("Get(%q): want %+v, got %+v", key, w, got)
+		}
+		if !w.CreatedAt.IsZero() && !got.CreatedAt.Equal(w.CreatedAt) {
+			t.Errorf("Get(%q): want creation time %v, got %v", key, w.CreatedAt, got.CreatedAt)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	now := time.Now()
+	src := store.NewMemory(0, nil)
+	want := map[string]store.Entry{
+		"plain": {Value: []byte("value")},
+		"":      {Value: []byte("empty key")},
+		"empty": {},
+		"expiring": {
+			Value:     []byte("soon"),
+			ExpiresAt: now.Add(time.Hour),
+		},
+		"metadata": {
+			Value:           []byte("{}"),
+			ContentType:     "application/json",
+			ContentEncoding: "gzip",
+			Metadata:        map[string]string{"owner": "me", "empty": ""},
+			CreatedAt:       time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC),
+		},
+	}
+	for key, e := range want {
+		mustPut(t, src, key, e)
+	}
+	mustPut(t, src, "expired", store.Entry{Value: []byte("gone"), ExpiresAt: now.Add(time.Millisecond)})
+
+	var buf bytes.Buffer
+	if n, err := Write(&buf, src); err != nil || n != len(want)+1 {
+		t.Fatalf("Write: want %d entries written, got %d and %v", len(want)+1, n, err)
+	}
+
+	// entries already in the store are replaced
+	dst := store.NewMemory(0, nil)
+	mustPut(t, dst, "plain", store.Entry{Value: []byte("replaced")})
+	n, err := Read(&buf, dst, now.Add(time.Second))
+	if err != nil || n != len(want) {
+		t.Fatalf("Read: want %d entries restored, got %d and %v", len(want), n, err)
+	}
+	if _, err := dst.Get("expired"); err != store.ErrNotFound {
+		t.Errorf("Get of an entry expired when reading: want ErrNotFound, got %v", err)
+	}
+	checkEntries(t, dst, want)
+}
+
+// snapshotV1 encodes entries in the format version 1, whose entry records end
+// after their expiration.
+func snapshotV1(entries []store.KeyEntry) []byte {
+	b := append([]byte("CSNP"), 0, 0, 0, 1)
+	for _, ke := range entries {
+		start := len(b)
+		if ke.Entry.ExpiresAt.IsZero() {
+			b = append(b, tagEntry)
+		} else {
+			b = append(b, tagExpiringEntry)
+		}
+		b = appendString(b, ke.Key)
+		b = appendString(b, string(ke.Entry.Value))
+		if !ke.Entry.ExpiresAt.IsZero() {
+			b = appendTime(b, ke.Entry.ExpiresAt)
+		}
+		b = appendUint32(b, crc32.Checksum(b[start:], table))
+	}
+	sum := crc32.Checksum(b, table)
+	b = append(b, tagEnd)
+	b = appendUvarint(b, uint64(len(entries)))
+	return appendUint32(b, sum)
+}
+
+func TestReadVersion1(t *testing.T) {
+	now := time.Now()
+	expiresAt := time.Unix(now.Unix()+3600, 0)
+	data := snapshotV1([]store.KeyEntry{
+		{Key: "a", Entry: store.Entry{Value: []byte("alpha")}},
+		{Key: "b", Entry: store.Entry{Value: []byte("bravo"), ExpiresAt: expiresAt}},
+		{Key: "c", Entry: store.Entry{Value: []byte("gone"), ExpiresAt: now.Add(-time.Second)}},
+	})
+
+	s := store.NewMemory(0, nil)
+	n, err := Read(bytes.NewReader(data), s, now)
+	if err != nil || n != 2 {
+		t.Fatalf("Read: want 2 entries restored, got %d and %v", n, err)
+	}
+	checkEntries(t, s, map[string]store.Entry{
+		"a": {Value: []byte("alpha")},
+		"b": {Value: []byte("bravo"), ExpiresAt: expiresAt},
+	})
+}
+
+func TestReadInvalid(t *testing.T) {
+	src := store.NewMemory(0, nil)
+	mustPut(t, src, "a", store.Entry{Value: []byte("alpha")})
+	mustPut(t, src, "b", store.Entry{Value: []byte("bravo")})
+	var buf bytes.Buffer
+	if _, err := Write(&buf, src); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	valid := buf.Bytes()
+
+	flipped := append([]byte(nil), valid...)
+	flipped[len(magic)+4+3] ^= 0xff
+	future := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint32(future[len(magic):], Version+1)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, ErrFormat},
+		{"bad magic", append([]byte("XXXX"), valid[4:]...), ErrFormat},
+		{"flipped byte", flipped, ErrCorrupt},
+		{"truncated", valid[:len(valid)-1], ErrCorrupt},
+		{"no end record", valid[:len(valid)-6], ErrCorrupt},
+		{"unknown tag", append(append([]byte(nil), valid[:len(magic)+4]...), 9), ErrCorrupt},
+	}
+	for _, tt := range tests {
+		s := store.NewMemory(0, nil)
+		if n, err := Read(bytes.NewReader(tt.data), s, time.Now()); err != tt.err || n != 0 {
+			t.Errorf("%s: want %v and no entries, got %v and %d", tt.name, tt.err, err, n)
+		}
+		if st := s.Stats(); st.Keys != 0 {
+			t.Errorf("%s: invalid snapshot restored %d keys", tt.name, st.Keys)
+		}
+	}
+
+	if _, err := Read(bytes.NewReader(future), store.NewMemory(0, nil), time.Now()); err == nil || err == ErrCorrupt {
+		t.Errorf("Read of a future version: want an unsupported version error, got %v", err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cachely-snapshot-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.snap")
+
+	if _, err := Load(path, store.NewMemory(0, nil)); !os.IsNotExist(err) {
+		t.Errorf("Load of a missing snapshot: want a not exist error, got %v", err)
+	}
+
+	src := store.NewMemory(0, nil)
+	mustPut(t, src, "a", store.Entry{Value: []byte("alpha")})
+	if n, err := Save(path, src); err != nil || n != 1 {
+		t.Fatalf("Save: want 1 entry saved, got %d and %v", n, err)
+	}
+	// saving again replaces the snapshot
+	mustPut(t, src, "b", store.Entry{Value: []byte("bravo")})
+	if n, err := Save(path, src); err != nil || n != 2 {
+		t.Fatalf("Save: want 2 entries saved, got %d and %v", n, err)
+	}
+
+	dst := store.NewMemory(0, nil)
+	if n, err := Load(path, dst); err != nil || n != 2 {
+		t.Fatalf("Load: want 2 entries restored, got %d and %v", n, err)
+	}
+	checkEntries(t, dst, map[string]store.Entry{
+		"a": {Value: []byte("alpha")},
+		"b": {Value: []byte("bravo")},
+	})
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Errorf("want only the snapshot left in its directory, got %d files and %v", len(files), err)
+	}
+}
@@ -0,0 +1,97 @@
+package store
+
+// WriteMode controls how a write treats an existing entry at its key.
+type WriteMode int
+
+const (
+	// ModeInsert only writes keys that are absent, like Store.Put.
+	ModeInsert WriteMode = iota
+
+	// ModeReplace only writes keys that are present, like Replace.
+	ModeReplace
+
+	// ModeUpsert writes keys whether or not they are present, like Upsert.
+	ModeUpsert
+)
+
+// Write is a single write in a batch.
+type Write struct {
+	Key   string
+	Entry Entry
+	Mode  WriteMode
+
+	// Version, when non-zero, only lets the write through if the entry at
+	// Key is at this version, as with CompareAndSwap. Mode is then ignored.
+	Version uint64
+}
+
+// Result is the outcome of a single read or write in a batch. Entry is the
+// entry read or stored, and is only meaningful when Err is nil.
+type Result struct {
+	Entry Entry
+	Err   error
+}
+
+// Batcher is implemented by stores that can apply a batch of operations more
+// efficiently than one at a time, for instance by taking each lock only once.
+// Each operation in a batch succeeds or fails on its own, and results are
+// returned in the order of the operations.
+type Batcher interface {
+	GetBatch(keys []string) []Result
+	PutBatch(writes []Write) []Result
+	DeleteBatch(keys []string) []error
+}
+
+// GetBatch reads every key in keys, in a single pass if s is a Batcher.
+func GetBatch(s Store, keys []string) []Result {
+	if b, ok := s.(Batcher); ok {
+		return b.GetBatch(keys)
+	}
+
+	results := make([]Result, len(keys))
+	for i, key := range keys {
+		results[i].Entry, results[i].Err = s.Get(key)
+	}
+	return results
+}
+
+// PutBatch applies every write in writes, in a single pass if s is a Batcher.
+// Writes to the same key are applied in order.
+func PutBatch(s Store, writes []Write) []Result {
+	if b, ok := s.(Batcher); ok {
+		return b.PutBatch(writes)
+	}
+
+	results := make([]Result, len(writes))
+	for i, w := range writes {
+		results[i].Entry, results[i].Err = Apply(s, w)
+	}
+	return results
+}
+
+// Apply performs a single write according to its expected version or mode.
+func Apply(s Store, w Write) (Entry, error) {
+	if w.Version != 0 {
+		return CompareAndSwap(s, w.Key, w.Version, w.Entry)
+	}
+	switch w.Mode {
+	case ModeReplace:
+		return Replace(s, w.Key, w.Entry)
+	case ModeUpsert:
+		return Upsert(s, w.Key, w.Entry)
+	}
+	return s.Put(w.Key, w.Entry)
+}
+
+// DeleteBatch removes every key in keys, in a single pass if s is a Batcher.
+func DeleteBatch(s Store, keys []string) []error {
+	if b, ok := s.(Batcher); ok {
+		return b.DeleteBatch(keys)
+	}
+
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = s.Delete(key)
+	}
+	return errs
+}
@@ -0,0 +1,98 @@
+package store_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// kv is the subset of store.Store exercised by the benchmarks.
+type kv interface {
+	Get(key string) (store.Entry, error)
+	Put(key string, e store.Entry) (store.Entry, error)
+	Delete(key string) error
+}
+
+// syncMap keeps entries in a sync.Map, as a baseline for the stores.
+type syncMap struct {
+	data sync.Map
+}
+
+func (s *syncMap) Get(key string) (store.Entry, error) {
+	v, ok := s.data.Load(key)
+	if !ok || v.(store.Entry).Expired(time.Now()) {
+		return store.Entry{}, store.ErrNotFound
+	}
+	return v.(store.Entry), nil
+}
+
+func (s *syncMap) Put(key string, e store.Entry) (store.Entry, error) {
+	if _, loaded := s.data.LoadOrStore(key, e); loaded {
+		return store.Entry{}, store.ErrExists
+	}
+	return e, nil
+}
+
+func (s *syncMap) Delete(key string) error {
+	if _, ok := s.data.Load(key); !ok {
+		return store.ErrNotFound
+	}
+	s.data.Delete(key)
+	return nil
+}
+
+// BenchmarkMixed compares the stores with a sync.Map under parallel workloads
+// mixing reads with inserts and deletes of 10000 keys.
+func BenchmarkMixed(b *testing.B) {
+	backends := []struct {
+		name string
+		new  func() kv
+	}{
+		{"syncmap", func() kv { return &syncMap{} }},
+		{"memory", func() kv { return store.NewMemory(0, nil) }},
+		{"sharded", func() kv { return store.NewSharded(16, 0, nil) }},
+	}
+
+	for _, reads := range []int{90, 50, 10} {
+		for _, be := range backends {
+			b.Run(fmt.Sprintf("reads=%d/%s", reads, be.name), func(b *testing.B) {
+				benchmarkMixed(b, be.new(), 10000, reads)
+			})
+		}
+	}
+}
+
+// benchmarkMixed runs a parallel benchmark in which reads percent of the
+// operations are Gets, and the rest alternate between inserting and deleting
+// keys.
+func benchmarkMixed(b *testing.B, s kv, keys, reads int) {
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "key-" + strconv.Itoa(i)
+		if i%2 == 0 {
+			s.Put(names[i], store.Entry{Value: []byte(names[i])})
+		}
+	}
+
+	var seed int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		rng := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			key := names[rng.Intn(len(names))]
+			if rng.Intn(100) < reads {
+				s.Get(key)
+				continue
+			}
+			if _, err := s.Put(key, store.Entry{Value: []byte(key)}); err == store.ErrExists {
+				s.Delete(key)
+			}
+		}
+	})
+}
@@ -0,0 +1,417 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// errCorruptValue is returned when a value read back from disk fails its
+// checksum.
+var errCorruptValue = errors.New("store: corrupt value on disk")
+
+// segmentExt is the extension of the files holding the disk tier.
+const segmentExt = ".seg"
+
+// gcLiveRatio is the proportion of live bytes under which a sealed segment is
+// rewritten by garbage collection.
+const gcLiveRatio = 0.5
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// disk is the disk tier of a Tiered store. Values are appended to segment
+// files, each prefixed with their CRC-32C, while keys and metadata are
+// indexed in memory. Once the active segment is full, a new one is started.
+// Overwritten and deleted values stay in their segment until it is garbage
+// collected or evicted as a whole, oldest first, to stay within the budget.
+//
+// The disk tier only extends memory: its segments are not meant to survive
+// the process, and are removed when it starts.
+type disk struct {
+	mu          sync.RWMutex
+	dir         string
+	maxBytes    int64 // budget for segment files
+	segmentSize int64 // size past which the active segment is sealed
+	index       map[string]*diskEntry
+	segments    []*segment // oldest first, the last one being active
+	nextID      uint64
+	used        int64 // bytes in segment files, live or not
+	bytes       int64 // bytes held by live keys, values and metadata
+	stats       Stats // evictions and expirations
+	reclaimed   int64 // bytes freed by garbage collection
+
+	// evicted is called with the entries dropped to stay within the budget
+	// or because they expired, with the write lock held.
+	evicted func(t EventType, key string, e Entry)
+}
+
+// diskEntry locates a value in a segment.
+type diskEntry struct {
+	seg  *segment
+	off  int64 // offset of the record in seg
+	size int64 // length of the value
+	meta Entry // the entry without its value
+}
+
+// entry returns the metadata of the entry, without its value.
+func (de *diskEntry) entry() Entry {
+	return de.meta
+}
+
+// recordSize is the number of bytes taken by the value in its segment.
+func (de *diskEntry) recordSize() int64 {
+	return 4 + de.size
+}
+
+// segment is a file holding values.
+type segment struct {
+	id   uint64
+	f    *os.File
+	size int64               // bytes written to f
+	live int64               // bytes of the records still indexed
+	keys map[string]struct{} // keys whose values are held in f
+}
+
+// newDisk returns an empty disk tier storing its segments in dir, after
+// removing the segments left there by a previous process.
+func newDisk(dir string, maxBytes, segmentSize int64) (*disk, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	stale, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if segmentSize <= 0 || segmentSize > maxBytes {
+		segmentSize = maxBytes
+	}
+	return &disk{
+		dir:         dir,
+		maxBytes:    maxBytes,
+		segmentSize: segmentSize,
+		index:       make(map[string]*diskEntry),
+	}, nil
+}
+
+// get reads the live entry at key.
+func (d *disk) get(key string, now time.Time) (Entry, *diskEntry, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	de, ok := d.index[key]
+	if !ok || de.entry().Expired(now) {
+		return Entry{}, nil, ErrNotFound
+	}
+	value, err := d.read(de)
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	e := de.entry()
+	e.Value = value
+	return e, de, nil
+}
+
+// has reports whether there is a live entry at key.
+func (d *disk) has(key string, now time.Time) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	de, ok := d.index[key]
+	return ok && !de.entry().Expired(now)
+}
+
+// read reads the value of de from its segment. The caller must hold d.mu.
+func (d *disk) read(de *diskEntry) ([]byte, error) {
+	buf := make([]byte, de.recordSize())
+	if _, err := de.seg.f.ReadAt(buf, de.off); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(buf[4:], crcTable) != binary.BigEndian.Uint32(buf) {
+		return nil, errCorruptValue
+	}
+	return buf[4:], nil
+}
+
+// put stores e at key, replacing any entry already there, and keeps the
+// version of e. The oldest segments are evicted as needed to stay within the
+// budget.
+func (d *disk) put(key string, e Entry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	size := 4 + int64(len(e.Value))
+	if size > d.segmentSize {
+		return ErrTooLarge
+	}
+	if old, ok := d.index[key]; ok {
+		d.remove(key, old)
+	}
+	d.makeRoom(size)
+
+	seg, err := d.active(size)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint32(buf, crc32.Checksum(e.Value, crcTable))
+	copy(buf[4:], e.Value)
+	if _, err := seg.f.WriteAt(buf, seg.size); err != nil {
+		return err
+	}
+
+	meta := e
+	meta.Value = nil
+	de := &diskEntry{
+		seg:  seg,
+		off:  seg.size,
+		size: int64(len(e.Value)),
+		meta: meta,
+	}
+	seg.size += size
+	d.used += size
+	d.add(key, de)
+	return nil
+}
+
+// active returns the segment new records of the given size are appended to,
+// starting a new one if the current one is full. The caller must hold d.mu.
+func (d *disk) active(size int64) (*segment, error) {
+	if n := len(d.segments); n > 0 && d.segments[n-1].size+size <= d.segmentSize {
+		return d.segments[n-1], nil
+	}
+
+	d.nextID++
+	path := filepath.Join(d.dir, fmt.Sprintf("%016x%s", d.nextID, segmentExt))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	seg := &segment{id: d.nextID, f: f, keys: make(map[string]struct{})}
+	d.segments = append(d.segments, seg)
+
+	// the previous segment is sealed, and may already be all dead
+	if n := len(d.segments); n > 1 && d.segments[n-2].live == 0 {
+		d.drop(d.segments[n-2])
+	}
+	return seg, nil
+}
+
+// makeRoom evicts the oldest segments until size more bytes fit in the
+// budget. The caller must hold d.mu.
+func (d *disk) makeRoom(size int64) {
+	for d.used+size > d.maxBytes && len(d.segments) > 0 {
+		seg := d.segments[0]
+		for key := range seg.keys {
+			de := d.index[key]
+			d.remove(key, de)
+			d.stats.Evictions++
+			if d.evicted != nil {
+				d.evicted(EventEvict, key, de.entry())
+			}
+		}
+		d.drop(seg)
+	}
+}
+
+// add indexes de at key. The caller must hold d.mu.
+func (d *disk) add(key string, de *diskEntry) {
+	d.index[key] = de
+	de.seg.keys[key] = struct{}{}
+	de.seg.live += de.recordSize()
+	d.bytes += int64(len(key)) + de.size + de.meta.metadataSize()
+}
+
+// remove drops de, the entry at key, from the index, and its segment if it
+// is sealed and no longer holds anything live. The caller must hold d.mu.
+func (d *disk) remove(key string, de *diskEntry) {
+	delete(d.index, key)
+	delete(de.seg.keys, key)
+	de.seg.live -= de.recordSize()
+	d.bytes -= int64(len(key)) + de.size + de.meta.metadataSize()
+
+	if de.seg.live == 0 && de.seg != d.segments[len(d.segments)-1] {
+		d.drop(de.seg)
+	}
+}
+
+// drop closes and deletes seg, which must not hold live entries, unless it
+// has already been dropped. The caller must hold d.mu.
+func (d *disk) drop(seg *segment) {
+	i := 0
+	for i < len(d.segments) && d.segments[i] != seg {
+		i++
+	}
+	if i == len(d.segments) {
+		return
+	}
+	d.segments = append(d.segments[:i], d.segments[i+1:]...)
+	d.used -= seg.size
+	seg.f.Close()
+	os.Remove(seg.f.Name())
+}
+
+// delete removes the entry at key, live or not, and returns it.
+func (d *disk) delete(key string) (Entry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	de, ok := d.index[key]
+	if !ok {
+		return Entry{}, false
+	}
+	d.remove(key, de)
+	return de.entry(), true
+}
+
+// deleteVersion removes the live entry at key if it is at version, and
+// returns it.
+func (d *disk) deleteVersion(key string, version uint64, now time.Time) (Entry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	de, ok := d.index[key]
+	switch {
+	case !ok || de.entry().Expired(now):
+		return Entry{}, ErrNotFound
+	case de.meta.Version != version:
+		return Entry{}, ErrVersionMismatch
+	}
+	d.remove(key, de)
+	return de.entry(), nil
+}
+
+// deleteIf removes the entry at key if it is still de, that is if it has not
+// been written since de was read.
+func (d *disk) deleteIf(key string, de *diskEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.index[key] == de {
+		d.remove(key, de)
+	}
+}
+
+// rangeEntries calls fn with every live entry, reading their values from
+// disk, until fn returns false. Values that cannot be read are skipped.
+func (d *disk) rangeEntries(fn func(key string, e Entry) bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := time.Now()
+	for key, de := range d.index {
+		if de.entry().Expired(now) {
+			continue
+		}
+		value, err := d.read(de)
+		if err != nil {
+			continue
+		}
+		e := de.entry()
+		e.Value = value
+		if !fn(key, e) {
+			return
+		}
+	}
+}
+
+// reapExpired removes every entry that has expired as of now.
+func (d *disk) reapExpired(now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for key, de := range d.index {
+		if !de.entry().Expired(now) {
+			continue
+		}
+		d.remove(key, de)
+		d.stats.Expirations++
+		if d.evicted != nil {
+			d.evicted(EventExpire, key, de.entry())
+		}
+		n++
+	}
+	return n
+}
+
+// collectGarbage rewrites the sealed segments that are mostly dead, moving
+// their live values to the active segment, and returns the number of bytes
+// freed.
+func (d *disk) collectGarbage() (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.segments) == 0 {
+		return 0, nil
+	}
+	var victims []*segment
+	for _, seg := range d.segments[:len(d.segments)-1] {
+		if float64(seg.live) < gcLiveRatio*float64(seg.size) {
+			victims = append(victims, seg)
+		}
+	}
+
+	before := d.used
+	for _, seg := range victims {
+		for key := range seg.keys {
+			de := d.index[key]
+			value, err := d.read(de)
+			if err != nil {
+				// the value is lost either way
+				d.remove(key, de)
+				continue
+			}
+
+			size := de.recordSize()
+			active, err := d.active(size)
+			if err != nil {
+				return before - d.used, err
+			}
+			buf := make([]byte, size)
+			binary.BigEndian.PutUint32(buf, crc32.Checksum(value, crcTable))
+			copy(buf[4:], value)
+			if _, err := active.f.WriteAt(buf, active.size); err != nil {
+				return before - d.used, err
+			}
+
+			// de is moved in place, since readers holding it rely on its
+			// identity to tell whether the entry was written since
+			d.remove(key, de)
+			de.seg, de.off = active, active.size
+			active.size += size
+			d.used += size
+			d.add(key, de)
+		}
+		d.drop(seg)
+	}
+
+	freed := before - d.used
+	d.reclaimed += freed
+	return freed, nil
+}
+
+// close removes every segment.
+func (d *disk) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, seg := range d.segments {
+		seg.f.Close()
+		os.Remove(seg.f.Name())
+	}
+	d.segments = nil
+	d.index = make(map[string]*diskEntry)
+	d.used, d.bytes = 0, 0
+	return nil
+}
@@ -0,0 +1,55 @@
+package store
+
+// EventType describes how the entry at a key changed.
+type EventType int
+
+const (
+	// EventPut is a write to a key that held no live entry.
+	EventPut EventType = iota + 1
+
+	// EventUpdate is a write replacing the live entry at a key.
+	EventUpdate
+
+	// EventDelete is the removal of an entry by Delete.
+	EventDelete
+
+	// EventExpire is the removal of an entry past its expiration time.
+	EventExpire
+
+	// EventEvict is the removal of an entry to stay within the store's
+	// memory budget.
+	EventEvict
+)
+
+var eventTypeNames = map[EventType]string{
+	EventPut:    "put",
+	EventUpdate: "update",
+	EventDelete: "delete",
+	EventExpire: "expire",
+	EventEvict:  "evict",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Event describes a change to the entry at Key. Entry is the entry written by
+// puts and updates, and the entry removed otherwise.
+type Event struct {
+	Type  EventType
+	Key   string
+	Entry Entry
+}
+
+// Notifier is implemented by stores that report the changes made to their
+// entries.
+type Notifier interface {
+	// Notify registers fn to be called with every subsequent change. fn is
+	// called synchronously while the changed key is locked, so changes to a
+	// key are reported in the order they happen. fn must not block or call
+	// back into the store.
+	Notify(fn func(Event))
+}
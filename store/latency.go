@@ -0,0 +1,69 @@
+package store
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Latency summarizes a distribution of operation latencies. Percentiles are
+// approximated by the upper bound of power of two buckets.
+type Latency struct {
+	Count uint64        `json:"count"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// latencyRecorder accumulates latencies in a histogram that can be updated
+// concurrently without locking.
+type latencyRecorder struct {
+	buckets [64]uint64 // latencies under 1<<i nanoseconds, but not under 1<<(i-1)
+	count   uint64
+	sum     uint64 // nanoseconds
+	max     uint64 // nanoseconds
+}
+
+// record adds a latency to the histogram.
+func (r *latencyRecorder) record(d time.Duration) {
+	ns := uint64(d)
+	if d < 0 {
+		ns = 0
+	}
+	atomic.AddUint64(&r.buckets[bits.Len64(ns)%64], 1)
+	atomic.AddUint64(&r.count, 1)
+	atomic.AddUint64(&r.sum, ns)
+	for {
+		max := atomic.LoadUint64(&r.max)
+		if ns <= max || atomic.CompareAndSwapUint64(&r.max, max, ns) {
+			return
+		}
+	}
+}
+
+// summary summarizes the latencies recorded so far.
+func (r *latencyRecorder) summary() Latency {
+	l := Latency{
+		Count: atomic.LoadUint64(&r.count),
+		Max:   time.Duration(atomic.LoadUint64(&r.max)),
+	}
+	if l.Count == 0 {
+		return l
+	}
+	l.Mean = time.Duration(atomic.LoadUint64(&r.sum) / l.Count)
+
+	var seen uint64
+	for i := range r.buckets {
+		seen += atomic.LoadUint64(&r.buckets[i])
+		upper := time.Duration(uint64(1) << uint(i))
+		if l.P50 == 0 && seen*2 >= l.Count {
+			l.P50 = upper
+		}
+		if seen*100 >= l.Count*99 {
+			l.P99 = upper
+			break
+		}
+	}
+	return l
+}
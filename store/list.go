@@ -0,0 +1,73 @@
+package store
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+)
+
+// KeyEntry is an entry along with the key it is stored at.
+type KeyEntry struct {
+	Key   string
+	Entry Entry
+}
+
+// List returns, in lexical order, up to limit live entries whose keys start
+// with prefix and do not sort before start. It also reports whether more
+// entries remain, in which case the next page starts at KeyAfter the last key
+// returned.
+//
+// Stores do not keep their keys ordered, so every call walks the whole store
+// but only holds on to limit entries at a time.
+func List(s Store, prefix, start string, limit int) ([]KeyEntry, bool) {
+	if limit <= 0 {
+		return nil, false
+	}
+
+	// keep the limit+1 smallest matching keys; the extra one tells whether
+	// there is another page
+	var page keyHeap
+	s.Range(func(key string, e Entry) bool {
+		if !strings.HasPrefix(key, prefix) || key < start {
+			return true
+		}
+		if len(page) <= limit {
+			heap.Push(&page, KeyEntry{Key: key, Entry: e})
+		} else if key < page[0].Key {
+			page[0] = KeyEntry{Key: key, Entry: e}
+			heap.Fix(&page, 0)
+		}
+		return true
+	})
+
+	sort.Slice(page, func(i, j int) bool { return page[i].Key < page[j].Key })
+	if len(page) > limit {
+		return page[:limit], true
+	}
+	return page, false
+}
+
+// KeyAfter returns the first key sorting after key, where the listing of the
+// keys following key starts.
+func KeyAfter(key string) string {
+	return key + "\x00"
+}
+
+// keyHeap is a max-heap of entries ordered by key.
+type keyHeap []KeyEntry
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i].Key > h[j].Key }
+func (h keyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *keyHeap) Push(x interface{}) {
+	*h = append(*h, x.(KeyEntry))
+}
+
+func (h *keyHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ke := old[n-1]
+	*h = old[:n-1]
+	return ke
+}
@@ -0,0 +1,506 @@
+package store
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/timraymond/cachely/eviction"
+)
+
+// item is an entry held by a Memory store along with its bookkeeping.
+type item struct {
+	Entry
+	key   string
+	index int // position in the expiry queue, -1 when not queued
+}
+
+// size is the number of bytes charged against the memory budget for it.
+func (it *item) size() int64 {
+	return int64(len(it.key)+len(it.Value)) + it.metadataSize()
+}
+
+// Memory is a Store that keeps its entries in a map behind a read-write lock.
+// It can be bounded to a number of bytes, in which case its eviction policy
+// picks the entries given up to make room for new ones. Since the policy has
+// to observe every read, only unbounded stores serve reads concurrently.
+//
+// Expired entries are dropped when they are next written or read under the
+// write lock, or when ReapExpired is called, whichever comes first. Their
+// expiration is only reported to observers at that point.
+type Memory struct {
+	hits   uint64 // accessed atomically, kept first for alignment
+	misses uint64 // accessed atomically
+
+	mu       sync.RWMutex
+	items    map[string]*item
+	expiry   expiryQueue
+	policy   eviction.Policy // picks the entries evicted to stay within maxBytes, nil when unbounded
+	used     int64           // bytes held by keys, values and metadata in items
+	maxBytes int64           // memory budget, unlimited when zero
+	stats    Stats           // evictions and expirations, guarded by mu
+	clock    *uint64         // source of entry versions, accessed atomically
+	notify   []func(Event)   // observers registered with Notify, guarded by mu
+
+	// demote is handed the entries evicted to stay within maxBytes, for a
+	// lower tier to keep. It reports whether it did; entries it turns down are
+	// reported to observers as evicted. Nil when the store has no lower tier.
+	demote func(key string, e Entry) bool
+}
+
+// NewMemory returns an empty Memory store holding at most maxBytes of keys,
+// values and metadata, or an unbounded one if maxBytes is zero. A nil policy
+// defaults to LRU. Unbounded stores do not need a policy and ignore it.
+func NewMemory(maxBytes int64, policy eviction.Policy) *Memory {
+	return newMemory(maxBytes, policy, new(uint64))
+}
+
+// newMemory returns an empty Memory store that draws entry versions from
+// clock, which may be shared with other stores.
+func newMemory(maxBytes int64, policy eviction.Policy, clock *uint64) *Memory {
+	if maxBytes <= 0 {
+		policy = nil
+	} else if policy == nil {
+		policy = eviction.NewLRU()
+	}
+	return &Memory{
+		items:    make(map[string]*item),
+		policy:   policy,
+		maxBytes: maxBytes,
+		clock:    clock,
+	}
+}
+
+// Get returns the entry at key.
+func (m *Memory) Get(key string) (Entry, error) {
+	var e Entry
+	var ok bool
+	if m.policy == nil {
+		e, ok = m.get(key)
+	} else {
+		e, ok = m.getAndTrack(key)
+	}
+
+	if !ok {
+		atomic.AddUint64(&m.misses, 1)
+		return Entry{}, ErrNotFound
+	}
+	atomic.AddUint64(&m.hits, 1)
+	return e, nil
+}
+
+// get looks up key under the read lock. Expired entries are left for a writer
+// or the reaper to remove.
+func (m *Memory) get(key string) (Entry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	it, ok := m.items[key]
+	if !ok || it.Expired(time.Now()) {
+		return Entry{}, false
+	}
+	return it.Entry, true
+}
+
+// getAndTrack looks up key under the write lock, reporting the access to the
+// eviction policy.
+func (m *Memory) getAndTrack(key string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.policy.Access(key)
+	it, ok := m.lookup(key, time.Now())
+	if !ok {
+		return Entry{}, false
+	}
+	return it.Entry, true
+}
+
+// Put stores e at key, evicting other entries if the store is over its budget.
+func (m *Memory) Put(key string, e Entry) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.write(Write{Key: key, Entry: e}, time.Now())
+}
+
+// Update atomically replaces the entry at key with the one returned by fn.
+func (m *Memory) Update(key string, fn UpdateFunc) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	cur, ok := m.lookup(key, now)
+	var e Entry
+	var err error
+	if ok {
+		e, err = fn(cur.Entry, true)
+	} else {
+		e, err = fn(Entry{}, false)
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	return m.replace(cur, key, e, now)
+}
+
+// Delete removes the entry at key.
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.delete(key, time.Now())
+}
+
+// CompareAndDelete removes the entry at key if it is at version.
+func (m *Memory) CompareAndDelete(key string, version uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if it, ok := m.lookup(key, now); ok && it.Version != version {
+		return ErrVersionMismatch
+	}
+	return m.delete(key, now)
+}
+
+// GetBatch reads every key in keys under a single acquisition of the lock.
+func (m *Memory) GetBatch(keys []string) []Result {
+	results := make([]Result, len(keys))
+	var hits uint64
+	if m.policy == nil {
+		m.mu.RLock()
+		now := time.Now()
+		for i, key := range keys {
+			if it, ok := m.items[key]; ok && !it.Expired(now) {
+				results[i].Entry = it.Entry
+				hits++
+			} else {
+				results[i].Err = ErrNotFound
+			}
+		}
+		m.mu.RUnlock()
+	} else {
+		m.mu.Lock()
+		now := time.Now()
+		for i, key := range keys {
+			m.policy.Access(key)
+			if it, ok := m.lookup(key, now); ok {
+				results[i].Entry = it.Entry
+				hits++
+			} else {
+				results[i].Err = ErrNotFound
+			}
+		}
+		m.mu.Unlock()
+	}
+
+	atomic.AddUint64(&m.hits, hits)
+	atomic.AddUint64(&m.misses, uint64(len(keys))-hits)
+	return results
+}
+
+// PutBatch applies every write in writes under a single acquisition of the
+// lock.
+func (m *Memory) PutBatch(writes []Write) []Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	results := make([]Result, len(writes))
+	for i, w := range writes {
+		results[i].Entry, results[i].Err = m.write(w, now)
+	}
+	return results
+}
+
+// DeleteBatch removes every key in keys under a single acquisition of the
+// lock.
+func (m *Memory) DeleteBatch(keys []string) []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = m.delete(key, now)
+	}
+	return errs
+}
+
+// Range calls fn for each live entry. Writers are blocked for the duration.
+func (m *Memory) Range(fn func(key string, e Entry) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	for key, it := range m.items {
+		if it.Expired(now) {
+			continue
+		}
+		if !fn(key, it.Entry) {
+			return
+		}
+	}
+}
+
+// Stats summarizes the store's contents and activity.
+func (m *Memory) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	st := m.stats
+	st.Keys = len(m.items)
+	st.Bytes = m.used
+	st.Hits = atomic.LoadUint64(&m.hits)
+	st.Misses = atomic.LoadUint64(&m.misses)
+	return st
+}
+
+// Notify registers fn to be called with every subsequent change to the
+// store's entries. See Notifier.
+func (m *Memory) Notify(fn func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.notify = append(m.notify, fn)
+}
+
+// ReapExpired removes every entry that has expired as of now.
+func (m *Memory) ReapExpired(now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for len(m.expiry) > 0 && m.expiry[0].Expired(now) {
+		m.expire(m.expiry[0])
+		n++
+	}
+	return n
+}
+
+// promote stores e, read from a lower tier, at key unless a live entry is
+// already present. Unlike a write, e keeps its version and observers are not
+// told, since the entry has not changed. It reports whether e was stored.
+func (m *Memory) promote(key string, e Entry) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := m.lookup(key, now); ok || e.Expired(now) {
+		return false
+	}
+	it := &item{Entry: e, key: key}
+	if m.makeRoom(it.size(), now, nil) != nil {
+		return false
+	}
+	m.store(it)
+	return true
+}
+
+// storeBelow stores e at key in a lower tier through put instead of in
+// memory, assigning its version and timestamps as replace would, and drops
+// the entry memory holds at key, if any, once put succeeds.
+func (m *Memory) storeBelow(key string, e Entry, put func(key string, e Entry) error) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	cur, ok := m.lookup(key, now)
+	e.Version = atomic.AddUint64(m.clock, 1)
+	e.ModifiedAt = now
+	if ok {
+		e.CreatedAt = cur.CreatedAt
+	} else if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	if err := put(key, e); err != nil {
+		return Entry{}, err
+	}
+	if ok {
+		m.remove(cur)
+	}
+	return e, nil
+}
+
+// write applies w if its expected version or mode allows it given the current
+// entry at its key. The caller must hold m.mu.
+func (m *Memory) write(w Write, now time.Time) (Entry, error) {
+	cur, ok := m.lookup(w.Key, now)
+	switch {
+	case w.Version != 0 && !ok:
+		return Entry{}, ErrNotFound
+	case w.Version != 0 && cur.Version != w.Version:
+		return Entry{}, ErrVersionMismatch
+	case w.Version != 0:
+	case ok && w.Mode == ModeInsert:
+		return Entry{}, ErrExists
+	case !ok && w.Mode == ModeReplace:
+		return Entry{}, ErrNotFound
+	}
+	return m.replace(cur, w.Key, w.Entry, now)
+}
+
+// delete removes the live entry at key. The caller must hold m.mu.
+func (m *Memory) delete(key string, now time.Time) error {
+	it, ok := m.lookup(key, now)
+	if !ok {
+		return ErrNotFound
+	}
+	m.remove(it)
+	m.emit(EventDelete, it)
+	return nil
+}
+
+// replace stores e at key in place of cur, which is nil if the key is absent.
+// Other entries are evicted as needed to fit e in the budget, counting the
+// room cur takes as free; cur is only dropped once e fits, so that it is kept
+// if e cannot be stored. An entry that is replaced stays known to the eviction
+// policy as a use of its key. The caller must hold m.mu.
+func (m *Memory) replace(cur *item, key string, e Entry, now time.Time) (Entry, error) {
+	it := &item{Entry: e, key: key}
+	if m.maxBytes > 0 && it.size() > m.maxBytes {
+		return Entry{}, ErrTooLarge
+	}
+
+	if err := m.makeRoom(it.size(), now, cur); err != nil {
+		return Entry{}, err
+	}
+	it.Version = atomic.AddUint64(m.clock, 1)
+	it.ModifiedAt = now
+	if cur != nil {
+		it.CreatedAt = cur.CreatedAt
+	} else if it.CreatedAt.IsZero() {
+		it.CreatedAt = now
+	}
+	if cur != nil && m.items[key] == cur {
+		m.unlink(cur)
+		m.link(it)
+		if m.policy != nil {
+			m.policy.Access(key)
+		}
+	} else {
+		// the policy may have chosen cur to make room, in which case it no
+		// longer tracks the key
+		m.store(it)
+	}
+	if cur != nil {
+		m.emit(EventUpdate, it)
+	} else {
+		m.emit(EventPut, it)
+	}
+	return it.Entry, nil
+}
+
+// lookup returns the live item at key, dropping it if it has expired. The
+// caller must hold m.mu.
+func (m *Memory) lookup(key string, now time.Time) (*item, bool) {
+	it, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	if it.Expired(now) {
+		// the reaper has not caught up with this entry yet, so expire it now
+		m.expire(it)
+		return nil, false
+	}
+	return it, true
+}
+
+// makeRoom ensures that an item of the given size fits within the memory
+// budget, first by discarding expired items and then by evicting the items
+// chosen by the eviction policy. The room taken by replaced, the item the new
+// one replaces if not nil, is counted as free; replaced is dropped without
+// notice if the policy chooses it. The caller must hold m.mu.
+func (m *Memory) makeRoom(size int64, now time.Time, replaced *item) error {
+	if m.maxBytes <= 0 {
+		return nil
+	}
+	if size > m.maxBytes {
+		return ErrTooLarge
+	}
+	if replaced != nil {
+		size -= replaced.size()
+	}
+
+	for len(m.expiry) > 0 && m.used+size > m.maxBytes && m.expiry[0].Expired(now) {
+		m.expire(m.expiry[0])
+	}
+
+	for m.used+size > m.maxBytes {
+		key, ok := m.policy.Evict()
+		if !ok {
+			// the policy and the store disagree on what is held, which
+			// should never happen
+			return ErrTooLarge
+		}
+		victim := m.items[key]
+		if victim == replaced {
+			m.unlink(victim)
+			size += victim.size()
+			replaced = nil
+			continue
+		}
+		m.remove(victim)
+		m.stats.Evictions++
+		if m.demote == nil || !m.demote(victim.key, victim.Entry) {
+			m.emit(EventEvict, victim)
+		}
+	}
+	return nil
+}
+
+// store adds it to the store and the eviction policy. The caller must hold
+// m.mu and must have made room for it.
+func (m *Memory) store(it *item) {
+	m.link(it)
+	if m.policy != nil {
+		m.policy.Add(it.key)
+	}
+}
+
+// link adds it to the store, scheduling it for expiration if necessary, but
+// not to the eviction policy. The caller must hold m.mu.
+func (m *Memory) link(it *item) {
+	it.index = -1
+	m.items[it.key] = it
+	m.used += it.size()
+	if !it.ExpiresAt.IsZero() {
+		heap.Push(&m.expiry, it)
+	}
+}
+
+// remove drops it from the store, the expiry queue and the eviction policy.
+// The caller must hold m.mu.
+func (m *Memory) remove(it *item) {
+	m.unlink(it)
+	if m.policy != nil {
+		m.policy.Remove(it.key)
+	}
+}
+
+// unlink drops it from the store and the expiry queue, but not from the
+// eviction policy. The caller must hold m.mu.
+func (m *Memory) unlink(it *item) {
+	delete(m.items, it.key)
+	m.used -= it.size()
+	if it.index >= 0 {
+		heap.Remove(&m.expiry, it.index)
+	}
+}
+
+// expire removes an expired item. The caller must hold m.mu.
+func (m *Memory) expire(it *item) {
+	m.remove(it)
+	m.stats.Expirations++
+	m.emit(EventExpire, it)
+}
+
+// emit reports a change to it to the registered observers. The caller must
+// hold m.mu.
+func (m *Memory) emit(t EventType, it *item) {
+	for _, fn := range m.notify {
+		fn(Event{Type: t, Key: it.key, Entry: it.Entry})
+	}
+}
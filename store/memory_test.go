@@ -0,0 +1,217 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/eviction"
+	"github.com/timraymond/cachely/store"
+	"github.com/timraymond/cachely/store/storetest"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	storetest.Run(t, func() store.Store {
+		return store.NewMemory(0, nil)
+	})
+}
+
+func TestMemoryReapExpired(t *testing.T) {
+	m := store.NewMemory(0, nil)
+	now := time.Now()
+	var expired []string
+	m.Notify(func(ev store.Event) {
+		if ev.Type == store.EventExpire {
+			expired = append(expired, ev.Key)
+		}
+	})
+
+	for key, ttl := range map[string]time.Duration{"a": time.Minute, "b": 2 * time.Minute, "c": time.Hour} {
+		if _, err := m.Put(key, store.Entry{Value: []byte(key), ExpiresAt: now.Add(ttl)}); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+	if _, err := m.Put("forever", store.Entry{Value: []byte("forever")}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+
+	if n := m.ReapExpired(now); n != 0 {
+		t.Errorf("ReapExpired before any expiration: want 0 entries reaped, got %d", n)
+	}
+	if n := m.ReapExpired(now.Add(2 * time.Minute)); n != 2 {
+		t.Errorf("ReapExpired: want 2 entries reaped, got %d", n)
+	}
+	if len(expired) != 2 || expired[0] != "a" || expired[1] != "b" {
+		t.Errorf("want expirations of a then b to be reported, got %q", expired)
+	}
+
+	st := m.Stats()
+	if st.Keys != 2 || st.Expirations != 2 {
+		t.Errorf("Stats after reaping: want 2 keys and 2 expirations, got %d and %d", st.Keys, st.Expirations)
+	}
+	if st.Bytes != int64(len("c")*2+len("forever")*2) {
+		t.Errorf("Stats after reaping: reaped entries still charged, got %d bytes", st.Bytes)
+	}
+}
+
+func TestMemoryExpiresOnWrite(t *testing.T) {
+	m := store.NewMemory(0, nil)
+	if _, err := m.Put("a", store.Entry{Value: []byte("old"), ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+
+	// the entry is past its expiration, so the key is free to insert into
+	// before the reaper gets to it
+	e, err := m.Put("a", store.Entry{Value: []byte("new")})
+	if err != nil {
+		t.Fatalf("Put over an expired entry: unexpected error: %v", err)
+	}
+	if !e.ExpiresAt.IsZero() {
+		t.Errorf("Put over an expired entry: expiration carried over: %v", e.ExpiresAt)
+	}
+	if st := m.Stats(); st.Expirations != 1 || st.Keys != 1 {
+		t.Errorf("Stats: want 1 key and 1 expiration, got %d and %d", st.Keys, st.Expirations)
+	}
+	if n := m.ReapExpired(time.Now()); n != 0 {
+		t.Errorf("ReapExpired: replaced entry still scheduled, %d reaped", n)
+	}
+}
+
+func TestMemoryEvictsWithinBudget(t *testing.T) {
+	// each entry takes 10 bytes: a 1 byte key and a 9 byte value
+	m := store.NewMemory(30, nil)
+	var evicted []string
+	m.Notify(func(ev store.Event) {
+		if ev.Type == store.EventEvict {
+			evicted = append(evicted, ev.Key)
+		}
+	})
+	value := []byte("123456789")
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := m.Put(key, store.Entry{Value: value}); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+	if _, err := m.Get("a"); err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if _, err := m.Put("d", store.Entry{Value: value}); err != nil {
+		t.Fatalf("Put over budget: unexpected error: %v", err)
+	}
+
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("want the least recently used key b evicted, got %q", evicted)
+	}
+	if _, err := m.Get("b"); err != store.ErrNotFound {
+		t.Errorf("Get of an evicted key: want store.ErrNotFound, got %v", err)
+	}
+	st := m.Stats()
+	if st.Bytes != 30 || st.Keys != 3 || st.Evictions != 1 {
+		t.Errorf("Stats: want 30 bytes, 3 keys and 1 eviction, got %d, %d and %d", st.Bytes, st.Keys, st.Evictions)
+	}
+
+	if _, err := m.Put("big", store.Entry{Value: make([]byte, 30)}); err != store.ErrTooLarge {
+		t.Errorf("Put of an entry larger than the budget: want store.ErrTooLarge, got %v", err)
+	}
+	if st := m.Stats(); st.Keys != 3 {
+		t.Errorf("rejected Put evicted entries: %d keys left", st.Keys)
+	}
+}
+
+func TestMemoryOverwriteKeepsPolicyState(t *testing.T) {
+	m := store.NewMemory(30, eviction.NewLFU())
+	value := []byte("123456789")
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := m.Put(key, store.Entry{Value: value}); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+	for _, key := range []string{"a", "a", "a", "b", "b", "c"} {
+		if _, err := m.Get(key); err != nil {
+			t.Fatalf("Get(%q): unexpected error: %v", key, err)
+		}
+	}
+
+	// overwriting a counts as a use rather than starting it over
+	if _, err := m.Update("a", func(store.Entry, bool) (store.Entry, error) { return store.Entry{Value: value}, nil }); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if _, err := m.Put("d", store.Entry{Value: value}); err != nil {
+		t.Fatalf("Put over budget: unexpected error: %v", err)
+	}
+	for key, want := range map[string]error{"a": nil, "b": nil, "c": store.ErrNotFound, "d": nil} {
+		if _, err := m.Get(key); err != want {
+			t.Errorf("Get(%q): want %v, got %v", key, want, err)
+		}
+	}
+}
+
+func TestMemoryOverwriteMakesRoom(t *testing.T) {
+	m := store.NewMemory(30, nil)
+	var events []string
+	m.Notify(func(ev store.Event) {
+		events = append(events, ev.Type.String()+" "+ev.Key)
+	})
+	value := []byte("123456789")
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := m.Put(key, store.Entry{Value: value}); err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+	}
+	if _, err := m.Get("a"); err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	events = nil
+
+	// b is the least recently used key, but it is replaced rather than
+	// evicted to make room for itself, so that growing it only evicts c
+	grown := make([]byte, 19)
+	if _, err := m.Update("b", func(store.Entry, bool) (store.Entry, error) { return store.Entry{Value: grown}, nil }); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if want := []string{"evict c", "update b"}; !equalStrings(events, want) {
+		t.Errorf("want events %q, got %q", want, events)
+	}
+	if st := m.Stats(); st.Bytes != 30 || st.Keys != 2 {
+		t.Errorf("Stats: want 30 bytes in 2 keys, got %d in %d", st.Bytes, st.Keys)
+	}
+
+	// the replaced entry is still tracked by the policy
+	if _, err := m.Get("a"); err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	events = nil
+	if _, err := m.Update("b", func(store.Entry, bool) (store.Entry, error) { return store.Entry{Value: make([]byte, 29)}, nil }); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if want := []string{"evict a", "update b"}; !equalStrings(events, want) {
+		t.Errorf("want events %q, got %q", want, events)
+	}
+	e, err := m.Get("b")
+	if err != nil || len(e.Value) != 29 {
+		t.Errorf("Get: want the 29 byte value, got %d bytes and %v", len(e.Value), err)
+	}
+	if st := m.Stats(); st.Bytes != 30 || st.Keys != 1 || st.Evictions != 2 {
+		t.Errorf("Stats: want 30 bytes in 1 key and 2 evictions, got %d in %d and %d", st.Bytes, st.Keys, st.Evictions)
+	}
+
+	// an entry that could never fit leaves the current one in place
+	if _, err := m.Update("b", func(store.Entry, bool) (store.Entry, error) { return store.Entry{Value: make([]byte, 30)}, nil }); err != store.ErrTooLarge {
+		t.Fatalf("Update: want store.ErrTooLarge, got %v", err)
+	}
+	if _, err := m.Get("b"); err != nil {
+		t.Errorf("Get after a rejected overwrite: unexpected error: %v", err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"time"
+
+	"github.com/timraymond/cachely/eviction"
+)
+
+// Sharded is a Store that stripes its keys across several Memory stores, each
+// with its own lock, so that writes to different keys rarely contend. It
+// suits workloads with heavy Put and Delete churn, where a single lock or a
+// sync.Map would become the bottleneck.
+type Sharded struct {
+	shards []*Memory
+	mask   uint32
+}
+
+// NewSharded returns an empty Sharded store. The number of shards is rounded up
+// to a power of two. The memory budget is split evenly between the shards, so
+// no entry larger than a single shard's share can be stored; zero leaves the
+// store unbounded. A budget too small to give every shard a byte of it is
+// split between fewer shards. newPolicy is called once per shard and may be
+// nil to use the Memory default.
+func NewSharded(shards int, maxBytes int64, newPolicy func() eviction.Policy) *Sharded {
+	n := 1
+	for n < shards {
+		n *= 2
+	}
+	// a shard given no budget at all would be unbounded
+	for maxBytes > 0 && int64(n) > maxBytes {
+		n /= 2
+	}
+
+	s := &Sharded{
+		shards: make([]*Memory, n),
+		mask:   uint32(n - 1),
+	}
+	// the shards share a version clock so that versions stay unique
+	clock := new(uint64)
+	for i := range s.shards {
+		var policy eviction.Policy
+		if newPolicy != nil {
+			policy = newPolicy()
+		}
+		s.shards[i] = newMemory(maxBytes/int64(n), policy, clock)
+	}
+	return s
+}
+
+// shard returns the shard responsible for key.
+func (s *Sharded) shard(key string) *Memory {
+	return s.shards[s.index(key)]
+}
+
+// index returns the position of the shard responsible for key.
+func (s *Sharded) index(key string) uint32 {
+	return hashKey(key) & s.mask
+}
+
+// hashKey returns the FNV-1a hash of key.
+func hashKey(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// partition groups the positions of n keys by the shard responsible for
+// them, preserving their order within each shard.
+func (s *Sharded) partition(n int, key func(i int) string) [][]int {
+	groups := make([][]int, len(s.shards))
+	for i := 0; i < n; i++ {
+		idx := s.index(key(i))
+		groups[idx] = append(groups[idx], i)
+	}
+	return groups
+}
+
+// Get returns the entry at key.
+func (s *Sharded) Get(key string) (Entry, error) {
+	return s.shard(key).Get(key)
+}
+
+// Put stores e at key unless a live entry is already present. The check and
+// the insert happen atomically under the shard's lock.
+func (s *Sharded) Put(key string, e Entry) (Entry, error) {
+	return s.shard(key).Put(key, e)
+}
+
+// Update atomically replaces the entry at key with the one returned by fn.
+func (s *Sharded) Update(key string, fn UpdateFunc) (Entry, error) {
+	return s.shard(key).Update(key, fn)
+}
+
+// Delete removes the entry at key.
+func (s *Sharded) Delete(key string) error {
+	return s.shard(key).Delete(key)
+}
+
+// CompareAndDelete removes the entry at key if it is at version.
+func (s *Sharded) CompareAndDelete(key string, version uint64) error {
+	return s.shard(key).CompareAndDelete(key, version)
+}
+
+// GetBatch reads every key in keys, locking each shard once.
+func (s *Sharded) GetBatch(keys []string) []Result {
+	results := make([]Result, len(keys))
+	for shard, group := range s.partition(len(keys), func(i int) string { return keys[i] }) {
+		if len(group) == 0 {
+			continue
+		}
+		sub := make([]string, len(group))
+		for j, i := range group {
+			sub[j] = keys[i]
+		}
+		for j, r := range s.shards[shard].GetBatch(sub) {
+			results[group[j]] = r
+		}
+	}
+	return results
+}
+
+// PutBatch applies every write in writes, locking each shard once.
+func (s *Sharded) PutBatch(writes []Write) []Result {
+	results := make([]Result, len(writes))
+	for shard, group := range s.partition(len(writes), func(i int) string { return writes[i].Key }) {
+		if len(group) == 0 {
+			continue
+		}
+		sub := make([]Write, len(group))
+		for j, i := range group {
+			sub[j] = writes[i]
+		}
+		for j, r := range s.shards[shard].PutBatch(sub) {
+			results[group[j]] = r
+		}
+	}
+	return results
+}
+
+// DeleteBatch removes every key in keys, locking each shard once.
+func (s *Sharded) DeleteBatch(keys []string) []error {
+	errs := make([]error, len(keys))
+	for shard, group := range s.partition(len(keys), func(i int) string { return keys[i] }) {
+		if len(group) == 0 {
+			continue
+		}
+		sub := make([]string, len(group))
+		for j, i := range group {
+			sub[j] = keys[i]
+		}
+		for j, err := range s.shards[shard].DeleteBatch(sub) {
+			errs[group[j]] = err
+		}
+	}
+	return errs
+}
+
+// Range calls fn for each live entry, one shard at a time. Entries written to
+// a shard that has already been visited may be missed.
+func (s *Sharded) Range(fn func(key string, e Entry) bool) {
+	for _, m := range s.shards {
+		more := true
+		m.Range(func(key string, e Entry) bool {
+			more = fn(key, e)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// Stats sums the statistics of every shard.
+func (s *Sharded) Stats() Stats {
+	var st Stats
+	for _, m := range s.shards {
+		ms := m.Stats()
+		st.Keys += ms.Keys
+		st.Bytes += ms.Bytes
+		st.Hits += ms.Hits
+		st.Misses += ms.Misses
+		st.Evictions += ms.Evictions
+		st.Expirations += ms.Expirations
+	}
+	return st
+}
+
+// Notify registers fn with every shard. Changes to different shards may be
+// reported concurrently.
+func (s *Sharded) Notify(fn func(Event)) {
+	for _, m := range s.shards {
+		m.Notify(fn)
+	}
+}
+
+// promote stores e, read from a lower tier, at key. See Memory.promote.
+func (s *Sharded) promote(key string, e Entry) bool {
+	return s.shard(key).promote(key, e)
+}
+
+// storeBelow stores e at key in a lower tier through put. See
+// Memory.storeBelow.
+func (s *Sharded) storeBelow(key string, e Entry, put func(key string, e Entry) error) (Entry, error) {
+	return s.shard(key).storeBelow(key, e, put)
+}
+
+// setDemote hands the entries evicted from every shard to demote. See
+// Memory.demote.
+func (s *Sharded) setDemote(demote func(key string, e Entry) bool) {
+	for _, m := range s.shards {
+		m.mu.Lock()
+		m.demote = demote
+		m.mu.Unlock()
+	}
+}
+
+// ReapExpired removes every entry that has expired as of now.
+func (s *Sharded) ReapExpired(now time.Time) int {
+	n := 0
+	for _, m := range s.shards {
+		n += m.ReapExpired(now)
+	}
+	return n
+}
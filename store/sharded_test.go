@@ -0,0 +1,32 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/timraymond/cachely/store"
+	"github.com/timraymond/cachely/store/storetest"
+)
+
+func TestShardedConformance(t *testing.T) {
+	storetest.Run(t, func() store.Store {
+		return store.NewSharded(8, 0, nil)
+	})
+}
+
+func TestShardedSmallBudget(t *testing.T) {
+	// the budget is too small to give a byte to each of the 16 shards asked
+	// for, and must not leave them unbounded
+	s := store.NewSharded(16, 10, nil)
+	if _, err := s.Put("k", store.Entry{Value: make([]byte, 20)}); err != store.ErrTooLarge {
+		t.Errorf("Put of an entry larger than the budget: want ErrTooLarge, got %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := string(rune('a' + i%26))
+		s.Delete(key)
+		s.Put(key, store.Entry{})
+	}
+	if st := s.Stats(); st.Bytes > 10 {
+		t.Errorf("want at most 10 bytes held, got %d", st.Bytes)
+	}
+}
@@ -0,0 +1,179 @@
+// Package store defines the storage backends behind the cachely CacheAPI.
+//
+// The gRPC service is a thin adapter over a Store, so alternative backends
+// such as sharded maps, disk-backed stores or remote stores can be plugged in
+// without touching the RPC handlers. The storetest package holds a conformance
+// suite every implementation is expected to pass.
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when there is no live entry at a key.
+	ErrNotFound = errors.New("store: key not found")
+
+	// ErrExists is returned when inserting at a key that already holds a live
+	// entry.
+	ErrExists = errors.New("store: key already exists")
+
+	// ErrTooLarge is returned when an entry could never fit in the store.
+	ErrTooLarge = errors.New("store: entry exceeds the store capacity")
+
+	// ErrVersionMismatch is returned by CompareAndSwap when the entry is not
+	// at the expected version.
+	ErrVersionMismatch = errors.New("store: entry version mismatch")
+)
+
+// Entry is a value held by a Store along with its metadata.
+type Entry struct {
+	Value           []byte
+	ExpiresAt       time.Time         // zero when the entry never expires
+	ContentType     string            // media type of the value, empty when unknown
+	ContentEncoding string            // encoding applied to the value, such as gzip, empty when none
+	Metadata        map[string]string // user-defined attributes, shared with the store like the value
+
+	// CreatedAt and ModifiedAt are assigned by the store. CreatedAt is set
+	// when an entry is written to an absent key and kept when the entry is
+	// replaced, while ModifiedAt is set every time the entry is written. A
+	// CreatedAt set by the writer of an absent key is kept, so that entries
+	// restored or moved from elsewhere keep their age.
+	CreatedAt  time.Time
+	ModifiedAt time.Time
+
+	// Version is assigned by the store every time the entry is written. It
+	// increases monotonically across the whole store, so a key that is
+	// deleted and written again never repeats an earlier version. It is
+	// ignored when writing.
+	Version uint64
+}
+
+// Expired reports whether the entry should no longer be served at now.
+func (e Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// metadataSize is the number of bytes taken by the content type, encoding and
+// user-defined attributes of the entry.
+func (e Entry) metadataSize() int64 {
+	n := len(e.ContentType) + len(e.ContentEncoding)
+	for k, v := range e.Metadata {
+		n += len(k) + len(v)
+	}
+	return int64(n)
+}
+
+// Stats is a point in time summary of a Store's contents and activity.
+type Stats struct {
+	Keys        int    `json:"keys"`
+	Bytes       int64  `json:"bytes"` // sum of the key, value and metadata sizes
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
+}
+
+// Store holds entries addressed by a string key. Implementations must be safe
+// for concurrent use. Entries past their expiration time are never returned,
+// and are treated as absent by every operation.
+//
+// Values passed to and returned from a Store are shared with it, so callers
+// must not modify them.
+type Store interface {
+	// Get returns the entry at key, or ErrNotFound.
+	Get(key string) (Entry, error)
+
+	// Put stores e at key and returns the stored entry. It returns ErrExists
+	// if a live entry is already present.
+	Put(key string, e Entry) (Entry, error)
+
+	// Update atomically replaces the entry at key with the one returned by
+	// fn, and returns the stored entry. fn is passed the current entry and
+	// whether it exists. If fn returns an error, the store is left untouched
+	// and the error is returned. fn must not call back into the store.
+	Update(key string, fn UpdateFunc) (Entry, error)
+
+	// Delete removes the entry at key, or returns ErrNotFound.
+	Delete(key string) error
+
+	// Range calls fn for each live entry in no particular order, stopping
+	// early if fn returns false. fn must not call back into the store.
+	Range(fn func(key string, e Entry) bool)
+
+	// Stats summarizes the store's contents and activity.
+	Stats() Stats
+}
+
+// UpdateFunc computes the new entry for a key from its current one. See
+// Store.Update.
+type UpdateFunc func(cur Entry, exists bool) (Entry, error)
+
+// CompareAndSwap stores e at key only if the current entry is at version. A
+// version of zero expects the key to be absent. It returns ErrNotFound if
+// there is no entry to swap, and ErrVersionMismatch if the entry has been
+// written since the caller read it.
+func CompareAndSwap(s Store, key string, version uint64, e Entry) (Entry, error) {
+	return s.Update(key, func(cur Entry, exists bool) (Entry, error) {
+		switch {
+		case !exists && version != 0:
+			return Entry{}, ErrNotFound
+		case exists && cur.Version != version:
+			return Entry{}, ErrVersionMismatch
+		}
+		return e, nil
+	})
+}
+
+// CompareAndDeleter is implemented by stores that can atomically delete an
+// entry only if it is at a given version.
+type CompareAndDeleter interface {
+	CompareAndDelete(key string, version uint64) error
+}
+
+// CompareAndDelete removes the entry at key only if it is at version. It
+// returns ErrNotFound if there is no entry to delete, and ErrVersionMismatch
+// if the entry has been written since the caller read it. Stores that are not
+// a CompareAndDeleter check the version with Get before deleting the entry,
+// so a write made in between is deleted along with it.
+func CompareAndDelete(s Store, key string, version uint64) error {
+	if d, ok := s.(CompareAndDeleter); ok {
+		return d.CompareAndDelete(key, version)
+	}
+
+	e, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+	if e.Version != version {
+		return ErrVersionMismatch
+	}
+	return s.Delete(key)
+}
+
+// Replace stores e at key only if a live entry is already present, and
+// returns ErrNotFound otherwise.
+func Replace(s Store, key string, e Entry) (Entry, error) {
+	return s.Update(key, func(_ Entry, exists bool) (Entry, error) {
+		if !exists {
+			return Entry{}, ErrNotFound
+		}
+		return e, nil
+	})
+}
+
+// Upsert stores e at key whether or not an entry is already present.
+func Upsert(s Store, key string, e Entry) (Entry, error) {
+	return s.Update(key, func(Entry, bool) (Entry, error) {
+		return e, nil
+	})
+}
+
+// Reaper is implemented by stores that hold on to expired entries until they
+// are reaped, rather than dropping them as soon as they expire.
+type Reaper interface {
+	// ReapExpired removes every entry that has expired as of now and returns
+	// the number of entries removed.
+	ReapExpired(now time.Time) int
+}
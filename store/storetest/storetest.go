@@ -0,0 +1,617 @@
+// Package storetest provides a conformance suite for store.Store
+// implementations. A backend runs it from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func() store.Store {
+//			return mybackend.New()
+//		})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+// Run exercises the behaviour every Store must provide. newStore is called
+// once per subtest and must return an empty, unbounded store.
+func Run(t *testing.T, newStore func() store.Store) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, store.Store)
+	}{
+		{"GetMissing", testGetMissing},
+		{"PutGet", testPutGet},
+		{"PutExisting", testPutExisting},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"Expiration", testExpiration},
+		{"Range", testRange},
+		{"RangeStop", testRangeStop},
+		{"Stats", testStats},
+		{"Versions", testVersions},
+		{"Metadata", testMetadata},
+		{"Update", testUpdate},
+		{"UpdateError", testUpdateError},
+		{"CompareAndSwap", testCompareAndSwap},
+		{"CompareAndDelete", testCompareAndDelete},
+		{"Replace", testReplace},
+		{"Upsert", testUpsert},
+		{"Batch", testBatch},
+		{"List", testList},
+		{"Notify", testNotify},
+		{"Concurrent", testConcurrent},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore())
+		})
+	}
+}
+
+func mustPut(t *testing.T, s store.Store, key string, e store.Entry) store.Entry {
+	t.Helper()
+	stored, err := s.Put(key, e)
+	if err != nil {
+		t.Fatalf("Put(%q): unexpected error: %v", key, err)
+	}
+	return stored
+}
+
+func mustGet(t *testing.T, s store.Store, key string) store.Entry {
+	t.Helper()
+	e, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q): unexpected error: %v", key, err)
+	}
+	return e
+}
+
+func testGetMissing(t *testing.T, s store.Store) {
+	if _, err := s.Get("missing"); err != store.ErrNotFound {
+		t.Errorf("Get of a missing key: want ErrNotFound, got %v", err)
+	}
+}
+
+func testPutGet(t *testing.T, s store.Store) {
+	expiresAt := time.Now().Add(time.Hour)
+	mustPut(t, s, "a", store.Entry{Value: []byte("alpha")})
+	mustPut(t, s, "b", store.Entry{Value: []byte("bravo"), ExpiresAt: expiresAt})
+	mustPut(t, s, "", store.Entry{Value: []byte("empty key")})
+	mustPut(t, s, "nil", store.Entry{})
+
+	tests := []struct {
+		key       string
+		value     []byte
+		expiresAt time.Time
+	}{
+		{"a", []byte("alpha"), time.Time{}},
+		{"b", []byte("bravo"), expiresAt},
+		{"", []byte("empty key"), time.Time{}},
+		{"nil", nil, time.Time{}},
+	}
+	for _, tt := range tests {
+		e, err := s.Get(tt.key)
+		if err != nil {
+			t.Errorf("Get(%q): unexpected error: %v", tt.key, err)
+			continue
+		}
+		if !bytes.Equal(e.Value, tt.value) {
+			t.Errorf("Get(%q): want value %q, got %q", tt.key, tt.value, e.Value)
+		}
+		if !e.ExpiresAt.Equal(tt.expiresAt) {
+			t.Errorf("Get(%q): want expiration %v, got %v", tt.key, tt.expiresAt, e.ExpiresAt)
+		}
+	}
+}
+
+func testPutExisting(t *testing.T, s store.Store) {
+	mustPut(t, s, "a", store.Entry{Value: []byte("first")})
+	if _, err := s.Put("a", store.Entry{Value: []byte("second")}); err != store.ErrExists {
+		t.Fatalf("Put over an existing key: want ErrExists, got %v", err)
+	}
+
+	if e := mustGet(t, s, "a"); string(e.Value) != "first" {
+		t.Errorf("rejected Put replaced the value: got %q", e.Value)
+	}
+}
+
+func testDelete(t *testing.T, s store.Store) {
+	mustPut(t, s, "a", store.Entry{Value: []byte("alpha")})
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if _, err := s.Get("a"); err != store.ErrNotFound {
+		t.Errorf("Get after Delete: want ErrNotFound, got %v", err)
+	}
+	// the key may be reused once deleted
+	mustPut(t, s, "a", store.Entry{Value: []byte("again")})
+}
+
+func testDeleteMissing(t *testing.T, s store.Store) {
+	if err := s.Delete("missing"); err != store.ErrNotFound {
+		t.Errorf("Delete of a missing key: want ErrNotFound, got %v", err)
+	}
+}
+
+func testExpiration(t *testing.T, s store.Store) {
+	ttl := 50 * time.Millisecond
+	mustPut(t, s, "short", store.Entry{Value: []byte("x"), ExpiresAt: time.Now().Add(ttl)})
+	mustPut(t, s, "long", store.Entry{Value: []byte("y"), ExpiresAt: time.Now().Add(time.Hour)})
+
+	if _, err := s.Get("short"); err != nil {
+		t.Fatalf("Get before expiration: unexpected error: %v", err)
+	}
+	time.Sleep(2 * ttl)
+
+	if _, err := s.Get("short"); err != store.ErrNotFound {
+		t.Errorf("Get after expiration: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.Get("long"); err != nil {
+		t.Errorf("Get of an unexpired key: unexpected error: %v", err)
+	}
+	if err := s.Delete("short"); err != store.ErrNotFound {
+		t.Errorf("Delete after expiration: want ErrNotFound, got %v", err)
+	}
+	s.Range(func(key string, _ store.Entry) bool {
+		if key == "short" {
+			t.Errorf("Range visited an expired key")
+		}
+		return true
+	})
+
+	// an expired key may be reused
+	mustPut(t, s, "short", store.Entry{Value: []byte("z")})
+}
+
+func testRange(t *testing.T, s store.Store) {
+	want := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key, value := fmt.Sprintf("key-%d", i), fmt.Sprintf("value-%d", i)
+		want[key] = value
+		mustPut(t, s, key, store.Entry{Value: []byte(value)})
+	}
+
+	got := make(map[string]string)
+	s.Range(func(key string, e store.Entry) bool {
+		if _, ok := got[key]; ok {
+			t.Errorf("Range visited %q twice", key)
+		}
+		got[key] = string(e.Value)
+		return true
+	})
+
+	if len(got) != len(want) {
+		t.Errorf("Range: want %d entries, got %d", len(want), len(got))
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("Range: want %q at %q, got %q", value, key, got[key])
+		}
+	}
+}
+
+func testRangeStop(t *testing.T, s store.Store) {
+	for i := 0; i < 10; i++ {
+		mustPut(t, s, fmt.Sprintf("key-%d", i), store.Entry{Value: []byte("x")})
+	}
+
+	visited := 0
+	s.Range(func(string, store.Entry) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Errorf("Range did not stop when asked: visited %d entries", visited)
+	}
+}
+
+func testStats(t *testing.T, s store.Store) {
+	mustPut(t, s, "a", store.Entry{Value: []byte("12345")})
+	mustPut(t, s, "bb", store.Entry{Value: []byte("123")})
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	st := s.Stats()
+	if st.Keys != 1 {
+		t.Errorf("Stats: want 1 key, got %d", st.Keys)
+	}
+	if st.Bytes != 5 {
+		t.Errorf("Stats: want 5 bytes, got %d", st.Bytes)
+	}
+
+	s.Get("bb")
+	s.Get("missing")
+	st2 := s.Stats()
+	if st2.Hits != st.Hits+1 || st2.Misses != st.Misses+1 {
+		t.Errorf("Stats: want one more hit and miss, got %+v then %+v", st, st2)
+	}
+}
+
+func testVersions(t *testing.T, s store.Store) {
+	a := mustPut(t, s, "a", store.Entry{Value: []byte("alpha"), Version: 1000})
+	if a.Version == 0 || a.Version == 1000 {
+		t.Fatalf("Put: want a version assigned by the store, got %d", a.Version)
+	}
+	if got := mustGet(t, s, "a").Version; got != a.Version {
+		t.Errorf("Get: want version %d, got %d", a.Version, got)
+	}
+
+	b := mustPut(t, s, "b", store.Entry{Value: []byte("bravo")})
+	if b.Version <= a.Version {
+		t.Errorf("Put: want a version above %d, got %d", a.Version, b.Version)
+	}
+
+	// versions are not reused when a key is written again
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	a2 := mustPut(t, s, "a", store.Entry{Value: []byte("alpha")})
+	if a2.Version <= b.Version {
+		t.Errorf("Put after Delete: want a version above %d, got %d", b.Version, a2.Version)
+	}
+}
+
+func testMetadata(t *testing.T, s store.Store) {
+	before := time.Now()
+	a := mustPut(t, s, "a", store.Entry{
+		Value:           []byte("alpha"),
+		ContentType:     "text/plain",
+		ContentEncoding: "identity",
+		Metadata:        map[string]string{"owner": "ops"},
+	})
+	if a.CreatedAt.Before(before) || !a.ModifiedAt.Equal(a.CreatedAt) {
+		t.Errorf("Put: want the creation and modification times of the write, got %v and %v", a.CreatedAt, a.ModifiedAt)
+	}
+
+	got := mustGet(t, s, "a")
+	if got.ContentType != "text/plain" || got.ContentEncoding != "identity" || got.Metadata["owner"] != "ops" {
+		t.Errorf("Get: want the metadata written, got %q, %q and %v", got.ContentType, got.ContentEncoding, got.Metadata)
+	}
+	if !got.CreatedAt.Equal(a.CreatedAt) || !got.ModifiedAt.Equal(a.ModifiedAt) {
+		t.Errorf("Get: want times %v and %v, got %v and %v", a.CreatedAt, a.ModifiedAt, got.CreatedAt, got.ModifiedAt)
+	}
+
+	time.Sleep(time.Millisecond)
+	a2, err := store.Upsert(s, "a", store.Entry{Value: []byte("alpha2")})
+	if err != nil {
+		t.Fatalf("Upsert: unexpected error: %v", err)
+	}
+	if !a2.CreatedAt.Equal(a.CreatedAt) {
+		t.Errorf("Upsert: want the creation time kept at %v, got %v", a.CreatedAt, a2.CreatedAt)
+	}
+	if !a2.ModifiedAt.After(a.ModifiedAt) {
+		t.Errorf("Upsert: want a modification time after %v, got %v", a.ModifiedAt, a2.ModifiedAt)
+	}
+	if a2.ContentType != "" || a2.Metadata != nil {
+		t.Errorf("Upsert: want the metadata replaced along with the value, got %q and %v", a2.ContentType, a2.Metadata)
+	}
+
+	// entries restored from elsewhere keep their age
+	created := before.Add(-time.Hour)
+	if b := mustPut(t, s, "b", store.Entry{CreatedAt: created}); !b.CreatedAt.Equal(created) {
+		t.Errorf("Put with a creation time: want %v, got %v", created, b.CreatedAt)
+	}
+}
+
+func testUpdate(t *testing.T, s store.Store) {
+	appendX := func(cur store.Entry, exists bool) (store.Entry, error) {
+		v := "x"
+		if exists {
+			v = string(cur.Value) + "x"
+		}
+		return store.Entry{Value: []byte(v)}, nil
+	}
+
+	first, err := s.Update("a", appendX)
+	if err != nil {
+		t.Fatalf("Update of a missing key: unexpected error: %v", err)
+	}
+	second, err := s.Update("a", appendX)
+	if err != nil {
+		t.Fatalf("Update of an existing key: unexpected error: %v", err)
+	}
+
+	if string(second.Value) != "xx" {
+		t.Errorf("Update: want value %q, got %q", "xx", second.Value)
+	}
+	if second.Version <= first.Version {
+		t.Errorf("Update: want a version above %d, got %d", first.Version, second.Version)
+	}
+	if e := mustGet(t, s, "a"); string(e.Value) != "xx" || e.Version != second.Version {
+		t.Errorf("Get after Update: want %q at version %d, got %q at %d", "xx", second.Version, e.Value, e.Version)
+	}
+}
+
+func testUpdateError(t *testing.T, s store.Store) {
+	stored := mustPut(t, s, "a", store.Entry{Value: []byte("alpha")})
+
+	errAbort := errors.New("abort")
+	_, err := s.Update("a", func(store.Entry, bool) (store.Entry, error) {
+		return store.Entry{Value: []byte("changed")}, errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("Update: want the error returned by fn, got %v", err)
+	}
+	if e := mustGet(t, s, "a"); string(e.Value) != "alpha" || e.Version != stored.Version {
+		t.Errorf("failed Update changed the entry: got %q at version %d", e.Value, e.Version)
+	}
+
+	_, err = s.Update("missing", func(store.Entry, bool) (store.Entry, error) {
+		return store.Entry{}, errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("Update: want the error returned by fn, got %v", err)
+	}
+	if _, err := s.Get("missing"); err != store.ErrNotFound {
+		t.Errorf("failed Update created the key: got %v", err)
+	}
+}
+
+func testCompareAndSwap(t *testing.T, s store.Store) {
+	if _, err := store.CompareAndSwap(s, "a", 1, store.Entry{Value: []byte("x")}); err != store.ErrNotFound {
+		t.Errorf("swap of a missing key: want ErrNotFound, got %v", err)
+	}
+
+	created, err := store.CompareAndSwap(s, "a", 0, store.Entry{Value: []byte("first")})
+	if err != nil {
+		t.Fatalf("swap expecting an absent key: unexpected error: %v", err)
+	}
+	if _, err := store.CompareAndSwap(s, "a", 0, store.Entry{Value: []byte("x")}); err != store.ErrVersionMismatch {
+		t.Errorf("swap expecting an absent key that exists: want ErrVersionMismatch, got %v", err)
+	}
+
+	swapped, err := store.CompareAndSwap(s, "a", created.Version, store.Entry{Value: []byte("second")})
+	if err != nil {
+		t.Fatalf("swap at the current version: unexpected error: %v", err)
+	}
+	if _, err := store.CompareAndSwap(s, "a", created.Version, store.Entry{Value: []byte("x")}); err != store.ErrVersionMismatch {
+		t.Errorf("swap at a stale version: want ErrVersionMismatch, got %v", err)
+	}
+
+	if e := mustGet(t, s, "a"); string(e.Value) != "second" || e.Version != swapped.Version {
+		t.Errorf("Get: want %q at version %d, got %q at %d", "second", swapped.Version, e.Value, e.Version)
+	}
+}
+
+func testCompareAndDelete(t *testing.T, s store.Store) {
+	if err := store.CompareAndDelete(s, "a", 1); err != store.ErrNotFound {
+		t.Errorf("delete of a missing key: want ErrNotFound, got %v", err)
+	}
+
+	first := mustPut(t, s, "a", store.Entry{Value: []byte("first")})
+	second, err := store.Upsert(s, "a", store.Entry{Value: []byte("second")})
+	if err != nil {
+		t.Fatalf("Upsert: unexpected error: %v", err)
+	}
+	if err := store.CompareAndDelete(s, "a", first.Version); err != store.ErrVersionMismatch {
+		t.Errorf("delete at a stale version: want ErrVersionMismatch, got %v", err)
+	}
+	if e := mustGet(t, s, "a"); string(e.Value) != "second" {
+		t.Errorf("Get after a failed delete: want %q, got %q", "second", e.Value)
+	}
+
+	if err := store.CompareAndDelete(s, "a", second.Version); err != nil {
+		t.Fatalf("delete at the current version: unexpected error: %v", err)
+	}
+	if _, err := s.Get("a"); err != store.ErrNotFound {
+		t.Errorf("Get after delete: want ErrNotFound, got %v", err)
+	}
+}
+
+func testReplace(t *testing.T, s store.Store) {
+	if _, err := store.Replace(s, "a", store.Entry{Value: []byte("x")}); err != store.ErrNotFound {
+		t.Errorf("Replace of a missing key: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.Get("a"); err != store.ErrNotFound {
+		t.Errorf("failed Replace created the key: got %v", err)
+	}
+
+	first := mustPut(t, s, "a", store.Entry{Value: []byte("first")})
+	second, err := store.Replace(s, "a", store.Entry{Value: []byte("second")})
+	if err != nil {
+		t.Fatalf("Replace of an existing key: unexpected error: %v", err)
+	}
+	if second.Version <= first.Version {
+		t.Errorf("Replace: want a version above %d, got %d", first.Version, second.Version)
+	}
+	if e := mustGet(t, s, "a"); string(e.Value) != "second" {
+		t.Errorf("Get after Replace: want %q, got %q", "second", e.Value)
+	}
+}
+
+func testUpsert(t *testing.T, s store.Store) {
+	for _, value := range []string{"first", "second"} {
+		if _, err := store.Upsert(s, "a", store.Entry{Value: []byte(value)}); err != nil {
+			t.Fatalf("Upsert(%q): unexpected error: %v", value, err)
+		}
+		if e := mustGet(t, s, "a"); string(e.Value) != value {
+			t.Errorf("Get after Upsert: want %q, got %q", value, e.Value)
+		}
+	}
+}
+
+func testBatch(t *testing.T, s store.Store) {
+	old := mustPut(t, s, "existing", store.Entry{Value: []byte("old")})
+
+	writes := []store.Write{
+		{Key: "a", Entry: store.Entry{Value: []byte("alpha")}},
+		{Key: "existing", Entry: store.Entry{Value: []byte("x")}, Mode: store.ModeInsert},
+		{Key: "missing", Entry: store.Entry{Value: []byte("x")}, Mode: store.ModeReplace},
+		{Key: "existing", Entry: store.Entry{Value: []byte("new")}, Mode: store.ModeReplace},
+		{Key: "b", Entry: store.Entry{Value: []byte("bravo")}, Mode: store.ModeUpsert},
+		{Key: "b", Entry: store.Entry{Value: []byte("bravo2")}, Mode: store.ModeUpsert},
+		{Key: "existing", Entry: store.Entry{Value: []byte("x")}, Version: old.Version},
+		{Key: "missing", Entry: store.Entry{Value: []byte("x")}, Mode: store.ModeUpsert, Version: old.Version},
+	}
+	wantErrs := []error{nil, store.ErrExists, store.ErrNotFound, nil, nil, nil, store.ErrVersionMismatch, store.ErrNotFound}
+	results := store.PutBatch(s, writes)
+	if len(results) != len(writes) {
+		t.Fatalf("PutBatch: want %d results, got %d", len(writes), len(results))
+	}
+	for i, r := range results {
+		if r.Err != wantErrs[i] {
+			t.Errorf("PutBatch write %d to %q: want error %v, got %v", i, writes[i].Key, wantErrs[i], r.Err)
+		}
+		if r.Err == nil && r.Entry.Version == 0 {
+			t.Errorf("PutBatch write %d to %q: no version assigned", i, writes[i].Key)
+		}
+	}
+
+	keys := []string{"a", "missing", "existing", "b", "a"}
+	want := []string{"alpha", "", "new", "bravo2", "alpha"}
+	for i, r := range store.GetBatch(s, keys) {
+		switch {
+		case want[i] == "" && r.Err != store.ErrNotFound:
+			t.Errorf("GetBatch %q: want ErrNotFound, got %v", keys[i], r.Err)
+		case want[i] != "" && r.Err != nil:
+			t.Errorf("GetBatch %q: unexpected error: %v", keys[i], r.Err)
+		case want[i] != "" && string(r.Entry.Value) != want[i]:
+			t.Errorf("GetBatch %q: want %q, got %q", keys[i], want[i], r.Entry.Value)
+		}
+	}
+
+	keys = []string{"a", "missing", "a", "b"}
+	wantErrs = []error{nil, store.ErrNotFound, store.ErrNotFound, nil}
+	for i, err := range store.DeleteBatch(s, keys) {
+		if err != wantErrs[i] {
+			t.Errorf("DeleteBatch %q: want error %v, got %v", keys[i], wantErrs[i], err)
+		}
+	}
+	if st := s.Stats(); st.Keys != 1 {
+		t.Errorf("Stats after DeleteBatch: want 1 key, got %d", st.Keys)
+	}
+}
+
+func testList(t *testing.T, s store.Store) {
+	var want []string
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("users/%02d", i)
+		want = append(want, key)
+		mustPut(t, s, key, store.Entry{Value: []byte(key)})
+		mustPut(t, s, fmt.Sprintf("groups/%02d", i), store.Entry{Value: []byte("x")})
+	}
+
+	var got []string
+	start := ""
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatalf("List did not finish after %d pages", pages)
+		}
+		page, more := store.List(s, "users/", start, 10)
+		for _, ke := range page {
+			if string(ke.Entry.Value) != ke.Key {
+				t.Errorf("List: want value %q at %q, got %q", ke.Key, ke.Key, ke.Entry.Value)
+			}
+			got = append(got, ke.Key)
+		}
+		if !more {
+			break
+		}
+		if len(page) != 10 {
+			t.Errorf("List: want a full page before the last one, got %d entries", len(page))
+		}
+		start = store.KeyAfter(page[len(page)-1].Key)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("List: want keys %v, got %v", want, got)
+	}
+
+	// the empty key sorts first, and only the empty prefix matches it
+	mustPut(t, s, "", store.Entry{Value: []byte("empty")})
+	if page, _ := store.List(s, "", "", 1); len(page) != 1 || page[0].Key != "" {
+		t.Errorf("List from the start: want the empty key first, got %v", page)
+	}
+	if page, _ := store.List(s, "", store.KeyAfter(""), 1); len(page) != 1 || page[0].Key != "groups/00" {
+		t.Errorf("List after the empty key: want groups/00 first, got %v", page)
+	}
+}
+
+func testNotify(t *testing.T, s store.Store) {
+	n, ok := s.(store.Notifier)
+	if !ok {
+		t.Skip("store does not report changes")
+	}
+
+	var mu sync.Mutex
+	var got []string
+	n.Notify(func(ev store.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, fmt.Sprintf("%s %s %s", ev.Type, ev.Key, ev.Entry.Value))
+	})
+
+	mustPut(t, s, "a", store.Entry{Value: []byte("1")})
+	if _, err := store.Upsert(s, "a", store.Entry{Value: []byte("2")}); err != nil {
+		t.Fatalf("Upsert: unexpected error: %v", err)
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if err := s.Delete("a"); err != store.ErrNotFound {
+		t.Fatalf("Delete of a deleted key: want ErrNotFound, got %v", err)
+	}
+	mustPut(t, s, "b", store.Entry{Value: []byte("3"), ExpiresAt: time.Now().Add(20 * time.Millisecond)})
+	time.Sleep(40 * time.Millisecond)
+	// writing over an expired entry expires it first
+	mustPut(t, s, "b", store.Entry{Value: []byte("4")})
+
+	want := []string{"put a 1", "update a 2", "delete a 2", "put b 3", "expire b 3", "put b 4"}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Notify: want events %q, got %q", want, got)
+	}
+}
+
+func testConcurrent(t *testing.T, s store.Store) {
+	const workers, keys = 8, 200
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				key := fmt.Sprintf("key-%d", i)
+				switch (w + i) % 3 {
+				case 0:
+					s.Put(key, store.Entry{Value: []byte(key)})
+				case 1:
+					if e, err := s.Get(key); err == nil && string(e.Value) != key {
+						t.Errorf("Get(%q): got value %q", key, e.Value)
+					}
+				case 2:
+					s.Delete(key)
+				}
+			}
+			s.Range(func(string, store.Entry) bool { return true })
+			s.Stats()
+
+			// updates to a single key must not be lost
+			for i := 0; i < keys; i++ {
+				_, err := s.Update("counter", func(cur store.Entry, _ bool) (store.Entry, error) {
+					return store.Entry{Value: append(cur.Value[:len(cur.Value):len(cur.Value)], 'x')}, nil
+				})
+				if err != nil {
+					t.Errorf("Update: unexpected error: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if got := len(mustGet(t, s, "counter").Value); got != workers*keys {
+		t.Errorf("concurrent Updates: want %d increments, got %d", workers*keys, got)
+	}
+}
@@ -0,0 +1,361 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tiered is a Store that keeps its hot entries in memory and the rest on
+// disk. Entries evicted from the memory tier are demoted to the disk tier
+// instead of being dropped, and are promoted back to memory when they are
+// read. The disk tier has a budget of its own, beyond which its oldest
+// entries are evicted.
+//
+// Entries too large for the memory tier are written straight to the disk
+// tier, and stay there when read. Entries keep their version as they move
+// between tiers. Events for entries removed from the disk tier carry their
+// metadata but not their value.
+type Tiered struct {
+	memLatency  latencyRecorder // kept first for alignment
+	diskLatency latencyRecorder
+	hits        uint64 // accessed atomically
+	misses      uint64 // accessed atomically
+	promotions  uint64 // accessed atomically
+	demotions   uint64 // accessed atomically
+
+	mem  *Sharded
+	disk *disk
+
+	// locks serialize the operations on a key across both tiers. The memory
+	// tier can still move entries to disk on its own, but never the other
+	// way around.
+	locks [64]sync.Mutex
+
+	notifyMu sync.RWMutex
+	notify   []func(Event)
+}
+
+// TierStats details the contents and activity of each tier of a Tiered
+// store.
+type TierStats struct {
+	Memory     TierStat `json:"memory"`
+	Disk       TierStat `json:"disk"`
+	Promotions uint64   `json:"promotions"`
+	Demotions  uint64   `json:"demotions"`
+	Segments   int      `json:"segments"`
+	DiskUsed   int64    `json:"disk_used"`      // bytes in segment files, including dead values
+	Reclaimed  int64    `json:"disk_reclaimed"` // bytes freed by garbage collection
+}
+
+// TierStat summarizes the contents of a tier and the latency of the reads it
+// served.
+type TierStat struct {
+	Keys       int     `json:"keys"`
+	Bytes      int64   `json:"bytes"`
+	GetLatency Latency `json:"get_latency"`
+}
+
+// NewTiered returns an empty Tiered store using mem as its memory tier, which
+// should be bounded and must not be used directly afterwards. The disk tier
+// keeps its segments in dir, and is limited to maxBytes of segment files of
+// segmentSize bytes each. Values larger than a segment cannot be demoted, and
+// writes of entries too large for both a shard of the memory tier and a
+// segment fail with ErrTooLarge.
+func NewTiered(mem *Sharded, dir string, maxBytes, segmentSize int64) (*Tiered, error) {
+	d, err := newDisk(dir, maxBytes, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tiered{
+		mem:  mem,
+		disk: d,
+	}
+	mem.setDemote(t.demote)
+	d.evicted = t.diskEvicted
+	return t, nil
+}
+
+// lock returns the mutex serializing the operations on key.
+func (t *Tiered) lock(key string) *sync.Mutex {
+	return &t.locks[hashKey(key)%uint32(len(t.locks))]
+}
+
+// demote moves an entry evicted from memory to disk.
+func (t *Tiered) demote(key string, e Entry) bool {
+	if err := t.disk.put(key, e); err != nil {
+		return false
+	}
+	atomic.AddUint64(&t.demotions, 1)
+	return true
+}
+
+// diskEvicted reports the entries dropped by the disk tier.
+func (t *Tiered) diskEvicted(typ EventType, key string, e Entry) {
+	t.emit(Event{Type: typ, Key: key, Entry: e})
+}
+
+// Get returns the entry at key, promoting it to memory if it was on disk.
+func (t *Tiered) Get(key string) (Entry, error) {
+	start := time.Now()
+	if e, err := t.mem.Get(key); err == nil {
+		t.memLatency.record(time.Since(start))
+		atomic.AddUint64(&t.hits, 1)
+		return e, nil
+	}
+
+	mu := t.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	e, de, err := t.disk.get(key, start)
+	if err != nil {
+		// the entry may have been written to memory while waiting for the
+		// lock
+		if e, err := t.mem.Get(key); err == nil {
+			atomic.AddUint64(&t.hits, 1)
+			return e, nil
+		}
+		atomic.AddUint64(&t.misses, 1)
+		return Entry{}, ErrNotFound
+	}
+
+	if t.mem.promote(key, e) {
+		// the entry may have been demoted again already, in which case the
+		// new copy on disk is kept
+		t.disk.deleteIf(key, de)
+		atomic.AddUint64(&t.promotions, 1)
+	}
+	t.diskLatency.record(time.Since(start))
+	atomic.AddUint64(&t.hits, 1)
+	return e, nil
+}
+
+// Put stores e at key unless a live entry is present in either tier.
+func (t *Tiered) Put(key string, e Entry) (Entry, error) {
+	mu := t.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if t.disk.has(key, time.Now()) {
+		return Entry{}, ErrExists
+	}
+	stored, err := t.mem.Put(key, e)
+	if err == ErrTooLarge {
+		return t.putDisk(key, e, false)
+	}
+	return stored, err
+}
+
+// Update atomically replaces the entry at key, in either tier, with the one
+// returned by fn. The new entry is stored in memory.
+func (t *Tiered) Update(key string, fn UpdateFunc) (Entry, error) {
+	mu := t.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	var onDisk *diskEntry
+	var next Entry
+	var computed, existed bool
+	e, err := t.mem.Update(key, func(cur Entry, exists bool) (Entry, error) {
+		if !exists {
+			if de, loc, err := t.disk.get(key, time.Now()); err == nil {
+				cur, exists, onDisk = de, true, loc
+			}
+		}
+		e, err := fn(cur, exists)
+		if onDisk != nil {
+			// the entry is replaced rather than created
+			e.CreatedAt = cur.CreatedAt
+		}
+		next, computed, existed = e, err == nil, exists
+		return e, err
+	})
+	switch {
+	case err == ErrTooLarge && computed:
+		// the copy on disk, if any, is replaced
+		return t.putDisk(key, next, existed)
+	case err == nil && onDisk != nil:
+		t.disk.deleteIf(key, onDisk)
+	}
+	return e, err
+}
+
+// putDisk stores e, too large for the memory tier, at key on disk, replacing
+// the entry in either tier. existed tells whether there was a live entry to
+// replace, for the event reported. The caller must hold the lock of key.
+func (t *Tiered) putDisk(key string, e Entry, existed bool) (Entry, error) {
+	stored, err := t.mem.storeBelow(key, e, t.disk.put)
+	if err != nil {
+		return Entry{}, err
+	}
+	typ := EventPut
+	if existed {
+		typ = EventUpdate
+	}
+	t.emit(Event{Type: typ, Key: key, Entry: stored})
+	return stored, nil
+}
+
+// Delete removes the entry at key from both tiers.
+func (t *Tiered) Delete(key string) error {
+	mu := t.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	memErr := t.mem.Delete(key)
+	// a copy on disk is stale if the entry was in memory
+	e, ok := t.disk.delete(key)
+	switch {
+	case memErr == nil:
+		return nil
+	case ok && !e.Expired(time.Now()):
+		t.emit(Event{Type: EventDelete, Key: key, Entry: e})
+		return nil
+	}
+	return ErrNotFound
+}
+
+// CompareAndDelete removes the entry at key, in either tier, if it is at
+// version.
+func (t *Tiered) CompareAndDelete(key string, version uint64) error {
+	mu := t.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	switch err := t.mem.CompareAndDelete(key, version); err {
+	case nil:
+		// a copy on disk is stale if the entry was in memory
+		t.disk.delete(key)
+		return nil
+	case ErrNotFound:
+	default:
+		return err
+	}
+
+	e, err := t.disk.deleteVersion(key, version, time.Now())
+	if err != nil {
+		return err
+	}
+	t.emit(Event{Type: EventDelete, Key: key, Entry: e})
+	return nil
+}
+
+// Range calls fn for each live entry in memory, then for each one on disk.
+// Entries read from disk are not promoted.
+func (t *Tiered) Range(fn func(key string, e Entry) bool) {
+	inMemory := make(map[string]struct{})
+	stopped := false
+	t.mem.Range(func(key string, e Entry) bool {
+		inMemory[key] = struct{}{}
+		stopped = !fn(key, e)
+		return !stopped
+	})
+	if stopped {
+		return
+	}
+
+	t.disk.rangeEntries(func(key string, e Entry) bool {
+		if _, ok := inMemory[key]; ok {
+			return true
+		}
+		return fn(key, e)
+	})
+}
+
+// Stats summarizes the store's contents and activity across both tiers.
+// Evictions only count the entries dropped from disk, or from memory when
+// they could not be demoted.
+func (t *Tiered) Stats() Stats {
+	// entries are evicted from memory before they are demoted
+	demotions := atomic.LoadUint64(&t.demotions)
+	ms := t.mem.Stats()
+
+	t.disk.mu.RLock()
+	ds := t.disk.stats
+	ds.Keys = len(t.disk.index)
+	ds.Bytes = t.disk.bytes
+	t.disk.mu.RUnlock()
+
+	return Stats{
+		Keys:        ms.Keys + ds.Keys,
+		Bytes:       ms.Bytes + ds.Bytes,
+		Hits:        atomic.LoadUint64(&t.hits),
+		Misses:      atomic.LoadUint64(&t.misses),
+		Evictions:   ms.Evictions - demotions + ds.Evictions,
+		Expirations: ms.Expirations + ds.Expirations,
+	}
+}
+
+// TierStats details the contents and activity of each tier.
+func (t *Tiered) TierStats() TierStats {
+	ms := t.mem.Stats()
+
+	t.disk.mu.RLock()
+	st := TierStats{
+		Disk: TierStat{
+			Keys:  len(t.disk.index),
+			Bytes: t.disk.bytes,
+		},
+		Segments:  len(t.disk.segments),
+		DiskUsed:  t.disk.used,
+		Reclaimed: t.disk.reclaimed,
+	}
+	t.disk.mu.RUnlock()
+
+	st.Memory = TierStat{
+		Keys:       ms.Keys,
+		Bytes:      ms.Bytes,
+		GetLatency: t.memLatency.summary(),
+	}
+	st.Disk.GetLatency = t.diskLatency.summary()
+	st.Promotions = atomic.LoadUint64(&t.promotions)
+	st.Demotions = atomic.LoadUint64(&t.demotions)
+	return st
+}
+
+// Notify registers fn to be called with every subsequent change to the
+// store's entries. Moves between tiers are not reported. See Notifier.
+func (t *Tiered) Notify(fn func(Event)) {
+	t.notifyMu.Lock()
+	t.notify = append(t.notify, fn)
+	t.notifyMu.Unlock()
+
+	t.mem.Notify(func(ev Event) {
+		// writing to memory over an entry held on disk updates it
+		if ev.Type == EventPut && t.disk.has(ev.Key, time.Now()) {
+			ev.Type = EventUpdate
+		}
+		fn(ev)
+	})
+}
+
+// emit reports a change made outside of the memory tier.
+func (t *Tiered) emit(ev Event) {
+	t.notifyMu.RLock()
+	defer t.notifyMu.RUnlock()
+
+	for _, fn := range t.notify {
+		fn(ev)
+	}
+}
+
+// ReapExpired removes every entry that has expired as of now from both
+// tiers.
+func (t *Tiered) ReapExpired(now time.Time) int {
+	return t.mem.ReapExpired(now) + t.disk.reapExpired(now)
+}
+
+// CollectGarbage rewrites the disk segments that mostly hold values that
+// have since been overwritten, deleted or promoted, and returns the number of
+// bytes of disk freed.
+func (t *Tiered) CollectGarbage() (int64, error) {
+	return t.disk.collectGarbage()
+}
+
+// Close removes the disk tier's segments. The store must not be used
+// afterwards.
+func (t *Tiered) Close() error {
+	return t.disk.close()
+}
@@ -0,0 +1,389 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+	"github.com/timraymond/cachely/store/storetest"
+)
+
+// tempDir creates a temporary directory, and returns it along with a
+// function removing it.
+func tempDir(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cachely-store-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %v", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestTieredConformance(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	var stores []*store.Tiered
+	defer func() {
+		for _, s := range stores {
+			s.Close()
+		}
+	}()
+	storetest.Run(t, func() store.Store {
+		sub, err := ioutil.TempDir(dir, "")
+		if err != nil {
+			t.Fatalf("creating temporary directory: %v", err)
+		}
+		s, err := store.NewTiered(store.NewSharded(8, 0, nil), sub, 1<<20, 64<<10)
+		if err != nil {
+			t.Fatalf("NewTiered: unexpected error: %v", err)
+		}
+		stores = append(stores, s)
+		return s
+	})
+}
+
+// newTestTiered returns a Tiered store whose memory tier holds two entries
+// of 10 bytes, along with the events it reports and a function closing it.
+func newTestTiered(t *testing.T, diskBytes, segmentSize int64) (*store.Tiered, *[]string, func()) {
+	t.Helper()
+	dir, cleanup := tempDir(t)
+	s, err := store.NewTiered(store.NewSharded(1, 20, nil), dir, diskBytes, segmentSize)
+	if err != nil {
+		cleanup()
+		t.Fatalf("NewTiered: unexpected error: %v", err)
+	}
+	events := new([]string)
+	s.Notify(func(ev store.Event) {
+		*events = append(*events, ev.Type.String()+" "+ev.Key)
+	})
+	return s, events, func() {
+		s.Close()
+		cleanup()
+	}
+}
+
+// value is the 9 byte value of key in the tests of Tiered, making up a 10
+// byte entry with a 1 byte key.
+func value(key string) []byte {
+	return []byte(key + "12345678")
+}
+
+func mustPutValues(t *testing.T, s store.Store, keys ...string) map[string]store.Entry {
+	t.Helper()
+	entries := make(map[string]store.Entry)
+	for _, key := range keys {
+		e, err := s.Put(key, store.Entry{Value: value(key)})
+		if err != nil {
+			t.Fatalf("Put(%q): unexpected error: %v", key, err)
+		}
+		entries[key] = e
+	}
+	return entries
+}
+
+func TestTieredDemotion(t *testing.T) {
+	s, events, cleanup := newTestTiered(t, 1<<20, 1<<10)
+	defer cleanup()
+
+	entries := mustPutValues(t, s, "a", "b", "c")
+	if want := []string{"put a", "put b", "put c"}; !equalStrings(*events, want) {
+		t.Errorf("want events %q, got %q, moves between tiers are not reported", want, *events)
+	}
+	st := s.TierStats()
+	if st.Memory.Keys != 2 || st.Disk.Keys != 1 || st.Disk.Bytes != 10 || st.Demotions != 1 || st.Segments != 1 {
+		t.Errorf("TierStats: want 2 keys in memory, 1 of 10 bytes on disk in 1 segment and 1 demotion, got %+v", st)
+	}
+
+	// reading a promotes it, which demotes b, the least recently used key
+	e, err := s.Get("a")
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if string(e.Value) != string(value("a")) || e.Version != entries["a"].Version || !e.CreatedAt.Equal(entries["a"].CreatedAt) {
+		t.Errorf("Get: want %+v, got %+v", entries["a"], e)
+	}
+	st = s.TierStats()
+	if st.Memory.Keys != 2 || st.Disk.Keys != 1 || st.Promotions != 1 || st.Demotions != 2 {
+		t.Errorf("TierStats: want 2 keys in memory, 1 on disk, 1 promotion and 2 demotions, got %+v", st)
+	}
+	if st := s.Stats(); st.Keys != 3 || st.Bytes != 30 || st.Hits != 1 || st.Evictions != 0 {
+		t.Errorf("Stats: want 3 keys of 30 bytes, 1 hit and no eviction, got %+v", st)
+	}
+
+	// entries on disk are live
+	if _, err := s.Put("b", store.Entry{Value: value("b")}); err != store.ErrExists {
+		t.Errorf("Put over an entry on disk: want store.ErrExists, got %v", err)
+	}
+	var keys []string
+	s.Range(func(key string, e store.Entry) bool {
+		keys = append(keys, key)
+		if string(e.Value) != string(value(key)) {
+			t.Errorf("Range: want %q at %q, got %q", value(key), key, e.Value)
+		}
+		return true
+	})
+	if len(keys) != 3 {
+		t.Errorf("Range: want 3 keys, got %q", keys)
+	}
+	if st := s.TierStats(); st.Promotions != 1 {
+		t.Errorf("Range: want entries on disk left there, got %d promotions", st.Promotions)
+	}
+}
+
+func TestTieredWritesToDisk(t *testing.T) {
+	s, events, cleanup := newTestTiered(t, 1<<20, 1<<10)
+	defer cleanup()
+
+	// a and b are demoted
+	entries := mustPutValues(t, s, "a", "b", "c", "d")
+	*events = nil
+
+	// updating an entry on disk replaces it
+	e, err := s.Update("a", func(cur store.Entry, exists bool) (store.Entry, error) {
+		if !exists || string(cur.Value) != string(value("a")) {
+			t.Errorf("Update: want the entry on disk, got %q and %v", cur.Value, exists)
+		}
+		cur.Value = value("z")
+		return cur, nil
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if e.Version <= entries["a"].Version || !e.CreatedAt.Equal(entries["a"].CreatedAt) {
+		t.Errorf("Update: want a new version created at %v, got %+v", entries["a"].CreatedAt, e)
+	}
+	if got, err := s.Get("a"); err != nil || string(got.Value) != string(value("z")) {
+		t.Errorf("Get after Update: want %q, got %q and %v", value("z"), got.Value, err)
+	}
+
+	if err := s.CompareAndDelete("b", entries["b"].Version+100); err != store.ErrVersionMismatch {
+		t.Errorf("CompareAndDelete at another version: want store.ErrVersionMismatch, got %v", err)
+	}
+	if err := s.CompareAndDelete("b", entries["b"].Version); err != nil {
+		t.Errorf("CompareAndDelete: unexpected error: %v", err)
+	}
+	// c was demoted to make room for a
+	if err := s.Delete("c"); err != nil {
+		t.Errorf("Delete: unexpected error: %v", err)
+	}
+	if err := s.Delete("c"); err != store.ErrNotFound {
+		t.Errorf("Delete of a deleted key: want store.ErrNotFound, got %v", err)
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, err := s.Get(key); err != store.ErrNotFound {
+			t.Errorf("Get(%q) after it was deleted: want store.ErrNotFound, got %v", key, err)
+		}
+	}
+	if want := []string{"update a", "delete b", "delete c"}; !equalStrings(*events, want) {
+		t.Errorf("want events %q, got %q", want, *events)
+	}
+	if st := s.TierStats(); st.Memory.Keys+st.Disk.Keys != 2 {
+		t.Errorf("TierStats: want 2 keys left, got %+v", st)
+	}
+}
+
+func TestTieredLargeEntries(t *testing.T) {
+	s, events, cleanup := newTestTiered(t, 1<<20, 1<<10)
+	defer cleanup()
+
+	// entries too large for memory are written to disk, and stay there
+	entries := mustPutValues(t, s, "a")
+	large := make([]byte, 100)
+	big, err := s.Put("big", store.Entry{Value: large})
+	if err != nil {
+		t.Fatalf("Put of an entry larger than memory: unexpected error: %v", err)
+	}
+	if big.Version <= entries["a"].Version || big.CreatedAt.IsZero() || !big.ModifiedAt.Equal(big.CreatedAt) {
+		t.Errorf("Put: want a new version and its timestamps, got %+v", big)
+	}
+	if _, err := s.Put("big", store.Entry{Value: value("b")}); err != store.ErrExists {
+		t.Errorf("Put over a large entry: want store.ErrExists, got %v", err)
+	}
+	if e, err := s.Get("big"); err != nil || len(e.Value) != 100 || e.Version != big.Version {
+		t.Errorf("Get of a large entry: want %+v, got %+v and %v", big, e, err)
+	}
+	st := s.TierStats()
+	if st.Memory.Keys != 1 || st.Disk.Keys != 1 || st.Promotions != 0 || st.Demotions != 0 {
+		t.Errorf("TierStats: want a key in each tier and no move between them, got %+v", st)
+	}
+
+	// an entry in memory grown too large for it moves to disk
+	e, err := s.Update("a", func(cur store.Entry, exists bool) (store.Entry, error) {
+		cur.Value = large
+		return cur, nil
+	})
+	if err != nil || e.Version <= big.Version || !e.CreatedAt.Equal(entries["a"].CreatedAt) {
+		t.Errorf("Update to a large entry: want a new version created at %v, got %+v and %v", entries["a"].CreatedAt, e, err)
+	}
+	if st := s.TierStats(); st.Memory.Keys != 0 || st.Disk.Keys != 2 {
+		t.Errorf("TierStats: want both keys on disk, got %+v", st)
+	}
+
+	// and back to memory once it fits again
+	if _, err := s.Update("a", func(cur store.Entry, exists bool) (store.Entry, error) {
+		cur.Value = value("a")
+		return cur, nil
+	}); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if st := s.TierStats(); st.Memory.Keys != 1 || st.Disk.Keys != 1 {
+		t.Errorf("TierStats: want a key in each tier, got %+v", st)
+	}
+	if got, err := s.Get("a"); err != nil || string(got.Value) != string(value("a")) {
+		t.Errorf("Get: want %q, got %q and %v", value("a"), got.Value, err)
+	}
+
+	if err := s.Delete("big"); err != nil {
+		t.Errorf("Delete of a large entry: unexpected error: %v", err)
+	}
+	if want := []string{"put a", "put big", "update a", "update a", "delete big"}; !equalStrings(*events, want) {
+		t.Errorf("want events %q, got %q", want, *events)
+	}
+}
+
+func TestTieredDiskEviction(t *testing.T) {
+	// each value takes 13 bytes on disk, its checksum included, so each
+	// segment holds a single value and the disk two of them
+	s, events, cleanup := newTestTiered(t, 30, 15)
+	defer cleanup()
+
+	mustPutValues(t, s, "a", "b", "c", "d", "e", "f")
+	var evicted []string
+	for _, ev := range *events {
+		if ev[:5] == "evict" {
+			evicted = append(evicted, ev)
+		}
+	}
+	if want := []string{"evict a", "evict b"}; !equalStrings(evicted, want) {
+		t.Errorf("want the oldest entries on disk evicted, got %q", evicted)
+	}
+	if st := s.Stats(); st.Evictions != 2 {
+		t.Errorf("Stats: want 2 evictions, got %d", st.Evictions)
+	}
+	if st := s.TierStats(); st.DiskUsed > 30 || st.Segments > 2 {
+		t.Errorf("TierStats: want at most 30 bytes on disk in 2 segments, got %d in %d", st.DiskUsed, st.Segments)
+	}
+	var keys []string
+	s.Range(func(key string, e store.Entry) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+	if want := []string{"c", "d", "e", "f"}; !equalStrings(keys, want) {
+		t.Errorf("Range: want keys %q, got %q", want, keys)
+	}
+	if _, err := s.Get("a"); err != store.ErrNotFound {
+		t.Errorf("Get of an entry evicted from disk: want store.ErrNotFound, got %v", err)
+	}
+
+	// values larger than a segment are evicted rather than demoted
+	s, events, cleanup = newTestTiered(t, 30, 5)
+	defer cleanup()
+	mustPutValues(t, s, "a", "b", "c")
+	if want := []string{"put a", "put b", "evict a", "put c"}; !equalStrings(*events, want) {
+		t.Errorf("want events %q, got %q", want, *events)
+	}
+	if st := s.Stats(); st.Evictions != 1 || st.Keys != 2 {
+		t.Errorf("Stats: want 2 keys and 1 eviction, got %+v", st)
+	}
+	if _, err := s.Put("big", store.Entry{Value: make([]byte, 20)}); err != store.ErrTooLarge {
+		t.Errorf("Put of an entry larger than memory and a segment: want store.ErrTooLarge, got %v", err)
+	}
+}
+
+func TestTieredCollectGarbage(t *testing.T) {
+	// each segment holds four values of 13 bytes
+	s, _, cleanup := newTestTiered(t, 1<<20, 52)
+	defer cleanup()
+
+	// a to h are demoted to two segments
+	mustPutValues(t, s, "a", "b", "c", "d", "e", "f", "g", "h", "i", "j")
+	for _, key := range []string{"a", "b", "c"} {
+		if err := s.Delete(key); err != nil {
+			t.Fatalf("Delete(%q): unexpected error: %v", key, err)
+		}
+	}
+	if st := s.TierStats(); st.Segments != 2 || st.DiskUsed != 104 {
+		t.Fatalf("TierStats: want 104 bytes on disk in 2 segments, got %d in %d", st.DiskUsed, st.Segments)
+	}
+
+	// the first segment only holds d, which is moved to a new segment
+	freed, err := s.CollectGarbage()
+	if err != nil || freed != 39 {
+		t.Fatalf("CollectGarbage: want 39 bytes freed, got %d and %v", freed, err)
+	}
+	st := s.TierStats()
+	if st.Reclaimed != 39 || st.DiskUsed != 65 || st.Segments != 2 || st.Disk.Keys != 5 {
+		t.Errorf("TierStats: want 39 bytes reclaimed, 65 used in 2 segments by 5 keys, got %+v", st)
+	}
+	for _, key := range []string{"d", "e", "f", "g", "h"} {
+		if e, err := s.Get(key); err != nil || string(e.Value) != string(value(key)) {
+			t.Errorf("Get(%q): want %q, got %q and %v", key, value(key), e.Value, err)
+		}
+	}
+}
+
+func TestTieredCollectGarbageDuringUpdate(t *testing.T) {
+	s, _, cleanup := newTestTiered(t, 1<<20, 52)
+	defer cleanup()
+
+	// d is left alone in the first segment, as above
+	mustPutValues(t, s, "a", "b", "c", "d", "e", "f", "g", "h", "i", "j")
+	for _, key := range []string{"a", "b", "c"} {
+		if err := s.Delete(key); err != nil {
+			t.Fatalf("Delete(%q): unexpected error: %v", key, err)
+		}
+	}
+
+	// d is moved by garbage collection after it was read from disk, and the
+	// copy moved is still dropped once d is written to memory
+	_, err := s.Update("d", func(cur store.Entry, exists bool) (store.Entry, error) {
+		if freed, err := s.CollectGarbage(); err != nil || freed == 0 {
+			t.Errorf("CollectGarbage: want d moved, got %d bytes freed and %v", freed, err)
+		}
+		cur.Value = value("z")
+		cur.ExpiresAt = time.Now().Add(20 * time.Millisecond)
+		return cur, nil
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if st := s.TierStats(); st.Disk.Keys != 5 {
+		t.Errorf("TierStats: want e to h and one of i and j on disk, got %d keys", st.Disk.Keys)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if e, err := s.Get("d"); err != store.ErrNotFound {
+		t.Errorf("Get after d expired: want store.ErrNotFound, got %q and %v", e.Value, err)
+	}
+}
+
+func TestTieredSegmentFiles(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// the segments left by a previous process are removed
+	stale := filepath.Join(dir, "0000000000000001.seg")
+	if err := ioutil.WriteFile(stale, []byte("stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.NewTiered(store.NewSharded(1, 20, nil), dir, 1<<20, 1<<10)
+	if err != nil {
+		t.Fatalf("NewTiered: unexpected error: %v", err)
+	}
+	mustPutValues(t, s, "a", "b", "c", "d")
+	if got, err := ioutil.ReadFile(stale); err != nil || string(got) == "stale" {
+		t.Errorf("ReadFile: want the stale segment replaced, got %q and %v", got, err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(dir, "*.seg")); len(segments) != 0 {
+		t.Errorf("Close: want the segments removed, got %q", segments)
+	}
+}
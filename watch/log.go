@@ -0,0 +1,228 @@
+// Package watch keeps a bounded history of the changes made to a store so
+// that clients can follow them, and resume following them after a
+// disconnection without missing any.
+package watch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+var (
+	// ErrRevisionUnavailable is returned when resuming from a revision that
+	// has already been dropped from the log, or that the log has not reached,
+	// as happens after a restart. The watcher has to resynchronize from the
+	// store instead.
+	ErrRevisionUnavailable = errors.New("watch: revision is not in the change log")
+
+	// ErrLagging is returned by Watcher.Next once the watcher has fallen so
+	// far behind that the log stopped buffering changes for it.
+	ErrLagging = errors.New("watch: watcher fell too far behind")
+
+	// ErrStopped is returned by Watcher.Next after Stop has been called.
+	ErrStopped = errors.New("watch: watcher stopped")
+)
+
+// watcherBuffer is the number of changes buffered for a watcher before it is
+// considered to be lagging.
+const watcherBuffer = 1024
+
+// Event is a change recorded in a Log. Values are not retained, so the
+// memory held by the log only depends on its capacity and key sizes.
+type Event struct {
+	// Revision identifies the change. It increases by one with every change
+	// recorded in the log.
+	Revision uint64
+
+	Type      store.EventType
+	Key       string
+	Version   uint64    // version of the entry written or removed
+	ExpiresAt time.Time // expiration of the entry written or removed
+}
+
+// Filter selects the keys a watcher follows: either a single key, or every
+// key starting with Key when Prefix is set.
+type Filter struct {
+	Key    string
+	Prefix bool
+}
+
+// Match reports whether the filter selects key.
+func (f Filter) Match(key string) bool {
+	if f.Prefix {
+		return strings.HasPrefix(key, f.Key)
+	}
+	return key == f.Key
+}
+
+// Log records the changes reported by a store.Notifier in a ring buffer of
+// fixed capacity and fans them out to watchers. It is safe for concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	events   []Event // ring buffer holding the latest changes
+	start    int     // position of the oldest change in events
+	n        int     // number of changes held in events
+	rev      uint64  // revision of the latest change
+	watchers map[*Watcher]struct{}
+}
+
+// NewLog returns an empty Log remembering the last capacity changes, which
+// must be positive.
+func NewLog(capacity int) *Log {
+	if capacity <= 0 {
+		panic("watch: non-positive log capacity")
+	}
+	return &Log{
+		events:   make([]Event, capacity),
+		watchers: make(map[*Watcher]struct{}),
+	}
+}
+
+// Record appends a change to the log and hands it to the watchers following
+// its key. It never blocks, so it can be registered with store.Notifier.
+func (l *Log) Record(ev store.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rev++
+	e := Event{
+		Revision:  l.rev,
+		Type:      ev.Type,
+		Key:       ev.Key,
+		Version:   ev.Entry.Version,
+		ExpiresAt: ev.Entry.ExpiresAt,
+	}
+
+	if l.n < len(l.events) {
+		l.events[(l.start+l.n)%len(l.events)] = e
+		l.n++
+	} else {
+		l.events[l.start] = e
+		l.start = (l.start + 1) % len(l.events)
+	}
+
+	for w := range l.watchers {
+		if !w.filter.Match(e.Key) {
+			continue
+		}
+		select {
+		case w.ch <- e:
+		default:
+			l.stop(w, ErrLagging)
+		}
+	}
+}
+
+// Revision returns the revision of the latest change, or zero if nothing has
+// been recorded.
+func (l *Log) Revision() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rev
+}
+
+// Watch starts following the changes selected by f. If after is zero, only
+// changes recorded from now on are delivered; otherwise every change with a
+// later revision is replayed first. Watch returns ErrRevisionUnavailable if
+// some of those changes are no longer in the log.
+//
+// The watcher must be stopped once it is no longer needed.
+func (l *Log) Watch(f Filter, after uint64) (*Watcher, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := &Watcher{
+		log:    l,
+		filter: f,
+		start:  l.rev,
+		ch:     make(chan Event, watcherBuffer),
+		done:   make(chan struct{}),
+	}
+
+	if after != 0 {
+		oldest := l.rev - uint64(l.n) // revision just before the oldest change held
+		if after < oldest || after > l.rev {
+			return nil, ErrRevisionUnavailable
+		}
+		for i := int(after - oldest); i < l.n; i++ {
+			e := l.events[(l.start+i)%len(l.events)]
+			if f.Match(e.Key) {
+				w.backlog = append(w.backlog, e)
+			}
+		}
+	}
+
+	l.watchers[w] = struct{}{}
+	return w, nil
+}
+
+// stop detaches w from the log, making its Next fail with err once it has
+// drained the changes already handed to it. The caller must hold l.mu.
+func (l *Log) stop(w *Watcher, err error) {
+	if _, ok := l.watchers[w]; !ok {
+		return
+	}
+	delete(l.watchers, w)
+	w.err = err
+	close(w.done)
+}
+
+// Watcher follows the changes recorded in a Log. Its methods must not be
+// called concurrently.
+type Watcher struct {
+	log     *Log
+	filter  Filter
+	start   uint64        // revision of the log when the watcher started
+	backlog []Event       // replayed changes, delivered before ch
+	ch      chan Event    // changes recorded since the watcher started
+	done    chan struct{} // closed when the log stops feeding ch
+	err     error         // why done was closed, set before closing it
+}
+
+// Next returns the next change followed by the watcher, blocking until one
+// is recorded or ctx is done. It returns ErrLagging if the watcher fell
+// behind; the caller can watch again from the revision of the last change it
+// got.
+func (w *Watcher) Next(ctx context.Context) (Event, error) {
+	if len(w.backlog) > 0 {
+		e := w.backlog[0]
+		w.backlog = w.backlog[1:]
+		return e, nil
+	}
+
+	// deliver the changes that were buffered before the watcher was stopped
+	select {
+	case e := <-w.ch:
+		return e, nil
+	default:
+	}
+
+	select {
+	case e := <-w.ch:
+		return e, nil
+	case <-w.done:
+		return Event{}, w.err
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Revision returns the revision of the latest change recorded when the
+// watcher started. Later changes are delivered by Next.
+func (w *Watcher) Revision() uint64 {
+	return w.start
+}
+
+// Stop detaches the watcher from its log.
+func (w *Watcher) Stop() {
+	w.log.mu.Lock()
+	defer w.log.mu.Unlock()
+
+	w.log.stop(w, ErrStopped)
+}
@@ -0,0 +1,125 @@
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timraymond/cachely/store"
+)
+
+func record(l *Log, typ store.EventType, keys ...string) {
+	for _, key := range keys {
+		l.Record(store.Event{Type: typ, Key: key})
+	}
+}
+
+// next returns the next change delivered to w, failing the test if none comes
+// quickly.
+func next(t *testing.T, w *Watcher) Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := w.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	return e
+}
+
+func TestWatchFilters(t *testing.T) {
+	l := NewLog(10)
+	key, err := l.Watch(Filter{Key: "users/1"}, 0)
+	if err != nil {
+		t.Fatalf("Watch: unexpected error: %v", err)
+	}
+	defer key.Stop()
+	prefix, err := l.Watch(Filter{Key: "users/", Prefix: true}, 0)
+	if err != nil {
+		t.Fatalf("Watch: unexpected error: %v", err)
+	}
+	defer prefix.Stop()
+
+	record(l, store.EventPut, "users/1", "groups/1", "users/2", "users/10")
+	record(l, store.EventDelete, "users/1")
+
+	for _, want := range []Event{{Revision: 1, Type: store.EventPut, Key: "users/1"}, {Revision: 5, Type: store.EventDelete, Key: "users/1"}} {
+		if e := next(t, key); e != want {
+			t.Errorf("key watcher: want %+v, got %+v", want, e)
+		}
+	}
+	for _, want := range []string{"users/1", "users/2", "users/10", "users/1"} {
+		if e := next(t, prefix); e.Key != want {
+			t.Errorf("prefix watcher: want a change to %q, got %+v", want, e)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := key.Next(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Next without changes: want DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWatchResume(t *testing.T) {
+	l := NewLog(3)
+	record(l, store.EventPut, "a", "b", "c", "d", "e")
+
+	// revisions 3 to 5 are held, so the watch can resume from 2 onwards
+	w, err := l.Watch(Filter{Prefix: true}, 3)
+	if err != nil {
+		t.Fatalf("Watch: unexpected error: %v", err)
+	}
+	defer w.Stop()
+	if w.Revision() != 5 {
+		t.Errorf("Revision: want 5, got %d", w.Revision())
+	}
+	record(l, store.EventPut, "f")
+	for _, want := range []string{"d", "e", "f"} {
+		if e := next(t, w); e.Key != want {
+			t.Errorf("want the change to %q, got %+v", want, e)
+		}
+	}
+
+	for _, after := range []uint64{1, 7} {
+		if _, err := l.Watch(Filter{Prefix: true}, after); err != ErrRevisionUnavailable {
+			t.Errorf("Watch from revision %d: want ErrRevisionUnavailable, got %v", after, err)
+		}
+	}
+	if _, err := l.Watch(Filter{Prefix: true}, 6); err != nil {
+		t.Errorf("Watch from the latest revision: unexpected error: %v", err)
+	}
+}
+
+func TestWatchLagging(t *testing.T) {
+	l := NewLog(10)
+	w, err := l.Watch(Filter{Prefix: true}, 0)
+	if err != nil {
+		t.Fatalf("Watch: unexpected error: %v", err)
+	}
+	for i := 0; i <= watcherBuffer; i++ {
+		record(l, store.EventPut, "k")
+	}
+
+	// the changes buffered before the watcher fell behind are still delivered
+	for i := 0; i < watcherBuffer; i++ {
+		next(t, w)
+	}
+	if _, err := w.Next(context.Background()); err != ErrLagging {
+		t.Errorf("Next past the buffer: want ErrLagging, got %v", err)
+	}
+}
+
+func TestWatchStop(t *testing.T) {
+	l := NewLog(10)
+	w, err := l.Watch(Filter{Prefix: true}, 0)
+	if err != nil {
+		t.Fatalf("Watch: unexpected error: %v", err)
+	}
+	w.Stop()
+	w.Stop()
+	record(l, store.EventPut, "k")
+	if _, err := w.Next(context.Background()); err != ErrStopped {
+		t.Errorf("Next after Stop: want ErrStopped, got %v", err)
+	}
+}